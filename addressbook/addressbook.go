@@ -0,0 +1,384 @@
+package addressbook
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romanornr/autodealer/util"
+	"github.com/spf13/viper"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+)
+
+const (
+	// defaultPath is where the shared address book is kept unless ADDRESS_BOOK points elsewhere
+	defaultPath = "~/.autodealer/addressbook.json"
+	// DefaultCoolingOff is how long a new address waits before it can be withdrawn to
+	DefaultCoolingOff = 24 * time.Hour
+	// DefaultApprovalTTL is how long a withdrawal waits for approval before it expires
+	DefaultApprovalTTL = time.Hour
+	// DefaultApprovalWindow is how far back the withdrawals to an entry add up towards the approval threshold
+	DefaultApprovalWindow = 24 * time.Hour
+)
+
+var (
+	ErrInvalidEntry   = errors.New("address book entry needs a name, currency and address")
+	ErrEntryExists    = errors.New("address book entry already exists")
+	ErrEntryNotFound  = errors.New("address book entry not found")
+	ErrNotWhitelisted = errors.New("destination is not in the address book")
+	ErrCoolingOff     = errors.New("address is still cooling off")
+	ErrNotFound       = errors.New("withdrawal not found")
+	ErrNotPending     = errors.New("withdrawal is not pending approval")
+	ErrExpired        = errors.New("withdrawal approval expired")
+	ErrSelfApproval   = errors.New("withdrawal must be approved by someone other than who requested it")
+)
+
+// Entry is a whitelisted withdrawal destination
+type Entry struct {
+	Name     string        `json:"name"`
+	Currency currency.Code `json:"currency"`
+	// Chain is the network of the address, empty is the default network of the exchange
+	Chain   string    `json:"chain"`
+	Address string    `json:"address"`
+	Tag     string    `json:"tag,omitempty"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// Status is the state of a withdrawal that went through the address book
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+	StatusExpired  Status = "expired"
+	StatusExecuted Status = "executed"
+	StatusFailed   Status = "failed"
+)
+
+// Withdrawal is a withdrawal to an entry of the address book. Withdrawals that take what went to the entry within the approval
+// window above the threshold of their currency are kept pending until a second call approves them.
+type Withdrawal struct {
+	ID          string    `json:"id"`
+	Exchange    string    `json:"exchange"`
+	Entry       Entry     `json:"entry"`
+	Amount      float64   `json:"amount"`
+	Status      Status    `json:"status"`
+	RequestedAt time.Time `json:"requestedAt"`
+	RequestedBy string    `json:"requestedBy"`
+	DecidedAt   time.Time `json:"decidedAt,omitempty"`
+	DecidedBy   string    `json:"decidedBy,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	// Immediate is set when the withdrawal stayed below the threshold and was approved on request
+	Immediate bool `json:"immediate,omitempty"`
+}
+
+// document is the file the book is stored in
+type document struct {
+	Entries     []Entry      `json:"entries"`
+	Withdrawals []Withdrawal `json:"withdrawals"`
+}
+
+// Book is the address book, it is stored as a JSON file that is rewritten on every change.
+type Book struct {
+	// CoolingOff is how long a new entry waits before it can be withdrawn to
+	CoolingOff time.Duration
+	// ApprovalTTL is how long a pending withdrawal can be approved
+	ApprovalTTL time.Duration
+	// ApprovalWindow is how far back the withdrawals to an entry add up towards the threshold, zero only counts the withdrawal itself
+	ApprovalWindow time.Duration
+	// Threshold returns the amount of the currency from which withdrawals need approval, zero means never
+	Threshold func(code currency.Code) float64
+
+	path string
+	now  func() time.Time
+
+	mu  sync.Mutex
+	doc document
+}
+
+// Open opens the address book at the path, it is created on the first change when it does not exist
+func Open(path string) (*Book, error) {
+	b := &Book{
+		CoolingOff:     DefaultCoolingOff,
+		ApprovalTTL:    DefaultApprovalTTL,
+		ApprovalWindow: DefaultApprovalWindow,
+		Threshold:      func(currency.Code) float64 { return 0 },
+		path:           path,
+		now:            time.Now,
+	}
+
+	if err := util.ReadJSON(path, &b.doc); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+var (
+	book     *Book
+	bookErr  error
+	bookOnce sync.Once
+)
+
+// Default returns the address book shared by the application. It is kept at the path of the ADDRESS_BOOK environment variable,
+// or ~/.autodealer/addressbook.json. ADDRESS_BOOK_COOLING_OFF, WITHDRAW_APPROVAL_TTL and WITHDRAW_APPROVAL_WINDOW set the
+// durations, a key such as WITHDRAW_APPROVAL_BTC the amount of the currency from which withdrawals need approval.
+func Default() (*Book, error) {
+	bookOnce.Do(func() {
+		if book, bookErr = Open(util.StatePath("ADDRESS_BOOK", defaultPath)); bookErr != nil {
+			return
+		}
+
+		if viper.IsSet("ADDRESS_BOOK_COOLING_OFF") {
+			book.CoolingOff = viper.GetDuration("ADDRESS_BOOK_COOLING_OFF")
+		}
+		if viper.IsSet("WITHDRAW_APPROVAL_TTL") {
+			book.ApprovalTTL = viper.GetDuration("WITHDRAW_APPROVAL_TTL")
+		}
+		if viper.IsSet("WITHDRAW_APPROVAL_WINDOW") {
+			book.ApprovalWindow = viper.GetDuration("WITHDRAW_APPROVAL_WINDOW")
+		}
+		book.Threshold = func(code currency.Code) float64 {
+			return viper.GetFloat64("WITHDRAW_APPROVAL_" + code.Upper().String())
+		}
+	})
+	return book, bookErr
+}
+
+// Add puts the entry in the book, it can be withdrawn to once the cooling-off period has passed
+func (b *Book) Add(e Entry) (Entry, error) {
+	e.Name = strings.TrimSpace(e.Name)
+	e.Address = strings.TrimSpace(e.Address)
+	if e.Name == "" || e.Currency.IsEmpty() || e.Address == "" {
+		return e, ErrInvalidEntry
+	}
+	e.Currency = e.Currency.Upper()
+	e.AddedAt = b.now().UTC()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.entry(e.Name); ok {
+		return e, fmt.Errorf("%w: %s", ErrEntryExists, e.Name)
+	}
+	b.doc.Entries = append(b.doc.Entries, e)
+	return e, b.save()
+}
+
+// Remove takes the entry out of the book, pending withdrawals to it can't be approved anymore
+func (b *Book) Remove(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, e := range b.doc.Entries {
+		if strings.EqualFold(e.Name, name) {
+			b.doc.Entries = append(b.doc.Entries[:i], b.doc.Entries[i+1:]...)
+			return b.save()
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrEntryNotFound, name)
+}
+
+// Entries returns the entries sorted by name
+func (b *Book) Entries() []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entries := append([]Entry{}, b.doc.Entries...)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Resolve returns the entry the currency can be withdrawn to. The destination is the name of the entry, or its address
+// together with the chain. Entries that are still cooling off are returned with ErrCoolingOff.
+func (b *Book) Resolve(code currency.Code, chain, destination string) (Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.entry(destination)
+	if !ok || !e.Currency.Equal(code) {
+		ok = false
+		for _, x := range b.doc.Entries {
+			if x.Currency.Equal(code) && x.Address == destination && strings.EqualFold(x.Chain, chain) {
+				e, ok = x, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return Entry{}, fmt.Errorf("%w: %s %s", ErrNotWhitelisted, code, destination)
+	}
+	return e, b.usable(e)
+}
+
+// Request registers a withdrawal to the entry. It is approved right away when it stays below the approval threshold of the
+// currency together with what went to the entry within the approval window, otherwise it is kept pending until Approve
+// is called. Splitting a withdrawal doesn't get it past the threshold.
+func (b *Book) Request(exchangeName string, e Entry, amount float64, initiator string) (Withdrawal, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	w := Withdrawal{
+		ID:          uuid.NewString(),
+		Exchange:    exchangeName,
+		Entry:       e,
+		Amount:      amount,
+		Status:      StatusPending,
+		RequestedAt: b.now().UTC(),
+		RequestedBy: initiator,
+	}
+	if threshold := b.Threshold(e.Currency); threshold <= 0 || b.withdrawn(e, w.RequestedAt)+amount < threshold {
+		w.Status, w.Immediate = StatusApproved, true
+		w.DecidedAt, w.DecidedBy = w.RequestedAt, initiator
+	}
+
+	b.doc.Withdrawals = append(b.doc.Withdrawals, w)
+	return w, b.save()
+}
+
+// Approve approves a pending withdrawal, the entry it goes to must still be in the book and usable. The approver must be
+// known and differ from who requested the withdrawal.
+func (b *Book) Approve(id, initiator string) (Withdrawal, error) {
+	return b.decide(id, initiator, func(w *Withdrawal) error {
+		if initiator == "" || initiator == w.RequestedBy {
+			return fmt.Errorf("%w: %s", ErrSelfApproval, id)
+		}
+		e, ok := b.entry(w.Entry.Name)
+		if !ok || e.Address != w.Entry.Address || e.Tag != w.Entry.Tag {
+			return fmt.Errorf("%w: %s", ErrNotWhitelisted, w.Entry.Name)
+		}
+		if err := b.usable(e); err != nil {
+			return err
+		}
+		w.Status = StatusApproved
+		return nil
+	})
+}
+
+// Reject rejects a pending withdrawal
+func (b *Book) Reject(id, initiator string) (Withdrawal, error) {
+	return b.decide(id, initiator, func(w *Withdrawal) error {
+		w.Status = StatusRejected
+		return nil
+	})
+}
+
+// Complete records the outcome of an approved withdrawal
+func (b *Book) Complete(id string, err error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	w, ok := b.withdrawal(id)
+	if !ok {
+		return nil
+	}
+	w.Status = StatusExecuted
+	if err != nil {
+		w.Status, w.Error = StatusFailed, err.Error()
+	}
+	return b.save()
+}
+
+// Withdrawals returns the withdrawals that needed approval, most recent first
+func (b *Book) Withdrawals() []Withdrawal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var withdrawals []Withdrawal
+	for _, w := range b.doc.Withdrawals {
+		if w.Immediate {
+			continue
+		}
+		if b.expired(w) {
+			w.Status = StatusExpired
+		}
+		withdrawals = append(withdrawals, w)
+	}
+	sort.Slice(withdrawals, func(i, j int) bool {
+		return withdrawals[i].RequestedAt.After(withdrawals[j].RequestedAt)
+	})
+	return withdrawals
+}
+
+func (b *Book) decide(id, initiator string, f func(w *Withdrawal) error) (Withdrawal, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	w, ok := b.withdrawal(id)
+	if !ok {
+		return Withdrawal{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if b.expired(*w) {
+		w.Status = StatusExpired
+		if err := b.save(); err != nil {
+			return *w, err
+		}
+		return *w, fmt.Errorf("%w: %s", ErrExpired, id)
+	}
+	if w.Status != StatusPending {
+		return *w, fmt.Errorf("%w: %s is %s", ErrNotPending, id, w.Status)
+	}
+	if err := f(w); err != nil {
+		return *w, err
+	}
+
+	w.DecidedAt, w.DecidedBy = b.now().UTC(), initiator
+	return *w, b.save()
+}
+
+// usable returns ErrCoolingOff while the entry is cooling off
+func (b *Book) usable(e Entry) error {
+	if ready := e.AddedAt.Add(b.CoolingOff); b.now().Before(ready) {
+		return fmt.Errorf("%w: %s can be used from %s", ErrCoolingOff, e.Name, ready.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// withdrawn returns the amount requested to the entry within the approval window before the time. Withdrawals that were
+// rejected, expired or failed don't count.
+func (b *Book) withdrawn(e Entry, now time.Time) float64 {
+	var sum float64
+	for _, w := range b.doc.Withdrawals {
+		if !strings.EqualFold(w.Entry.Name, e.Name) || !w.Entry.Currency.Equal(e.Currency) || !now.Before(w.RequestedAt.Add(b.ApprovalWindow)) {
+			continue
+		}
+		if w.Status == StatusRejected || w.Status == StatusExpired || w.Status == StatusFailed || b.expired(w) {
+			continue
+		}
+		sum += w.Amount
+	}
+	return sum
+}
+
+func (b *Book) expired(w Withdrawal) bool {
+	return w.Status == StatusPending && b.ApprovalTTL > 0 && b.now().After(w.RequestedAt.Add(b.ApprovalTTL))
+}
+
+func (b *Book) entry(name string) (Entry, bool) {
+	for _, e := range b.doc.Entries {
+		if strings.EqualFold(e.Name, name) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+func (b *Book) withdrawal(id string) (*Withdrawal, bool) {
+	for i := range b.doc.Withdrawals {
+		if b.doc.Withdrawals[i].ID == id {
+			return &b.doc.Withdrawals[i], true
+		}
+	}
+	return nil, false
+}
+
+// save writes the book, a crash never leaves half a book behind
+func (b *Book) save() error {
+	return util.WriteJSON(b.path, b.doc)
+}
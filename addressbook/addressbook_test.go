@@ -0,0 +1,183 @@
+package addressbook
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+)
+
+func testBook(t *testing.T) (*Book, *time.Time, string) {
+	path := filepath.Join(t.TempDir(), "addressbook.json")
+	b, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1600000000, 0)
+	b.now = func() time.Time { return now }
+	b.Threshold = func(code currency.Code) float64 {
+		if code.Equal(currency.BTC) {
+			return 1
+		}
+		return 0
+	}
+	return b, &now, path
+}
+
+func TestResolve(t *testing.T) {
+	b, now, _ := testBook(t)
+
+	if _, err := b.Add(Entry{Name: "cold", Currency: currency.BTC, Chain: "btc", Address: "bc1cold"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Add(Entry{Name: "cold", Currency: currency.ETH, Address: "0xcold"}); !errors.Is(err, ErrEntryExists) {
+		t.Errorf("expected: %v, actual: %v", ErrEntryExists, err)
+	}
+
+	if _, err := b.Resolve(currency.BTC, "btc", "cold"); !errors.Is(err, ErrCoolingOff) {
+		t.Errorf("expected: %v, actual: %v", ErrCoolingOff, err)
+	}
+
+	*now = now.Add(b.CoolingOff)
+	for _, destination := range []string{"cold", "bc1cold"} {
+		e, err := b.Resolve(currency.BTC, "BTC", destination)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Address != "bc1cold" {
+			t.Errorf("expected: %v, actual: %v", "bc1cold", e.Address)
+		}
+	}
+
+	if _, err := b.Resolve(currency.BTC, "btc", "bc1other"); !errors.Is(err, ErrNotWhitelisted) {
+		t.Errorf("expected: %v, actual: %v", ErrNotWhitelisted, err)
+	}
+	if _, err := b.Resolve(currency.ETH, "", "cold"); !errors.Is(err, ErrNotWhitelisted) {
+		t.Errorf("expected: %v, actual: %v", ErrNotWhitelisted, err)
+	}
+}
+
+func TestApproval(t *testing.T) {
+	b, now, path := testBook(t)
+
+	e, err := b.Add(Entry{Name: "cold", Currency: currency.BTC, Address: "bc1cold"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(b.CoolingOff)
+
+	small, err := b.Request("binance", e, 0.5, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if small.Status != StatusApproved {
+		t.Errorf("expected: %v, actual: %v", StatusApproved, small.Status)
+	}
+
+	large, err := b.Request("binance", e, 2, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if large.Status != StatusPending {
+		t.Fatalf("expected: %v, actual: %v", StatusPending, large.Status)
+	}
+
+	// pending withdrawals survive a restart
+	b, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.now = func() time.Time { return *now }
+	b.Threshold = func(currency.Code) float64 { return 1 }
+
+	// the requester can't approve their own withdrawal
+	if _, err = b.Approve(large.ID, "api"); !errors.Is(err, ErrSelfApproval) {
+		t.Errorf("expected: %v, actual: %v", ErrSelfApproval, err)
+	}
+
+	approved, err := b.Approve(large.ID, "api other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status != StatusApproved || approved.DecidedBy != "api other" {
+		t.Errorf("expected: approved by api other, actual: %v by %v", approved.Status, approved.DecidedBy)
+	}
+	if _, err = b.Approve(large.ID, "api"); !errors.Is(err, ErrNotPending) {
+		t.Errorf("expected: %v, actual: %v", ErrNotPending, err)
+	}
+
+	expired, err := b.Request("binance", e, 3, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(b.ApprovalTTL + time.Second)
+	if _, err = b.Approve(expired.ID, "api"); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected: %v, actual: %v", ErrExpired, err)
+	}
+
+	removed, err := b.Request("binance", e, 4, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Remove("cold"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.Approve(removed.ID, "api other"); !errors.Is(err, ErrNotWhitelisted) {
+		t.Errorf("expected: %v, actual: %v", ErrNotWhitelisted, err)
+	}
+}
+
+func TestApprovalWindow(t *testing.T) {
+	b, now, _ := testBook(t)
+
+	e, err := b.Add(Entry{Name: "cold", Currency: currency.BTC, Address: "bc1cold"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(b.CoolingOff)
+
+	first, err := b.Request("binance", e, 0.6, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != StatusApproved {
+		t.Errorf("expected: %v, actual: %v", StatusApproved, first.Status)
+	}
+
+	// together with the first withdrawal the second one reaches the threshold
+	second, err := b.Request("binance", e, 0.6, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Status != StatusPending {
+		t.Errorf("expected: %v, actual: %v", StatusPending, second.Status)
+	}
+	if _, err = b.Reject(second.ID, "api other"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a rejected withdrawal doesn't count
+	third, err := b.Request("binance", e, 0.3, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Status != StatusApproved {
+		t.Errorf("expected: %v, actual: %v", StatusApproved, third.Status)
+	}
+
+	*now = now.Add(b.ApprovalWindow)
+	fourth, err := b.Request("binance", e, 0.6, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fourth.Status != StatusApproved {
+		t.Errorf("expected: %v, actual: %v", StatusApproved, fourth.Status)
+	}
+
+	if withdrawals := b.Withdrawals(); len(withdrawals) != 1 || withdrawals[0].ID != second.ID {
+		t.Errorf("expected: %v, actual: %v", second.ID, withdrawals)
+	}
+}
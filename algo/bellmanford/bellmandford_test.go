@@ -137,3 +137,22 @@ func TestWithPythonDataSource(t *testing.T) {
 		}
 	}
 }
+
+func TestCycleThroughAllVertices(t *testing.T) {
+	g := NewGraph([]*Edge{
+		NewEdge(0, 1, -math.Log(2)),
+		NewEdge(1, 2, -math.Log(1)),
+		NewEdge(2, 0, -math.Log(0.6)),
+	}, []uint{0, 1, 2})
+
+	loop := g.FindArbitrageLoop(0)
+	res := []uint{0, 2, 1, 0}
+	if len(loop) != len(res) {
+		t.Fatalf("loops have different lengths (%d != %d)", loop, res)
+	}
+	for i, v := range loop {
+		if res[i] != v {
+			t.Fatalf("incorrect arbitrage loop (%v != %v)\n", loop, res)
+		}
+	}
+}
@@ -69,7 +69,8 @@ func (g *Graph) FindNegativeWeightCycle(predecessors []uint, distances []float64
 
 func arbitrageLoop(predecessors []uint, source uint) []uint {
 	size := len(predecessors)
-	loop := make([]uint, size)
+	// a cycle through every vertex visits the source twice
+	loop := make([]uint, size+1)
 	loop[0] = source
 
 	exists := make([]bool, size)
@@ -0,0 +1,364 @@
+package iceberg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/orderbuilder"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+var (
+	ErrInvalidAmount = errors.New("iceberg total and display amount must be positive")
+	ErrInvalidSide   = errors.New("iceberg side must be buy or sell")
+)
+
+// Config describes an iceberg order
+type Config struct {
+	Exchange string
+	Pair     currency.Pair
+	Asset    asset.Item
+	Side     order.Side
+	// TotalAmount is the hidden base quantity that has to be executed in total
+	TotalAmount float64
+	// DisplayAmount is the base quantity of the visible order
+	DisplayAmount float64
+	// LimitPrice is the worst price the visible order is placed at, zero means no limit
+	LimitPrice float64
+	// ChaseTicks moves the visible order to the new top of book once the best price moved away by more than this amount of ticks, zero disables chasing
+	ChaseTicks int
+	// TickSize is used when the exchange does not report the price step of the pair
+	TickSize float64
+}
+
+// Strategy executes an iceberg order: a single visible limit order of the display size rests at the top of the book,
+// a new one is placed every time the previous one is filled until the total amount is executed.
+// The visible order is placed once the first order book update of the pair arrives.
+// Fills are observed through dealer.Slots stored as user data of every visible order, so the dealer calls back as soon as the order is filled.
+type Strategy struct {
+	config Config
+
+	mu sync.Mutex
+	// filled is the executed amount of all visible orders that are done
+	filled float64
+	// orderID, price, amount and executed describe the visible order that is resting on the exchange
+	orderID  string
+	price    float64
+	amount   float64
+	executed float64
+	// best is the last known top of book price on our side of the book
+	best float64
+	done chan struct{}
+}
+
+// New returns an iceberg strategy for the given configuration
+func New(c Config) (*Strategy, error) {
+	if c.TotalAmount <= 0 || c.DisplayAmount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if c.Side != order.Buy && c.Side != order.Sell {
+		return nil, ErrInvalidSide
+	}
+	if c.DisplayAmount > c.TotalAmount {
+		c.DisplayAmount = c.TotalAmount
+	}
+
+	return &Strategy{
+		config: c,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Progress returns the executed and the total amount
+func (s *Strategy) Progress() (float64, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filled + s.executed, s.config.TotalAmount
+}
+
+// Done is closed once the total amount has been executed
+func (s *Strategy) Done() <-chan struct{} {
+	return s.done
+}
+
+// remaining returns the amount that is neither executed nor resting on the exchange
+func (s *Strategy) remaining() float64 {
+	return s.config.TotalAmount - s.filled
+}
+
+func (s *Strategy) isFinished() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// matches reports whether the event concerns the pair of the iceberg
+func (s *Strategy) matches(e exchange.IBotExchange, p currency.Pair, a asset.Item) bool {
+	return e.GetName() == s.config.Exchange && a == s.config.Asset && p.Equal(s.config.Pair)
+}
+
+// tickSize returns the price step of the pair
+func (s *Strategy) tickSize(e exchange.IBotExchange) float64 {
+	if limits, err := e.GetOrderExecutionLimits(s.config.Asset, s.config.Pair); err == nil && limits.PriceStepIncrementSize > 0 {
+		return limits.PriceStepIncrementSize
+	}
+	return s.config.TickSize
+}
+
+// ShouldChase reports whether the best price moved away from the price of the visible order by more than the threshold.
+// A buy order chases a rising bid and a sell order a falling ask, a market moving towards the order fills it instead.
+func ShouldChase(side order.Side, price, best, threshold float64) bool {
+	if side == order.Buy {
+		return best-price > threshold
+	}
+	return price-best > threshold
+}
+
+// WithinLimit returns the price the visible order may be placed at, capped by the limit price
+func WithinLimit(side order.Side, best, limit float64) float64 {
+	switch {
+	case limit <= 0:
+		return best
+	case side == order.Buy:
+		return math.Min(best, limit)
+	default:
+		return math.Max(best, limit)
+	}
+}
+
+// place submits the next visible order at the given price. The caller must hold the lock.
+func (s *Strategy) place(d *dealer.Dealer, e exchange.IBotExchange, price float64) error {
+	amount := math.Min(s.config.DisplayAmount, s.remaining())
+	if limits, err := e.GetOrderExecutionLimits(s.config.Asset, s.config.Pair); err == nil {
+		amount = limits.ConformToAmount(amount)
+	}
+
+	if amount <= 0 {
+		// the rest is below the minimal order size of the exchange
+		s.finish()
+		return nil
+	}
+
+	ob := orderbuilder.NewOrderBuilder()
+	ob.
+		AtExchange(e.GetName()).
+		ForCurrencyPair(s.config.Pair).
+		WithAssetType(s.config.Asset).
+		ForPrice(price).
+		WithAmount(amount).
+		UseOrderType(order.Limit).
+		SetSide(s.config.Side)
+
+	o, err := ob.Build()
+	if err != nil {
+		return err
+	}
+
+	observer := &dealer.Slots{OnFilledSlot: s.onFilled}
+	response, err := d.SubmitOrderUD(context.Background(), e, *o, observer)
+	if err != nil {
+		return fmt.Errorf("failed to submit iceberg order: %w", err)
+	}
+
+	logrus.Printf("iceberg %s %s %f at %f placed %s\n", s.config.Side.Lower(), s.config.Pair, amount, price, response.OrderID)
+
+	s.orderID = response.OrderID
+	s.price = price
+	s.amount = amount
+	s.executed = 0
+
+	// the order may have crossed the book and be filled right away
+	if response.Status == order.Filled {
+		return s.filledLocked(d, e, response.OrderID, amount)
+	}
+	return nil
+}
+
+// onFilled is called by the dealer once the visible order is filled
+func (s *Strategy) onFilled(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	amount := x.ExecutedAmount
+	if amount == 0 {
+		amount = x.Amount
+	}
+
+	if err := s.filledLocked(d, e, x.OrderID, amount); err != nil {
+		logrus.Errorf("iceberg %s: %s\n", s.config.Pair, err)
+	}
+}
+
+// filledLocked books the fill of the visible order and replenishes it. The caller must hold the lock.
+func (s *Strategy) filledLocked(d *dealer.Dealer, e exchange.IBotExchange, orderID string, amount float64) error {
+	if orderID != s.orderID {
+		return nil
+	}
+
+	s.filled += amount
+	s.orderID = ""
+	s.executed = 0
+
+	if s.remaining() <= 0 {
+		s.finish()
+		return nil
+	}
+
+	price := s.best
+	if price == 0 {
+		price = s.price
+	}
+	return s.place(d, e, WithinLimit(s.config.Side, price, s.config.LimitPrice))
+}
+
+// finish marks the iceberg as done
+func (s *Strategy) finish() {
+	if !s.isFinished() {
+		logrus.Printf("iceberg %s %s done, %f executed\n", s.config.Side.Lower(), s.config.Pair, s.filled)
+		close(s.done)
+	}
+}
+
+// chase cancels the visible order and places the rest at the new price. The caller must hold the lock.
+func (s *Strategy) chase(d *dealer.Dealer, e exchange.IBotExchange, price float64) error {
+	err := d.CancelOrder(context.Background(), e, order.Cancel{
+		Exchange:  e.GetName(),
+		OrderID:   s.orderID,
+		Side:      s.config.Side,
+		AssetType: s.config.Asset,
+		Pair:      s.config.Pair,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel iceberg order %s: %w", s.orderID, err)
+	}
+
+	// whatever was executed before the cancellation counts towards the total
+	s.filled += s.executed
+	s.orderID = ""
+	s.executed = 0
+
+	if s.remaining() <= 0 {
+		s.finish()
+		return nil
+	}
+	return s.place(d, e, price)
+}
+
+func (s *Strategy) Init(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	return nil
+}
+
+func (s *Strategy) OnFunding(d *dealer.Dealer, e exchange.IBotExchange, x stream.FundingData) error {
+	return nil
+}
+
+func (s *Strategy) OnPrice(d *dealer.Dealer, e exchange.IBotExchange, x ticker.Price) error {
+	return nil
+}
+
+func (s *Strategy) OnKline(d *dealer.Dealer, e exchange.IBotExchange, x stream.KlineData) error {
+	return nil
+}
+
+// OnOrderBook places the first visible order at the top of the book and chases the best price when it moves away
+func (s *Strategy) OnOrderBook(d *dealer.Dealer, e exchange.IBotExchange, x orderbook.Base) error {
+	if !s.matches(e, x.Pair, x.Asset) || s.isFinished() {
+		return nil
+	}
+
+	levels := x.Bids
+	if s.config.Side == order.Sell {
+		levels = x.Asks
+	}
+	if len(levels) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.best = levels[0].Price
+	price := WithinLimit(s.config.Side, s.best, s.config.LimitPrice)
+
+	switch {
+	case s.orderID == "":
+		return s.place(d, e, price)
+	case s.config.ChaseTicks > 0 && ShouldChase(s.config.Side, s.price, price, float64(s.config.ChaseTicks)*s.tickSize(e)):
+		return s.chase(d, e, price)
+	}
+	return nil
+}
+
+// OnOrder keeps track of partial fills of the visible order
+func (s *Strategy) OnOrder(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) error {
+	if !s.matches(e, x.Pair, x.AssetType) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if x.OrderID == s.orderID && x.ExecutedAmount > s.executed {
+		s.executed = x.ExecutedAmount
+	}
+	return nil
+}
+
+func (s *Strategy) OnModify(d *dealer.Dealer, e exchange.IBotExchange, x order.Modify) error {
+	return nil
+}
+
+func (s *Strategy) OnBalanceChange(d *dealer.Dealer, e exchange.IBotExchange, x account.Change) error {
+	return nil
+}
+
+func (s *Strategy) OnTrade(d *dealer.Dealer, e exchange.IBotExchange, x []trade.Data) error {
+	return nil
+}
+
+func (s *Strategy) OnFill(d *dealer.Dealer, e exchange.IBotExchange, x []fill.Data) error {
+	return nil
+}
+
+func (s *Strategy) OnUnrecognized(d *dealer.Dealer, e exchange.IBotExchange, x interface{}) error {
+	return nil
+}
+
+// Deinit cancels the visible order
+func (s *Strategy) Deinit(d *dealer.Dealer, e exchange.IBotExchange) error {
+	if e.GetName() != s.config.Exchange {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.orderID == "" {
+		return nil
+	}
+
+	return d.CancelOrder(context.Background(), e, order.Cancel{
+		Exchange:  e.GetName(),
+		OrderID:   s.orderID,
+		Side:      s.config.Side,
+		AssetType: s.config.Asset,
+		Pair:      s.config.Pair,
+	})
+}
@@ -0,0 +1,69 @@
+package iceberg
+
+import (
+	"testing"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+func TestNew(t *testing.T) {
+	c := Config{
+		Exchange:      "Binance",
+		Pair:          currency.NewPair(currency.BTC, currency.USDT),
+		Asset:         asset.Spot,
+		Side:          order.Buy,
+		TotalAmount:   1,
+		DisplayAmount: 2,
+	}
+
+	s, err := New(c)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if s.config.DisplayAmount != 1 {
+		t.Errorf("expected: %f, actual: %f", 1.0, s.config.DisplayAmount)
+	}
+
+	c.TotalAmount = 0
+	if _, err = New(c); err != ErrInvalidAmount {
+		t.Errorf("expected: %v, actual: %v", ErrInvalidAmount, err)
+	}
+
+	c.TotalAmount = 1
+	c.Side = order.AnySide
+	if _, err = New(c); err != ErrInvalidSide {
+		t.Errorf("expected: %v, actual: %v", ErrInvalidSide, err)
+	}
+}
+
+func TestShouldChase(t *testing.T) {
+	if !ShouldChase(order.Buy, 100, 100.5, 0.3) {
+		t.Error("expected buy order to chase a rising bid")
+	}
+	if ShouldChase(order.Buy, 100, 100.2, 0.3) {
+		t.Error("expected buy order to stay within the threshold")
+	}
+	if ShouldChase(order.Buy, 100, 99, 0.3) {
+		t.Error("expected buy order not to chase a falling bid")
+	}
+	if !ShouldChase(order.Sell, 100, 99.5, 0.3) {
+		t.Error("expected sell order to chase a falling ask")
+	}
+	if ShouldChase(order.Sell, 100, 101, 0.3) {
+		t.Error("expected sell order not to chase a rising ask")
+	}
+}
+
+func TestWithinLimit(t *testing.T) {
+	if p := WithinLimit(order.Buy, 105, 100); p != 100 {
+		t.Errorf("expected: %f, actual: %f", 100.0, p)
+	}
+	if p := WithinLimit(order.Sell, 95, 100); p != 100 {
+		t.Errorf("expected: %f, actual: %f", 100.0, p)
+	}
+	if p := WithinLimit(order.Buy, 105, 0); p != 105 {
+		t.Errorf("expected: %f, actual: %f", 105.0, p)
+	}
+}
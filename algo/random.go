@@ -2,17 +2,14 @@ package algo
 
 import (
 	"math/rand"
-	"time"
 
 	"github.com/shopspring/decimal"
 )
 
+// RandomizeSize returns a random size between minimalSize and maximumSize rounded to two decimals
 func RandomizeSize(minimalSize, maximumSize float64) decimal.Decimal {
-	rand.Seed(time.Now().Unix())
 	x := rand.Float64()*(maximumSize-minimalSize) + minimalSize
-	r := decimal.NewFromFloat(x)
-	hundred := decimal.New(100, 1)
-	return r.Mul(hundred).Div(hundred)
+	return decimal.NewFromFloat(x).Round(2)
 }
 
 func RandFloats(min, max float64, n int) []float64 {
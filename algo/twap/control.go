@@ -0,0 +1,119 @@
+package twap
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/romanornr/autodealer/singleton"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"go.uber.org/multierr"
+)
+
+// Pause stops a running job. The remaining slices are dequeued and enqueued again when the job is resumed.
+func Pause(ctx context.Context, id string) (*Job, error) {
+	job, err := Jobs().Update(ctx, id, func(j *Job) error {
+		if j.Status != JobRunning {
+			return ErrJobNotRunning
+		}
+		j.Status = JobPaused
+		j.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return job, err
+	}
+
+	return job, dequeueSlices(ctx, job)
+}
+
+// Resume continues a paused job, the remaining slices are scheduled from now on at the original interval.
+func Resume(ctx context.Context, id string) (*Job, error) {
+	job, err := Jobs().Update(ctx, id, func(j *Job) error {
+		if j.Status != JobPaused {
+			return ErrJobNotPaused
+		}
+		j.Status = JobRunning
+		j.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return job, err
+	}
+
+	if err = enqueueSlices(ctx, job, job.RemainingSizes(), time.Now()); err != nil {
+		return job, err
+	}
+	return Jobs().Get(ctx, id)
+}
+
+// Cancel stops a job for good. The remaining slices are dequeued and child orders that are still resting on the exchange are cancelled.
+func Cancel(ctx context.Context, id string) (*Job, error) {
+	job, err := Jobs().Update(ctx, id, cancelJob)
+	if err != nil {
+		return job, err
+	}
+
+	err = multierr.Append(dequeueSlices(ctx, job), cancelChildren(ctx, job))
+	return job, err
+}
+
+// cancelJob marks the job cancelled, a job that already reached a terminal state is left as it is
+func cancelJob(j *Job) error {
+	if j.IsFinished() {
+		return ErrJobFinished
+	}
+	j.Status = JobCancelled
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+// dequeueSlices deletes the order tasks of the job that have not been processed yet
+func dequeueSlices(ctx context.Context, job *Job) error {
+	inspector := asynq.NewInspector(asynq.RedisClientOpt{Addr: redisAddr})
+	defer inspector.Close()
+
+	var err error
+	for _, taskID := range job.TaskIDs {
+		// tasks that already ran, or are running right now, can't be deleted anymore
+		if e := inspector.DeleteTask(queue, taskID); e != nil && !errors.Is(e, asynq.ErrTaskNotFound) && !errors.Is(e, asynq.ErrQueueNotFound) {
+			err = multierr.Append(err, e)
+		}
+	}
+
+	_, e := Jobs().Update(ctx, job.ID, func(j *Job) error {
+		j.TaskIDs = []string{}
+		return nil
+	})
+	return multierr.Append(err, e)
+}
+
+// cancelChildren cancels all child orders of the job that are still working
+func cancelChildren(ctx context.Context, job *Job) error {
+	d, err := singleton.GetDealer(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range job.Children {
+		if c.Filled || c.State.Terminal() {
+			continue
+		}
+
+		cancel := order.Cancel{
+			Exchange:  job.Payload.Exchange,
+			OrderID:   c.OrderID,
+			Side:      job.Payload.Side,
+			AssetType: job.Payload.Asset,
+			Pair:      job.Payload.Pair,
+		}
+
+		if e := d.CancelOrder(ctx, job.Payload.Exchange, cancel); e != nil {
+			logrus.Errorf("failed to cancel twap order %s: %s\n", c.OrderID, e)
+			err = multierr.Append(err, e)
+		}
+	}
+	return err
+}
@@ -0,0 +1,227 @@
+package twap
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romanornr/autodealer/dealer"
+)
+
+var (
+	ErrJobNotFound   = errors.New("twap job not found")
+	ErrJobNotRunning = errors.New("twap job is not running")
+	ErrJobNotPaused  = errors.New("twap job is not paused")
+	ErrJobFinished   = errors.New("twap job has already finished")
+	ErrSliceStarted  = errors.New("twap slice has already been submitted")
+)
+
+// JobStatus is the state of a TWAP job
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobPaused    JobStatus = "paused"
+	JobCancelled JobStatus = "cancelled"
+	JobCompleted JobStatus = "completed"
+)
+
+// ChildOrder is a single slice of a TWAP job that has been submitted to the exchange
+type ChildOrder struct {
+	OrderID     string    `json:"orderID"`
+	QuoteAmount float64   `json:"quoteAmount"`
+	Price       float64   `json:"price"`
+	FilledBase  float64   `json:"filledBase"`
+	FilledQuote float64   `json:"filledQuote"`
+	Filled      bool      `json:"filled"`
+	Time        time.Time `json:"time"`
+	// State is the state the order ended in, filled, cancelled or rejected, empty while it is working
+	State dealer.OrderState `json:"state,omitempty"`
+}
+
+// Job holds the state of a TWAP execution. It is persisted after every change, so progress survives restarts of the worker.
+type Job struct {
+	ID                string        `json:"id"`
+	Payload           Payload       `json:"payload"`
+	TargetAmountQuote float64       `json:"targetAmountQuote"`
+	FilledQuote       float64       `json:"filledQuote"`
+	FilledBase        float64       `json:"filledBase"`
+	AveragePrice      float64       `json:"averagePrice"`
+	Slices            int64         `json:"slices"`
+	SlicesDone        int64         `json:"slicesDone"`
+	SlicesSkipped     int64         `json:"slicesSkipped"`
+	SliceQuote        float64       `json:"sliceQuote"`
+	SliceSizes        []float64     `json:"sliceSizes"`
+	Interval          time.Duration `json:"interval"`
+	Children          []ChildOrder  `json:"children"`
+	TaskIDs           []string      `json:"taskIDs"`
+	// Started holds the task IDs of the slices whose order was sent, a retried task does not send it again
+	Started   []string  `json:"started"`
+	Status    JobStatus `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// NewJobID returns a new unique TWAP job ID
+func NewJobID() string {
+	return uuid.NewString()
+}
+
+// NewJob creates a running job for the given payload, the quote size of every slice and the interval between them
+func NewJob(p Payload, sizes []float64, interval time.Duration) *Job {
+	if p.ID == "" {
+		p.ID = NewJobID()
+	}
+
+	var sliceQuote float64
+	if len(sizes) > 0 {
+		sliceQuote = p.TargetAmountQuote / float64(len(sizes))
+	}
+
+	now := time.Now()
+	return &Job{
+		ID:                p.ID,
+		Payload:           p,
+		TargetAmountQuote: p.TargetAmountQuote,
+		Slices:            int64(len(sizes)),
+		SliceQuote:        sliceQuote,
+		SliceSizes:        sizes,
+		Interval:          interval,
+		Children:          []ChildOrder{},
+		TaskIDs:           []string{},
+		Started:           []string{},
+		Status:            JobRunning,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+}
+
+// OrderIDs returns the exchange order IDs of all child orders
+func (j *Job) OrderIDs() []string {
+	ids := make([]string, 0, len(j.Children))
+	for _, c := range j.Children {
+		ids = append(ids, c.OrderID)
+	}
+	return ids
+}
+
+// Remaining returns the amount of slices that have not been processed yet
+func (j *Job) Remaining() int64 {
+	if j.SlicesDone >= j.Slices {
+		return 0
+	}
+	return j.Slices - j.SlicesDone
+}
+
+// RemainingSizes returns the quote sizes of the slices that have not been processed yet
+func (j *Job) RemainingSizes() []float64 {
+	n := j.Remaining()
+	if int64(len(j.SliceSizes)) < j.Slices {
+		sizes := make([]float64, n)
+		for i := range sizes {
+			sizes[i] = j.SliceQuote
+		}
+		return sizes
+	}
+	return j.SliceSizes[int64(len(j.SliceSizes))-n:]
+}
+
+// IsFinished reports whether the job reached a terminal state
+func (j *Job) IsFinished() bool {
+	return j.Status == JobCancelled || j.Status == JobCompleted
+}
+
+// AddChild records a submitted child order and marks the slice as done. The dealer notifies the observer of the order as soon
+// as it is registered, so a fill may be recorded before the submission is, in that case the child already exists.
+func (j *Job) AddChild(orderID string, quoteAmount, price float64) {
+	if c := j.child(orderID); c != nil {
+		c.QuoteAmount = quoteAmount
+		c.Price = price
+	} else {
+		j.Children = append(j.Children, ChildOrder{
+			OrderID:     orderID,
+			QuoteAmount: quoteAmount,
+			Price:       price,
+			Time:        time.Now(),
+		})
+	}
+	j.SlicesDone++
+	j.touch()
+}
+
+// StartSlice records that the order of the slice with the task ID is about to be sent, ErrSliceStarted is returned when it
+// was sent before. Slices without a task ID are not recorded.
+func (j *Job) StartSlice(taskID string) error {
+	if taskID == "" {
+		return nil
+	}
+	for _, id := range j.Started {
+		if id == taskID {
+			return ErrSliceStarted
+		}
+	}
+	j.Started = append(j.Started, taskID)
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+// SkipSlice marks a slice as done without submitting an order, e.g. because the price moved beyond the limit price
+func (j *Job) SkipSlice() {
+	j.SlicesDone++
+	j.SlicesSkipped++
+	j.touch()
+}
+
+// ApplyFill records the cumulative execution of a child order and updates the filled amounts and average price of the job.
+// Fills never go backwards, a report that doesn't add to the execution of the order is ignored.
+func (j *Job) ApplyFill(orderID string, base, price float64) bool {
+	c := j.add(orderID, price)
+	if base <= c.FilledBase {
+		return false
+	}
+
+	j.FilledBase += base - c.FilledBase
+	j.FilledQuote += base*price - c.FilledQuote
+	c.FilledBase = base
+	c.FilledQuote = base * price
+	if j.FilledBase > 0 {
+		j.AveragePrice = j.FilledQuote / j.FilledBase
+	}
+	j.touch()
+	return true
+}
+
+// CloseChild records the state a child order ended in, what it filled until then stays on the job
+func (j *Job) CloseChild(orderID string, state dealer.OrderState) {
+	c := j.add(orderID, 0)
+	c.State = state
+	c.Filled = state == dealer.OrderFilled
+	j.touch()
+}
+
+// add returns the child order with the given order ID, it is added when the submission was not recorded yet
+func (j *Job) add(orderID string, price float64) *ChildOrder {
+	if c := j.child(orderID); c != nil {
+		return c
+	}
+	j.Children = append(j.Children, ChildOrder{OrderID: orderID, Price: price, Time: time.Now()})
+	return &j.Children[len(j.Children)-1]
+}
+
+// child returns the child order with the given order ID or nil
+func (j *Job) child(orderID string) *ChildOrder {
+	for i := range j.Children {
+		if j.Children[i].OrderID == orderID {
+			return &j.Children[i]
+		}
+	}
+	return nil
+}
+
+// touch updates the modification time and completes the job once every slice has been processed
+func (j *Job) touch() {
+	j.UpdatedAt = time.Now()
+	if j.Status == JobRunning && j.Remaining() == 0 {
+		j.Status = JobCompleted
+	}
+}
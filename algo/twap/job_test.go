@@ -0,0 +1,126 @@
+package twap
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+func TestJobProgress(t *testing.T) {
+	j := NewJob(Payload{TargetAmountQuote: 30}, []float64{10, 10, 10}, time.Minute)
+	if j.ID == "" {
+		t.Fatal("expected job ID to be set")
+	}
+
+	j.AddChild("1", 10, 100)
+	if j.Remaining() != 2 {
+		t.Errorf("expected: %d, actual: %d", 2, j.Remaining())
+	}
+
+	if !j.ApplyFill("1", 0.1, 100) {
+		t.Error("expected fill to be applied")
+	}
+	// the same fill reported twice is only counted once
+	if j.ApplyFill("1", 0.1, 100) {
+		t.Error("expected duplicate fill to be ignored")
+	}
+
+	// the observer may record a fill before the submission is recorded, fills are cumulative
+	j.ApplyFill("2", 0.02, 200)
+	j.AddChild("2", 10, 190)
+	j.ApplyFill("2", 0.05, 200)
+	j.CloseChild("2", dealer.OrderCancelled)
+	if len(j.Children) != 2 {
+		t.Errorf("expected: %d, actual: %d", 2, len(j.Children))
+	}
+	if c := j.child("2"); c.State != dealer.OrderCancelled || c.Filled || c.FilledBase != 0.05 {
+		t.Errorf("expected: cancelled after 0.05, actual: %v %v %f", c.State, c.Filled, c.FilledBase)
+	}
+
+	if j.FilledQuote != 20 {
+		t.Errorf("expected: %f, actual: %f", 20.0, j.FilledQuote)
+	}
+	if avg := 20 / 0.15; math.Abs(j.AveragePrice-avg) > 1e-9 {
+		t.Errorf("expected: %f, actual: %f", avg, j.AveragePrice)
+	}
+	if j.Status != JobRunning {
+		t.Errorf("expected: %s, actual: %s", JobRunning, j.Status)
+	}
+
+	if sizes := j.RemainingSizes(); len(sizes) != 1 {
+		t.Errorf("expected: %d, actual: %d", 1, len(sizes))
+	}
+
+	j.SkipSlice()
+	if j.Status != JobCompleted {
+		t.Errorf("expected: %s, actual: %s", JobCompleted, j.Status)
+	}
+	if j.SlicesSkipped != 1 {
+		t.Errorf("expected: %d, actual: %d", 1, j.SlicesSkipped)
+	}
+	if !j.IsFinished() {
+		t.Error("expected job to be finished")
+	}
+}
+
+func TestVolumeStrategy(t *testing.T) {
+	v := NewVolumeStrategy()
+	p := currency.NewPair(currency.BTC, currency.USDT)
+	now := time.Now()
+
+	v.Record(trade.Data{Exchange: "Binance", AssetType: asset.Spot, CurrencyPair: p, Price: 100, Amount: 2, Timestamp: now})
+	v.Record(trade.Data{Exchange: "Binance", AssetType: asset.Spot, CurrencyPair: p, Price: 100, Amount: 1, Timestamp: now.Add(-time.Hour)})
+	v.Record(trade.Data{Exchange: "Kraken", AssetType: asset.Spot, CurrencyPair: p, Price: 100, Amount: 5, Timestamp: now})
+
+	if q := v.QuoteVolume("Binance", asset.Spot, p, now.Add(-time.Minute)); q != 200 {
+		t.Errorf("expected: %f, actual: %f", 200.0, q)
+	}
+	if q := v.QuoteVolume("Binance", asset.Spot, p, now.Add(-2*time.Hour)); q != 300 {
+		t.Errorf("expected: %f, actual: %f", 300.0, q)
+	}
+}
+
+func TestStartSlice(t *testing.T) {
+	j := NewJob(Payload{TargetAmountQuote: 20}, []float64{10, 10}, time.Minute)
+
+	if err := j.StartSlice("task-0"); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+	// a retry of the task finds the slice started
+	if err := j.StartSlice("task-0"); !errors.Is(err, ErrSliceStarted) {
+		t.Errorf("expected: %v, actual: %v", ErrSliceStarted, err)
+	}
+	if err := j.StartSlice(""); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+	if len(j.Started) != 1 {
+		t.Errorf("expected: %v, actual: %v", 1, j.Started)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	for _, status := range []JobStatus{JobCompleted, JobCancelled} {
+		j := NewJob(Payload{TargetAmountQuote: 20}, []float64{10, 10}, time.Minute)
+		j.Status = status
+		if err := cancelJob(j); !errors.Is(err, ErrJobFinished) {
+			t.Errorf("expected: %v, actual: %v", ErrJobFinished, err)
+		}
+		if j.Status != status {
+			t.Errorf("expected: %v, actual: %v", status, j.Status)
+		}
+	}
+
+	for _, status := range []JobStatus{JobRunning, JobPaused} {
+		j := NewJob(Payload{TargetAmountQuote: 20}, []float64{10, 10}, time.Minute)
+		j.Status = status
+		if err := cancelJob(j); err != nil || j.Status != JobCancelled {
+			t.Errorf("expected: %v, actual: %v %v", JobCancelled, j.Status, err)
+		}
+	}
+}
@@ -3,30 +3,296 @@ package twap
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/hibiken/asynq"
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/orderbuilder"
+	"github.com/romanornr/autodealer/singleton"
 	"github.com/sirupsen/logrus"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
 )
 
-func NewOrderTask(order *order.Submit) (*asynq.Task, error) {
-	payload, err := json.Marshal(order)
+var ErrNoPrice = errors.New("no price available for pair")
+
+func NewOrderTask(payload OrderPayload) (*asynq.Task, error) {
+	data, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
-
-	//logrus.Printf("sending order %s\n", order.ID)
-
-	return asynq.NewTask(TypeOrder, payload), nil
+	return asynq.NewTask(TypeOrder, data), nil
 }
 
+// HandleOrderTask submits a single TWAP slice through the dealer.
+// The slice is priced from the live ticker, converted from quote to base quantity and submitted with a fill observer as user data,
+// so the order can be found back in the OrderRegistry and its execution is recorded on the job.
+// Slices of jobs that are paused or finished are skipped. The slice is recorded on the job before its order is sent,
+// so a retried task never submits the same slice twice.
 func HandleOrderTask(ctx context.Context, task *asynq.Task) error {
-	var o *order.Submit // order.Submit
-	err := json.Unmarshal(task.Payload(), &o)
+	var p OrderPayload
+	if err := json.Unmarshal(task.Payload(), &p); err != nil {
+		return err
+	}
+
+	job, err := Jobs().Get(ctx, p.JobID)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != JobRunning {
+		logrus.Printf("skipping slice of twap job %s: %s\n", job.ID, job.Status)
+		return nil
+	}
+
+	if p.QuoteAmount <= 0 {
+		return skipSlice(ctx, job.ID)
+	}
+
+	d, err := singleton.GetDealer(ctx)
+	if err != nil {
+		return err
+	}
+
+	e, err := d.GetExchangeByName(p.Exchange)
+	if err != nil {
+		return err
+	}
+
+	price, err := SlicePrice(ctx, e, p)
 	if err != nil {
 		return err
 	}
 
-	// example Submitting market buy order for BNB-USD amount 5.000000 at 11.000000 FTX
+	if !WithinLimit(job.Payload, price) {
+		logrus.Printf("skipping slice of twap job %s: price %f beyond limit %f\n", job.ID, price, job.Payload.LimitPrice)
+		return skipSlice(ctx, job.ID)
+	}
+
+	if job.Payload.MaxParticipation > 0 {
+		WatchVolume(d)
+
+		window := job.Interval
+		if window < time.Minute {
+			window = time.Minute
+		}
+
+		observed := Volume().QuoteVolume(p.Exchange, p.Asset, p.Pair, time.Now().Add(-window))
+		quote := CapSlice(p.QuoteAmount, observed, job.Payload.MaxParticipation)
+		if quote == 0 {
+			logrus.Printf("skipping slice of twap job %s: %f traded in the last %s is too little for a participation of %f\n", job.ID, observed, window, job.Payload.MaxParticipation)
+			return skipSlice(ctx, job.ID)
+		}
+		p.QuoteAmount = quote
+	}
+
+	if job.Payload.CapToFree {
+		free, err := freeQuote(ctx, e, p, price)
+		if err != nil {
+			return err
+		}
+		if free < p.QuoteAmount {
+			if free < minimalSliceQuote {
+				logrus.Printf("skipping slice of twap job %s: %f free is too little to trade\n", job.ID, free)
+				return skipSlice(ctx, job.ID)
+			}
+			p.QuoteAmount = free
+		}
+	}
+
+	o, err := BuildSliceOrder(e, p, price)
+	if err != nil {
+		return err
+	}
+
+	// the slice is recorded before its order is sent, a retry of the task must not send it twice
+	taskID, _ := asynq.GetTaskID(ctx)
+	if _, err = Jobs().Update(ctx, p.JobID, func(j *Job) error { return j.StartSlice(taskID) }); err != nil {
+		if errors.Is(err, ErrSliceStarted) {
+			logrus.Printf("skipping slice %s of twap job %s: already submitted\n", taskID, job.ID)
+			return nil
+		}
+		return err
+	}
+
+	// example Submitting market buy order for BNB-USD amount 0.021000 at 238.000000 Binance
 	logrus.Printf("Submitting %s %s order for %s amount %f at %f %s\n", o.Type.Lower(), o.Side.Lower(), o.Pair.String(), o.Amount, o.Price, o.Exchange)
+
+	// from here on the order may have reached the exchange, errors are logged and the task is not retried
+	response, err := d.SubmitOrderUD(ctx, e, *o, &childObserver{payload: p})
+	if err != nil {
+		logrus.Errorf("failed to submit twap order of job %s: %s\n", job.ID, err)
+		if e := skipSlice(ctx, job.ID); e != nil {
+			logrus.Errorf("failed to skip slice of twap job %s: %s\n", job.ID, e)
+		}
+		return fmt.Errorf("failed to submit twap order: %s: %w", err, asynq.SkipRetry)
+	}
+
+	logrus.Printf("twap order %s placed %s\n", response.OrderID, response.Status.String())
+
+	_, err = Jobs().Update(ctx, p.JobID, func(j *Job) error {
+		j.AddChild(response.OrderID, p.QuoteAmount, price)
+
+		// market orders are usually filled by the time the exchange responds, the dealer does not notify the fills of the response
+		if base, quote := dealer.Executed(response, price); base > 0 {
+			j.ApplyFill(response.OrderID, base, quote/base)
+		}
+		if response.Status == order.Filled {
+			j.CloseChild(response.OrderID, dealer.OrderFilled)
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Errorf("failed to record twap order %s on job %s: %s\n", response.OrderID, job.ID, err)
+	}
 	return nil
 }
+
+// skipSlice marks a slice of the job as done without placing an order
+func skipSlice(ctx context.Context, jobID string) error {
+	_, err := Jobs().Update(ctx, jobID, func(j *Job) error {
+		j.SkipSlice()
+		return nil
+	})
+	return err
+}
+
+// WithinLimit reports whether a slice may execute at the given price. Buys are skipped above and sells below the limit price.
+func WithinLimit(p Payload, price float64) bool {
+	switch {
+	case p.LimitPrice <= 0:
+		return true
+	case p.Side == order.Buy:
+		return price <= p.LimitPrice
+	case p.Side == order.Sell:
+		return price >= p.LimitPrice
+	}
+	return true
+}
+
+// CapSlice limits the slice to the given fraction of the observed volume.
+// Zero is returned when the capped slice would fall below minimalSliceQuote, the slice should be skipped in that case.
+func CapSlice(quote, observed, participation float64) float64 {
+	allowed := observed * participation
+	switch {
+	case quote <= allowed:
+		return quote
+	case allowed < minimalSliceQuote:
+		return 0
+	default:
+		return allowed
+	}
+}
+
+// freeQuote returns the free balance the slice spends valued in quote currency, the quote currency itself when buying
+// and the base currency at the price when selling
+func freeQuote(ctx context.Context, e exchange.IBotExchange, p OrderPayload, price float64) (float64, error) {
+	if p.Side == order.Buy {
+		return dealer.FreeBalance(ctx, e, p.Asset, p.Pair.Quote)
+	}
+	free, err := dealer.FreeBalance(ctx, e, p.Asset, p.Pair.Base)
+	return free * price, err
+}
+
+// childObserver is stored as user data of every child order in the OrderRegistry, the dealer calls it for every fill of the
+// order and once the order is filled, cancelled or rejected.
+type childObserver struct {
+	payload OrderPayload
+}
+
+// OnPartialFill records the execution of the child order so far on its job
+func (c *childObserver) OnPartialFill(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) {
+	c.record(x, "")
+}
+
+// OnFilled records the execution of the child order on its job
+func (c *childObserver) OnFilled(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) {
+	if x.ExecutedAmount == 0 {
+		x.ExecutedAmount = x.Amount
+	}
+	c.record(x, dealer.OrderFilled)
+}
+
+// OnCancelled records that the child order was cancelled, with what it filled before
+func (c *childObserver) OnCancelled(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) {
+	c.record(x, dealer.OrderCancelled)
+}
+
+// OnRejected records that the exchange rejected the child order
+func (c *childObserver) OnRejected(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) {
+	c.record(x, dealer.OrderRejected)
+}
+
+// record applies the cumulative execution of the child order to its job, and the state it ended in when it is done
+func (c *childObserver) record(x order.Detail, state dealer.OrderState) {
+	_, err := Jobs().Update(context.Background(), c.payload.JobID, func(j *Job) error {
+		if x.ExecutedAmount > 0 {
+			j.ApplyFill(x.OrderID, x.ExecutedAmount, fillPrice(x.AverageExecutedPrice, x.Cost, x.ExecutedAmount, x.Price))
+		}
+		if state != "" {
+			j.CloseChild(x.OrderID, state)
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Errorf("failed to record execution of twap order %s: %s\n", x.OrderID, err)
+	}
+}
+
+// fillPrice returns the execution price of an order, derived from its cost when the exchange does not report it.
+func fillPrice(price, cost, amount, fallback float64) float64 {
+	switch {
+	case price > 0:
+		return price
+	case cost > 0 && amount > 0:
+		return cost / amount
+	default:
+		return fallback
+	}
+}
+
+// SlicePrice returns the price the slice is expected to execute at. Buys are priced at the ask and sells at the bid,
+// the last traded price is used when the exchange does not return the top of book in its ticker.
+func SlicePrice(ctx context.Context, e exchange.IBotExchange, p OrderPayload) (float64, error) {
+	t, err := e.UpdateTicker(ctx, p.Pair, p.Asset)
+	if err != nil {
+		return 0, err
+	}
+
+	price := t.Last
+	switch {
+	case p.Side == order.Buy && t.Ask > 0:
+		price = t.Ask
+	case p.Side == order.Sell && t.Bid > 0:
+		price = t.Bid
+	}
+
+	if price <= 0 {
+		return 0, fmt.Errorf("%w %s", ErrNoPrice, p.Pair)
+	}
+	return price, nil
+}
+
+// BuildSliceOrder converts the quote denominated slice into an order for the base quantity at the given price.
+// The quantity is conformed to the exchange execution limits when they are known.
+func BuildSliceOrder(e exchange.IBotExchange, p OrderPayload, price float64) (*order.Submit, error) {
+	qty := p.QuoteAmount / price
+
+	if limits, err := e.GetOrderExecutionLimits(p.Asset, p.Pair); err == nil {
+		qty = limits.ConformToAmount(qty)
+	}
+
+	ob := orderbuilder.NewOrderBuilder()
+	ob.
+		AtExchange(e.GetName()).
+		ForCurrencyPair(p.Pair).
+		WithAssetType(p.Asset).
+		ForPrice(price).
+		WithAmount(qty).
+		UseOrderType(p.OrderType).
+		SetSide(p.Side)
+
+	return ob.Build()
+}
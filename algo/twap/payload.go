@@ -1,6 +1,7 @@
 package twap
 
 import (
+	"github.com/romanornr/autodealer/util"
 	"github.com/thrasher-corp/gocryptotrader/currency"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
@@ -9,6 +10,7 @@ import (
 
 // Payload is the payload for the TWAP algorithm
 type Payload struct {
+	ID                string
 	Exchange          string
 	AccountID         string
 	Pair              currency.Pair
@@ -19,4 +21,41 @@ type Payload struct {
 	Side              order.Side
 	OrderType         order.Type
 	Status            string
+
+	// MinSliceQuote and MaxSliceQuote randomize the size of every slice between the two bounds, slices are equally sized when unset
+	MinSliceQuote float64
+	MaxSliceQuote float64
+	// Jitter shifts the execution time of every slice by a random duration of at most Jitter in either direction
+	Jitter time.Duration
+	// LimitPrice skips slices while buying above or selling below this price, zero means no limit
+	LimitPrice float64
+	// MaxParticipation caps every slice to this fraction of the trade volume observed since the previous slice, zero means no cap
+	MaxParticipation float64
+	// CapToFree caps every slice to the free balance it spends, the quote currency when buying and the base when selling
+	CapToFree bool
+}
+
+// MarshalJSON encodes the side as a string
+func (p Payload) MarshalJSON() ([]byte, error) {
+	type alias Payload
+	return util.MarshalSide(alias(p), "Side", p.Side)
+}
+
+// OrderPayload is the payload of a single child order of the TWAP algorithm.
+// The size is expressed in quote currency and converted to base quantity at the time the order is submitted.
+type OrderPayload struct {
+	JobID       string
+	Exchange    string
+	AccountID   string
+	Pair        currency.Pair
+	Asset       asset.Item
+	Side        order.Side
+	OrderType   order.Type
+	QuoteAmount float64
+}
+
+// MarshalJSON encodes the side as a string
+func (p OrderPayload) MarshalJSON() ([]byte, error) {
+	type alias OrderPayload
+	return util.MarshalSide(alias(p), "Side", p.Side)
 }
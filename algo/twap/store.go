@@ -0,0 +1,115 @@
+package twap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	jobKeyPrefix = "autodealer:twap:job:"
+	jobIndexKey  = "autodealer:twap:jobs"
+)
+
+// Store persists TWAP jobs in Redis, next to the asynq queues that execute them.
+// Every job is stored as a JSON document under its own key and the IDs of all jobs are kept in a set.
+type Store struct {
+	client redis.UniversalClient
+	// mu serializes read-modify-write cycles of Update within this process
+	mu sync.Mutex
+}
+
+// NewStore returns a job store using the given redis client
+func NewStore(client redis.UniversalClient) *Store {
+	return &Store{client: client}
+}
+
+var (
+	defaultStore     *Store
+	defaultStoreOnce sync.Once
+)
+
+// Jobs returns the job store shared by the task handlers and the webserver
+func Jobs() *Store {
+	defaultStoreOnce.Do(func() {
+		defaultStore = NewStore(redis.NewClient(&redis.Options{Addr: redisAddr}))
+	})
+	return defaultStore
+}
+
+// Save writes the job to redis
+func (s *Store) Save(ctx context.Context, j *Job) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, jobKeyPrefix+j.ID, data, 0)
+		pipe.SAdd(ctx, jobIndexKey, j.ID)
+		return nil
+	})
+	return err
+}
+
+// Get loads a job by its ID
+func (s *Store) Get(ctx context.Context, id string) (*Job, error) {
+	data, err := s.client.Get(ctx, jobKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var j Job
+	if err = json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// List returns all known jobs, most recent first
+func (s *Store) List(ctx context.Context) ([]*Job, error) {
+	ids, err := s.client.SMembers(ctx, jobIndexKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(ids))
+	for _, id := range ids {
+		j, err := s.Get(ctx, id)
+		if errors.Is(err, ErrJobNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].CreatedAt.After(jobs[b].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// Update loads the job, applies f and saves the result. Nothing is saved when f returns an error.
+func (s *Store) Update(ctx context.Context, id string, f func(j *Job) error) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = f(j); err != nil {
+		return j, err
+	}
+	return j, s.Save(ctx, j)
+}
@@ -12,7 +12,11 @@ const (
 )
 
 // NewTwapTask represents a task for TWAP algorithm.
+// The payload gets a job ID when it has none, so a retried task finds the job it already started.
 func NewTwapTask(twap Payload) (*asynq.Task, error) {
+	if twap.ID == "" {
+		twap.ID = NewJobID()
+	}
 	payload, err := json.Marshal(twap)
 	if err != nil {
 		return nil, err
@@ -20,7 +24,7 @@ func NewTwapTask(twap Payload) (*asynq.Task, error) {
 	return asynq.NewTask(TypeTwap, payload), nil
 }
 
-// HandleTwapTask handles a task for TWAP algorithm.
+// HandleTwapTask handles a task for TWAP algorithm. A retry of the task returns early once the job exists.
 func HandleTwapTask(ctx context.Context, t *asynq.Task) error {
 	var p Payload
 	err := json.Unmarshal(t.Payload(), &p)
@@ -28,7 +32,6 @@ func HandleTwapTask(ctx context.Context, t *asynq.Task) error {
 		return err
 	}
 
-	go Execute(p)
-
-	return nil
+	_, err = Execute(ctx, p)
+	return err
 }
@@ -1,85 +1,243 @@
 package twap
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
 	"github.com/hibiken/asynq"
-	"github.com/romanornr/autodealer/orderbuilder"
+	"github.com/romanornr/autodealer/algo"
+	"github.com/romanornr/autodealer/singleton"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
-	"time"
+	"go.uber.org/multierr"
 )
 
-// Execute executes the TWAP algorithm
-// We can improve the algorithm by using a queue of orders and processing them in a separate goroutine TODO
-func Execute(t Payload) {
-	// minimal size in dollars for each order
-	minimalSize := decimal.NewFromFloat(5)
+const (
+	redisAddr = "127.0.0.1:6379"
+	queue     = "default"
 
-	// convert the target amount to a decimal
-	targetQuote := decimal.NewFromFloat(t.TargetAmountQuote)
-
-	// Calculate the average size of the order in dollars of each minute
-	averageSizeFillPerMinute := AverageSizeFillPerMinute(t.Start, t.End, targetQuote)
+	// minimalSliceQuote is the minimal size in quote currency of each child order
+	minimalSliceQuote = 5
+)
 
-	// minutes it takes to fill target amount (ie 900 minutes)
-	targetQuoteFillTimeMinutes := targetQuote.Div(averageSizeFillPerMinute)
+var (
+	ErrInvalidSchedule      = errors.New("twap end time must be after the start time")
+	ErrInvalidSliceSize     = errors.New("twap minimal slice size must be positive and below the maximal slice size")
+	ErrInvalidParticipation = errors.New("twap participation rate must be between 0 and 1")
+)
 
-	// Amount of orders it takes to fill target amount
-	amountOfOrders := targetQuote.Div(minimalSize)
+// Execute executes the TWAP algorithm
+// The target amount is split in slices which are enqueued as TypeOrder tasks at evenly spaced times between start and end.
+// Without slice bounds the slices are equally sized, at most one slice per minute is scheduled and no slice is smaller than minimalSliceQuote.
+// With MinSliceQuote and MaxSliceQuote set every slice gets a random size between the bounds, and Jitter randomizes the execution times,
+// so large orders do not leave a predictable footprint in the order book.
+// The job is persisted before any slice is enqueued, so its progress can be followed and controlled through the job store.
+func Execute(ctx context.Context, t Payload) (*Job, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
 
-	// minutes in between each order
-	minutesBetweenOrders := targetQuoteFillTimeMinutes.Div(amountOfOrders)
+	sizes, interval, err := SliceSizes(t)
+	if err != nil {
+		return nil, err
+	}
 
-	qty := minimalSize.InexactFloat64()
+	return ExecuteSlices(ctx, t, sizes, interval)
+}
 
-	var nextExecutionTime = t.Start
+// ExecuteSlices starts a job that executes the given quote sizes one interval apart from the start of the payload.
+// It allows other execution algorithms, like VWAP, to decide on the size of every slice while reusing the TWAP job plumbing.
+// Slices with a size of zero are skipped when they are due.
+func ExecuteSlices(ctx context.Context, t Payload, sizes []float64, interval time.Duration) (*Job, error) {
+	if t.MaxParticipation > 0 {
+		d, err := singleton.GetDealer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		WatchVolume(d)
+	}
 
-	logrus.Printf("minutes it takes to fill target amount %s\n: ", targetQuoteFillTimeMinutes.String())
-	logrus.Printf("amount of orders it takes to fill target amount %s\n: ", amountOfOrders.String())
+	// asynq retries a failed task with the same payload, the job it started is not started again
+	if t.ID != "" {
+		if job, err := Jobs().Get(ctx, t.ID); err == nil {
+			logrus.Printf("twap %s already started, not starting it again\n", job.ID)
+			return job, nil
+		} else if !errors.Is(err, ErrJobNotFound) {
+			return nil, err
+		}
+	}
 
-	client := asynq.NewClient(asynq.RedisClientOpt{Addr: "localhost:6379"})
+	job := NewJob(t, sizes, interval)
+	if err := Jobs().Save(ctx, job); err != nil {
+		return nil, err
+	}
 
-	for amountOfOrders.Cmp(decimal.Zero) > 0 {
+	logrus.Printf("twap %s %s %s: %d slices of %s on average every %s\n", job.ID, t.Exchange, t.Pair, job.Slices, decimal.NewFromFloat(job.SliceQuote).StringFixed(2), interval)
 
-		// create a new order
-		// if nextExecutionTime is before t.End
-		// execute order
+	return job, enqueueSlices(ctx, job, sizes, t.Start)
+}
 
-		// next execution time is nextExecutionTime + minutesBetweenOrders
-		nextExecutionTime = nextExecutionTime.Add(time.Minute * time.Duration(minutesBetweenOrders.IntPart()))
+// SliceTaskID returns the task ID of the slice with the index within the job, asynq rejects a slice that is enqueued twice
+func SliceTaskID(jobID string, index int64) string {
+	return fmt.Sprintf("%s-%d", jobID, index)
+}
 
-		// amountOfOrders--
-		amountOfOrders = amountOfOrders.Sub(decimal.NewFromFloat(1))
-		logrus.Printf("Order placed in queue. Next execution time: %v\n", nextExecutionTime)
+// enqueueSlices schedules the slices of the given quote sizes starting at the given time and records the task IDs on the job,
+// so the remaining tasks can be dequeued when the job is paused or cancelled. The sizes are the last slices of the job.
+func enqueueSlices(ctx context.Context, job *Job, sizes []float64, start time.Time) error {
+	client := asynq.NewClient(asynq.RedisClientOpt{Addr: redisAddr})
+	defer client.Close()
+
+	taskIDs := make([]string, 0, len(sizes))
+	jitter := algo.RandFloats(-float64(job.Payload.Jitter), float64(job.Payload.Jitter), len(sizes))
+	nextExecutionTime := start
+	first := job.Slices - int64(len(sizes))
+
+	// the tasks enqueued so far are recorded even when enqueueing fails halfway
+	record := func() error {
+		_, err := Jobs().Update(ctx, job.ID, func(j *Job) error {
+			j.TaskIDs = append(j.TaskIDs, taskIDs...)
+			return nil
+		})
+		return err
+	}
 
-		ob := orderbuilder.NewOrderBuilder()
-		ob.
-			AtExchange(t.Exchange).
-			//ForAccountID(t.AccountID).
-			ForCurrencyPair(t.Pair).
-			WithAssetType(t.Asset).
-			ForPrice(11).
-			WithAmount(qty).
-			UseOrderType(t.OrderType).
-			SetSide(t.Side)
-		//
-		newOrder, err := ob.Build()
+	for i, size := range sizes {
+		task, err := NewOrderTask(OrderPayload{
+			JobID:       job.ID,
+			Exchange:    job.Payload.Exchange,
+			AccountID:   job.Payload.AccountID,
+			Pair:        job.Payload.Pair,
+			Asset:       job.Payload.Asset,
+			Side:        job.Payload.Side,
+			OrderType:   job.Payload.OrderType,
+			QuoteAmount: size,
+		})
 		if err != nil {
-			logrus.Errorf("failed to build order: %v", err)
-			return
+			return multierr.Append(err, record())
 		}
 
-		t1, err := NewOrderTask(newOrder)
+		// slices are never moved before the start of the schedule
+		at := nextExecutionTime.Add(time.Duration(jitter[i]))
+		if at.Before(start) {
+			at = start
+		}
+		nextExecutionTime = nextExecutionTime.Add(job.Interval)
+
+		taskID := SliceTaskID(job.ID, first+int64(i))
+		info, err := client.EnqueueContext(ctx, task, asynq.Queue(queue), asynq.ProcessAt(at), asynq.TaskID(taskID))
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			logrus.Printf("Order task %s is already enqueued\n", taskID)
+			taskIDs = append(taskIDs, taskID)
+			continue
+		}
 		if err != nil {
-			logrus.Errorf("Error creating order task: %v", err)
+			return multierr.Append(err, record())
 		}
+		logrus.Printf("Order task enqueued: %s at %v\n", info.ID, at)
 
-		info, err := client.Enqueue(t1, asynq.ProcessAt(nextExecutionTime))
-		if err != nil {
-			logrus.Errorf("Error enqueuing order task: %v", err)
+		taskIDs = append(taskIDs, info.ID)
+	}
+
+	return record()
+}
+
+// Validate checks the optional parameters of the payload
+func (p Payload) Validate() error {
+	if p.MinSliceQuote < 0 || p.MaxSliceQuote < 0 || (p.MaxSliceQuote > 0 && p.MinSliceQuote > p.MaxSliceQuote) {
+		return ErrInvalidSliceSize
+	}
+	if p.MaxParticipation < 0 || p.MaxParticipation > 1 {
+		return ErrInvalidParticipation
+	}
+	return nil
+}
+
+// SliceSizes returns the quote size of every slice and the time in between them.
+// Slices are randomized between MinSliceQuote and MaxSliceQuote when both are set, otherwise the target is split by Schedule.
+func SliceSizes(p Payload) ([]float64, time.Duration, error) {
+	target := decimal.NewFromFloat(p.TargetAmountQuote)
+
+	if p.MinSliceQuote > 0 && p.MaxSliceQuote > 0 {
+		duration := p.End.Sub(p.Start)
+		if duration <= 0 {
+			return nil, 0, ErrInvalidSchedule
 		}
-		logrus.Printf("Order task enqueued: %s\n", info.ID)
+
+		sizes := RandomSlices(target, p.MinSliceQuote, p.MaxSliceQuote)
+		return sizes, duration / time.Duration(len(sizes)), nil
+	}
+
+	slices, interval, err := Schedule(p.Start, p.End, target)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sliceQuote := target.Div(decimal.NewFromInt(slices)).InexactFloat64()
+	sizes := make([]float64, slices)
+	for i := range sizes {
+		sizes[i] = sliceQuote
+	}
+	return sizes, interval, nil
+}
+
+// RandomSlices splits the target in slices of a random size between min and max.
+// The amount of slices is chosen so the sizes average around the middle of the bounds,
+// every size is then drawn from the range that still allows the remainder to be split within the bounds.
+func RandomSlices(target decimal.Decimal, min, max float64) []float64 {
+	total := target.InexactFloat64()
+	if total <= max {
+		return []float64{total}
+	}
+
+	n := int(math.Round(total / ((min + max) / 2)))
+	if lowest := int(math.Ceil(total / max)); n < lowest {
+		n = lowest
+	}
+	if highest := int(math.Floor(total / min)); n > highest && highest > 0 {
+		n = highest
+	}
+
+	sizes := make([]float64, 0, n)
+	remaining := target
+	for i := n - 1; i > 0; i-- {
+		rest := float64(i)
+		r := remaining.InexactFloat64()
+
+		// round the bounds inwards to cents, RandomizeSize rounds to cents as well
+		lo := math.Ceil(math.Max(min, r-rest*max)*100) / 100
+		hi := math.Floor(math.Min(max, r-rest*min)*100) / 100
+
+		size := decimal.NewFromFloat(r / (rest + 1)).Round(2)
+		if lo <= hi {
+			size = algo.RandomizeSize(lo, hi)
+		}
+		sizes = append(sizes, size.InexactFloat64())
+		remaining = remaining.Sub(size)
+	}
+	return append(sizes, remaining.InexactFloat64())
+}
+
+// Schedule returns the amount of slices and the time in between them to fill the target amount between start and end.
+func Schedule(start, end time.Time, targetQuote decimal.Decimal) (int64, time.Duration, error) {
+	duration := end.Sub(start)
+	if duration <= 0 {
+		return 0, 0, ErrInvalidSchedule
+	}
+
+	// the amount of slices is bound by the minimal slice size and by one slice per minute
+	slices := targetQuote.Div(decimal.NewFromInt(minimalSliceQuote)).IntPart()
+	if minutes := int64(duration / time.Minute); slices > minutes {
+		slices = minutes
+	}
+	if slices < 1 {
+		slices = 1
 	}
 
+	return slices, duration / time.Duration(slices), nil
 }
 
 // AverageSizeFillPerMinute returns the average size of the order that has to be filled per second to reach the target amount
@@ -0,0 +1,167 @@
+package twap
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+func TestSchedule(t *testing.T) {
+	start := time.Now()
+
+	slices, interval, err := Schedule(start, start.Add(time.Hour), decimal.NewFromInt(100))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if slices != 20 {
+		t.Errorf("expected: %d, actual: %d", 20, slices)
+	}
+	if interval != 3*time.Minute {
+		t.Errorf("expected: %s, actual: %s", 3*time.Minute, interval)
+	}
+
+	// one slice per minute at most
+	slices, _, err = Schedule(start, start.Add(10*time.Minute), decimal.NewFromInt(1000))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if slices != 10 {
+		t.Errorf("expected: %d, actual: %d", 10, slices)
+	}
+
+	// below the minimal slice size a single order is placed
+	slices, _, err = Schedule(start, start.Add(time.Hour), decimal.NewFromInt(2))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if slices != 1 {
+		t.Errorf("expected: %d, actual: %d", 1, slices)
+	}
+
+	if _, _, err = Schedule(start, start, decimal.NewFromInt(100)); err != ErrInvalidSchedule {
+		t.Errorf("expected: %v, actual: %v", ErrInvalidSchedule, err)
+	}
+}
+
+func TestOrderPayloadJSON(t *testing.T) {
+	p := OrderPayload{
+		Exchange:    "Binance",
+		Pair:        currency.NewPairWithDelimiter("BTC", "USDT", "-"),
+		Asset:       asset.Spot,
+		Side:        order.Sell,
+		OrderType:   order.Market,
+		QuoteAmount: 25,
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var decoded OrderPayload
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if decoded.Side != p.Side {
+		t.Errorf("expected: %s, actual: %s", p.Side, decoded.Side)
+	}
+	if !decoded.Pair.Equal(p.Pair) {
+		t.Errorf("expected: %s, actual: %s", p.Pair, decoded.Pair)
+	}
+	if decoded.QuoteAmount != p.QuoteAmount {
+		t.Errorf("expected: %f, actual: %f", p.QuoteAmount, decoded.QuoteAmount)
+	}
+}
+
+func TestRandomSlices(t *testing.T) {
+	target := decimal.NewFromInt(1000)
+
+	for i := 0; i < 100; i++ {
+		sizes := RandomSlices(target, 40, 60)
+
+		sum := decimal.Zero
+		for _, s := range sizes {
+			if s < 40 || s > 60 {
+				t.Fatalf("slice %f outside of bounds", s)
+			}
+			sum = sum.Add(decimal.NewFromFloat(s))
+		}
+		if !sum.Equal(target) {
+			t.Fatalf("expected: %s, actual: %s", target, sum)
+		}
+	}
+}
+
+func TestSliceSizes(t *testing.T) {
+	start := time.Now()
+
+	sizes, interval, err := SliceSizes(Payload{Start: start, End: start.Add(time.Hour), TargetAmountQuote: 100})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sizes) != 20 || sizes[0] != 5 {
+		t.Errorf("expected: 20 slices of 5, actual: %d slices of %f", len(sizes), sizes[0])
+	}
+	if interval != 3*time.Minute {
+		t.Errorf("expected: %s, actual: %s", 3*time.Minute, interval)
+	}
+
+	if err = (Payload{MinSliceQuote: 20, MaxSliceQuote: 10}).Validate(); err != ErrInvalidSliceSize {
+		t.Errorf("expected: %v, actual: %v", ErrInvalidSliceSize, err)
+	}
+	if err = (Payload{MaxParticipation: 1.5}).Validate(); err != ErrInvalidParticipation {
+		t.Errorf("expected: %v, actual: %v", ErrInvalidParticipation, err)
+	}
+}
+
+func TestWithinLimit(t *testing.T) {
+	buy := Payload{Side: order.Buy, LimitPrice: 100}
+	if !WithinLimit(buy, 99) || WithinLimit(buy, 101) {
+		t.Error("expected buys to be limited above the limit price")
+	}
+
+	sell := Payload{Side: order.Sell, LimitPrice: 100}
+	if WithinLimit(sell, 99) || !WithinLimit(sell, 101) {
+		t.Error("expected sells to be limited below the limit price")
+	}
+
+	if !WithinLimit(Payload{Side: order.Buy}, 1e9) {
+		t.Error("expected no limit without limit price")
+	}
+}
+
+func TestCapSlice(t *testing.T) {
+	if q := CapSlice(50, 10000, 0.1); q != 50 {
+		t.Errorf("expected: %f, actual: %f", 50.0, q)
+	}
+	if q := CapSlice(50, 200, 0.1); q != 20 {
+		t.Errorf("expected: %f, actual: %f", 20.0, q)
+	}
+	if q := CapSlice(50, 20, 0.1); q != 0 {
+		t.Errorf("expected: %f, actual: %f", 0.0, q)
+	}
+}
+
+func TestNewTwapTaskID(t *testing.T) {
+	task, err := NewTwapTask(Payload{Exchange: "binance", Side: order.Buy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p Payload
+	if err = json.Unmarshal(task.Payload(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID == "" {
+		t.Errorf("expected: %v, actual: %v", "a job ID", p.ID)
+	}
+	if id := SliceTaskID(p.ID, 3); id != p.ID+"-3" {
+		t.Errorf("expected: %v, actual: %v", p.ID+"-3", id)
+	}
+}
@@ -0,0 +1,159 @@
+package twap
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+const (
+	volumeStrategyName = "twap-volume"
+
+	// volumeRetention is how long the observed trade volume is kept
+	volumeRetention = 24 * time.Hour
+)
+
+// VolumeStrategy keeps the traded volume in quote currency per exchange, asset and pair in buckets of one minute.
+// It is used to cap TWAP slices to a fraction of the market volume.
+type VolumeStrategy struct {
+	// buckets maps exchange, asset and pair to the quote volume per minute
+	buckets map[string]map[int64]float64
+	mu      sync.Mutex
+}
+
+// NewVolumeStrategy returns an empty VolumeStrategy
+func NewVolumeStrategy() *VolumeStrategy {
+	return &VolumeStrategy{
+		buckets: make(map[string]map[int64]float64),
+	}
+}
+
+var (
+	volume     *VolumeStrategy
+	volumeOnce sync.Once
+)
+
+// Volume returns the VolumeStrategy shared by all TWAP jobs
+func Volume() *VolumeStrategy {
+	volumeOnce.Do(func() {
+		volume = NewVolumeStrategy()
+	})
+	return volume
+}
+
+// WatchVolume adds the shared VolumeStrategy to the dealer, adding it more than once has no effect
+func WatchVolume(d *dealer.Dealer) {
+	d.Root.Add(volumeStrategyName, Volume())
+}
+
+func volumeKey(exchangeName string, a asset.Item, p currency.Pair) string {
+	return exchangeName + "/" + a.String() + "/" + p.String()
+}
+
+// Record adds a trade to the volume of its minute
+func (v *VolumeStrategy) Record(x trade.Data) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key := volumeKey(x.Exchange, x.AssetType, x.CurrencyPair)
+	b, ok := v.buckets[key]
+	if !ok {
+		b = make(map[int64]float64)
+		v.buckets[key] = b
+	}
+
+	ts := x.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	b[ts.Truncate(time.Minute).Unix()] += x.Price * x.Amount
+
+	expired := time.Now().Add(-volumeRetention).Unix()
+	for minute := range b {
+		if minute < expired {
+			delete(b, minute)
+		}
+	}
+}
+
+// QuoteVolume returns the volume in quote currency traded since the given time, rounded down to the minute
+func (v *VolumeStrategy) QuoteVolume(exchangeName string, a asset.Item, p currency.Pair, since time.Time) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var sum float64
+	from := since.Truncate(time.Minute).Unix()
+	for minute, quote := range v.buckets[volumeKey(exchangeName, a, p)] {
+		if minute >= from {
+			sum += quote
+		}
+	}
+	return sum
+}
+
+func (v *VolumeStrategy) Init(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	return nil
+}
+
+func (v *VolumeStrategy) OnFunding(d *dealer.Dealer, e exchange.IBotExchange, x stream.FundingData) error {
+	return nil
+}
+
+func (v *VolumeStrategy) OnPrice(d *dealer.Dealer, e exchange.IBotExchange, x ticker.Price) error {
+	return nil
+}
+
+func (v *VolumeStrategy) OnKline(d *dealer.Dealer, e exchange.IBotExchange, x stream.KlineData) error {
+	return nil
+}
+
+func (v *VolumeStrategy) OnOrderBook(d *dealer.Dealer, e exchange.IBotExchange, x orderbook.Base) error {
+	return nil
+}
+
+func (v *VolumeStrategy) OnOrder(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) error {
+	return nil
+}
+
+func (v *VolumeStrategy) OnModify(d *dealer.Dealer, e exchange.IBotExchange, x order.Modify) error {
+	return nil
+}
+
+func (v *VolumeStrategy) OnBalanceChange(d *dealer.Dealer, e exchange.IBotExchange, x account.Change) error {
+	return nil
+}
+
+// OnTrade records the volume of the public trades
+func (v *VolumeStrategy) OnTrade(d *dealer.Dealer, e exchange.IBotExchange, x []trade.Data) error {
+	for _, t := range x {
+		if t.Exchange == "" {
+			t.Exchange = e.GetName()
+		}
+		v.Record(t)
+	}
+	return nil
+}
+
+func (v *VolumeStrategy) OnFill(d *dealer.Dealer, e exchange.IBotExchange, x []fill.Data) error {
+	return nil
+}
+
+func (v *VolumeStrategy) OnUnrecognized(d *dealer.Dealer, e exchange.IBotExchange, x interface{}) error {
+	return nil
+}
+
+func (v *VolumeStrategy) Deinit(d *dealer.Dealer, e exchange.IBotExchange) error {
+	return nil
+}
@@ -0,0 +1,17 @@
+package vwap
+
+import (
+	"time"
+
+	"github.com/romanornr/autodealer/algo/twap"
+)
+
+// Payload is the payload for the VWAP algorithm.
+// The order itself is described by a twap.Payload, the slices are sized by the volume profile instead of being equally sized.
+type Payload struct {
+	Order twap.Payload
+	// Lookback is how far back the historic candles are fetched to build the volume profile
+	Lookback time.Duration
+	// Interval is the candle interval of the volume profile and the time in between slices
+	Interval time.Duration
+}
@@ -0,0 +1,36 @@
+package vwap
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hibiken/asynq"
+	"github.com/romanornr/autodealer/algo/twap"
+)
+
+const TypeVwap = "vwap"
+
+// NewVwapTask represents a task for VWAP algorithm.
+// The payload gets a job ID when it has none, so a retried task finds the job it already started.
+func NewVwapTask(vwap Payload) (*asynq.Task, error) {
+	if vwap.Order.ID == "" {
+		vwap.Order.ID = twap.NewJobID()
+	}
+	payload, err := json.Marshal(vwap)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeVwap, payload), nil
+}
+
+// HandleVwapTask handles a task for VWAP algorithm. A retry of the task returns early once the job exists.
+func HandleVwapTask(ctx context.Context, t *asynq.Task) error {
+	var p Payload
+	err := json.Unmarshal(t.Payload(), &p)
+	if err != nil {
+		return err
+	}
+
+	_, err = Execute(ctx, p)
+	return err
+}
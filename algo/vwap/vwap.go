@@ -0,0 +1,167 @@
+package vwap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/romanornr/autodealer/algo/twap"
+	"github.com/romanornr/autodealer/singleton"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/kline"
+)
+
+const (
+	defaultLookback = 7 * 24 * time.Hour
+	defaultInterval = 15 * time.Minute
+
+	// minimalSliceQuote is the minimal size in quote currency of each child order, smaller slices are carried over to the next one
+	minimalSliceQuote = 5
+)
+
+var ErrInvalidInterval = errors.New("vwap interval must be a kline interval between a minute and a day")
+
+// Execute executes the VWAP algorithm
+// The historic candles of the lookback period are folded into an intraday volume profile: the average volume traded at every time of the day.
+// The execution window is split in slices of one candle interval and the target amount is distributed over the slices in proportion to the profile,
+// so most is traded when the market is the most liquid. The slices are executed by the TWAP job plumbing and can be followed and controlled as TWAP jobs.
+func Execute(ctx context.Context, p Payload) (*twap.Job, error) {
+	if err := p.Order.Validate(); err != nil {
+		return nil, err
+	}
+
+	if p.Lookback <= 0 {
+		p.Lookback = defaultLookback
+	}
+	if p.Interval == 0 {
+		p.Interval = defaultInterval
+	}
+	if err := ValidateInterval(p.Interval); err != nil {
+		return nil, err
+	}
+	if !p.Order.End.After(p.Order.Start) {
+		return nil, twap.ErrInvalidSchedule
+	}
+
+	d, err := singleton.GetDealer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := d.GetExchangeByName(p.Order.Exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	candles, err := e.GetHistoricCandlesExtended(ctx, p.Order.Pair, p.Order.Asset, kline.Interval(p.Interval), p.Order.Start.Add(-p.Lookback), p.Order.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := Weights(Profile(candles.Candles, p.Interval), p.Order.Start, p.Order.End, p.Interval)
+	sizes := SliceSizes(decimal.NewFromFloat(p.Order.TargetAmountQuote), weights)
+
+	logrus.Printf("vwap %s %s: %d candles over %s, %d slices every %s\n", p.Order.Exchange, p.Order.Pair, len(candles.Candles), p.Lookback, len(sizes), p.Interval)
+
+	return twap.ExecuteSlices(ctx, p.Order, sizes, p.Interval)
+}
+
+// ValidateInterval checks that candles of the interval can be fetched, it has to be one of the kline intervals between
+// a minute and a day
+func ValidateInterval(interval time.Duration) error {
+	if interval < time.Minute || interval > 24*time.Hour {
+		return fmt.Errorf("%w: %s", ErrInvalidInterval, interval)
+	}
+	for _, i := range kline.SupportedIntervals {
+		if i.Duration() == interval {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidInterval, interval)
+}
+
+// timeOfDay returns the start of the interval the time falls in, as an offset from midnight UTC
+func timeOfDay(t time.Time, interval time.Duration) time.Duration {
+	t = t.UTC()
+	return t.Sub(t.Truncate(24 * time.Hour)).Truncate(interval)
+}
+
+// Profile returns the average volume per time of the day
+func Profile(candles []kline.Candle, interval time.Duration) map[time.Duration]float64 {
+	sum := make(map[time.Duration]float64)
+	count := make(map[time.Duration]float64)
+
+	for _, c := range candles {
+		offset := timeOfDay(c.Time, interval)
+		sum[offset] += c.Volume
+		count[offset]++
+	}
+
+	profile := make(map[time.Duration]float64, len(sum))
+	for offset, volume := range sum {
+		profile[offset] = volume / count[offset]
+	}
+	return profile
+}
+
+// Weights returns the expected volume of every slice between start and end.
+// The last slice may be shorter than the interval and is weighted accordingly.
+// Slices are weighted equally when the profile has no volume at all, which leaves a plain TWAP.
+func Weights(profile map[time.Duration]float64, start, end time.Time, interval time.Duration) []float64 {
+	n := int(math.Ceil(float64(end.Sub(start)) / float64(interval)))
+	weights := make([]float64, n)
+
+	var total float64
+	for i := range weights {
+		at := start.Add(time.Duration(i) * interval)
+
+		fraction := 1.0
+		if remaining := end.Sub(at); remaining < interval {
+			fraction = float64(remaining) / float64(interval)
+		}
+
+		weights[i] = profile[timeOfDay(at, interval)] * fraction
+		total += weights[i]
+	}
+
+	if total == 0 {
+		logrus.Warnf("vwap: no historic volume, falling back to equally weighted slices\n")
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	return weights
+}
+
+// SliceSizes distributes the target over the slices in proportion to their weight.
+// Slices below minimalSliceQuote are carried over to the next slice and get a size of zero, the last slice takes whatever is left.
+func SliceSizes(target decimal.Decimal, weights []float64) []float64 {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+
+	sizes := make([]float64, len(weights))
+	if len(weights) == 0 || total == 0 {
+		return sizes
+	}
+
+	remaining := target
+	carry := decimal.Zero
+	for i, w := range weights[:len(weights)-1] {
+		carry = carry.Add(target.Mul(decimal.NewFromFloat(w / total)))
+		if carry.LessThan(decimal.NewFromInt(minimalSliceQuote)) {
+			continue
+		}
+
+		size := carry.Round(8)
+		sizes[i] = size.InexactFloat64()
+		remaining = remaining.Sub(size)
+		carry = decimal.Zero
+	}
+	sizes[len(sizes)-1] = remaining.InexactFloat64()
+	return sizes
+}
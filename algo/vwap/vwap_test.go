@@ -0,0 +1,73 @@
+package vwap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/kline"
+)
+
+func TestProfile(t *testing.T) {
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := []kline.Candle{
+		{Time: day, Volume: 10},
+		{Time: day.Add(time.Hour), Volume: 30},
+		{Time: day.Add(24 * time.Hour), Volume: 20},
+		{Time: day.Add(25 * time.Hour), Volume: 50},
+	}
+
+	profile := Profile(candles, time.Hour)
+	if profile[0] != 15 {
+		t.Errorf("expected: %f, actual: %f", 15.0, profile[0])
+	}
+	if profile[time.Hour] != 40 {
+		t.Errorf("expected: %f, actual: %f", 40.0, profile[time.Hour])
+	}
+}
+
+func TestWeights(t *testing.T) {
+	start := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	profile := map[time.Duration]float64{0: 10, time.Hour: 30}
+
+	weights := Weights(profile, start, start.Add(90*time.Minute), time.Hour)
+	if len(weights) != 2 {
+		t.Fatalf("expected: %d, actual: %d", 2, len(weights))
+	}
+	if weights[0] != 10 || weights[1] != 15 {
+		t.Errorf("expected: [10 15], actual: %v", weights)
+	}
+
+	// without any volume every slice is weighted equally
+	weights = Weights(map[time.Duration]float64{}, start, start.Add(2*time.Hour), time.Hour)
+	if weights[0] != 1 || weights[1] != 1 {
+		t.Errorf("expected: [1 1], actual: %v", weights)
+	}
+}
+
+func TestSliceSizes(t *testing.T) {
+	sizes := SliceSizes(decimal.NewFromInt(100), []float64{1, 3})
+	if sizes[0] != 25 || sizes[1] != 75 {
+		t.Errorf("expected: [25 75], actual: %v", sizes)
+	}
+
+	// slices below the minimal size are carried over to the next slice
+	sizes = SliceSizes(decimal.NewFromInt(100), []float64{1, 1, 96, 2})
+	if sizes[0] != 0 || sizes[1] != 0 || sizes[2] != 98 || sizes[3] != 2 {
+		t.Errorf("expected: [0 0 98 2], actual: %v", sizes)
+	}
+}
+
+func TestValidateInterval(t *testing.T) {
+	for _, interval := range []time.Duration{time.Minute, 15 * time.Minute, 4 * time.Hour, 24 * time.Hour} {
+		if err := ValidateInterval(interval); err != nil {
+			t.Errorf("expected: %v, actual: %v", nil, err)
+		}
+	}
+	for _, interval := range []time.Duration{7 * time.Minute, 10 * time.Second, 3 * 24 * time.Hour} {
+		if err := ValidateInterval(interval); !errors.Is(err, ErrInvalidInterval) {
+			t.Errorf("expected: %v, actual: %v", ErrInvalidInterval, err)
+		}
+	}
+}
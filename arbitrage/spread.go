@@ -0,0 +1,556 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+	"go.uber.org/multierr"
+)
+
+const defaultMaxQuoteAge = 10 * time.Second
+
+var (
+	ErrNoInventory  = errors.New("not enough inventory on both venues")
+	ErrBelowMinimum = errors.New("amount is below the minimum order size of a venue")
+)
+
+// Quote is the best bid and ask of the pair on a venue
+type Quote struct {
+	Exchange  string    `json:"exchange"`
+	Bid       float64   `json:"bid"`
+	BidAmount float64   `json:"bidAmount"`
+	Ask       float64   `json:"ask"`
+	AskAmount float64   `json:"askAmount"`
+	Time      time.Time `json:"time"`
+}
+
+// Spread is an opportunity to buy on one venue and sell on another
+type Spread struct {
+	Pair  currency.Pair `json:"pair"`
+	Buy   Quote         `json:"buy"`
+	Sell  Quote         `json:"sell"`
+	Gross float64       `json:"gross"`
+	// Net is the relative gain after trading fees and the withdrawal cost of moving the base back
+	Net    float64 `json:"net"`
+	Amount float64 `json:"amount"`
+	// Profit is the gain in quote currency when Amount is traded
+	Profit   float64   `json:"profit"`
+	Executed bool      `json:"executed"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// SpreadConfig configures the cross-exchange spread strategy
+type SpreadConfig struct {
+	Pair  currency.Pair
+	Asset asset.Item
+	// Exchanges are the venues that are compared, every exchange that streams the pair is used when empty
+	Exchanges []string
+	// TakerFees is the fee per exchange, 0.001 is 0.1%. Exchanges without a fee use the default taker fee.
+	TakerFees map[string]float64
+	// WithdrawalFees is the fee in base currency to withdraw the base from an exchange, it is charged on the venue that is bought on
+	// because the inventory has to be moved back eventually
+	WithdrawalFees map[string]float64
+	// MinNet is the minimal net spread of an opportunity
+	MinNet float64
+	// MaxAmount caps the amount of base traded per opportunity
+	MaxAmount float64
+	// MaxQuoteAge ignores venues whose quote has not been updated for this long
+	MaxQuoteAge time.Duration
+	// Execute buys and sells simultaneously from the inventory held on both venues
+	Execute  bool
+	Cooldown time.Duration
+}
+
+// InventoryPosition is the inventory of a venue and how it moved since the strategy started trading
+type InventoryPosition struct {
+	Exchange string  `json:"exchange"`
+	Base     float64 `json:"base"`
+	Quote    float64 `json:"quote"`
+	// BaseDrift and QuoteDrift are the changes caused by executed spreads
+	BaseDrift  float64 `json:"baseDrift"`
+	QuoteDrift float64 `json:"quoteDrift"`
+}
+
+// InventoryReport shows how the inventory moved between the venues. Arbitrage keeps the total roughly constant but moves the base
+// towards the expensive venue and the quote towards the cheap one, until one of the venues runs dry and has to be refilled.
+type InventoryReport struct {
+	Pair       currency.Pair       `json:"pair"`
+	Venues     []InventoryPosition `json:"venues"`
+	BaseDrift  float64             `json:"baseDrift"`
+	QuoteDrift float64             `json:"quoteDrift"`
+	Executed   int                 `json:"executed"`
+	Time       time.Time           `json:"time"`
+}
+
+// SpreadStrategy monitors the same pair on several exchanges and reports when the best bid of one venue exceeds the best ask of another
+// by more than the fees and withdrawal costs. In executor mode both legs are submitted at the same time as immediate-or-cancel orders
+// against the inventory that is already on both venues, no funds are moved between the exchanges.
+type SpreadStrategy struct {
+	config SpreadConfig
+
+	mu            sync.Mutex
+	quotes        map[string]Quote
+	spreads       []Spread
+	drift         map[string]*InventoryPosition
+	executed      int
+	lastExecution time.Time
+	// executing is set while a spread is being executed
+	executing bool
+}
+
+// NewSpreadStrategy returns a cross-exchange spread strategy
+func NewSpreadStrategy(c SpreadConfig) *SpreadStrategy {
+	if c.Asset == asset.Empty {
+		c.Asset = asset.Spot
+	}
+	if c.MaxQuoteAge == 0 {
+		c.MaxQuoteAge = defaultMaxQuoteAge
+	}
+	if c.Cooldown == 0 {
+		c.Cooldown = defaultCooldown
+	}
+
+	return &SpreadStrategy{
+		config: c,
+		quotes: make(map[string]Quote),
+		drift:  make(map[string]*InventoryPosition),
+	}
+}
+
+// Quotes returns the latest quote of every venue
+func (s *SpreadStrategy) Quotes() []Quote {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes := make([]Quote, 0, len(s.quotes))
+	for _, q := range s.quotes {
+		quotes = append(quotes, q)
+	}
+	sort.Slice(quotes, func(i, j int) bool { return quotes[i].Exchange < quotes[j].Exchange })
+	return quotes
+}
+
+// Spreads returns the most recent opportunities
+func (s *SpreadStrategy) Spreads() []Spread {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Spread(nil), s.spreads...)
+}
+
+// BestSpread compares every venue with every other venue and returns the opportunity with the highest net spread.
+// The amount is bound by the depth at the top of book of both venues and by maxAmount when it is set.
+func BestSpread(c SpreadConfig, quotes []Quote) (Spread, bool) {
+	var (
+		best  Spread
+		found bool
+	)
+
+	for _, buy := range quotes {
+		for _, sell := range quotes {
+			if buy.Exchange == sell.Exchange || buy.Ask <= 0 || sell.Bid <= buy.Ask {
+				continue
+			}
+
+			amount := math.Min(buy.AskAmount, sell.BidAmount)
+			if c.MaxAmount > 0 {
+				amount = math.Min(amount, c.MaxAmount)
+			}
+			if amount <= 0 {
+				continue
+			}
+
+			cost := amount * buy.Ask * (1 + c.takerFee(buy.Exchange))
+			proceeds := amount * sell.Bid * (1 - c.takerFee(sell.Exchange))
+			withdrawal := c.WithdrawalFees[buy.Exchange] * buy.Ask
+
+			sp := Spread{
+				Pair:   c.Pair,
+				Buy:    buy,
+				Sell:   sell,
+				Gross:  sell.Bid/buy.Ask - 1,
+				Net:    (proceeds-withdrawal)/cost - 1,
+				Amount: amount,
+				Profit: proceeds - withdrawal - cost,
+				Time:   time.Now(),
+			}
+
+			if !found || sp.Net > best.Net {
+				best, found = sp, true
+			}
+		}
+	}
+	return best, found
+}
+
+// takerFee returns the taker fee of the exchange
+func (c SpreadConfig) takerFee(exchangeName string) float64 {
+	if fee, ok := c.TakerFees[exchangeName]; ok {
+		return fee
+	}
+	return defaultTakerFee
+}
+
+// venue reports whether the exchange is one of the configured venues
+func (c SpreadConfig) venue(exchangeName string) bool {
+	if len(c.Exchanges) == 0 {
+		return true
+	}
+	for _, name := range c.Exchanges {
+		if strings.EqualFold(name, exchangeName) {
+			return true
+		}
+	}
+	return false
+}
+
+// update stores the quote of the venue and evaluates the spreads
+func (s *SpreadStrategy) update(d *dealer.Dealer, q Quote) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.quotes[q.Exchange] = q
+
+	fresh := make([]Quote, 0, len(s.quotes))
+	for _, quote := range s.quotes {
+		if time.Since(quote.Time) <= s.config.MaxQuoteAge {
+			fresh = append(fresh, quote)
+		}
+	}
+
+	sp, ok := BestSpread(s.config, fresh)
+	if !ok || sp.Net < s.config.MinNet {
+		return
+	}
+
+	logrus.Printf("spread %s: buy %s at %f sell %s at %f net %f amount %f profit %f\n", sp.Pair, sp.Buy.Exchange, sp.Buy.Ask, sp.Sell.Exchange, sp.Sell.Bid, sp.Net, sp.Amount, sp.Profit)
+	d.ReportValue(dealer.ArbitrageOpportunityMetric, sp.Net, sp.Buy.Exchange, sp.Sell.Exchange)
+
+	// the orders take network round trips, they are submitted away from the stream and one spread is executed at a time
+	if s.config.Execute && !s.executing && time.Since(s.lastExecution) >= s.config.Cooldown {
+		s.lastExecution = time.Now()
+		s.executing = true
+		go s.run(d, sp)
+		return
+	}
+	s.record(sp)
+}
+
+// run executes the spread and records it once both legs returned
+func (s *SpreadStrategy) run(d *dealer.Dealer, sp Spread) {
+	fills, err := s.execute(d, &sp)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.executing = false
+	for _, f := range fills {
+		s.book(f.exchange, f.base, f.quote)
+	}
+	if len(fills) > 0 {
+		s.executed++
+	}
+	if err != nil {
+		logrus.Errorf("spread %s: %s\n", sp.Pair, err)
+		sp.Error = err.Error()
+	}
+	s.record(sp)
+}
+
+// record keeps the spread among the recent opportunities. The caller must hold the lock.
+func (s *SpreadStrategy) record(sp Spread) {
+	s.spreads = append(s.spreads, sp)
+	if len(s.spreads) > maxOpportunities {
+		s.spreads = s.spreads[len(s.spreads)-maxOpportunities:]
+	}
+}
+
+// inventory returns the base and quote held on the exchange over all sub accounts
+func (s *SpreadStrategy) inventory(d *dealer.Dealer, exchangeName string) (float64, float64, error) {
+	holdings, err := dealer.Holdings(d, exchangeName)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var base, quote float64
+	for _, a := range holdings.Accounts {
+		for code, balance := range a.Balances[s.config.Asset] {
+			available := balance.TotalValue - balance.Hold
+			switch {
+			case code.Equal(s.config.Pair.Base):
+				base += available
+			case code.Equal(s.config.Pair.Quote):
+				quote += available
+			}
+		}
+	}
+	return base, quote, nil
+}
+
+// legFill is the inventory change of an executed leg
+type legFill struct {
+	exchange    string
+	base, quote float64
+}
+
+// execute buys on the cheap venue and sells on the expensive venue at the same time. The amount is capped by the quote available on the
+// venue that is bought on and the base available on the venue that is sold on. The fills of the legs that went through are returned,
+// the caller books them. It does not need the lock.
+func (s *SpreadStrategy) execute(d *dealer.Dealer, sp *Spread) ([]legFill, error) {
+	_, quote, err := s.inventory(d, sp.Buy.Exchange)
+	if err != nil {
+		return nil, err
+	}
+	base, _, err := s.inventory(d, sp.Sell.Exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := math.Min(sp.Amount, math.Min(base, quote/(sp.Buy.Ask*(1+s.config.takerFee(sp.Buy.Exchange)))))
+	if amount <= 0 {
+		return nil, ErrNoInventory
+	}
+
+	legs := []order.Submit{
+		{Exchange: sp.Buy.Exchange, Side: order.Buy, Price: sp.Buy.Ask},
+		{Exchange: sp.Sell.Exchange, Side: order.Sell, Price: sp.Sell.Bid},
+	}
+
+	venues := make([]exchange.IBotExchange, len(legs))
+	for i, leg := range legs {
+		if venues[i], err = d.GetExchangeByName(leg.Exchange); err != nil {
+			return nil, err
+		}
+	}
+	if amount, err = s.conform(venues, amount); err != nil {
+		return nil, err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		errs      = make([]error, len(legs))
+		responses = make([]*order.SubmitResponse, len(legs))
+	)
+	for i, leg := range legs {
+		e := venues[i]
+		leg.Type = order.Limit
+		leg.Pair = s.config.Pair
+		leg.AssetType = s.config.Asset
+		leg.ImmediateOrCancel = true
+		leg.Amount = amount
+
+		wg.Add(1)
+		go func(i int, e exchange.IBotExchange, leg order.Submit) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			responses[i], errs[i] = d.SubmitOrder(ctx, e, leg)
+		}(i, e, leg)
+	}
+	wg.Wait()
+
+	// every leg that went through is booked with what it executed, immediate-or-cancel orders may be filled partially
+	var fills []legFill
+	for i, leg := range legs {
+		if errs[i] != nil {
+			continue
+		}
+		base, quote := dealer.Executed(responses[i], leg.Price)
+		// a buy adds base and spends quote, a sell the other way round
+		if leg.Side == order.Sell {
+			base = -base
+		} else {
+			quote = -quote
+		}
+		fills = append(fills, legFill{exchange: leg.Exchange, base: base, quote: quote})
+	}
+
+	err = multierr.Combine(errs...)
+	sp.Amount = amount
+	sp.Executed = err == nil
+	return fills, err
+}
+
+// conform rounds the amount down to the coarser step size of the venues, so both legs trade the same amount.
+// ErrBelowMinimum is returned when the amount falls below the minimum of either venue.
+func (s *SpreadStrategy) conform(venues []exchange.IBotExchange, amount float64) (float64, error) {
+	var coarsest order.MinMaxLevel
+	var levels []order.MinMaxLevel
+	for _, e := range venues {
+		limits, err := e.GetOrderExecutionLimits(s.config.Asset, s.config.Pair)
+		if err != nil {
+			continue
+		}
+		levels = append(levels, limits)
+		if limits.AmountStepIncrementSize > coarsest.AmountStepIncrementSize {
+			coarsest = limits
+		}
+	}
+
+	amount = coarsest.ConformToAmount(amount)
+	for _, limits := range levels {
+		if amount <= 0 || amount < limits.MinimumBaseAmount {
+			return 0, fmt.Errorf("%w: %f %s", ErrBelowMinimum, amount, s.config.Pair)
+		}
+	}
+	return amount, nil
+}
+
+// book records the inventory change of an executed leg. The caller must hold the lock.
+func (s *SpreadStrategy) book(exchangeName string, base, quote float64) {
+	p, ok := s.drift[exchangeName]
+	if !ok {
+		p = &InventoryPosition{Exchange: exchangeName}
+		s.drift[exchangeName] = p
+	}
+	p.BaseDrift += base
+	p.QuoteDrift += quote
+}
+
+// Inventory returns the inventory of every venue together with the drift caused by executed spreads
+func (s *SpreadStrategy) Inventory(d *dealer.Dealer) InventoryReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := InventoryReport{
+		Pair:     s.config.Pair,
+		Executed: s.executed,
+		Time:     time.Now(),
+	}
+
+	names := make(map[string]struct{})
+	for name := range s.quotes {
+		names[name] = struct{}{}
+	}
+	for name := range s.drift {
+		names[name] = struct{}{}
+	}
+
+	for name := range names {
+		p := InventoryPosition{Exchange: name}
+		if drift, ok := s.drift[name]; ok {
+			p = *drift
+		}
+
+		base, quote, err := s.inventory(d, name)
+		if err != nil {
+			logrus.Errorf("spread %s: inventory of %s: %s\n", s.config.Pair, name, err)
+		}
+		p.Base, p.Quote = base, quote
+
+		report.BaseDrift += p.BaseDrift
+		report.QuoteDrift += p.QuoteDrift
+		report.Venues = append(report.Venues, p)
+	}
+
+	sort.Slice(report.Venues, func(i, j int) bool { return report.Venues[i].Exchange < report.Venues[j].Exchange })
+	return report
+}
+
+// Init subscribes the venue to the order book of the pair
+func (s *SpreadStrategy) Init(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	if !s.config.venue(e.GetName()) {
+		return nil
+	}
+
+	if err := d.ActivateAsset(e, s.config.Asset); err != nil {
+		return err
+	}
+
+	pairs, err := e.GetEnabledPairs(s.config.Asset)
+	if err != nil {
+		return err
+	}
+	if !pairs.Contains(s.config.Pair, false) {
+		return d.ActivatePair(e, s.config.Asset, s.config.Pair)
+	}
+	return nil
+}
+
+func (s *SpreadStrategy) OnFunding(d *dealer.Dealer, e exchange.IBotExchange, x stream.FundingData) error {
+	return nil
+}
+
+// OnPrice updates the quote of the venue from the ticker, for exchanges that do not stream the order book
+func (s *SpreadStrategy) OnPrice(d *dealer.Dealer, e exchange.IBotExchange, x ticker.Price) error {
+	if !s.config.venue(e.GetName()) || x.AssetType != s.config.Asset || !x.Pair.Equal(s.config.Pair) || x.Bid <= 0 || x.Ask <= 0 {
+		return nil
+	}
+
+	s.update(d, Quote{
+		Exchange:  e.GetName(),
+		Bid:       x.Bid,
+		BidAmount: x.BidSize,
+		Ask:       x.Ask,
+		AskAmount: x.AskSize,
+		Time:      time.Now(),
+	})
+	return nil
+}
+
+func (s *SpreadStrategy) OnKline(d *dealer.Dealer, e exchange.IBotExchange, x stream.KlineData) error {
+	return nil
+}
+
+// OnOrderBook updates the quote of the venue from the top of the book
+func (s *SpreadStrategy) OnOrderBook(d *dealer.Dealer, e exchange.IBotExchange, x orderbook.Base) error {
+	if !s.config.venue(e.GetName()) || x.Asset != s.config.Asset || !x.Pair.Equal(s.config.Pair) || len(x.Bids) == 0 || len(x.Asks) == 0 {
+		return nil
+	}
+
+	s.update(d, Quote{
+		Exchange:  e.GetName(),
+		Bid:       x.Bids[0].Price,
+		BidAmount: x.Bids[0].Amount,
+		Ask:       x.Asks[0].Price,
+		AskAmount: x.Asks[0].Amount,
+		Time:      time.Now(),
+	})
+	return nil
+}
+
+func (s *SpreadStrategy) OnOrder(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) error {
+	return nil
+}
+
+func (s *SpreadStrategy) OnModify(d *dealer.Dealer, e exchange.IBotExchange, x order.Modify) error {
+	return nil
+}
+
+func (s *SpreadStrategy) OnBalanceChange(d *dealer.Dealer, e exchange.IBotExchange, x account.Change) error {
+	return nil
+}
+
+func (s *SpreadStrategy) OnTrade(d *dealer.Dealer, e exchange.IBotExchange, x []trade.Data) error {
+	return nil
+}
+
+func (s *SpreadStrategy) OnFill(d *dealer.Dealer, e exchange.IBotExchange, x []fill.Data) error {
+	return nil
+}
+
+func (s *SpreadStrategy) OnUnrecognized(d *dealer.Dealer, e exchange.IBotExchange, x interface{}) error {
+	return nil
+}
+
+func (s *SpreadStrategy) Deinit(d *dealer.Dealer, e exchange.IBotExchange) error {
+	return nil
+}
@@ -0,0 +1,111 @@
+package arbitrage
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// limitsExchange only returns its execution limits
+type limitsExchange struct {
+	exchange.IBotExchange
+	limits order.MinMaxLevel
+}
+
+func (e *limitsExchange) GetOrderExecutionLimits(a asset.Item, p currency.Pair) (order.MinMaxLevel, error) {
+	return e.limits, nil
+}
+
+func TestBestSpread(t *testing.T) {
+	c := SpreadConfig{
+		Pair:           currency.NewPair(currency.BTC, currency.USDT),
+		TakerFees:      map[string]float64{"binance": 0.001, "kraken": 0.002},
+		WithdrawalFees: map[string]float64{"binance": 0.0005},
+	}
+
+	quotes := []Quote{
+		{Exchange: "binance", Bid: 19990, BidAmount: 1, Ask: 20000, AskAmount: 2},
+		{Exchange: "kraken", Bid: 20300, BidAmount: 0.5, Ask: 20310, AskAmount: 1},
+	}
+
+	sp, ok := BestSpread(c, quotes)
+	if !ok {
+		t.Fatalf("expected: %v, actual: %v", true, ok)
+	}
+	if sp.Buy.Exchange != "binance" || sp.Sell.Exchange != "kraken" {
+		t.Errorf("expected: %v, actual: %v", "binance -> kraken", sp.Buy.Exchange+" -> "+sp.Sell.Exchange)
+	}
+	if sp.Amount != 0.5 {
+		t.Errorf("expected: %v, actual: %v", 0.5, sp.Amount)
+	}
+
+	// 0.5 * 20300 * 0.998 - 0.0005 * 20000 - 0.5 * 20000 * 1.001
+	profit := 10129.7 - 10 - 10010
+	if math.Abs(sp.Profit-profit) > 1e-6 {
+		t.Errorf("expected: %v, actual: %v", profit, sp.Profit)
+	}
+	if net := profit / 10010; math.Abs(sp.Net-net) > 1e-9 {
+		t.Errorf("expected: %v, actual: %v", net, sp.Net)
+	}
+
+	c.MaxAmount = 0.1
+	if sp, _ = BestSpread(c, quotes); sp.Amount != 0.1 {
+		t.Errorf("expected: %v, actual: %v", 0.1, sp.Amount)
+	}
+
+	// crossed books only
+	quotes[1].Bid = 20000
+	if _, ok = BestSpread(c, quotes); ok {
+		t.Errorf("expected: %v, actual: %v", false, ok)
+	}
+}
+
+func TestBestSpreadWithdrawalCost(t *testing.T) {
+	c := SpreadConfig{
+		Pair:           currency.NewPair(currency.BTC, currency.USDT),
+		TakerFees:      map[string]float64{"binance": 0, "kraken": 0},
+		WithdrawalFees: map[string]float64{"binance": 0.01},
+	}
+
+	quotes := []Quote{
+		{Exchange: "binance", Bid: 19990, BidAmount: 1, Ask: 20000, AskAmount: 0.1},
+		{Exchange: "kraken", Bid: 20100, BidAmount: 0.1, Ask: 20110, AskAmount: 1},
+	}
+
+	// the gross profit of 10 does not cover withdrawing 0.01 BTC
+	sp, ok := BestSpread(c, quotes)
+	if !ok {
+		t.Fatalf("expected: %v, actual: %v", true, ok)
+	}
+	if sp.Net >= 0 {
+		t.Errorf("expected: %v, actual: %v", "negative net spread", sp.Net)
+	}
+	if sp.Gross <= 0 {
+		t.Errorf("expected: %v, actual: %v", "positive gross spread", sp.Gross)
+	}
+}
+
+func TestConform(t *testing.T) {
+	s := NewSpreadStrategy(SpreadConfig{Pair: currency.NewPair(currency.BTC, currency.USDT)})
+	venues := []exchange.IBotExchange{
+		&limitsExchange{limits: order.MinMaxLevel{AmountStepIncrementSize: 0.001, MinimumBaseAmount: 0.001}},
+		&limitsExchange{limits: order.MinMaxLevel{AmountStepIncrementSize: 0.01, MinimumBaseAmount: 0.05}},
+	}
+
+	amount, err := s.conform(venues, 0.1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(amount-0.12) > 1e-9 {
+		t.Errorf("expected: %f, actual: %f", 0.12, amount)
+	}
+
+	if _, err = s.conform(venues, 0.0456); !errors.Is(err, ErrBelowMinimum) {
+		t.Errorf("expected: %v, actual: %v", ErrBelowMinimum, err)
+	}
+}
@@ -0,0 +1,450 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/romanornr/autodealer/algo/bellmanford"
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/util"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+var ErrNoCycleInventory = errors.New("not enough inventory for every leg of the cycle")
+
+const (
+	defaultTakerFee = 0.001
+	defaultInterval = time.Second
+	defaultCooldown = 10 * time.Second
+
+	// maxOpportunities is the amount of recent opportunities that is kept
+	maxOpportunities = 100
+)
+
+// Top is the best bid and ask of a pair with the amount available at those prices
+type Top struct {
+	Bid       float64
+	BidAmount float64
+	Ask       float64
+	AskAmount float64
+}
+
+// Leg is a single conversion of a cycle, selling the base at the bid or buying it at the ask
+type Leg struct {
+	Pair  currency.Pair `json:"pair"`
+	Side  order.Side    `json:"side"`
+	From  currency.Code `json:"from"`
+	To    currency.Code `json:"to"`
+	Price float64       `json:"price"`
+	// Rate is the amount of To received for one From after fees
+	Rate float64 `json:"rate"`
+	// Capacity is the amount of From the top of book can absorb
+	Capacity float64 `json:"capacity"`
+}
+
+// MarshalJSON encodes the side as a string
+func (l Leg) MarshalJSON() ([]byte, error) {
+	type alias Leg
+	return util.MarshalSide(alias(l), "side", l.Side)
+}
+
+// Opportunity is a profitable cycle of conversions
+type Opportunity struct {
+	Exchange string `json:"exchange"`
+	Legs     []Leg  `json:"legs"`
+	// Return is the relative gain of a round trip after fees, 0.01 is 1%
+	Return float64 `json:"return"`
+	// Amount is the largest amount of the first currency of the cycle the top of book depth allows to trade
+	Amount float64 `json:"amount"`
+	// Profit is the gain in the first currency of the cycle when Amount is traded
+	Profit   float64   `json:"profit"`
+	Executed bool      `json:"executed"`
+	Time     time.Time `json:"time"`
+}
+
+// TriangularConfig configures the triangular arbitrage strategy
+type TriangularConfig struct {
+	Exchange string
+	Asset    asset.Item
+	// Start is the currency every cycle starts and ends in
+	Start currency.Code
+	// TakerFee is the fee per conversion, 0.001 is 0.1%
+	TakerFee float64
+	// MinReturn is the minimal return after fees of a cycle to be reported
+	MinReturn float64
+	// MaxAmount caps the amount of the start currency that is traded per cycle. Executing needs it held up front, together with
+	// what the other legs spend of the other currencies of the cycle
+	MaxAmount float64
+	// Interval batches order book updates, the cycle search runs at most once per interval
+	Interval time.Duration
+	// Execute submits the orders of profitable cycles, otherwise they are only reported
+	Execute bool
+	// Cooldown is the time after an execution before another cycle is executed, so a cycle is not traded twice on a stale book
+	Cooldown time.Duration
+}
+
+// TriangularStrategy searches for arbitrage cycles between the pairs of an exchange.
+// The top of book of every pair is kept from the order book updates and turned into a graph where every currency is a vertex and every
+// conversion an edge weighted by -log(rate), the rate including the taker fee. A cycle whose weights add up to a negative number
+// multiplies the amount traded through it and is found with the Bellman-Ford algorithm.
+// All legs of a cycle are submitted at once as immediate-or-cancel limit orders at the top of book. They are not chained, every leg spends
+// inventory that is already held, so a cycle is only executed when the balance of every currency it spends covers its leg.
+type TriangularStrategy struct {
+	config TriangularConfig
+
+	mu            sync.Mutex
+	books         map[currency.Pair]Top
+	enabled       currency.Pairs
+	lastSearch    time.Time
+	lastExecution time.Time
+	opportunities []Opportunity
+	// executing is set while a cycle is being executed
+	executing bool
+}
+
+// NewTriangularStrategy returns a triangular arbitrage strategy
+func NewTriangularStrategy(c TriangularConfig) *TriangularStrategy {
+	if c.Asset == asset.Empty {
+		c.Asset = asset.Spot
+	}
+	if c.TakerFee == 0 {
+		c.TakerFee = defaultTakerFee
+	}
+	if c.Interval == 0 {
+		c.Interval = defaultInterval
+	}
+	if c.Cooldown == 0 {
+		c.Cooldown = defaultCooldown
+	}
+
+	return &TriangularStrategy{
+		config: c,
+		books:  make(map[currency.Pair]Top),
+	}
+}
+
+// Opportunities returns the most recent profitable cycles
+func (s *TriangularStrategy) Opportunities() []Opportunity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Opportunity(nil), s.opportunities...)
+}
+
+// FindCycle builds the conversion graph from the top of book of every pair and returns the arbitrage cycle through the start currency, if any.
+// The cycle is rotated to begin with the start currency when it passes through it.
+func FindCycle(books map[currency.Pair]Top, fee float64, start currency.Code) (Opportunity, bool) {
+	vertices := make(map[currency.Code]uint)
+	var codes []currency.Code
+	vertex := func(c currency.Code) uint {
+		c = c.Upper()
+		if v, ok := vertices[c]; ok {
+			return v
+		}
+		vertices[c] = uint(len(codes))
+		codes = append(codes, c)
+		return vertices[c]
+	}
+
+	type arc struct{ from, to uint }
+	legs := make(map[arc]Leg)
+
+	add := func(l Leg) {
+		a := arc{vertex(l.From), vertex(l.To)}
+		if existing, ok := legs[a]; !ok || l.Rate > existing.Rate {
+			legs[a] = l
+		}
+	}
+
+	source := vertex(start)
+	for p, top := range books {
+		if top.Bid > 0 {
+			add(Leg{Pair: p, Side: order.Sell, From: p.Base, To: p.Quote, Price: top.Bid, Rate: top.Bid * (1 - fee), Capacity: top.BidAmount})
+		}
+		if top.Ask > 0 {
+			add(Leg{Pair: p, Side: order.Buy, From: p.Quote, To: p.Base, Price: top.Ask, Rate: (1 / top.Ask) * (1 - fee), Capacity: top.AskAmount * top.Ask})
+		}
+	}
+
+	edges := make([]*bellmanford.Edge, 0, len(legs))
+	for a, l := range legs {
+		edges = append(edges, bellmanford.NewEdge(a.from, a.to, -math.Log(l.Rate)))
+	}
+
+	all := make([]uint, len(codes))
+	for i := range all {
+		all[i] = uint(i)
+	}
+
+	loop := bellmanford.NewGraph(edges, all).FindArbitrageLoop(source)
+	if len(loop) < 3 {
+		return Opportunity{}, false
+	}
+
+	// the loop follows the predecessors, trading goes the other way around
+	for i, j := 0, len(loop)-1; i < j; i, j = i+1, j-1 {
+		loop[i], loop[j] = loop[j], loop[i]
+	}
+
+	// rotate the cycle so it starts and ends in the start currency
+	cycle := loop[:len(loop)-1]
+	for i, v := range cycle {
+		if v == source {
+			cycle = append(append([]uint{}, cycle[i:]...), cycle[:i]...)
+			break
+		}
+	}
+	cycle = append(cycle, cycle[0])
+
+	o := Opportunity{Time: time.Now()}
+	multiplier := 1.0
+	amount := math.MaxFloat64
+	for i := 0; i < len(cycle)-1; i++ {
+		l, ok := legs[arc{cycle[i], cycle[i+1]}]
+		if !ok {
+			return Opportunity{}, false
+		}
+
+		// the capacity of the leg expressed in the first currency of the cycle
+		amount = math.Min(amount, l.Capacity/multiplier)
+		multiplier *= l.Rate
+		o.Legs = append(o.Legs, l)
+	}
+
+	o.Return = multiplier - 1
+	o.Amount = amount
+	o.Profit = amount * o.Return
+	return o, o.Return > 0
+}
+
+// search runs the cycle search on the current books. The caller must hold the lock.
+func (s *TriangularStrategy) search(d *dealer.Dealer, e exchange.IBotExchange) {
+	o, ok := FindCycle(s.books, s.config.TakerFee, s.config.Start)
+	if !ok || o.Return < s.config.MinReturn {
+		return
+	}
+	o.Exchange = e.GetName()
+
+	startsAtStart := o.Legs[0].From.Equal(s.config.Start)
+	if startsAtStart && s.config.MaxAmount > 0 && o.Amount > s.config.MaxAmount {
+		o.Amount = s.config.MaxAmount
+		o.Profit = o.Amount * o.Return
+	}
+
+	logrus.Printf("arbitrage %s: %s return %f amount %f profit %f\n", o.Exchange, cycleString(o), o.Return, o.Amount, o.Profit)
+	d.ReportValue(dealer.ArbitrageOpportunityMetric, o.Return, o.Exchange)
+
+	// the orders take network round trips, they are submitted away from the stream and one cycle is executed at a time
+	if s.config.Execute && startsAtStart && !s.executing && time.Since(s.lastExecution) >= s.config.Cooldown {
+		s.lastExecution = time.Now()
+		s.executing = true
+		go s.run(d, e, o)
+		return
+	}
+	s.record(o)
+}
+
+// run executes the cycle and records it once every leg returned
+func (s *TriangularStrategy) run(d *dealer.Dealer, e exchange.IBotExchange, o Opportunity) {
+	err := s.execute(d, e, o)
+	if err != nil {
+		logrus.Errorf("arbitrage %s: failed to execute %s: %s\n", o.Exchange, cycleString(o), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.executing = false
+	o.Executed = err == nil
+	s.record(o)
+}
+
+// record keeps the opportunity among the recent ones. The caller must hold the lock.
+func (s *TriangularStrategy) record(o Opportunity) {
+	s.opportunities = append(s.opportunities, o)
+	if len(s.opportunities) > maxOpportunities {
+		s.opportunities = s.opportunities[len(s.opportunities)-maxOpportunities:]
+	}
+}
+
+// Orders returns the immediate-or-cancel orders of every leg of the cycle for the given amount of the first currency
+func Orders(e exchange.IBotExchange, a asset.Item, o Opportunity) []order.Submit {
+	orders := make([]order.Submit, 0, len(o.Legs))
+
+	amount := o.Amount
+	for _, l := range o.Legs {
+		// a sell trades the base, a buy spends the quote
+		base := amount
+		if l.Side == order.Buy {
+			base = amount / l.Price
+		}
+		if limits, err := e.GetOrderExecutionLimits(a, l.Pair); err == nil {
+			base = limits.ConformToAmount(base)
+		}
+
+		orders = append(orders, order.Submit{
+			Exchange:          e.GetName(),
+			Type:              order.Limit,
+			Side:              l.Side,
+			Pair:              l.Pair,
+			AssetType:         a,
+			Price:             l.Price,
+			Amount:            base,
+			ImmediateOrCancel: true,
+		})
+		amount *= l.Rate
+	}
+	return orders
+}
+
+// execute submits all legs of the cycle at once. The legs run at the same time, so none of them can spend what another one
+// buys: the cycle is only executed when the available balance covers what every leg spends. It does not need the lock.
+func (s *TriangularStrategy) execute(d *dealer.Dealer, e exchange.IBotExchange, o Opportunity) error {
+	orders := Orders(e, s.config.Asset, o)
+
+	holdings, err := dealer.Holdings(d, e.GetName())
+	if err != nil {
+		return err
+	}
+	if err = Covered(holdings, s.config.Asset, orders); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return d.SubmitOrders(ctx, e, orders...)
+}
+
+// Covered returns ErrNoCycleInventory unless the available balance of every currency covers what the orders spend of it,
+// the base for sells and the quote for buys
+func Covered(holdings *dealer.ExchangeHoldings, a asset.Item, orders []order.Submit) error {
+	available := make(map[currency.Code]float64)
+	for _, sub := range holdings.Accounts {
+		for code, balance := range sub.Balances[a] {
+			available[code.Upper()] += balance.TotalValue - balance.Hold
+		}
+	}
+
+	spent := make(map[currency.Code]float64)
+	for _, o := range orders {
+		code, amount := o.Pair.Base.Upper(), o.Amount
+		if o.Side == order.Buy {
+			code, amount = o.Pair.Quote.Upper(), o.Amount*o.Price
+		}
+		spent[code] += amount
+		if spent[code] > available[code] {
+			return fmt.Errorf("%w: %f %s needed, %f available", ErrNoCycleInventory, spent[code], code, available[code])
+		}
+	}
+	return nil
+}
+
+// cycleString returns the currencies of the cycle, e.g. USDT > BTC > ETH > USDT
+func cycleString(o Opportunity) string {
+	var str string
+	for _, l := range o.Legs {
+		str += l.From.String() + " > "
+	}
+	if len(o.Legs) > 0 {
+		str += o.Legs[len(o.Legs)-1].To.String()
+	}
+	return str
+}
+
+// Init loads the enabled pairs of the exchange, only their order books are part of the graph
+func (s *TriangularStrategy) Init(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	if e.GetName() != s.config.Exchange {
+		return nil
+	}
+
+	pairs, err := e.GetEnabledPairs(s.config.Asset)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.enabled = pairs
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *TriangularStrategy) OnFunding(d *dealer.Dealer, e exchange.IBotExchange, x stream.FundingData) error {
+	return nil
+}
+
+func (s *TriangularStrategy) OnPrice(d *dealer.Dealer, e exchange.IBotExchange, x ticker.Price) error {
+	return nil
+}
+
+func (s *TriangularStrategy) OnKline(d *dealer.Dealer, e exchange.IBotExchange, x stream.KlineData) error {
+	return nil
+}
+
+// OnOrderBook updates the top of book of the pair and runs the cycle search once the interval has passed
+func (s *TriangularStrategy) OnOrderBook(d *dealer.Dealer, e exchange.IBotExchange, x orderbook.Base) error {
+	if e.GetName() != s.config.Exchange || x.Asset != s.config.Asset || len(x.Bids) == 0 || len(x.Asks) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.enabled) > 0 && !s.enabled.Contains(x.Pair, true) {
+		return nil
+	}
+
+	s.books[x.Pair] = Top{
+		Bid:       x.Bids[0].Price,
+		BidAmount: x.Bids[0].Amount,
+		Ask:       x.Asks[0].Price,
+		AskAmount: x.Asks[0].Amount,
+	}
+
+	if time.Since(s.lastSearch) < s.config.Interval {
+		return nil
+	}
+	s.lastSearch = time.Now()
+	s.search(d, e)
+	return nil
+}
+
+func (s *TriangularStrategy) OnOrder(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) error {
+	return nil
+}
+
+func (s *TriangularStrategy) OnModify(d *dealer.Dealer, e exchange.IBotExchange, x order.Modify) error {
+	return nil
+}
+
+func (s *TriangularStrategy) OnBalanceChange(d *dealer.Dealer, e exchange.IBotExchange, x account.Change) error {
+	return nil
+}
+
+func (s *TriangularStrategy) OnTrade(d *dealer.Dealer, e exchange.IBotExchange, x []trade.Data) error {
+	return nil
+}
+
+func (s *TriangularStrategy) OnFill(d *dealer.Dealer, e exchange.IBotExchange, x []fill.Data) error {
+	return nil
+}
+
+func (s *TriangularStrategy) OnUnrecognized(d *dealer.Dealer, e exchange.IBotExchange, x interface{}) error {
+	return nil
+}
+
+func (s *TriangularStrategy) Deinit(d *dealer.Dealer, e exchange.IBotExchange) error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package arbitrage
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+func TestFindCycle(t *testing.T) {
+	btcusdt := currency.NewPair(currency.BTC, currency.USDT)
+	ethbtc := currency.NewPair(currency.ETH, currency.BTC)
+	ethusdt := currency.NewPair(currency.ETH, currency.USDT)
+
+	books := map[currency.Pair]Top{
+		btcusdt: {Bid: 10000, BidAmount: 1, Ask: 10001, AskAmount: 1},
+		ethbtc:  {Bid: 0.05, BidAmount: 10, Ask: 0.0501, AskAmount: 10},
+		ethusdt: {Bid: 520, BidAmount: 2, Ask: 521, AskAmount: 2},
+	}
+
+	o, ok := FindCycle(books, 0.001, currency.USDT)
+	if !ok {
+		t.Fatal("expected an arbitrage cycle")
+	}
+	if len(o.Legs) != 3 {
+		t.Fatalf("expected: %d, actual: %d", 3, len(o.Legs))
+	}
+
+	want := []struct {
+		pair currency.Pair
+		side order.Side
+	}{{btcusdt, order.Buy}, {ethbtc, order.Buy}, {ethusdt, order.Sell}}
+	for i, w := range want {
+		if !o.Legs[i].Pair.Equal(w.pair) || o.Legs[i].Side != w.side {
+			t.Errorf("expected: %s %s, actual: %s %s", w.side, w.pair, o.Legs[i].Side, o.Legs[i].Pair)
+		}
+	}
+
+	expected := 1/10001.0/0.0501*520*math.Pow(0.999, 3) - 1
+	if math.Abs(o.Return-expected) > 1e-12 {
+		t.Errorf("expected: %f, actual: %f", expected, o.Return)
+	}
+
+	// selling 2 ETH at the last leg is the bottleneck
+	if o.Amount <= 0 || o.Amount > 2*10001*0.0501/0.999/0.999+1e-6 {
+		t.Errorf("unexpected amount %f", o.Amount)
+	}
+
+	// without the mispricing there is nothing to gain
+	books[ethusdt] = Top{Bid: 500, BidAmount: 2, Ask: 501, AskAmount: 2}
+	if _, ok = FindCycle(books, 0.001, currency.USDT); ok {
+		t.Error("expected no arbitrage cycle")
+	}
+}
+
+func TestCovered(t *testing.T) {
+	btcusdt := currency.NewPair(currency.BTC, currency.USDT)
+	ethbtc := currency.NewPair(currency.ETH, currency.BTC)
+	ethusdt := currency.NewPair(currency.ETH, currency.USDT)
+
+	holdings := &dealer.ExchangeHoldings{Accounts: map[string]dealer.SubAccount{"": {Balances: map[asset.Item]map[currency.Code]dealer.CurrencyBalance{
+		asset.Spot: {
+			currency.USDT: {Currency: currency.USDT, TotalValue: 1000},
+			currency.BTC:  {Currency: currency.BTC, TotalValue: 0.1, Hold: 0.05},
+			currency.ETH:  {Currency: currency.ETH, TotalValue: 1},
+		},
+	}}}}
+
+	// USDT > BTC > ETH > USDT, every leg spends what is already held
+	orders := []order.Submit{
+		{Pair: btcusdt, Side: order.Buy, Price: 10000, Amount: 0.05},
+		{Pair: ethbtc, Side: order.Buy, Price: 0.05, Amount: 1},
+		{Pair: ethusdt, Side: order.Sell, Price: 520, Amount: 1},
+	}
+	if err := Covered(holdings, asset.Spot, orders); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+
+	// the ETH bought by the second leg can't be sold by the third
+	orders[2].Amount = 1.5
+	if err := Covered(holdings, asset.Spot, orders); !errors.Is(err, ErrNoCycleInventory) {
+		t.Errorf("expected: %v, actual: %v", ErrNoCycleInventory, err)
+	}
+}
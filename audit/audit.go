@@ -0,0 +1,258 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/util"
+)
+
+// defaultPath is where the shared audit log is kept unless AUDIT_LOG points elsewhere
+const defaultPath = "~/.autodealer/audit.jsonl"
+
+// maxLineSize is the longest record that is read, responses of exchanges can be large
+const maxLineSize = 16 << 20
+
+var (
+	ErrChainBroken = errors.New("audit log hash chain is broken")
+	ErrClosed      = errors.New("audit log is closed")
+)
+
+// Record is a line of the audit log. Every record carries the hash of the record before it, the first one the hash of nothing,
+// so changing, removing or reordering a record breaks the chain from there on.
+type Record struct {
+	Seq       uint64          `json:"seq"`
+	Time      time.Time       `json:"time"`
+	Action    string          `json:"action"`
+	Initiator string          `json:"initiator"`
+	Exchange  string          `json:"exchange,omitempty"`
+	Request   json.RawMessage `json:"request,omitempty"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	PrevHash  string          `json:"prevHash"`
+	Hash      string          `json:"hash"`
+}
+
+// hash returns the hash of the record without its own hash
+func (r Record) hash() (string, error) {
+	r.Hash = ""
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Query selects records, empty fields match every record
+type Query struct {
+	Action   string
+	Exchange string
+	Since    time.Time
+	Until    time.Time
+}
+
+// Match reports whether the record is selected by the query
+func (q Query) Match(r Record) bool {
+	switch {
+	case q.Action != "" && q.Action != r.Action:
+		return false
+	case q.Exchange != "" && q.Exchange != r.Exchange:
+		return false
+	case !q.Since.IsZero() && r.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !r.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// Log is an append-only audit log of JSON lines, it implements dealer.Auditor.
+// Records are written to disk before Audit returns, an action is never lost because the process died right after it.
+type Log struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+	seq  uint64
+	last string
+}
+
+var _ dealer.Auditor = (*Log)(nil)
+
+// Open opens the audit log at the path, it is created when it does not exist. The chain of an existing log is verified,
+// a log that has been tampered with is not appended to.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	l := &Log{path: path}
+	if f, err := os.Open(path); err == nil {
+		records, err := Verify(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if n := len(records); n > 0 {
+			l.seq = records[n-1].Seq
+			l.last = records[n-1].Hash
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l.file = f
+	return l, nil
+}
+
+var (
+	auditLog     *Log
+	auditLogErr  error
+	auditLogOnce sync.Once
+)
+
+// Default returns the audit log shared by the application. It is kept at the path of the AUDIT_LOG environment variable,
+// or ~/.autodealer/audit.jsonl. The error is returned when the log can't be opened, for example when its hash chain is broken.
+func Default() (*Log, error) {
+	auditLogOnce.Do(func() {
+		auditLog, auditLogErr = Open(util.StatePath("AUDIT_LOG", defaultPath))
+	})
+	return auditLog, auditLogErr
+}
+
+// Audit appends the entry to the log
+func (l *Log) Audit(ctx context.Context, entry dealer.AuditEntry) error {
+	request, err := marshal(entry.Request)
+	if err != nil {
+		return err
+	}
+	response, err := marshal(entry.Response)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrClosed
+	}
+
+	r := Record{
+		Seq:       l.seq + 1,
+		Time:      entry.Time.UTC(),
+		Action:    entry.Action,
+		Initiator: entry.Initiator,
+		Exchange:  entry.Exchange,
+		Request:   request,
+		Response:  response,
+		Error:     entry.Error,
+		PrevHash:  l.last,
+	}
+	if r.Hash, err = r.hash(); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err = l.file.Write(append(raw, '\n')); err != nil {
+		return err
+	}
+	if err = l.file.Sync(); err != nil {
+		return err
+	}
+
+	l.seq = r.Seq
+	l.last = r.Hash
+	return nil
+}
+
+// Query returns the records matching the query in the order they were written. The chain is verified while reading,
+// ErrChainBroken is returned together with the records read up to the break.
+func (l *Log) Query(q Query) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := Verify(f)
+
+	selected := []Record{}
+	for _, r := range records {
+		if q.Match(r) {
+			selected = append(selected, r)
+		}
+	}
+	return selected, err
+}
+
+// Close closes the log, entries can't be audited anymore
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Verify reads the records and checks the hash chain, the records up to the first broken link are returned
+func Verify(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	var (
+		records []Record
+		last    string
+	)
+	for line := 1; scanner.Scan(); line++ {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return records, fmt.Errorf("%w: line %d: %s", ErrChainBroken, line, err)
+		}
+
+		hash, err := r.hash()
+		if err != nil {
+			return records, err
+		}
+		if r.PrevHash != last || r.Hash != hash || r.Seq != uint64(line) {
+			return records, fmt.Errorf("%w: line %d", ErrChainBroken, line)
+		}
+
+		records = append(records, r)
+		last = r.Hash
+	}
+	return records, scanner.Err()
+}
+
+func marshal(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+)
+
+func testLog(t *testing.T) (*Log, string) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, path
+}
+
+func TestLogChain(t *testing.T) {
+	l, path := testLog(t)
+	ctx := context.Background()
+	start := time.Unix(1600000000, 0)
+
+	entries := []dealer.AuditEntry{
+		{Time: start, Action: dealer.AuditSubmitOrder, Initiator: "dealer", Exchange: "binance", Request: map[string]float64{"amount": 1}},
+		{Time: start.Add(time.Minute), Action: dealer.AuditWithdraw, Initiator: "api 127.0.0.1", Exchange: "kraken", Error: "insufficient funds"},
+		{Time: start.Add(2 * time.Minute), Action: dealer.AuditCancelOrder, Initiator: "dealer", Exchange: "binance"},
+	}
+	for _, entry := range entries {
+		if err := l.Audit(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the chain continues after the log is opened again
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = l.Audit(ctx, dealer.AuditEntry{Time: start.Add(3 * time.Minute), Action: dealer.AuditBankTransfer, Exchange: "kraken"}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := l.Query(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected: %v, actual: %v", 4, len(records))
+	}
+	for i, r := range records {
+		if r.Seq != uint64(i+1) {
+			t.Errorf("expected: %v, actual: %v", i+1, r.Seq)
+		}
+		if i > 0 && r.PrevHash != records[i-1].Hash {
+			t.Errorf("expected record %d to chain to the one before", r.Seq)
+		}
+	}
+
+	binance, err := l.Query(Query{Exchange: "binance", Since: start.Add(time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(binance) != 1 || binance[0].Action != dealer.AuditCancelOrder {
+		t.Errorf("expected: the cancellation on binance, actual: %v", binance)
+	}
+}
+
+func TestLogTampered(t *testing.T) {
+	l, path := testLog(t)
+	ctx := context.Background()
+
+	for _, amount := range []string{"1", "2", "3"} {
+		if err := l.Audit(ctx, dealer.AuditEntry{Time: time.Unix(1600000000, 0), Action: dealer.AuditWithdraw, Request: map[string]string{"amount": amount}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(raw), `{"amount":"2"}`, `{"amount":"20"}`, 1)
+	if err = os.WriteFile(path, []byte(tampered), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := Verify(f)
+	if !errors.Is(err, ErrChainBroken) {
+		t.Errorf("expected: %v, actual: %v", ErrChainBroken, err)
+	}
+	if len(records) != 1 {
+		t.Errorf("expected: %v, actual: %v", 1, len(records))
+	}
+
+	if _, err = Open(path); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("expected: %v, actual: %v", ErrChainBroken, err)
+	}
+}
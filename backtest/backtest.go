@@ -0,0 +1,360 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/paper"
+	"github.com/romanornr/autodealer/pricing"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/engine"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+const (
+	strategyName = "backtest"
+
+	defaultBalanceRefresh = time.Minute
+)
+
+var (
+	ErrNoEvents    = errors.New("no events to replay")
+	ErrNoExchanges = errors.New("no exchanges configured")
+)
+
+// Config configures a backtest
+type Config struct {
+	// Exchanges are the simulated exchanges with their starting balances and fees, events of other exchanges are skipped
+	Exchanges map[string]paper.Config
+	// Quote is the currency the report is valued in, USDT by default
+	Quote currency.Code
+	// Depth is the amount assumed to be available at the price of tickers, klines and trades, which carry no order book.
+	// The depth is unlimited when it is zero.
+	Depth float64
+	// BalanceRefresh is the interval of the balances strategy in virtual time
+	BalanceRefresh time.Duration
+}
+
+// backtest is the state of a single run
+type backtest struct {
+	config    Config
+	clock     *VirtualClock
+	dealer    *dealer.Dealer
+	exchanges map[string]*paper.Exchange
+	assets    map[asset.Item]bool
+	books     map[string]map[currency.Pair]*orderbook.Base
+	report    *Report
+	// fills counts the trades of every order that are in the report
+	fills map[string]int
+}
+
+// Run replays the events in chronological order through the strategy, like the dealer streams them from the exchanges.
+// Orders are filled by paper exchanges against the recorded order books, or against a book made from the last price when the data
+// has no order books. Strategy tickers and historians run on a virtual clock that follows the events.
+func Run(ctx context.Context, c Config, s dealer.Strategy, events []Event) (*Report, error) {
+	if len(events) == 0 {
+		return nil, ErrNoEvents
+	}
+	if len(c.Exchanges) == 0 {
+		return nil, ErrNoExchanges
+	}
+	if c.Quote.IsEmpty() {
+		c.Quote = currency.USDT
+	}
+	if c.Depth <= 0 {
+		c.Depth = math.MaxFloat64
+	}
+	if c.BalanceRefresh <= 0 {
+		c.BalanceRefresh = defaultBalanceRefresh
+	}
+
+	Sort(events)
+
+	b := &backtest{
+		config:    c,
+		clock:     NewVirtualClock(events[0].Time),
+		exchanges: make(map[string]*paper.Exchange),
+		assets:    make(map[asset.Item]bool),
+		books:     make(map[string]map[currency.Pair]*orderbook.Base),
+		report:    &Report{Start: events[0].Time, End: events[len(events)-1].Time, Quote: c.Quote},
+		fills:     make(map[string]int),
+	}
+
+	if err := b.setup(events); err != nil {
+		return nil, err
+	}
+
+	b.dealer.Root.Add(strategyName, s)
+	for _, e := range b.exchanges {
+		if err := b.dealer.Root.Init(ctx, b.dealer, e); err != nil {
+			return nil, fmt.Errorf("failed to initialize strategy: %w", err)
+		}
+	}
+	b.flush()
+
+	for _, event := range events {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		e, ok := b.exchanges[strings.ToLower(event.Exchange)]
+		if !ok {
+			continue
+		}
+
+		b.clock.Advance(event.Time)
+		b.flush()
+
+		if book := b.book(event.Data); book != nil {
+			b.books[strings.ToLower(event.Exchange)][book.Pair] = book
+			e.Match(book)
+			b.flush()
+		}
+
+		if err := dealer.Dispatch(b.dealer, e, &b.dealer.Root, event.Data); err != nil {
+			logrus.Errorf("backtest: %s\n", err)
+		}
+		b.flush()
+
+		b.report.Events++
+		b.report.sample(b.equity(ctx))
+	}
+
+	for _, e := range b.exchanges {
+		if err := b.dealer.Root.Deinit(b.dealer, e); err != nil {
+			logrus.Errorf("backtest: deinit %s: %s\n", e.GetName(), err)
+		}
+	}
+
+	b.report.finish()
+	return b.report, nil
+}
+
+// setup creates a paper exchange for every configured exchange, the pairs of the events are enabled on them
+func (b *backtest) setup(events []Event) error {
+	pairs := make(map[string]map[asset.Item]currency.Pairs)
+	for _, event := range events {
+		name := strings.ToLower(event.Exchange)
+		pair, a, ok := market(event.Data)
+		if !ok {
+			continue
+		}
+		if _, ok = pairs[name]; !ok {
+			pairs[name] = make(map[asset.Item]currency.Pairs)
+		}
+		if !pairs[name][a].Contains(pair, true) {
+			pairs[name][a] = append(pairs[name][a], pair)
+		}
+		b.assets[a] = true
+	}
+
+	exchanges := make([]exchange.IBotExchange, 0, len(b.config.Exchanges))
+	for name, c := range b.config.Exchanges {
+		e, err := engine.NewSupportedExchangeByName(name)
+		if err != nil {
+			return err
+		}
+		e.SetDefaults()
+
+		base := e.GetBase()
+		base.Name = strings.ToLower(name)
+		for a, ps := range pairs[base.Name] {
+			base.CurrencyPairs.StorePairs(a, ps, false)
+			base.CurrencyPairs.StorePairs(a, ps, true)
+			if err = base.CurrencyPairs.SetAssetEnabled(a, true); err != nil && !errors.Is(err, currency.ErrAssetAlreadyEnabled) {
+				return err
+			}
+		}
+
+		c.Replay = true
+		c.Now = b.clock.Now
+		p := paper.New(e, c)
+
+		b.exchanges[base.Name] = p
+		b.books[base.Name] = make(map[currency.Pair]*orderbook.Base)
+		exchanges = append(exchanges, p)
+	}
+
+	d, err := dealer.NewBuilder().
+		Balances(b.config.BalanceRefresh).
+		Clock(b.clock).
+		BuildWithExchanges(exchanges...)
+	if err != nil {
+		return err
+	}
+	b.dealer = d
+	return nil
+}
+
+// market returns the pair and asset the data is about
+func market(data interface{}) (currency.Pair, asset.Item, bool) {
+	switch x := data.(type) {
+	case *ticker.Price:
+		return x.Pair, x.AssetType, true
+	case *stream.KlineData:
+		return x.Pair, x.AssetType, true
+	case *orderbook.Base:
+		return x.Pair, x.Asset, true
+	case []trade.Data:
+		if len(x) > 0 {
+			return x[0].CurrencyPair, x[0].AssetType, true
+		}
+	case stream.FundingData:
+		return x.CurrencyPair, x.AssetType, true
+	}
+	return currency.Pair{}, asset.Empty, false
+}
+
+// book returns the order book orders are filled against. Recorded order books are used as they are,
+// other data becomes a book with a single level at its price and the configured depth.
+func (b *backtest) book(data interface{}) *orderbook.Base {
+	var (
+		bid, ask         float64
+		bidSize, askSize = b.config.Depth, b.config.Depth
+	)
+
+	switch x := data.(type) {
+	case *orderbook.Base:
+		return x
+	case *ticker.Price:
+		bid, ask = x.Bid, x.Ask
+		if bid <= 0 || ask <= 0 {
+			bid, ask = x.Last, x.Last
+		}
+		if x.BidSize > 0 && x.AskSize > 0 {
+			bidSize, askSize = x.BidSize, x.AskSize
+		}
+	case *stream.KlineData:
+		bid, ask = x.ClosePrice, x.ClosePrice
+	case []trade.Data:
+		if len(x) == 0 {
+			return nil
+		}
+		bid, ask = x[len(x)-1].Price, x[len(x)-1].Price
+	default:
+		return nil
+	}
+
+	if bid <= 0 || ask <= 0 {
+		return nil
+	}
+
+	pair, a, _ := market(data)
+	return &orderbook.Base{
+		Pair:        pair,
+		Asset:       a,
+		Bids:        orderbook.Items{{Price: bid, Amount: bidSize}},
+		Asks:        orderbook.Items{{Price: ask, Amount: askSize}},
+		LastUpdated: b.clock.Now(),
+	}
+}
+
+// flush dispatches the order updates of the paper exchanges until the strategies stop reacting to them
+func (b *backtest) flush() {
+	for {
+		dispatched := false
+		for _, e := range b.exchanges {
+			for _, x := range e.Drain() {
+				dispatched = true
+				if d, ok := x.(*order.Detail); ok {
+					b.record(d)
+				}
+				if err := dealer.Dispatch(b.dealer, e, &b.dealer.Root, x); err != nil {
+					logrus.Errorf("backtest: %s\n", err)
+				}
+			}
+		}
+		if !dispatched {
+			return
+		}
+	}
+}
+
+// record adds the new executions of the order to the report
+func (b *backtest) record(d *order.Detail) {
+	key := d.Exchange + "/" + d.OrderID
+	for _, t := range d.Trades[b.fills[key]:] {
+		rate := b.rate(d.Exchange, d.Pair.Quote)
+
+		b.report.Trades = append(b.report.Trades, Trade{
+			Time:     t.Timestamp,
+			Exchange: d.Exchange,
+			OrderID:  d.OrderID,
+			Pair:     d.Pair,
+			Side:     d.Side,
+			Price:    t.Price,
+			Amount:   t.Amount,
+			Fee:      t.Fee,
+			FeeAsset: d.FeeAsset,
+		})
+		b.report.Turnover += t.Price * t.Amount * rate
+		b.report.Fees += t.Fee * rate
+	}
+	b.fills[key] = len(d.Trades)
+}
+
+// rate returns the value of the currency in the quote currency of the report from the last books of the exchange, zero when unknown
+func (b *backtest) rate(exchangeName string, code currency.Code) float64 {
+	if pricing.Equivalent(code, b.config.Quote) {
+		return 1
+	}
+
+	books := b.books[strings.ToLower(exchangeName)]
+	pairs := make(currency.Pairs, 0, len(books))
+	for pair := range books {
+		pairs = append(pairs, pair)
+	}
+
+	path, err := pricing.Resolve(pricing.NewGraph(pairs), code, b.config.Quote)
+	if err != nil {
+		return 0
+	}
+
+	rate := 1.0
+	for _, step := range path {
+		book := books[step.Pair]
+		if len(book.Bids) == 0 || len(book.Asks) == 0 {
+			return 0
+		}
+
+		// the equity is valued at the middle of the book
+		mid := (book.Bids[0].Price + book.Asks[0].Price) / 2
+		r, err := pricing.Rate(step, ticker.Price{Pair: step.Pair, Last: mid})
+		if err != nil {
+			return 0
+		}
+		rate *= r
+	}
+	return rate
+}
+
+// equity returns the value of the virtual balances of all exchanges
+func (b *backtest) equity(ctx context.Context) float64 {
+	var total float64
+	for name, e := range b.exchanges {
+		for a := range b.assets {
+			h, err := e.UpdateAccountInfo(ctx, a)
+			if err != nil {
+				continue
+			}
+			for _, sub := range h.Accounts {
+				for _, balance := range sub.Currencies {
+					total += balance.Total * b.rate(name, balance.Currency)
+				}
+			}
+		}
+	}
+	return total
+}
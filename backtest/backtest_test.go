@@ -0,0 +1,276 @@
+package backtest
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/paper"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+var testPair = currency.NewPair(currency.BTC, currency.USDT)
+
+func TestVirtualClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	c := NewVirtualClock(start)
+
+	var fired []time.Time
+	stop := c.Every(time.Minute, func() {
+		fired = append(fired, c.Now())
+	})
+
+	c.Advance(start.Add(150 * time.Second))
+	if len(fired) != 3 {
+		t.Fatalf("expected: %v, actual: %v", 3, len(fired))
+	}
+	if !fired[2].Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("expected: %v, actual: %v", start.Add(2*time.Minute), fired[2])
+	}
+	if !c.Now().Equal(start.Add(150 * time.Second)) {
+		t.Errorf("expected: %v, actual: %v", start.Add(150*time.Second), c.Now())
+	}
+
+	stop()
+	c.Advance(start.Add(time.Hour))
+	if len(fired) != 3 {
+		t.Errorf("expected: %v, actual: %v", 3, len(fired))
+	}
+
+	c.Advance(start)
+	if !c.Now().Equal(start.Add(time.Hour)) {
+		t.Errorf("expected: %v, actual: %v", start.Add(time.Hour), c.Now())
+	}
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	now := time.Unix(0, 1600000000123456789)
+	price := &ticker.Price{Pair: testPair, AssetType: asset.Spot, Bid: 99, Ask: 101, Last: 100}
+
+	records, err := NewRecords(now, "binance", price)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Time != now.UnixNano() {
+		t.Fatalf("expected: %v, actual: %v", now.UnixNano(), records)
+	}
+
+	e, err := records[0].Event()
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, ok := e.Data.(*ticker.Price)
+	if !ok {
+		t.Fatalf("expected: %v, actual: %T", "*ticker.Price", e.Data)
+	}
+	if !e.Time.Equal(now) || !x.Pair.Equal(testPair) || x.Bid != 99 || x.Ask != 101 {
+		t.Errorf("expected: %v, actual: %v", price, x)
+	}
+
+	records, err = NewRecords(now, "binance", []trade.Data{{CurrencyPair: testPair, AssetType: asset.Spot, Side: order.Buy, Price: 100, Amount: 1}, {CurrencyPair: testPair, AssetType: asset.Spot, Side: order.Sell, Price: 99, Amount: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Errorf("expected: %v, actual: %v", 2, len(records))
+	}
+
+	records, err = NewRecords(now, "binance", "not market data")
+	if err != nil || len(records) != 0 {
+		t.Errorf("expected: %v, actual: %v %v", "no records", records, err)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	input := "time,exchange,pair,bid,ask,last\n1600000000,binance,BTC-USDT,99,101,100\n1600000001000,binance,BTC-USDT,100,102,101\n"
+
+	events, err := ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected: %v, actual: %v", 2, len(events))
+	}
+	if !events[1].Time.Equal(time.Unix(1600000001, 0)) {
+		t.Errorf("expected: %v, actual: %v", time.Unix(1600000001, 0), events[1].Time)
+	}
+	if x := events[0].Data.(*ticker.Price); x.Ask != 101 || x.AssetType != asset.Spot {
+		t.Errorf("expected: %v, actual: %v", 101, x.Ask)
+	}
+
+	if _, err = ReadCSV(strings.NewReader("time,exchange,pair,foo\n")); err == nil {
+		t.Errorf("expected: %v, actual: %v", ErrUnknownHeader, err)
+	}
+	if _, err = ReadCSV(strings.NewReader("exchange,pair,bid,ask\n")); err == nil {
+		t.Errorf("expected: %v, actual: %v", ErrMissingColumn, err)
+	}
+}
+
+func TestReadJSONL(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	records, err := NewRecords(now, "binance", &stream.KlineData{Pair: testPair, AssetType: asset.Spot, ClosePrice: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(append(line, '\n'))
+	}
+
+	events, err := ReadJSONL(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected: %v, actual: %v", 1, len(events))
+	}
+	if x := events[0].Data.(*stream.KlineData); x.ClosePrice != 100 {
+		t.Errorf("expected: %v, actual: %v", 100, x.ClosePrice)
+	}
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	dd := MaxDrawdown([]float64{100, 120, 90, 110, 60, 130})
+	if math.Abs(dd-0.5) > 1e-9 {
+		t.Errorf("expected: %v, actual: %v", 0.5, dd)
+	}
+
+	if dd = MaxDrawdown([]float64{1, 2, 3}); dd != 0 {
+		t.Errorf("expected: %v, actual: %v", 0, dd)
+	}
+}
+
+// buyOnce buys a fixed amount at market on the first price it sees
+type buyOnce struct {
+	amount float64
+	bought bool
+	orders int
+}
+
+func (s *buyOnce) Init(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	return nil
+}
+
+func (s *buyOnce) OnFunding(d *dealer.Dealer, e exchange.IBotExchange, x stream.FundingData) error {
+	return nil
+}
+
+func (s *buyOnce) OnPrice(d *dealer.Dealer, e exchange.IBotExchange, x ticker.Price) error {
+	if s.bought {
+		return nil
+	}
+	s.bought = true
+
+	_, err := d.SubmitOrder(context.Background(), e, order.Submit{
+		Exchange:  e.GetName(),
+		Pair:      x.Pair,
+		AssetType: x.AssetType,
+		Side:      order.Buy,
+		Type:      order.Market,
+		Amount:    s.amount,
+	})
+	return err
+}
+
+func (s *buyOnce) OnKline(d *dealer.Dealer, e exchange.IBotExchange, x stream.KlineData) error {
+	return nil
+}
+
+func (s *buyOnce) OnOrderBook(d *dealer.Dealer, e exchange.IBotExchange, x orderbook.Base) error {
+	return nil
+}
+
+func (s *buyOnce) OnOrder(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) error {
+	s.orders++
+	return nil
+}
+
+func (s *buyOnce) OnModify(d *dealer.Dealer, e exchange.IBotExchange, x order.Modify) error {
+	return nil
+}
+
+func (s *buyOnce) OnBalanceChange(d *dealer.Dealer, e exchange.IBotExchange, x account.Change) error {
+	return nil
+}
+
+func (s *buyOnce) OnTrade(d *dealer.Dealer, e exchange.IBotExchange, x []trade.Data) error {
+	return nil
+}
+
+func (s *buyOnce) OnFill(d *dealer.Dealer, e exchange.IBotExchange, x []fill.Data) error {
+	return nil
+}
+
+func (s *buyOnce) OnUnrecognized(d *dealer.Dealer, e exchange.IBotExchange, x interface{}) error {
+	return nil
+}
+
+func (s *buyOnce) Deinit(d *dealer.Dealer, e exchange.IBotExchange) error {
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	events := make([]Event, 0, 3)
+	for i, last := range []float64{100, 80, 120} {
+		events = append(events, Event{
+			Time:     start.Add(time.Duration(i) * time.Minute),
+			Exchange: "binance",
+			Data:     &ticker.Price{ExchangeName: "binance", Pair: testPair, AssetType: asset.Spot, Last: last},
+		})
+	}
+
+	s := &buyOnce{amount: 1}
+	report, err := Run(context.Background(), Config{
+		Exchanges: map[string]paper.Config{
+			"binance": {Balances: map[currency.Code]float64{currency.USDT: 1000}, TakerFee: 0.001},
+		},
+	}, s, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Trades) != 1 {
+		t.Fatalf("expected: %v, actual: %v", 1, len(report.Trades))
+	}
+	if tr := report.Trades[0]; tr.Price != 100 || tr.Amount != 1 {
+		t.Errorf("expected: %v, actual: %v", "1 at 100", tr)
+	}
+	if s.orders == 0 {
+		t.Errorf("expected: %v, actual: %v", "order updates", s.orders)
+	}
+	if math.Abs(report.Turnover-100) > 1e-9 {
+		t.Errorf("expected: %v, actual: %v", 100, report.Turnover)
+	}
+	if report.Fees <= 0 {
+		t.Errorf("expected: %v, actual: %v", "fees", report.Fees)
+	}
+	if report.MaxDrawdown <= 0 {
+		t.Errorf("expected: %v, actual: %v", "drawdown", report.MaxDrawdown)
+	}
+	if report.Events != 3 || report.FinalEquity <= report.InitialEquity {
+		t.Errorf("expected: %v, actual: %v", "profit over 3 events", report)
+	}
+
+	if _, err = Run(context.Background(), Config{}, s, nil); err != ErrNoEvents {
+		t.Errorf("expected: %v, actual: %v", ErrNoEvents, err)
+	}
+}
@@ -0,0 +1,85 @@
+package backtest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+)
+
+// VirtualClock is a dealer.Clock whose time only moves when it is advanced. Periodic work registered through Every runs
+// synchronously while the clock is advanced, so strategy tickers fire at the same virtual times on every run.
+type VirtualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+type timer struct {
+	interval time.Duration
+	next     time.Time
+	f        func()
+	stopped  bool
+}
+
+var _ dealer.Clock = (*VirtualClock)(nil)
+
+// NewVirtualClock returns a clock set to the start time
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+// Now returns the virtual time
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Every calls f right away and then every interval of virtual time
+func (c *VirtualClock) Every(interval time.Duration, f func()) func() {
+	f()
+
+	if interval <= 0 {
+		return func() {}
+	}
+
+	c.mu.Lock()
+	t := &timer{interval: interval, next: c.now.Add(interval), f: f}
+	c.timers = append(c.timers, t)
+	c.mu.Unlock()
+
+	return func() {
+		c.mu.Lock()
+		t.stopped = true
+		c.mu.Unlock()
+	}
+}
+
+// Advance moves the clock forward and fires every timer that is due on the way, in chronological order.
+// The clock never moves backwards.
+func (c *VirtualClock) Advance(to time.Time) {
+	for {
+		c.mu.Lock()
+		var due *timer
+		for _, t := range c.timers {
+			if !t.stopped && !t.next.After(to) && (due == nil || t.next.Before(due.next)) {
+				due = t
+			}
+		}
+
+		if due == nil {
+			if to.After(c.now) {
+				c.now = to
+			}
+			c.mu.Unlock()
+			return
+		}
+
+		c.now = due.next
+		due.next = due.next.Add(due.interval)
+		c.mu.Unlock()
+
+		due.f()
+	}
+}
@@ -0,0 +1,278 @@
+package backtest
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+// maxLineSize is the longest JSONL line that is read, deep order books make long lines
+const maxLineSize = 16 << 20
+
+var (
+	ErrUnknownFormat = errors.New("unknown file format, expected .jsonl, .jsonl.gz or .csv")
+	ErrUnknownHeader = errors.New("unknown csv header, expected ticker, kline or trade columns")
+	ErrMissingColumn = errors.New("missing csv column")
+)
+
+// Load reads the events of all files and returns them in chronological order.
+// JSONL files contain records, optionally gzip compressed. CSV files contain tickers, klines or trades, the kind is derived from the header.
+// Directories are searched for such files, so the output directory of a recorder can be replayed as a whole.
+func Load(paths ...string) ([]Event, error) {
+	files, err := expand(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, path := range files {
+		xs, err := loadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		events = append(events, xs...)
+	}
+
+	Sort(events)
+	return events, nil
+}
+
+// Sort orders the events chronologically, events at the same time keep their order
+func Sort(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+}
+
+// expand replaces directories by the files with a known format below them
+func expand(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			switch filepath.Ext(strings.TrimSuffix(strings.ToLower(name), ".gz")) {
+			case ".jsonl", ".json", ".csv":
+				files = append(files, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func loadFile(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	name := strings.ToLower(path)
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+		name = strings.TrimSuffix(name, ".gz")
+	}
+
+	switch filepath.Ext(name) {
+	case ".jsonl", ".json":
+		return ReadJSONL(r)
+	case ".csv":
+		return ReadCSV(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// ReadJSONL reads one record per line, empty lines are skipped
+func ReadJSONL(r io.Reader) ([]Event, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	var (
+		events []Event
+		line   int
+	)
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		e, err := record.Event()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// ReadCSV reads tickers, klines or trades. Every file starts with a header, the columns time, exchange and pair are required,
+// asset defaults to spot. Tickers have bid, ask and last columns, klines open, high, low, close and volume, trades side, price and amount.
+func ReadCSV(r io.Reader) ([]Event, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var kind string
+	switch {
+	case has(columns, "bid", "ask"):
+		kind = TypeTicker
+	case has(columns, "open", "close"):
+		kind = TypeKline
+	case has(columns, "side", "price", "amount"):
+		kind = TypeTrade
+	default:
+		return nil, ErrUnknownHeader
+	}
+	if !has(columns, "time", "exchange", "pair") {
+		return nil, fmt.Errorf("%w: time, exchange and pair are required", ErrMissingColumn)
+	}
+
+	var events []Event
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := csvEvent(kind, columns, row)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+}
+
+func has(columns map[string]int, names ...string) bool {
+	for _, name := range names {
+		if _, ok := columns[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// csvEvent converts a row of the given kind
+func csvEvent(kind string, columns map[string]int, row []string) (Event, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var err error
+	number := func(name string) float64 {
+		s := field(name)
+		if s == "" || err != nil {
+			return 0
+		}
+		var v float64
+		v, err = strconv.ParseFloat(s, 64)
+		return v
+	}
+
+	t, err := ParseTime(field("time"))
+	if err != nil {
+		return Event{}, err
+	}
+
+	pair, err := currency.NewPairFromString(field("pair"))
+	if err != nil {
+		return Event{}, err
+	}
+
+	a := asset.Spot
+	if s := field("asset"); s != "" {
+		if a, err = asset.New(s); err != nil {
+			return Event{}, err
+		}
+	}
+
+	e := Event{Time: t, Exchange: field("exchange")}
+
+	switch kind {
+	case TypeTicker:
+		e.Data = TickerData{
+			Pair: pair, Asset: a, Bid: number("bid"), BidSize: number("bidsize"), Ask: number("ask"), AskSize: number("asksize"),
+			Last: number("last"), Volume: number("volume"),
+		}.Price(e.Exchange, t)
+	case TypeKline:
+		e.Data = KlineData{
+			Pair: pair, Asset: a, Interval: field("interval"), Start: t,
+			Open: number("open"), High: number("high"), Low: number("low"), Close: number("close"), Volume: number("volume"),
+		}.Kline(e.Exchange, t)
+	case TypeTrade:
+		var x trade.Data
+		x, err = TradeData{Pair: pair, Asset: a, Side: field("side"), Price: number("price"), Amount: number("amount")}.Trade(e.Exchange, t)
+		e.Data = []trade.Data{x}
+	}
+	return e, err
+}
+
+// ParseTime parses RFC 3339 times and unix timestamps in seconds, milliseconds or nanoseconds
+func ParseTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		switch {
+		case n < 1e11:
+			return time.Unix(n, 0), nil
+		case n < 1e14:
+			return time.UnixMilli(n), nil
+		default:
+			return time.Unix(0, n), nil
+		}
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
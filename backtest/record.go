@@ -0,0 +1,294 @@
+package backtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+// Record types
+const (
+	TypeTicker    = "ticker"
+	TypeKline     = "kline"
+	TypeOrderBook = "orderbook"
+	TypeTrade     = "trade"
+	TypeFunding   = "funding"
+)
+
+var ErrUnknownRecordType = errors.New("unknown record type")
+
+// Event is a single message of market data. Data has the type the websocket delivers it in:
+// *ticker.Price, *stream.KlineData, *orderbook.Base, []trade.Data or stream.FundingData.
+type Event struct {
+	Time     time.Time
+	Exchange string
+	Data     interface{}
+}
+
+// Record is the line format of recorded market data, one JSON object per line
+type Record struct {
+	// Time is the time the message was received in unix nanoseconds
+	Time     int64           `json:"time"`
+	Exchange string          `json:"exchange"`
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Level is a price and amount of an order book side
+type Level [2]float64
+
+// TickerData is the recorded form of a ticker
+type TickerData struct {
+	Pair    currency.Pair `json:"pair"`
+	Asset   asset.Item    `json:"asset"`
+	Bid     float64       `json:"bid"`
+	BidSize float64       `json:"bidSize,omitempty"`
+	Ask     float64       `json:"ask"`
+	AskSize float64       `json:"askSize,omitempty"`
+	Last    float64       `json:"last"`
+	Volume  float64       `json:"volume,omitempty"`
+}
+
+// KlineData is the recorded form of a candle
+type KlineData struct {
+	Pair     currency.Pair `json:"pair"`
+	Asset    asset.Item    `json:"asset"`
+	Interval string        `json:"interval"`
+	Start    time.Time     `json:"start"`
+	Open     float64       `json:"open"`
+	High     float64       `json:"high"`
+	Low      float64       `json:"low"`
+	Close    float64       `json:"close"`
+	Volume   float64       `json:"volume"`
+}
+
+// OrderBookData is the recorded form of an order book
+type OrderBookData struct {
+	Pair  currency.Pair `json:"pair"`
+	Asset asset.Item    `json:"asset"`
+	Bids  []Level       `json:"bids"`
+	Asks  []Level       `json:"asks"`
+}
+
+// TradeData is the recorded form of a public trade
+type TradeData struct {
+	Pair   currency.Pair `json:"pair"`
+	Asset  asset.Item    `json:"asset"`
+	Side   string        `json:"side"`
+	Price  float64       `json:"price"`
+	Amount float64       `json:"amount"`
+}
+
+// FundingData is the recorded form of a funding rate update
+type FundingData struct {
+	Pair   currency.Pair `json:"pair"`
+	Asset  asset.Item    `json:"asset"`
+	Amount float64       `json:"amount,omitempty"`
+	Rate   float64       `json:"rate"`
+	Period int64         `json:"period,omitempty"`
+	Side   string        `json:"side,omitempty"`
+}
+
+// NewRecords converts a websocket message into records, a batch of trades becomes a record per trade.
+// Messages that are not market data return no records.
+func NewRecords(t time.Time, exchangeName string, data interface{}) ([]Record, error) {
+	var (
+		kind     string
+		payloads []interface{}
+	)
+
+	switch x := data.(type) {
+	case *ticker.Price:
+		kind = TypeTicker
+		payloads = append(payloads, TickerData{
+			Pair: recordPair(x.Pair), Asset: x.AssetType, Bid: x.Bid, BidSize: x.BidSize, Ask: x.Ask, AskSize: x.AskSize, Last: x.Last, Volume: x.Volume,
+		})
+	case *stream.KlineData:
+		kind = TypeKline
+		payloads = append(payloads, KlineData{
+			Pair: recordPair(x.Pair), Asset: x.AssetType, Interval: x.Interval, Start: x.StartTime,
+			Open: x.OpenPrice, High: x.HighPrice, Low: x.LowPrice, Close: x.ClosePrice, Volume: x.Volume,
+		})
+	case *orderbook.Base:
+		kind = TypeOrderBook
+		payloads = append(payloads, OrderBookData{Pair: recordPair(x.Pair), Asset: x.Asset, Bids: levels(x.Bids), Asks: levels(x.Asks)})
+	case []trade.Data:
+		kind = TypeTrade
+		for i := range x {
+			payloads = append(payloads, TradeData{
+				Pair: recordPair(x[i].CurrencyPair), Asset: x[i].AssetType, Side: x[i].Side.String(), Price: x[i].Price, Amount: x[i].Amount,
+			})
+		}
+	case stream.FundingData:
+		kind = TypeFunding
+		payloads = append(payloads, FundingData{
+			Pair: recordPair(x.CurrencyPair), Asset: x.AssetType, Amount: x.Amount, Rate: x.Rate, Period: x.Period, Side: x.Side.String(),
+		})
+	default:
+		return nil, nil
+	}
+
+	records := make([]Record, 0, len(payloads))
+	for _, payload := range payloads {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, Record{Time: t.UnixNano(), Exchange: exchangeName, Type: kind, Data: raw})
+	}
+	return records, nil
+}
+
+// Event decodes the record into the message the websocket would have delivered
+func (r Record) Event() (Event, error) {
+	e := Event{Time: time.Unix(0, r.Time), Exchange: r.Exchange}
+
+	switch r.Type {
+	case TypeTicker:
+		var x TickerData
+		if err := json.Unmarshal(r.Data, &x); err != nil {
+			return e, err
+		}
+		e.Data = x.Price(r.Exchange, e.Time)
+	case TypeKline:
+		var x KlineData
+		if err := json.Unmarshal(r.Data, &x); err != nil {
+			return e, err
+		}
+		e.Data = x.Kline(r.Exchange, e.Time)
+	case TypeOrderBook:
+		var x OrderBookData
+		if err := json.Unmarshal(r.Data, &x); err != nil {
+			return e, err
+		}
+		e.Data = x.OrderBook(r.Exchange, e.Time)
+	case TypeTrade:
+		var x TradeData
+		if err := json.Unmarshal(r.Data, &x); err != nil {
+			return e, err
+		}
+		t, err := x.Trade(r.Exchange, e.Time)
+		if err != nil {
+			return e, err
+		}
+		e.Data = []trade.Data{t}
+	case TypeFunding:
+		var x FundingData
+		if err := json.Unmarshal(r.Data, &x); err != nil {
+			return e, err
+		}
+		f, err := x.Funding(r.Exchange, e.Time)
+		if err != nil {
+			return e, err
+		}
+		e.Data = f
+	default:
+		return e, fmt.Errorf("%w: %s", ErrUnknownRecordType, r.Type)
+	}
+	return e, nil
+}
+
+// Price converts the recorded ticker
+func (x TickerData) Price(exchangeName string, t time.Time) *ticker.Price {
+	return &ticker.Price{
+		ExchangeName: exchangeName,
+		Pair:         x.Pair,
+		AssetType:    x.Asset,
+		Bid:          x.Bid,
+		BidSize:      x.BidSize,
+		Ask:          x.Ask,
+		AskSize:      x.AskSize,
+		Last:         x.Last,
+		Volume:       x.Volume,
+		LastUpdated:  t,
+	}
+}
+
+// Kline converts the recorded candle
+func (x KlineData) Kline(exchangeName string, t time.Time) *stream.KlineData {
+	return &stream.KlineData{
+		Timestamp:  t,
+		Pair:       x.Pair,
+		AssetType:  x.Asset,
+		Exchange:   exchangeName,
+		StartTime:  x.Start,
+		CloseTime:  t,
+		Interval:   x.Interval,
+		OpenPrice:  x.Open,
+		HighPrice:  x.High,
+		LowPrice:   x.Low,
+		ClosePrice: x.Close,
+		Volume:     x.Volume,
+	}
+}
+
+// OrderBook converts the recorded order book
+func (x OrderBookData) OrderBook(exchangeName string, t time.Time) *orderbook.Base {
+	b := &orderbook.Base{Exchange: exchangeName, Pair: x.Pair, Asset: x.Asset, LastUpdated: t}
+	for _, l := range x.Bids {
+		b.Bids = append(b.Bids, orderbook.Item{Price: l[0], Amount: l[1]})
+	}
+	for _, l := range x.Asks {
+		b.Asks = append(b.Asks, orderbook.Item{Price: l[0], Amount: l[1]})
+	}
+	return b
+}
+
+// Trade converts the recorded trade
+func (x TradeData) Trade(exchangeName string, t time.Time) (trade.Data, error) {
+	side, err := order.StringToOrderSide(x.Side)
+	if err != nil && x.Side != "" && x.Side != order.UnknownSide.String() {
+		return trade.Data{}, err
+	}
+
+	return trade.Data{
+		Exchange:     exchangeName,
+		CurrencyPair: x.Pair,
+		AssetType:    x.Asset,
+		Side:         side,
+		Price:        x.Price,
+		Amount:       x.Amount,
+		Timestamp:    t,
+	}, nil
+}
+
+// Funding converts the recorded funding rate update
+func (x FundingData) Funding(exchangeName string, t time.Time) (stream.FundingData, error) {
+	side, err := order.StringToOrderSide(x.Side)
+	if err != nil && x.Side != "" && x.Side != order.UnknownSide.String() {
+		return stream.FundingData{}, err
+	}
+
+	return stream.FundingData{
+		Timestamp:    t,
+		CurrencyPair: x.Pair,
+		AssetType:    x.Asset,
+		Exchange:     exchangeName,
+		Amount:       x.Amount,
+		Rate:         x.Rate,
+		Period:       x.Period,
+		Side:         side,
+	}, nil
+}
+
+// recordPair formats the pair with a delimiter, pairs without one can't be parsed back without knowing the exchange
+func recordPair(p currency.Pair) currency.Pair {
+	return p.Format(currency.PairFormat{Uppercase: true, Delimiter: currency.DashDelimiter})
+}
+
+func levels(items orderbook.Items) []Level {
+	xs := make([]Level, 0, len(items))
+	for _, i := range items {
+		xs = append(xs, Level{i.Price, i.Amount})
+	}
+	return xs
+}
@@ -0,0 +1,82 @@
+package backtest
+
+import (
+	"math"
+	"time"
+
+	"github.com/romanornr/autodealer/util"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// Trade is a single execution of an order placed by the strategy
+type Trade struct {
+	Time     time.Time     `json:"time"`
+	Exchange string        `json:"exchange"`
+	OrderID  string        `json:"orderId"`
+	Pair     currency.Pair `json:"pair"`
+	Side     order.Side    `json:"side"`
+	Price    float64       `json:"price"`
+	Amount   float64       `json:"amount"`
+	Fee      float64       `json:"fee"`
+	FeeAsset currency.Code `json:"feeAsset"`
+}
+
+// MarshalJSON encodes the side as a string
+func (t Trade) MarshalJSON() ([]byte, error) {
+	type alias Trade
+	return util.MarshalSide(alias(t), "side", t.Side)
+}
+
+// Report is the result of a backtest, all values are in the quote currency
+type Report struct {
+	Start  time.Time     `json:"start"`
+	End    time.Time     `json:"end"`
+	Events int           `json:"events"`
+	Quote  currency.Code `json:"quote"`
+
+	InitialEquity float64 `json:"initialEquity"`
+	FinalEquity   float64 `json:"finalEquity"`
+	PnL           float64 `json:"pnl"`
+	Return        float64 `json:"return"`
+	// MaxDrawdown is the largest drop of the equity from a previous peak, as a fraction of that peak
+	MaxDrawdown float64 `json:"maxDrawdown"`
+	// Turnover is the traded notional
+	Turnover float64 `json:"turnover"`
+	Fees     float64 `json:"fees"`
+	Trades   []Trade `json:"trades"`
+
+	peak    float64
+	sampled bool
+}
+
+// sample adds a point of the equity curve
+func (r *Report) sample(equity float64) {
+	if !r.sampled {
+		r.InitialEquity = equity
+		r.sampled = true
+	}
+
+	r.FinalEquity = equity
+	r.peak = math.Max(r.peak, equity)
+	if r.peak > 0 {
+		r.MaxDrawdown = math.Max(r.MaxDrawdown, (r.peak-equity)/r.peak)
+	}
+}
+
+// finish derives the profit and return from the equity curve
+func (r *Report) finish() {
+	r.PnL = r.FinalEquity - r.InitialEquity
+	if r.InitialEquity > 0 {
+		r.Return = r.PnL / r.InitialEquity
+	}
+}
+
+// MaxDrawdown returns the largest drop of the values from a previous peak, as a fraction of that peak
+func MaxDrawdown(values []float64) float64 {
+	var r Report
+	for _, v := range values {
+		r.sample(v)
+	}
+	return r.MaxDrawdown
+}
@@ -0,0 +1,143 @@
+package dealer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// Audit actions
+const (
+	AuditKillSwitch   = "kill_switch"
+	AuditSubmitOrder  = "submit_order"
+	AuditModifyOrder  = "modify_order"
+	AuditCancelOrder  = "cancel_order"
+	AuditWithdraw     = "withdraw"
+	AuditBankTransfer = "bank_transfer"
+	AuditAddressBook  = "address_book"
+	AuditApproval     = "withdraw_approval"
+)
+
+// defaultInitiator is who initiated an action when the context does not tell, the dealer itself and its strategies
+const defaultInitiator = "dealer"
+
+type initiatorKey struct{}
+
+// AuditEntry is a record of an action that moved orders or funds: who initiated it, what was requested and what the exchange responded
+type AuditEntry struct {
+	Time      time.Time   `json:"time"`
+	Action    string      `json:"action"`
+	Initiator string      `json:"initiator"`
+	Exchange  string      `json:"exchange,omitempty"`
+	Request   interface{} `json:"request,omitempty"`
+	Response  interface{} `json:"response,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// Auditor records audit entries, entries are expected to be kept for good and never changed
+type Auditor interface {
+	Audit(ctx context.Context, entry AuditEntry) error
+}
+
+// LogAuditor writes audit entries to the log, it is the auditor of a dealer that has none configured
+type LogAuditor struct{}
+
+// Audit logs the entry as JSON
+func (LogAuditor) Audit(ctx context.Context, entry AuditEntry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	logrus.WithField("audit", entry.Action).Info(string(raw))
+	return nil
+}
+
+// WithInitiator returns a context that attributes the actions taken with it to the initiator, such as the address of an API client
+func WithInitiator(ctx context.Context, initiator string) context.Context {
+	return context.WithValue(ctx, initiatorKey{}, initiator)
+}
+
+// Initiator returns who initiated the actions taken with the context
+func Initiator(ctx context.Context) string {
+	if initiator, ok := ctx.Value(initiatorKey{}).(string); ok && initiator != "" {
+		return initiator
+	}
+	return defaultInitiator
+}
+
+// AuditError returns the message of the error, or nothing without one
+func AuditError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// auditOrder is the audited form of an order request or response. The order is kept whole, its side, type and status
+// are added by name as they are written as numbers.
+type auditOrder struct {
+	Side   string      `json:"side"`
+	Type   string      `json:"type"`
+	Status string      `json:"status,omitempty"`
+	Order  interface{} `json:"order"`
+}
+
+// Audit records the entry with the auditor of the dealer. The entry is stamped with the time of the dealer clock and the initiator of the context.
+// Failing to audit does not fail the action, it already happened, the failure is logged.
+func (bot *Dealer) Audit(ctx context.Context, entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = bot.Now()
+	}
+	if entry.Initiator == "" {
+		entry.Initiator = Initiator(ctx)
+	}
+
+	auditor := bot.auditor
+	if auditor == nil {
+		auditor = LogAuditor{}
+	}
+	if err := auditor.Audit(ctx, entry); err != nil {
+		logrus.Errorf("failed to audit %s: %s\n", entry.Action, err)
+	}
+}
+
+// auditSubmit records an order submission
+func (bot *Dealer) auditSubmit(ctx context.Context, submit order.Submit, resp *order.SubmitResponse, err error) {
+	entry := AuditEntry{
+		Action:   AuditSubmitOrder,
+		Exchange: submit.Exchange,
+		Request:  auditOrder{Side: submit.Side.String(), Type: submit.Type.String(), Order: submit},
+		Error:    AuditError(err),
+	}
+	if resp != nil {
+		entry.Response = auditOrder{Side: resp.Side.String(), Type: resp.Type.String(), Status: resp.Status.String(), Order: resp}
+	}
+	bot.Audit(ctx, entry)
+}
+
+// auditModify records an order modification with the response of the exchange
+func (bot *Dealer) auditModify(ctx context.Context, mod order.Modify, resp *order.ModifyResponse, err error) {
+	entry := AuditEntry{
+		Action:   AuditModifyOrder,
+		Exchange: mod.Exchange,
+		Request:  auditOrder{Side: mod.Side.String(), Type: mod.Type.String(), Order: mod},
+		Error:    AuditError(err),
+	}
+	if resp != nil {
+		entry.Response = auditOrder{Side: resp.Side.String(), Type: resp.Type.String(), Status: resp.Status.String(), Order: resp}
+	}
+	bot.Audit(ctx, entry)
+}
+
+// auditCancel records an order cancellation
+func (bot *Dealer) auditCancel(ctx context.Context, x order.Cancel, err error) {
+	bot.Audit(ctx, AuditEntry{
+		Action:   AuditCancelOrder,
+		Exchange: x.Exchange,
+		Request:  auditOrder{Side: x.Side.String(), Type: x.Type.String(), Order: x},
+		Error:    AuditError(err),
+	})
+}
@@ -0,0 +1,62 @@
+package dealer
+
+import (
+	"time"
+
+	"github.com/romanornr/autodealer/util"
+)
+
+// Clock tells strategies the time and runs their periodic work. Live trading uses the wall clock,
+// a backtest uses a virtual clock that moves along with the replayed events.
+type Clock interface {
+	Now() time.Time
+	// Every calls f right away and then once every interval until stop is called
+	Every(interval time.Duration, f func()) (stop func())
+}
+
+// wallClock is the Clock of live trading
+type wallClock struct{}
+
+// Now returns the current time
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+// Every calls f from its own goroutine
+func (wallClock) Every(interval time.Duration, f func()) func() {
+	t := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		util.CheckerPush()
+		defer util.CheckerPop()
+
+		f()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				f()
+			}
+		}
+	}()
+
+	return func() {
+		t.Stop()
+		close(done)
+	}
+}
+
+// Clock returns the clock of the dealer, the wall clock unless the builder was given another one
+func (bot *Dealer) Clock() Clock {
+	if bot.clock == nil {
+		return wallClock{}
+	}
+	return bot.clock
+}
+
+// Now returns the current time of the dealer's clock
+func (bot *Dealer) Now() time.Time {
+	return bot.Clock().Now()
+}
@@ -42,6 +42,11 @@ type Builder struct {
 	factory            ExchangeFactory
 	settings           engine.Settings
 	reporters          []Reporter
+	clock              Clock
+	orderStore         OrderStore
+	orderRetention     time.Duration
+	risk               RiskCheck
+	auditor            Auditor
 }
 
 // NewBuilder returns a new or configured keep builder
@@ -92,25 +97,52 @@ func (b *Builder) Reporter(r Reporter) *Builder {
 	return b
 }
 
-// Build function is used to build the dealer object. When we call `dealer, err := builder.build()` we get a *dealer and an error back.
-func (b Builder) Build(ctx context.Context) (*Dealer, error) {
-	b.settings.ConfigFile = util.ConfigFile(b.settings.ConfigFile)
-	filePath, err := config.GetAndMigrateDefaultPath(b.settings.ConfigFile)
-	if err != nil {
-		return nil, err
+// Clock replaces the wall clock, strategy tickers then run on the given clock. It is used to replay history in a backtest.
+func (b *Builder) Clock(c Clock) *Builder {
+	b.clock = c
+	return b
+}
+
+// Orders sets the store the order registry persists its records in, a RedisOrderStore keeps them across restarts.
+// Orders are evicted from the store once they reached a terminal state longer than the retention ago, zero keeps the default of a week.
+func (b *Builder) Orders(store OrderStore, retention time.Duration) *Builder {
+	b.orderStore = store
+	b.orderRetention = retention
+	return b
+}
+
+// Risk sets the pre-trade check every order submission and modification has to pass before it is sent to the exchange
+func (b *Builder) Risk(r RiskCheck) *Builder {
+	b.risk = r
+	return b
+}
+
+// Auditor sets where actions that move orders or funds are recorded, without one they are logged
+func (b *Builder) Auditor(a Auditor) *Builder {
+	b.auditor = a
+	return b
+}
+
+// newDealer returns a dealer with the history strategy and, when enabled, the balances strategy
+func (b Builder) newDealer() *Dealer {
+	dealer := &Dealer{
+		Settings:        b.settings,
+		ExchangeManager: *engine.NewExchangeManager(),
+		Root:            NewRootStrategy(),
+		registry:        NewOrderRegistry(),
+		reporters:       b.reporters,
+		clock:           b.clock,
+		risk:            b.risk,
+		auditor:         b.auditor,
 	}
 
-	var (
-		conf   config.Config
-		dealer = &Dealer{
-			Settings:        b.settings,
-			Config:          conf,
-			ExchangeManager: *engine.NewExchangeManager(),
-			Root:            NewRootStrategy(),
-			registry:        *NewOrderRegistry(),
-			reporters:       b.reporters,
-		}
-	)
+	if b.orderStore != nil {
+		dealer.registry.store = b.orderStore
+	}
+	if b.orderRetention > 0 {
+		dealer.registry.retention = b.orderRetention
+	}
+	dealer.registry.now = dealer.Now
 
 	// Add history strategy: a special type of strategy that may keep multiple channels of historical data available
 	hist := NewHistoryStrategy()
@@ -120,6 +152,30 @@ func (b Builder) Build(ctx context.Context) (*Dealer, error) {
 	if b.balanceRefreshRate > 0 {
 		dealer.Root.Add("balances", NewBalancesStrategy(b.balanceRefreshRate))
 	}
+	return dealer
+}
+
+// BuildWithExchanges builds a dealer around exchanges that are already set up, no configuration file is read.
+// It allows simulated exchanges to be traded without any connection to a real one.
+func (b Builder) BuildWithExchanges(exchanges ...exchange.IBotExchange) (*Dealer, error) {
+	dealer := b.newDealer()
+	for _, e := range exchanges {
+		if err := dealer.ExchangeManager.Add(e); err != nil {
+			return nil, err
+		}
+	}
+	return dealer, nil
+}
+
+// Build function is used to build the dealer object. When we call `dealer, err := builder.build()` we get a *dealer and an error back.
+func (b Builder) Build(ctx context.Context) (*Dealer, error) {
+	b.settings.ConfigFile = util.ConfigFile(b.settings.ConfigFile)
+	filePath, err := config.GetAndMigrateDefaultPath(b.settings.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	dealer := b.newDealer()
 
 	logrus.Infof("loading configuration file %s\n", filePath)
 	if err := dealer.Config.ReadConfigFromFile(filePath, b.settings.EnableDryRun); err != nil {
@@ -152,6 +208,9 @@ func (b Builder) Build(ctx context.Context) (*Dealer, error) {
 
 var ErrOrdersAlreadyExists = errors.New("order already exists")
 
+// orderEvictionInterval is how often the order registry is checked for orders past their retention
+const orderEvictionInterval = time.Hour
+
 // Dealer struct holds state. In this case it specifically has a definition function Augment().
 // It also stores internal values such as the path the configs will be read from, the closures/recipe function it will use while conditioning config values.
 // Our Augment() will run before the Build() code is called. In this case, the config itself may have been read from a filepath.
@@ -162,8 +221,15 @@ type Dealer struct {
 	Settings        engine.Settings
 	Config          config.Config
 	ExchangeManager engine.ExchangeManager
-	registry        OrderRegistry
+	registry        *OrderRegistry
 	reporters       []Reporter
+	clock           Clock
+	risk            RiskCheck
+	auditor         Auditor
+	// halted is set by the kill switch, orders can't be submitted while it is 1
+	halted int32
+	// running holds the names of the exchanges whose strategies are initialised
+	running sync.Map
 }
 
 // Run is the entry point of all exchange data streams.  Strategy.On*() events for a single exchange are invoked from the same thread.
@@ -176,6 +242,14 @@ func (bot *Dealer) Run(ctx context.Context) {
 		panic(err)
 	}
 
+	// terminal orders are evicted from the registry once their retention passed
+	stop := bot.Clock().Every(orderEvictionInterval, func() {
+		if _, err := bot.registry.Evict(ctx); err != nil {
+			logrus.Errorf("failed to evict orders: %s\n", err)
+		}
+	})
+	defer stop()
+
 	for _, x := range exchgs {
 		wg.Add(1)
 
@@ -185,10 +259,16 @@ func (bot *Dealer) Run(ctx context.Context) {
 			// fetch the root strategy
 			s := &bot.Root
 
+			// re-adopt orders that were placed before a restart
+			if err := bot.ReconcileOrders(ctx, x); err != nil {
+				logrus.Errorf("failed to reconcile orders of %s: %s\n", x.GetName(), err)
+			}
+
 			// Init root strategy for this exchange.
 			if err := s.Init(ctx, bot, x); err != nil {
 				panic(fmt.Errorf("failed to initialize strategy: %w", err))
 			}
+			bot.running.Store(x.GetName(), true)
 
 			// go into an infinite loop, either handling websocket
 			// events or just plain blocked when there are none
@@ -196,8 +276,8 @@ func (bot *Dealer) Run(ctx context.Context) {
 			// nolint: godox
 			// TODO: handle err on terminate when context gets cancelled
 
-			// Deinit root strategy for this exchange.
-			if err := s.Deinit(bot, x); err != nil {
+			// Deinit root strategy for this exchange, unless the kill switch already did.
+			if err := bot.deinit(x); err != nil {
 				panic(err)
 			}
 
@@ -249,6 +329,71 @@ func (bot *Dealer) GetOrderValue(exchangeName, orderID string) (OrderValue, bool
 	return bot.registry.GetOrderValue(exchangeName, orderID)
 }
 
+// GetOrder returns the record of an order placed through the dealer
+func (bot *Dealer) GetOrder(ctx context.Context, exchangeName, orderID string) (OrderRecord, error) {
+	return bot.registry.Get(ctx, exchangeName, orderID)
+}
+
+// QueryOrders returns the records of the orders matching the query, most recent first
+func (bot *Dealer) QueryOrders(ctx context.Context, q OrderQuery) ([]OrderRecord, error) {
+	return bot.registry.Query(ctx, q)
+}
+
+// ReconcileOrders brings the order registry in line with the exchange. Orders resting on the exchange are adopted when the registry
+// does not know them, and orders the registry still considers open are looked up when they are no longer active,
+// they were filled or cancelled while the dealer was not watching.
+func (bot *Dealer) ReconcileOrders(ctx context.Context, e exchange.IBotExchange) error {
+	active := make(map[string]bool)
+
+	for _, a := range e.GetAssetTypes(true) {
+		xs, err := bot.GetActiveOrders(ctx, e, order.MultiOrderRequest{AssetType: a, Type: order.AnyType, Side: order.AnySide})
+		if err != nil {
+			return err
+		}
+
+		for _, x := range xs {
+			active[x.OrderID] = true
+
+			adopted, err := bot.registry.Adopt(ctx, e.GetName(), x)
+			if err != nil {
+				return err
+			}
+			if adopted {
+				logrus.Infof("adopted %s order %s %s %s\n", e.GetName(), x.OrderID, x.Side, x.Pair)
+			}
+		}
+	}
+
+	open, err := bot.registry.Query(ctx, OrderQuery{Exchange: e.GetName(), States: []OrderState{OrderSubmitted, OrderPartiallyFilled}})
+	if err != nil {
+		return err
+	}
+
+	for _, o := range open {
+		if active[o.OrderID] {
+			continue
+		}
+
+		x, err := e.GetOrderInfo(ctx, o.OrderID, o.Pair, o.Asset)
+		if err != nil {
+			logrus.Errorf("failed to get %s order %s: %s\n", e.GetName(), o.OrderID, err)
+			continue
+		}
+
+		// an order that is not active anymore was closed one way or the other
+		state := NewOrderState(x.Status, x.ExecutedAmount, o.Amount)
+		if !state.Terminal() {
+			x.Status = order.Cancelled
+		}
+		u, err := bot.registry.Update(ctx, e.GetName(), *x)
+		if err != nil {
+			return err
+		}
+		bot.notify(e, u, *x)
+	}
+	return nil
+}
+
 // getExchange function returns an interface to IBotExchange from either an instance or a name of an exchange
 func (bot *Dealer) getExchange(x interface{}) exchange.IBotExchange {
 	switch x := x.(type) {
@@ -273,6 +418,7 @@ func (bot *Dealer) getExchange(x interface{}) exchange.IBotExchange {
 func (bot *Dealer) GetActiveOrders(ctx context.Context, exchangeOrName interface{}, request order.MultiOrderRequest) ([]order.Detail, error) {
 	e := bot.getExchange(exchangeOrName)
 
+	bot.ReportEvent(GetActiveOrdersMetric, e.GetName())
 	timer := time.Now()
 
 	defer bot.ReportLatency(GetActiveOrdersLatencyMetric, timer, e.GetName())
@@ -305,10 +451,26 @@ func (bot *Dealer) SubmitOrderUD(ctx context.Context, exchangeOrName interface{}
 		submit.Exchange = e.GetName()
 	}
 
+	if bot.Halted() {
+		return nil, ErrHalted
+	}
+
+	if bot.risk != nil {
+		if err := bot.risk.CheckSubmit(ctx, bot, e, submit); err != nil {
+			return nil, err
+		}
+	}
+
+	return bot.submit(ctx, e, submit, userData)
+}
+
+// submit sends the order to the exchange and stores it in the registry, without the halt and risk checks
+func (bot *Dealer) submit(ctx context.Context, e exchange.IBotExchange, submit order.Submit, userData interface{}) (*order.SubmitResponse, error) {
 	bot.ReportEvent(SubmitOrderMetric, e.GetName())
 
 	defer bot.ReportLatency(SubmitOrderLatencyMetric, time.Now(), e.GetName())
 	resp, err := e.SubmitOrder(ctx, &submit)
+	bot.auditSubmit(ctx, submit, resp, err)
 	if err != nil {
 		// post an error metric event
 		bot.ReportEvent(SubmitOrderErrorMetric, e.GetName())
@@ -327,7 +489,7 @@ func (bot *Dealer) SubmitOrderUD(ctx context.Context, exchangeOrName interface{}
 // ListOrder method will not be executed if Contains method returns an error.
 func (bot *Dealer) SubmitOrders(ctx context.Context, e exchange.IBotExchange, xs ...order.Submit) error {
 	var wg util.ErrorWaitGroup
-	bot.ReportEvent(SubmitBulkOrderLatencyMetric, e.GetName())
+	bot.ReportEvent(SubmitBulkOrderMetric, e.GetName())
 	defer bot.ReportLatency(SubmitBulkOrderLatencyMetric, time.Now(), e.GetName())
 
 	for _, x := range xs {
@@ -346,13 +508,31 @@ func (bot *Dealer) SubmitOrders(ctx context.Context, e exchange.IBotExchange, xs
 // CreateOrder method will not be executed if Contains method returns an error.
 func (bot *Dealer) ModifyOrder(ctx context.Context, exchangeOrName interface{}, mod order.Modify) (order.ModifyResponse, error) {
 	e := bot.getExchange(exchangeOrName)
+	if mod.Exchange == "" {
+		mod.Exchange = e.GetName()
+	}
+
+	if bot.Halted() {
+		return order.ModifyResponse{}, ErrHalted
+	}
+
+	if bot.risk != nil {
+		if err := bot.risk.CheckModify(ctx, bot, e, mod); err != nil {
+			return order.ModifyResponse{}, err
+		}
+	}
+
 	bot.ReportEvent(ModifyOrderMetric, e.GetName())
 
 	defer bot.ReportLatency(ModifyOrderLatencyMetric, time.Now(), e.GetName())
 
 	resp, err := e.ModifyOrder(ctx, &mod)
+	bot.auditModify(ctx, mod, resp, err)
 	if err != nil {
 		bot.ReportEvent(ModifyOrderErrorMetric, e.GetName())
+		if resp == nil {
+			return order.ModifyResponse{}, err
+		}
 		return *resp, err
 	}
 	return *resp, nil
@@ -370,10 +550,14 @@ func (bot *Dealer) CancelOrder(ctx context.Context, exchangeOrName interface{},
 	bot.ReportEvent(CancelOrderMetric, e.GetName())
 	defer bot.ReportLatency(CancelOrderLatencyMetric, time.Now(), e.GetName())
 
-	if err := e.CancelOrder(ctx, &x); err != nil {
+	err := e.CancelOrder(ctx, &x)
+	bot.auditCancel(ctx, x, err)
+	if err != nil {
 		bot.ReportEvent(CancelOrderErrorMetric, e.GetName())
 		return err
 	}
+
+	bot.cancelled(ctx, e, x.OrderID)
 	return nil
 }
 
@@ -381,21 +565,51 @@ func (bot *Dealer) CancelOrder(ctx context.Context, exchangeOrName interface{},
 // | Keep: Event observation |
 // +-------------------------+
 
-// OnOrder function calls the GetOrderValue method to see if an order exists with that dealer.
-// We have a GetValue method in the handler file. We modify the obtained value by setting its UserData to the OnFilled Observer required to perform the appropriate strategy.
-// In n our instance, our order may include two methods. One provides us with a transaction, while the other provides us with profit and loss information (P&L).
-// When we get an order, we set the User data to OnFilledObserver using the Value property and leave the handler code. Within the Handler.
-// OnFilled, we check two criteria to verify whether they are present in Value in order to optimize the strategy's execution.
+// OnOrder function applies an order update of the exchange to the order registry, which tracks the lifecycle and the cumulative fill of the order.
+// The user data of the order is notified of what changed: partial fills, the fill of the whole order, cancellation or rejection.
+// Fills the exchange already reported in its response to the submission are not notified, the submitter sees those in the response.
 func (bot *Dealer) OnOrder(e exchange.IBotExchange, x order.Detail) {
-	if x.Status == order.Filled {
-		value, ok := bot.GetOrderValue(e.GetName(), x.OrderID)
-		if !ok {
-			return
+	u, err := bot.registry.Update(context.Background(), e.GetName(), x)
+	if err != nil {
+		if !errors.Is(err, ErrOrderNotFound) {
+			logrus.Errorf("failed to update %s order %s: %s\n", e.GetName(), x.OrderID, err)
 		}
+		return
+	}
+	bot.notify(e, u, x)
+}
+
+// notify calls the observers in the user data of the order for the changes of the update.
+// The order detail they receive carries the cumulative fill of the registry.
+func (bot *Dealer) notify(e exchange.IBotExchange, u OrderUpdate, x order.Detail) {
+	value, ok := bot.GetOrderValue(e.GetName(), u.Record.OrderID)
+	if !ok || value.UserData == nil {
+		return
+	}
+
+	x = fillDetail(x, u.Record)
 
+	if obs, ok := value.UserData.(OnPartialFillObserver); ok && u.Filled() > 0 && u.Record.State != OrderFilled {
+		obs.OnPartialFill(bot, e, x)
+	}
+
+	if !u.Transitioned() {
+		return
+	}
+
+	switch u.Record.State {
+	case OrderFilled:
 		if obs, ok := value.UserData.(OnFilledObserver); ok {
 			obs.OnFilled(bot, e, x)
 		}
+	case OrderCancelled:
+		if obs, ok := value.UserData.(OnCancelledObserver); ok {
+			obs.OnCancelled(bot, e, x)
+		}
+	case OrderRejected:
+		if obs, ok := value.UserData.(OnRejectedObserver); ok {
+			obs.OnRejected(bot, e, x)
+		}
 	}
 }
 
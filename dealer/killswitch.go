@@ -0,0 +1,303 @@
+package dealer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+var (
+	ErrHalted         = errors.New("dealer is halted, order submission is disabled")
+	ErrKillIncomplete = errors.New("kill switch did not complete")
+	ErrNoMarket       = errors.New("no market to convert between the currencies")
+	ErrNoAsk          = errors.New("no ask to convert the amount at")
+	ErrBelowMinimum   = errors.New("amount is below the minimum order size")
+)
+
+// KillOptions configures the kill switch
+type KillOptions struct {
+	// Flatten sells every spot balance into Quote after the orders are cancelled
+	Flatten bool          `json:"flatten"`
+	Quote   currency.Code `json:"quote"`
+	Reason  string        `json:"reason"`
+}
+
+// KillOrder is an order the kill switch cancelled or placed to flatten a balance
+type KillOrder struct {
+	Exchange string     `json:"exchange"`
+	Asset    asset.Item `json:"asset"`
+	OrderID  string     `json:"orderID,omitempty"`
+	Pair     string     `json:"pair"`
+	Side     string     `json:"side"`
+	Amount   float64    `json:"amount"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// KillReport is what the kill switch did, it is recorded as audit entry
+type KillReport struct {
+	Time      time.Time   `json:"time"`
+	Reason    string      `json:"reason"`
+	Cancelled []KillOrder `json:"cancelled"`
+	Failed    []KillOrder `json:"failed"`
+	Flattened []KillOrder `json:"flattened"`
+	// Errors are failures that are not about a single order, such as an exchange that could not list its open orders
+	Errors []string `json:"errors"`
+
+	mu sync.Mutex
+}
+
+func (r *KillReport) cancelled(x KillOrder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Cancelled = append(r.Cancelled, x)
+}
+
+func (r *KillReport) failed(x KillOrder, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	x.Error = err.Error()
+	r.Failed = append(r.Failed, x)
+}
+
+func (r *KillReport) flattened(x KillOrder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Flattened = append(r.Flattened, x)
+}
+
+func (r *KillReport) error(e exchange.IBotExchange, a asset.Item, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if a == asset.Empty {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: %s", e.GetName(), err))
+		return
+	}
+	r.Errors = append(r.Errors, fmt.Sprintf("%s %s: %s", e.GetName(), a, err))
+}
+
+func killOrder(exchangeName string, x order.Detail) KillOrder {
+	return KillOrder{
+		Exchange: exchangeName,
+		Asset:    x.AssetType,
+		OrderID:  x.OrderID,
+		Pair:     x.Pair.String(),
+		Side:     x.Side.String(),
+		Amount:   x.Amount,
+	}
+}
+
+// Halted reports whether the kill switch stopped order submission
+func (bot *Dealer) Halted() bool {
+	return atomic.LoadInt32(&bot.halted) == 1
+}
+
+// Resume allows orders to be submitted again after the kill switch. Strategies the kill switch deinitialised stay stopped
+// until the dealer is restarted.
+func (bot *Dealer) Resume() {
+	atomic.StoreInt32(&bot.halted, 0)
+	logrus.Warnln("order submission resumed")
+}
+
+// KillSwitch stops all trading. Order submission and modification fail with ErrHalted from then on, every open order on every
+// exchange is cancelled and the strategies are deinitialised, so they no longer receive events. Orders are cancelled with a
+// single cancel-all request where the exchange supports it and one by one otherwise.
+// When Flatten is set, every spot balance that trades against the quote currency is sold into it with a market order.
+// What was cancelled is recorded as audit entry. ErrKillIncomplete is returned, together with the report, when an order
+// could not be cancelled.
+func (bot *Dealer) KillSwitch(ctx context.Context, opts KillOptions) (*KillReport, error) {
+	atomic.StoreInt32(&bot.halted, 1)
+	logrus.Warnf("kill switch engaged: %s\n", opts.Reason)
+
+	report := &KillReport{Time: bot.Now(), Reason: opts.Reason, Cancelled: []KillOrder{}, Failed: []KillOrder{}, Flattened: []KillOrder{}, Errors: []string{}}
+
+	var wg sync.WaitGroup
+	for _, e := range bot.GetExchanges() {
+		wg.Add(1)
+
+		go func(e exchange.IBotExchange) {
+			defer wg.Done()
+
+			for _, a := range e.GetAssetTypes(true) {
+				bot.cancelAll(ctx, e, a, report)
+			}
+
+			if opts.Flatten {
+				bot.flatten(ctx, e, opts.Quote, report)
+			}
+
+			if err := bot.deinit(e); err != nil {
+				report.error(e, asset.Empty, fmt.Errorf("failed to deinitialise strategies: %w", err))
+			}
+		}(e)
+	}
+	wg.Wait()
+
+	bot.Audit(ctx, AuditEntry{Action: AuditKillSwitch, Request: opts, Response: report})
+
+	if len(report.Failed) > 0 || len(report.Errors) > 0 {
+		return report, fmt.Errorf("%w: %d orders failed, %d errors", ErrKillIncomplete, len(report.Failed), len(report.Errors))
+	}
+	return report, nil
+}
+
+// cancelAll cancels the open orders of the asset. The orders are listed first so the report knows what was cancelled,
+// orders that are still open after the cancel-all request are cancelled one by one.
+func (bot *Dealer) cancelAll(ctx context.Context, e exchange.IBotExchange, a asset.Item, report *KillReport) {
+	request := order.MultiOrderRequest{AssetType: a, Type: order.AnyType, Side: order.AnySide}
+
+	open, err := bot.GetActiveOrders(ctx, e, request)
+	if err != nil {
+		report.error(e, a, err)
+		return
+	}
+	if len(open) == 0 {
+		return
+	}
+
+	bot.ReportEvent(CancelAllOrdersMetric, e.GetName())
+	timer := time.Now()
+	_, err = e.CancelAllOrders(ctx, &order.Cancel{Exchange: e.GetName(), AssetType: a})
+	bot.ReportLatency(CancelAllOrdersLatencyMetric, timer, e.GetName())
+
+	remaining := make(map[string]bool)
+	if err != nil {
+		bot.ReportEvent(CancelAllOrdersErrorMetric, e.GetName())
+		logrus.Warnf("cancel all %s %s orders failed, cancelling one by one: %s\n", e.GetName(), a, err)
+		for _, x := range open {
+			remaining[x.OrderID] = true
+		}
+	} else {
+		// exchanges differ in what they report per order, what is still active is what matters
+		still, err := bot.GetActiveOrders(ctx, e, request)
+		if err != nil {
+			report.error(e, a, err)
+			return
+		}
+		for _, x := range still {
+			remaining[x.OrderID] = true
+		}
+	}
+
+	for _, x := range open {
+		if remaining[x.OrderID] {
+			err := bot.CancelOrder(ctx, e, order.Cancel{OrderID: x.OrderID, Pair: x.Pair, AssetType: x.AssetType, Side: x.Side})
+			if err != nil {
+				report.failed(killOrder(e.GetName(), x), err)
+				continue
+			}
+		} else {
+			bot.cancelled(ctx, e, x.OrderID)
+		}
+		report.cancelled(killOrder(e.GetName(), x))
+	}
+}
+
+// cancelled marks the order cancelled in the registry and notifies its user data
+func (bot *Dealer) cancelled(ctx context.Context, e exchange.IBotExchange, orderID string) {
+	u, err := bot.registry.Cancelled(ctx, e.GetName(), orderID)
+	if err != nil {
+		if !errors.Is(err, ErrOrderNotFound) {
+			logrus.Errorf("failed to record cancellation of %s order %s: %s\n", e.GetName(), orderID, err)
+		}
+		return
+	}
+	bot.notify(e, u, u.Record.Detail())
+}
+
+// flatten sells the free spot balances into the quote currency. Balances without a market against the quote, or too small
+// to trade, are left alone.
+// The orders bypass the halt and the risk check, getting out is the point of the kill switch.
+func (bot *Dealer) flatten(ctx context.Context, e exchange.IBotExchange, quote currency.Code, report *KillReport) {
+	holdings, err := e.UpdateAccountInfo(ctx, asset.Spot)
+	if err != nil {
+		report.error(e, asset.Spot, err)
+		return
+	}
+
+	for _, account := range holdings.Accounts {
+		for _, balance := range account.Currencies {
+			if balance.Free <= 0 || balance.Currency.Equal(quote) {
+				continue
+			}
+
+			x := KillOrder{Exchange: e.GetName(), Asset: asset.Spot, Amount: balance.Free}
+			submit, err := ConversionOrder(ctx, e, balance.Currency, quote, balance.Free)
+			if errors.Is(err, ErrNoMarket) || errors.Is(err, ErrBelowMinimum) {
+				continue
+			}
+			if err != nil {
+				report.failed(x, err)
+				continue
+			}
+
+			x.Pair, x.Side, x.Amount = submit.Pair.String(), submit.Side.String(), submit.Amount
+			resp, err := bot.submit(ctx, e, submit, nil)
+			if err != nil {
+				report.failed(x, err)
+				continue
+			}
+			x.OrderID = resp.OrderID
+			report.flattened(x)
+		}
+	}
+}
+
+// ConversionOrder returns the spot market order on the exchange that turns the amount of the currency into the quote currency.
+// The currency is sold when it is the base of the pair. When it is the quote of the pair the base is bought, the amount is
+// converted into the base at the ask. Either way the amount is conformed to the step size of the market.
+func ConversionOrder(ctx context.Context, e exchange.IBotExchange, code, quote currency.Code, amount float64) (order.Submit, error) {
+	pairs, err := e.GetEnabledPairs(asset.Spot)
+	if err != nil {
+		return order.Submit{}, err
+	}
+
+	for _, p := range pairs {
+		s := order.Submit{Exchange: e.GetName(), Pair: p, AssetType: asset.Spot, Type: order.Market}
+		switch {
+		case p.Base.Equal(code) && p.Quote.Equal(quote):
+			s.Side = order.Sell
+			s.Amount = amount
+		case p.Base.Equal(quote) && p.Quote.Equal(code):
+			tick, err := e.FetchTicker(ctx, p, asset.Spot)
+			if err != nil {
+				return order.Submit{}, err
+			}
+			if tick.Ask <= 0 {
+				return order.Submit{}, fmt.Errorf("%w: %s on %s", ErrNoAsk, p, e.GetName())
+			}
+			s.Side = order.Buy
+			s.Amount = amount / tick.Ask
+		default:
+			continue
+		}
+
+		if limits, err := e.GetOrderExecutionLimits(asset.Spot, p); err == nil {
+			s.Amount = limits.ConformToAmount(s.Amount)
+			if s.Amount < limits.MinimumBaseAmount {
+				s.Amount = 0
+			}
+		}
+		if s.Amount <= 0 {
+			return order.Submit{}, fmt.Errorf("%w: %f %s on %s", ErrBelowMinimum, amount, code, e.GetName())
+		}
+		return s, nil
+	}
+	return order.Submit{}, fmt.Errorf("%w: %s to %s on %s", ErrNoMarket, code, quote, e.GetName())
+}
+
+// deinit deinitialises the strategies of the exchange once, and only when they were initialised
+func (bot *Dealer) deinit(e exchange.IBotExchange) error {
+	if _, ok := bot.running.LoadAndDelete(e.GetName()); !ok {
+		return nil
+	}
+	return bot.Root.Deinit(bot, e)
+}
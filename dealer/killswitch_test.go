@@ -0,0 +1,266 @@
+package dealer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/thrasher-corp/gocryptotrader/common"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+)
+
+var errCancelAllUnsupported = errors.New("cancel all not supported")
+
+// killExchange keeps open orders, cancel all is only supported when cancelAll is set
+type killExchange struct {
+	exchange.IBotExchange
+
+	name      string
+	cancelAll bool
+	// pairs, ask and limits are the spot markets, their ask and their execution limits, the test pair by default
+	pairs  currency.Pairs
+	ask    float64
+	limits *order.MinMaxLevel
+
+	mu        sync.Mutex
+	open      map[string]order.Detail
+	submitted []order.Submit
+}
+
+func newKillExchange(name string, cancelAll bool, ids ...string) *killExchange {
+	e := &killExchange{name: name, cancelAll: cancelAll, open: make(map[string]order.Detail)}
+	for _, id := range ids {
+		e.open[id] = order.Detail{OrderID: id, Pair: testOrderPair, AssetType: asset.Spot, Side: order.Buy, Amount: 1, Status: order.Active}
+	}
+	return e
+}
+
+func (e *killExchange) GetName() string {
+	return e.name
+}
+
+func (e *killExchange) GetAssetTypes(enabled bool) asset.Items {
+	return asset.Items{asset.Spot}
+}
+
+func (e *killExchange) GetBase() *exchange.Base {
+	return &exchange.Base{Name: e.name}
+}
+
+func (e *killExchange) GetActiveOrders(ctx context.Context, req *order.MultiOrderRequest) (order.FilteredOrders, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var xs order.FilteredOrders
+	for _, x := range e.open {
+		xs = append(xs, x)
+	}
+	return xs, nil
+}
+
+func (e *killExchange) CancelAllOrders(ctx context.Context, x *order.Cancel) (order.CancelAllResponse, error) {
+	if !e.cancelAll {
+		return order.CancelAllResponse{}, errCancelAllUnsupported
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := len(e.open)
+	e.open = make(map[string]order.Detail)
+	return order.CancelAllResponse{Count: int64(n)}, nil
+}
+
+func (e *killExchange) CancelOrder(ctx context.Context, x *order.Cancel) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.open, x.OrderID)
+	return nil
+}
+
+func (e *killExchange) SubmitOrder(ctx context.Context, s *order.Submit) (*order.SubmitResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.submitted = append(e.submitted, *s)
+	return &order.SubmitResponse{OrderID: "flatten", Pair: s.Pair, AssetType: s.AssetType, Side: s.Side, Amount: s.Amount, Status: order.New}, nil
+}
+
+func (e *killExchange) UpdateAccountInfo(ctx context.Context, a asset.Item) (account.Holdings, error) {
+	return account.Holdings{Exchange: e.name, Accounts: []account.SubAccount{{AssetType: a, Currencies: []account.Balance{
+		{Currency: currency.BTC, Total: 2, Free: 2},
+		{Currency: currency.USDT, Total: 100, Free: 100},
+		{Currency: currency.DOGE, Total: 5, Free: 5},
+	}}}}, nil
+}
+
+func (e *killExchange) GetEnabledPairs(a asset.Item) (currency.Pairs, error) {
+	if e.pairs != nil {
+		return e.pairs, nil
+	}
+	return currency.Pairs{testOrderPair}, nil
+}
+
+func (e *killExchange) FetchTicker(ctx context.Context, p currency.Pair, a asset.Item) (*ticker.Price, error) {
+	return &ticker.Price{Pair: p, AssetType: a, Ask: e.ask, Bid: e.ask}, nil
+}
+
+func (e *killExchange) GetOrderExecutionLimits(a asset.Item, p currency.Pair) (order.MinMaxLevel, error) {
+	if e.limits == nil {
+		return order.MinMaxLevel{}, common.ErrFunctionNotSupported
+	}
+	return *e.limits, nil
+}
+
+func (e *killExchange) ModifyOrder(ctx context.Context, m *order.Modify) (*order.ModifyResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.open[m.OrderID]; !ok {
+		return nil, errors.New("order not found")
+	}
+	return &order.ModifyResponse{Exchange: e.name, OrderID: m.OrderID, Pair: m.Pair, Price: m.Price, Amount: m.Amount, Status: order.Active}, nil
+}
+
+// recordingAuditor keeps the entries it audits
+type recordingAuditor struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func (a *recordingAuditor) Audit(ctx context.Context, entry AuditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func TestKillSwitch(t *testing.T) {
+	all := newKillExchange("all", true, "a1", "a2")
+	single := newKillExchange("single", false, "s1")
+	auditor := &recordingAuditor{}
+
+	d, err := NewBuilder().Balances(0).Auditor(auditor).BuildWithExchanges(all, single)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	report, err := d.KillSwitch(ctx, KillOptions{Reason: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !d.Halted() {
+		t.Errorf("expected the dealer to be halted")
+	}
+	if len(report.Cancelled) != 3 {
+		t.Errorf("expected: %v, actual: %v", 3, len(report.Cancelled))
+	}
+	if len(all.open) != 0 || len(single.open) != 0 {
+		t.Errorf("expected no open orders, actual: %v and %v", all.open, single.open)
+	}
+	// the order that had to be cancelled on its own is audited as well
+	actions := make(map[string]int)
+	for _, entry := range auditor.entries {
+		actions[entry.Action]++
+	}
+	if actions[AuditKillSwitch] != 1 || actions[AuditCancelOrder] != 1 {
+		t.Errorf("expected: one %s and one %s audit entry, actual: %v", AuditKillSwitch, AuditCancelOrder, actions)
+	}
+
+	if _, err = d.SubmitOrder(ctx, all, order.Submit{Pair: testOrderPair, AssetType: asset.Spot, Side: order.Buy, Type: order.Market, Amount: 1}); !errors.Is(err, ErrHalted) {
+		t.Errorf("expected: %v, actual: %v", ErrHalted, err)
+	}
+
+	d.Resume()
+	if _, err = d.SubmitOrder(ctx, all, order.Submit{Pair: testOrderPair, AssetType: asset.Spot, Side: order.Buy, Type: order.Market, Amount: 1}); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+}
+
+func TestKillSwitchFlatten(t *testing.T) {
+	e := newKillExchange("flatten", true)
+
+	d, err := NewBuilder().Balances(0).Auditor(&recordingAuditor{}).BuildWithExchanges(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := d.KillSwitch(context.Background(), KillOptions{Flatten: true, Quote: testOrderPair.Quote})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the base of the enabled pair can be sold, the quote is kept and DOGE has no market
+	if len(e.submitted) != 1 {
+		t.Fatalf("expected: %v, actual: %v", 1, len(e.submitted))
+	}
+	x := e.submitted[0]
+	if x.Side != order.Sell || x.Type != order.Market || x.Amount != 2 || !x.Pair.Equal(testOrderPair) {
+		t.Errorf("expected: market sell of 2 %s, actual: %v %v %v %s", testOrderPair, x.Type, x.Side, x.Amount, x.Pair)
+	}
+	if len(report.Flattened) != 1 {
+		t.Errorf("expected: %v, actual: %v", 1, len(report.Flattened))
+	}
+}
+
+func TestModifyOrderAudit(t *testing.T) {
+	e := newKillExchange("modify", false, "m1")
+	auditor := &recordingAuditor{}
+
+	d, err := NewBuilder().Balances(0).Auditor(auditor).BuildWithExchanges(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithInitiator(context.Background(), "test")
+	if _, err = d.ModifyOrder(ctx, e, order.Modify{OrderID: "m1", Pair: testOrderPair, Price: 10, Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// a failed modification is audited as well
+	if _, err = d.ModifyOrder(ctx, e, order.Modify{OrderID: "unknown", Pair: testOrderPair, Price: 10, Amount: 1}); err == nil {
+		t.Errorf("expected an error for an unknown order")
+	}
+
+	if len(auditor.entries) != 2 {
+		t.Fatalf("expected: %v, actual: %v", 2, len(auditor.entries))
+	}
+	for _, entry := range auditor.entries {
+		if entry.Action != AuditModifyOrder || entry.Initiator != "test" || entry.Exchange != "modify" {
+			t.Errorf("expected: %s by test on modify, actual: %s by %s on %s", AuditModifyOrder, entry.Action, entry.Initiator, entry.Exchange)
+		}
+	}
+	if auditor.entries[0].Response == nil || auditor.entries[1].Error == "" {
+		t.Errorf("expected: the response of the first and the error of the second, actual: %v and %v", auditor.entries[0].Response, auditor.entries[1].Error)
+	}
+}
+
+func TestConversionOrder(t *testing.T) {
+	e := newKillExchange("conversion", false)
+	e.pairs = currency.Pairs{currency.NewPair(currency.BTC, currency.USDT), currency.NewPair(currency.USDT, currency.TRY)}
+	e.ask = 30
+	e.limits = &order.MinMaxLevel{AmountStepIncrementSize: 0.1, MinimumBaseAmount: 1}
+
+	x, err := ConversionOrder(context.Background(), e, currency.BTC, currency.USDT, 2.25)
+	if err != nil || x.Side != order.Sell || x.Amount != 2.2 {
+		t.Errorf("expected: sell 2.2, actual: %v %v %v", err, x.Side, x.Amount)
+	}
+
+	// 300 TRY buys 10 USDT at the ask of 30
+	x, err = ConversionOrder(context.Background(), e, currency.TRY, currency.USDT, 300)
+	if err != nil || x.Side != order.Buy || x.Amount != 10 || x.QuoteAmount != 0 {
+		t.Errorf("expected: buy 10, actual: %v %v %v %v", err, x.Side, x.Amount, x.QuoteAmount)
+	}
+
+	if _, err = ConversionOrder(context.Background(), e, currency.TRY, currency.USDT, 15); !errors.Is(err, ErrBelowMinimum) {
+		t.Errorf("expected: %v, actual: %v", ErrBelowMinimum, err)
+	}
+
+	if _, err = ConversionOrder(context.Background(), e, currency.ETH, currency.USDT, 1); !errors.Is(err, ErrNoMarket) {
+		t.Errorf("expected: %v, actual: %v", ErrNoMarket, err)
+	}
+}
@@ -8,6 +8,8 @@ import (
 // A classic Observer design pattern implementation, allowing customizable post-order-fill actions.
 // Slots refers to a function pointer. So the function can be called indirectly through the variable.
 // The "OnFilledSlot" in the "Slots" struct is a function pointer, which is assigned a function that gets called when an order is filled.
+// The user data of an order may implement any of the observer interfaces below, the dealer calls them as the order moves through its lifecycle.
+// The order detail they receive carries the cumulative executed amount, average fill price and fee the registry tracked.
 
 // OnFilledObserver is an interface that responds to each placed order by the dealer.
 // The OnFilled method is expected to perform operations when a trade order is filled.
@@ -15,11 +17,46 @@ type OnFilledObserver interface {
 	OnFilled(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail)
 }
 
-// Slots is a struct that contains an OnFilled function pointer.
-// as the OnFilled method in the OnFilledObserver interface.
+// OnPartialFillObserver is called for every update that fills part of the order, but not the rest of it.
+type OnPartialFillObserver interface {
+	OnPartialFill(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail)
+}
+
+// OnCancelledObserver is called once the order is cancelled, it may have been partially filled before.
+type OnCancelledObserver interface {
+	OnCancelled(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail)
+}
+
+// OnRejectedObserver is called once the exchange rejects the order.
+type OnRejectedObserver interface {
+	OnRejected(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail)
+}
+
+// OrderObserver observes every transition of an order
+type OrderObserver interface {
+	OnPartialFillObserver
+	OnFilledObserver
+	OnCancelledObserver
+	OnRejectedObserver
+}
+
+// Slots is a struct that contains a function pointer for every transition of an order,
+// as the methods of the OrderObserver interface. Slots that are not set are skipped.
 // This allows for customizable behavior when an order gets filled.
 type Slots struct {
-	OnFilledSlot func(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail)
+	OnPartialFillSlot func(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail)
+	OnFilledSlot      func(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail)
+	OnCancelledSlot   func(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail)
+	OnRejectedSlot    func(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail)
+}
+
+var _ OrderObserver = Slots{}
+
+// OnPartialFill is invoked with the order and the exchange when part of the order is filled.
+func (s Slots) OnPartialFill(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail) {
+	if s.OnPartialFillSlot != nil {
+		s.OnPartialFillSlot(d, e, orderDetail)
+	}
 }
 
 // OnFilled is invoked with the placed order and the exchange.
@@ -31,3 +68,17 @@ func (s Slots) OnFilled(d *Dealer, e exchange.IBotExchange, orderDetail order.De
 		s.OnFilledSlot(d, e, orderDetail)
 	}
 }
+
+// OnCancelled is invoked with the order and the exchange once the order is cancelled.
+func (s Slots) OnCancelled(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail) {
+	if s.OnCancelledSlot != nil {
+		s.OnCancelledSlot(d, e, orderDetail)
+	}
+}
+
+// OnRejected is invoked with the order and the exchange once the order is rejected.
+func (s Slots) OnRejected(d *Dealer, e exchange.IBotExchange, orderDetail order.Detail) {
+	if s.OnRejectedSlot != nil {
+		s.OnRejectedSlot(d, e, orderDetail)
+	}
+}
@@ -1,5 +1,61 @@
 package dealer
 
+import (
+	"context"
+	"testing"
+
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+func TestOnOrderObservers(t *testing.T) {
+	d, err := NewBuilder().BuildWithExchanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := reconcileExchange{}
+
+	var calls []string
+	var executed []float64
+	record := func(name string) func(*Dealer, exchange.IBotExchange, order.Detail) {
+		return func(d *Dealer, e exchange.IBotExchange, x order.Detail) {
+			calls = append(calls, name)
+			executed = append(executed, x.ExecutedAmount)
+		}
+	}
+	observer := &Slots{
+		OnPartialFillSlot: record("partial"),
+		OnFilledSlot:      record("filled"),
+		OnCancelledSlot:   record("cancelled"),
+		OnRejectedSlot:    record("rejected"),
+	}
+
+	if _, err = d.SubmitOrderUD(context.Background(), e, order.Submit{}, observer); err != nil {
+		t.Fatal(err)
+	}
+
+	d.OnOrder(e, order.Detail{OrderID: "submitted", Status: order.Active, ExecutedAmount: 1})
+	d.OnOrder(e, order.Detail{OrderID: "submitted", Status: order.Active, ExecutedAmount: 1})
+	d.OnOrder(e, order.Detail{OrderID: "submitted", Status: order.Active, ExecutedAmount: 2})
+	d.OnOrder(e, order.Detail{OrderID: "submitted", Status: order.Filled})
+	d.OnOrder(e, order.Detail{OrderID: "submitted", Status: order.Filled})
+
+	want := []string{"partial", "partial", "filled"}
+	if len(calls) != len(want) || calls[0] != want[0] || calls[1] != want[1] || calls[2] != want[2] {
+		t.Errorf("expected: %v, actual: %v", want, calls)
+	}
+	if len(executed) == 3 && executed[2] != 3 {
+		t.Errorf("expected: %v, actual: %v", 3, executed[2])
+	}
+
+	calls = nil
+	d.registry.Store(e.GetName(), order.SubmitResponse{OrderID: "rejected", Amount: 1}, observer)
+	d.OnOrder(e, order.Detail{OrderID: "rejected", Status: order.Rejected})
+	if len(calls) != 1 || calls[0] != "rejected" {
+		t.Errorf("expected: %v, actual: %v", "rejected", calls)
+	}
+}
+
 //func TestOnFilled(t *testing.T) {
 //	d, err := NewBuilder().Build()
 //	if err != nil {
@@ -1,13 +1,19 @@
 package dealer
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
 )
 
+// defaultOrderRetention is how long terminal orders are kept before they are evicted
+const defaultOrderRetention = 7 * 24 * time.Hour
+
 // OrderKey struct implements the `Key` interface of the sync.Map, which used for type assertion of the key
 type OrderKey struct {
 	ExchangeName string
@@ -32,17 +38,29 @@ type OrderValue struct {
 // It has a single `int` property that represents the amount of orders currently in the registry.
 // The `int` property is an atomic.Int, which is part of the golang's atomic package.
 // It contains the modification of this `int`property that happens at the same time. The modification of an int cannot happen at two places in code in parallel. This provides a safe way to get or update integers from multiple routines, or from goroutines. In this case it’s the inner length field.
+// Next to the in memory values, which hold the user data of the orders, the registry keeps an OrderRecord of every order in its OrderStore.
+// The records follow the lifecycle of the orders and survive a restart when the store is durable, the user data does not.
 type OrderRegistry struct {
 	length int32
 	values sync.Map
+
+	store OrderStore
+	// retention is how long orders are kept after they reached a terminal state
+	retention time.Duration
+	now       func() time.Time
+	// mu serializes the read-modify-write cycles on the records
+	mu sync.Mutex
 }
 
 // NewOrderRegistry constructs a new OrderRegistry. The function initializes the field atomic.Int32 called length with 0
 // this means your r.length is incremented after every call of this function.
 func NewOrderRegistry() *OrderRegistry {
 	return &OrderRegistry{
-		length: 0,
-		values: sync.Map{},
+		length:    0,
+		values:    sync.Map{},
+		store:     NewMemoryOrderStore(),
+		retention: defaultOrderRetention,
+		now:       time.Now,
 	}
 }
 
@@ -63,6 +81,10 @@ func (r *OrderRegistry) Store(exchangeName string, response order.SubmitResponse
 	if !loaded {
 		// If not loaded, then it's stored, so length++.
 		atomic.AddInt32(&r.length, 1)
+
+		if err := r.save(context.Background(), r.newRecord(exchangeName, response)); err != nil {
+			logrus.Errorf("failed to save order %s %s: %s\n", exchangeName, response.OrderID, err)
+		}
 	}
 
 	return !loaded
@@ -98,3 +120,183 @@ func (r *OrderRegistry) GetOrderValue(exchangeName, orderID string) (OrderValue,
 func (r *OrderRegistry) Length() int {
 	return int(atomic.LoadInt32(&r.length))
 }
+
+// newRecord returns the record of a submitted order
+func (r *OrderRegistry) newRecord(exchangeName string, response order.SubmitResponse) OrderRecord {
+	now := r.now()
+	o := OrderRecord{
+		Exchange:      exchangeName,
+		OrderID:       response.OrderID,
+		ClientOrderID: response.ClientOrderID,
+		Pair:          response.Pair,
+		Asset:         response.AssetType,
+		Side:          response.Side,
+		Type:          response.Type,
+		Price:         response.Price,
+		Amount:        response.Amount,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	// orders can be (partially) filled by the time the exchange responds
+	o.applyFill(order.Detail{
+		Price:    response.Price,
+		Cost:     response.Cost,
+		Fee:      response.Fee,
+		FeeAsset: response.FeeAsset,
+		Trades:   response.Trades,
+	}, response.Status == order.Filled)
+	o.State = NewOrderState(response.Status, o.ExecutedAmount, o.Amount)
+	if o.State.Terminal() {
+		o.ClosedAt = now
+	}
+	return o
+}
+
+func (r *OrderRegistry) save(ctx context.Context, o OrderRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.store.Save(ctx, o)
+}
+
+// transition moves the order to the given state. Terminal orders never change again.
+// It returns the record and whether the state changed.
+func (r *OrderRegistry) transition(o OrderRecord, state OrderState) (OrderRecord, bool) {
+	if o.State.Terminal() || o.State == state {
+		return o, false
+	}
+
+	o.State = state
+	o.UpdatedAt = r.now()
+	if state.Terminal() {
+		o.ClosedAt = o.UpdatedAt
+	}
+	return o, true
+}
+
+// Update applies an order update from the exchange to the record of the order, it tracks the state and the cumulative fill.
+// Updates of unknown orders return ErrOrderNotFound, updates of terminal orders change nothing.
+func (r *OrderRegistry) Update(ctx context.Context, exchangeName string, x order.Detail) (OrderUpdate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, err := r.store.Get(ctx, exchangeName, x.OrderID)
+	if err != nil {
+		return OrderUpdate{Previous: o, Record: o}, err
+	}
+
+	u := OrderUpdate{Previous: o, Record: o}
+	if o.State.Terminal() {
+		return u, nil
+	}
+
+	if x.Amount > 0 {
+		o.Amount = x.Amount
+	}
+	o.applyFill(x, x.Status == order.Filled)
+
+	o, _ = r.transition(o, NewOrderState(x.Status, o.ExecutedAmount, o.Amount))
+	if o == u.Previous {
+		return u, nil
+	}
+	o.UpdatedAt = r.now()
+
+	u.Record = o
+	return u, r.store.Save(ctx, o)
+}
+
+// Cancelled marks the order as cancelled, orders that already reached a terminal state are left alone
+func (r *OrderRegistry) Cancelled(ctx context.Context, exchangeName, orderID string) (OrderUpdate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, err := r.store.Get(ctx, exchangeName, orderID)
+	u := OrderUpdate{Previous: o, Record: o}
+	if err != nil {
+		return u, err
+	}
+
+	o, changed := r.transition(o, OrderCancelled)
+	if !changed {
+		return u, nil
+	}
+
+	u.Record = o
+	return u, r.store.Save(ctx, o)
+}
+
+// Adopt registers an order found on the exchange. Orders the registry already knows are updated instead.
+// Adopted orders have no user data, it did not survive the restart.
+// It returns whether the order was unknown.
+func (r *OrderRegistry) Adopt(ctx context.Context, exchangeName string, x order.Detail) (bool, error) {
+	_, err := r.Update(ctx, exchangeName, x)
+	if !errors.Is(err, ErrOrderNotFound) {
+		return false, err
+	}
+
+	response := order.SubmitResponse{
+		Exchange:      exchangeName,
+		Type:          x.Type,
+		Side:          x.Side,
+		Pair:          x.Pair,
+		AssetType:     x.AssetType,
+		Price:         x.Price,
+		Amount:        x.Amount,
+		ClientOrderID: x.ClientOrderID,
+		Date:          x.Date,
+		LastUpdated:   x.LastUpdated,
+		Status:        x.Status,
+		OrderID:       x.OrderID,
+	}
+
+	o := r.newRecord(exchangeName, response)
+	o.applyFill(x, x.Status == order.Filled)
+	o.State = NewOrderState(x.Status, o.ExecutedAmount, x.Amount)
+	o.Adopted = true
+	if !x.Date.IsZero() {
+		o.CreatedAt = x.Date
+	}
+
+	key := OrderKey{ExchangeName: exchangeName, OrderID: x.OrderID}
+	if _, loaded := r.values.LoadOrStore(key, OrderValue{SubmitResponse: response}); !loaded {
+		atomic.AddInt32(&r.length, 1)
+	}
+	return true, r.save(ctx, o)
+}
+
+// Get returns the record of the order
+func (r *OrderRegistry) Get(ctx context.Context, exchangeName, orderID string) (OrderRecord, error) {
+	return r.store.Get(ctx, exchangeName, orderID)
+}
+
+// Query returns the records matching the query, most recent first
+func (r *OrderRegistry) Query(ctx context.Context, q OrderQuery) ([]OrderRecord, error) {
+	return r.store.Query(ctx, q)
+}
+
+// Evict removes the orders that reached a terminal state longer than the retention window ago.
+// It returns the amount of evicted orders.
+func (r *OrderRegistry) Evict(ctx context.Context) (int, error) {
+	xs, err := r.store.Query(ctx, OrderQuery{States: []OrderState{OrderFilled, OrderCancelled, OrderRejected}})
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := r.now().Add(-r.retention)
+
+	var evicted int
+	for _, o := range xs {
+		if o.ClosedAt.After(cutoff) {
+			continue
+		}
+
+		if err = r.store.Delete(ctx, o.Exchange, o.OrderID); err != nil {
+			return evicted, err
+		}
+		if _, loaded := r.values.LoadAndDelete(OrderKey{ExchangeName: o.Exchange, OrderID: o.OrderID}); loaded {
+			atomic.AddInt32(&r.length, -1)
+		}
+		evicted++
+	}
+	return evicted, nil
+}
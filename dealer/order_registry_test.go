@@ -1,5 +1,195 @@
 package dealer
 
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+var testOrderPair = currency.NewPair(currency.BTC, currency.USDT)
+
+// testClock is a registry clock that only moves when told to
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func testRegistry() (*OrderRegistry, *testClock) {
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	r := NewOrderRegistry()
+	r.now = clock.Now
+	return r, clock
+}
+
+func TestOrderRegistryLifecycle(t *testing.T) {
+	r, clock := testRegistry()
+	ctx := context.Background()
+
+	r.Store("binance", order.SubmitResponse{OrderID: "1", Pair: testOrderPair, AssetType: asset.Spot, Side: order.Buy, Amount: 2, Status: order.New}, nil)
+
+	o, err := r.Get(ctx, "binance", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.State != OrderSubmitted || o.ExecutedAmount != 0 {
+		t.Errorf("expected: %v, actual: %v", OrderSubmitted, o.State)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	u, err := r.Update(ctx, "binance", order.Detail{OrderID: "1", Status: order.PartiallyFilled, ExecutedAmount: 1, AverageExecutedPrice: 100})
+	if err != nil || !u.Transitioned() || u.Record.State != OrderPartiallyFilled {
+		t.Errorf("expected: %v, actual: %v %v", OrderPartiallyFilled, u.Record.State, err)
+	}
+
+	u, _ = r.Update(ctx, "binance", order.Detail{OrderID: "1", Status: order.Filled, ExecutedAmount: 2, AverageExecutedPrice: 101})
+	if !u.Transitioned() || u.Record.State != OrderFilled || !u.Record.ClosedAt.Equal(clock.now) {
+		t.Errorf("expected: %v, actual: %v %v", OrderFilled, u.Record.State, u.Record.ClosedAt)
+	}
+
+	// terminal orders stay terminal
+	u, _ = r.Update(ctx, "binance", order.Detail{OrderID: "1", Status: order.Cancelled})
+	if u.Transitioned() || u.Record.State != OrderFilled {
+		t.Errorf("expected: %v, actual: %v", OrderFilled, u.Record.State)
+	}
+
+	if _, err = r.Update(ctx, "binance", order.Detail{OrderID: "unknown"}); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected: %v, actual: %v", ErrOrderNotFound, err)
+	}
+}
+
+func TestOrderRegistryFills(t *testing.T) {
+	r, _ := testRegistry()
+	ctx := context.Background()
+
+	r.Store("binance", order.SubmitResponse{OrderID: "1", Price: 100, Amount: 3, Status: order.New}, nil)
+
+	// fills reported as trades only
+	u, err := r.Update(ctx, "binance", order.Detail{OrderID: "1", Status: order.Active, Trades: []order.TradeHistory{{Price: 100, Amount: 1, Fee: 0.1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Filled() != 1 || u.Record.AverageFillPrice != 100 || u.Record.Fee != 0.1 || u.Record.State != OrderPartiallyFilled {
+		t.Errorf("expected: %v, actual: %v", "1 at 100", u.Record)
+	}
+
+	// fills reported as a cumulative amount at the order price
+	u, _ = r.Update(ctx, "binance", order.Detail{OrderID: "1", Status: order.Active, ExecutedAmount: 2, Price: 103})
+	if u.Filled() != 1 || u.Record.AverageFillPrice != 101.5 {
+		t.Errorf("expected: %v, actual: %v", "2 at 101.5", u.Record)
+	}
+
+	// a late update doesn't undo a fill
+	u, _ = r.Update(ctx, "binance", order.Detail{OrderID: "1", Status: order.Active, ExecutedAmount: 1})
+	if u.Filled() != 0 || u.Record.ExecutedAmount != 2 {
+		t.Errorf("expected: %v, actual: %v", 2, u.Record.ExecutedAmount)
+	}
+
+	// exchanges that only report the status fill the rest
+	u, _ = r.Update(ctx, "binance", order.Detail{OrderID: "1", Status: order.Filled, Fee: 0.3})
+	if u.Filled() != 1 || u.Record.State != OrderFilled || u.Record.Fee != 0.3 || u.Record.Remaining() != 0 {
+		t.Errorf("expected: %v, actual: %v", "filled", u.Record)
+	}
+
+	// market orders can be filled by the time the exchange responds
+	r.Store("binance", order.SubmitResponse{OrderID: "2", Amount: 2, Cost: 200, Status: order.Filled}, nil)
+	o, _ := r.Get(ctx, "binance", "2")
+	if o.State != OrderFilled || o.ExecutedAmount != 2 {
+		t.Errorf("expected: %v, actual: %v", "filled", o)
+	}
+}
+
+func TestOrderRegistryEvict(t *testing.T) {
+	r, clock := testRegistry()
+	r.retention = time.Hour
+	ctx := context.Background()
+
+	r.Store("binance", order.SubmitResponse{OrderID: "1", Amount: 1}, nil)
+	r.Store("binance", order.SubmitResponse{OrderID: "2", Amount: 1}, nil)
+	if _, err := r.Cancelled(ctx, "binance", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	evicted, err := r.Evict(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evicted != 1 || r.Length() != 1 {
+		t.Errorf("expected: %v, actual: %v %v", 1, evicted, r.Length())
+	}
+	if _, err = r.Get(ctx, "binance", "1"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected: %v, actual: %v", ErrOrderNotFound, err)
+	}
+	if _, ok := r.GetOrderValue("binance", "2"); !ok {
+		t.Errorf("expected: %v, actual: %v", true, ok)
+	}
+}
+
+// reconcileExchange has one active order, knows a closed one and accepts every order
+type reconcileExchange struct {
+	exchange.IBotExchange
+}
+
+func (reconcileExchange) GetName() string {
+	return "test"
+}
+
+func (reconcileExchange) GetAssetTypes(enabled bool) asset.Items {
+	return asset.Items{asset.Spot}
+}
+
+func (reconcileExchange) GetActiveOrders(ctx context.Context, req *order.MultiOrderRequest) (order.FilteredOrders, error) {
+	return order.FilteredOrders{{OrderID: "resting", Pair: testOrderPair, AssetType: asset.Spot, Side: order.Sell, Amount: 1, Status: order.Active}}, nil
+}
+
+func (reconcileExchange) GetOrderInfo(ctx context.Context, orderID string, pair currency.Pair, a asset.Item) (*order.Detail, error) {
+	return &order.Detail{OrderID: orderID, Status: order.Filled, Amount: 1, ExecutedAmount: 1}, nil
+}
+
+func (reconcileExchange) SubmitOrder(ctx context.Context, s *order.Submit) (*order.SubmitResponse, error) {
+	return &order.SubmitResponse{OrderID: "submitted", Amount: 3, Status: order.New}, nil
+}
+
+func TestReconcileOrders(t *testing.T) {
+	d, err := NewBuilder().BuildWithExchanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// filled while the dealer was down
+	d.registry.Store("test", order.SubmitResponse{OrderID: "gone", Pair: testOrderPair, AssetType: asset.Spot, Amount: 1}, nil)
+
+	if err = d.ReconcileOrders(ctx, reconcileExchange{}); err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := d.GetOrder(ctx, "test", "resting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o.Adopted || o.State != OrderSubmitted || o.Side != order.Sell {
+		t.Errorf("expected: %v, actual: %v", "adopted resting sell", o)
+	}
+	if _, ok := d.GetOrderValue("test", "resting"); !ok {
+		t.Errorf("expected: %v, actual: %v", true, ok)
+	}
+
+	o, err = d.GetOrder(ctx, "test", "gone")
+	if err != nil || o.State != OrderFilled {
+		t.Errorf("expected: %v, actual: %v %v", OrderFilled, o.State, err)
+	}
+}
+
 //func TestOrderRegistry(t *testing.T) {
 //	orderID := "fake-order-id"
 //	response := order.SubmitResponse{
@@ -0,0 +1,354 @@
+package dealer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+var ErrOrderNotFound = errors.New("order not found")
+
+// OrderState is the stage of the lifecycle an order is in
+type OrderState string
+
+const (
+	OrderSubmitted       OrderState = "submitted"
+	OrderPartiallyFilled OrderState = "partially_filled"
+	OrderFilled          OrderState = "filled"
+	OrderCancelled       OrderState = "cancelled"
+	OrderRejected        OrderState = "rejected"
+)
+
+// Terminal reports whether the order can't change anymore
+func (s OrderState) Terminal() bool {
+	return s == OrderFilled || s == OrderCancelled || s == OrderRejected
+}
+
+// NewOrderState maps the status an exchange reports onto the lifecycle.
+// Exchanges close orders in many ways, everything that ends an order without filling it counts as cancelled.
+func NewOrderState(status order.Status, executed, amount float64) OrderState {
+	filled := amount > 0 && executed >= amount
+
+	switch status {
+	case order.Filled:
+		return OrderFilled
+	case order.Rejected, order.InsufficientBalance, order.MarketUnavailable:
+		return OrderRejected
+	case order.Cancelled, order.PartiallyCancelled, order.Expired, order.Closed, order.Liquidated, order.AutoDeleverage:
+		if filled {
+			return OrderFilled
+		}
+		return OrderCancelled
+	case order.PartiallyFilled:
+		return OrderPartiallyFilled
+	}
+
+	switch {
+	case filled:
+		return OrderFilled
+	case executed > 0:
+		return OrderPartiallyFilled
+	default:
+		return OrderSubmitted
+	}
+}
+
+// OrderRecord is the durable state of an order placed through the dealer or adopted from the exchange
+type OrderRecord struct {
+	Exchange      string        `json:"exchange"`
+	OrderID       string        `json:"orderId"`
+	ClientOrderID string        `json:"clientOrderId,omitempty"`
+	Pair          currency.Pair `json:"pair"`
+	Asset         asset.Item    `json:"asset"`
+	Side          order.Side    `json:"side"`
+	Type          order.Type    `json:"type"`
+	Price         float64       `json:"price"`
+	Amount        float64       `json:"amount"`
+	State         OrderState    `json:"state"`
+	// ExecutedAmount is the cumulative filled quantity, AverageFillPrice the volume weighted price it was filled at
+	ExecutedAmount   float64       `json:"executedAmount"`
+	AverageFillPrice float64       `json:"averageFillPrice"`
+	Fee              float64       `json:"fee"`
+	FeeAsset         currency.Code `json:"feeAsset"`
+	CreatedAt        time.Time     `json:"createdAt"`
+	UpdatedAt        time.Time     `json:"updatedAt"`
+	// ClosedAt is when the order reached a terminal state, the retention window starts from it
+	ClosedAt time.Time `json:"closedAt,omitempty"`
+	// Adopted orders were found resting on the exchange instead of being submitted by this process
+	Adopted bool `json:"adopted,omitempty"`
+}
+
+// Remaining returns the quantity that is still to be filled
+func (o OrderRecord) Remaining() float64 {
+	if o.ExecutedAmount >= o.Amount {
+		return 0
+	}
+	return o.Amount - o.ExecutedAmount
+}
+
+// Detail returns the order as the exchange would report it, with the cumulative fill of the record
+func (o OrderRecord) Detail() order.Detail {
+	return fillDetail(order.Detail{
+		Exchange:      o.Exchange,
+		OrderID:       o.OrderID,
+		ClientOrderID: o.ClientOrderID,
+		Pair:          o.Pair,
+		AssetType:     o.Asset,
+		Side:          o.Side,
+		Type:          o.Type,
+		Price:         o.Price,
+		Amount:        o.Amount,
+		Date:          o.CreatedAt,
+		LastUpdated:   o.UpdatedAt,
+	}, o)
+}
+
+// fillDetail overwrites the fill of the order update by the cumulative fill of the record
+func fillDetail(x order.Detail, o OrderRecord) order.Detail {
+	x.ExecutedAmount = o.ExecutedAmount
+	x.RemainingAmount = o.Remaining()
+	x.AverageExecutedPrice = o.AverageFillPrice
+	x.Cost = o.ExecutedAmount * o.AverageFillPrice
+	x.Fee = o.Fee
+	if !o.FeeAsset.IsEmpty() {
+		x.FeeAsset = o.FeeAsset
+	}
+
+	switch o.State {
+	case OrderFilled:
+		x.Status = order.Filled
+	case OrderCancelled:
+		x.Status = order.Cancelled
+	case OrderRejected:
+		x.Status = order.Rejected
+	case OrderPartiallyFilled:
+		x.Status = order.PartiallyFilled
+	}
+	return x
+}
+
+// applyFill updates the cumulative fill of the record from an order update. Exchanges report fills in different ways,
+// the executed quantity comes from the update, or else from its trades, and the price from the average price, the cost,
+// the trades or finally the price of the order. Fills never go backwards, so late updates are harmless.
+func (o *OrderRecord) applyFill(x order.Detail, filled bool) {
+	var tradeAmount, tradeCost, tradeFee float64
+	for _, t := range x.Trades {
+		tradeAmount += t.Amount
+		tradeCost += t.Amount * t.Price
+		tradeFee += t.Fee
+	}
+
+	executed := x.ExecutedAmount
+	if executed == 0 {
+		executed = tradeAmount
+	}
+	if executed == 0 && filled {
+		executed = o.Amount
+	}
+
+	if executed > o.ExecutedAmount {
+		switch {
+		case x.AverageExecutedPrice > 0:
+			o.AverageFillPrice = x.AverageExecutedPrice
+		case x.Cost > 0 && x.ExecutedAmount > 0:
+			o.AverageFillPrice = x.Cost / x.ExecutedAmount
+		case tradeAmount > 0 && tradeAmount >= executed:
+			o.AverageFillPrice = tradeCost / tradeAmount
+		default:
+			price := x.Price
+			if price == 0 {
+				price = o.Price
+			}
+			o.AverageFillPrice = (o.AverageFillPrice*o.ExecutedAmount + price*(executed-o.ExecutedAmount)) / executed
+		}
+		o.ExecutedAmount = executed
+	}
+
+	fee := x.Fee
+	if fee == 0 {
+		fee = tradeFee
+	}
+	if fee > o.Fee {
+		o.Fee = fee
+	}
+	if !x.FeeAsset.IsEmpty() {
+		o.FeeAsset = x.FeeAsset
+	}
+}
+
+// OrderUpdate is the change an order update made to a record
+type OrderUpdate struct {
+	Previous OrderRecord
+	Record   OrderRecord
+}
+
+// Transitioned reports whether the order moved to another state
+func (u OrderUpdate) Transitioned() bool {
+	return u.Previous.State != u.Record.State
+}
+
+// Filled returns the quantity filled by the update
+func (u OrderUpdate) Filled() float64 {
+	return u.Record.ExecutedAmount - u.Previous.ExecutedAmount
+}
+
+// orderRecordJSON is the encoded form of an OrderRecord. Side and type are written as strings and the pair with a delimiter,
+// otherwise they can't be decoded again.
+type orderRecordJSON struct {
+	orderRecordAlias
+	Pair string `json:"pair"`
+	Side string `json:"side"`
+	Type string `json:"type"`
+}
+
+type orderRecordAlias OrderRecord
+
+// MarshalJSON encodes the record
+func (o OrderRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(orderRecordJSON{
+		orderRecordAlias: orderRecordAlias(o),
+		Pair:             o.Pair.Format(currency.PairFormat{Uppercase: true, Delimiter: currency.DashDelimiter}).String(),
+		Side:             o.Side.String(),
+		Type:             o.Type.String(),
+	})
+}
+
+// UnmarshalJSON decodes a record written by MarshalJSON
+func (o *OrderRecord) UnmarshalJSON(data []byte) error {
+	var x orderRecordJSON
+	if err := json.Unmarshal(data, &x); err != nil {
+		return err
+	}
+	*o = OrderRecord(x.orderRecordAlias)
+
+	var err error
+	if x.Pair != "" {
+		if o.Pair, err = currency.NewPairFromString(x.Pair); err != nil {
+			return err
+		}
+	}
+	if o.Side, err = order.StringToOrderSide(x.Side); err != nil && x.Side != order.UnknownSide.String() {
+		return err
+	}
+	if o.Type, err = order.StringToOrderType(x.Type); err != nil && x.Type != order.UnknownType.String() {
+		return err
+	}
+	return nil
+}
+
+// OrderQuery selects order records, zero fields match everything
+type OrderQuery struct {
+	Exchange string
+	Pair     currency.Pair
+	Asset    asset.Item
+	States   []OrderState
+	// Since and Until bound the creation time of the orders
+	Since time.Time
+	Until time.Time
+}
+
+// Match reports whether the record is selected by the query
+func (q OrderQuery) Match(o OrderRecord) bool {
+	if q.Exchange != "" && !strings.EqualFold(q.Exchange, o.Exchange) {
+		return false
+	}
+	if !q.Pair.IsEmpty() && !q.Pair.Equal(o.Pair) {
+		return false
+	}
+	if q.Asset != asset.Empty && q.Asset != o.Asset {
+		return false
+	}
+	if !q.Since.IsZero() && o.CreatedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && o.CreatedAt.After(q.Until) {
+		return false
+	}
+	if len(q.States) == 0 {
+		return true
+	}
+	for _, s := range q.States {
+		if s == o.State {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStore persists order records, so the registry survives a restart of the dealer
+type OrderStore interface {
+	Save(ctx context.Context, o OrderRecord) error
+	// Get returns ErrOrderNotFound for unknown orders
+	Get(ctx context.Context, exchangeName, orderID string) (OrderRecord, error)
+	Query(ctx context.Context, q OrderQuery) ([]OrderRecord, error)
+	Delete(ctx context.Context, exchangeName, orderID string) error
+}
+
+// SortOrders orders the records by creation time, most recent first
+func SortOrders(xs []OrderRecord) {
+	sort.SliceStable(xs, func(i, j int) bool {
+		return xs[i].CreatedAt.After(xs[j].CreatedAt)
+	})
+}
+
+// MemoryOrderStore keeps the records in memory, it is the store of a dealer that is not given a durable one
+type MemoryOrderStore struct {
+	mu     sync.RWMutex
+	orders map[OrderKey]OrderRecord
+}
+
+// NewMemoryOrderStore returns an empty store
+func NewMemoryOrderStore() *MemoryOrderStore {
+	return &MemoryOrderStore{orders: make(map[OrderKey]OrderRecord)}
+}
+
+// Save stores the record
+func (s *MemoryOrderStore) Save(ctx context.Context, o OrderRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[OrderKey{ExchangeName: o.Exchange, OrderID: o.OrderID}] = o
+	return nil
+}
+
+// Get returns the record of the order
+func (s *MemoryOrderStore) Get(ctx context.Context, exchangeName, orderID string) (OrderRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, ok := s.orders[OrderKey{ExchangeName: exchangeName, OrderID: orderID}]
+	if !ok {
+		return o, ErrOrderNotFound
+	}
+	return o, nil
+}
+
+// Query returns the matching records, most recent first
+func (s *MemoryOrderStore) Query(ctx context.Context, q OrderQuery) ([]OrderRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	xs := make([]OrderRecord, 0)
+	for _, o := range s.orders {
+		if q.Match(o) {
+			xs = append(xs, o)
+		}
+	}
+	SortOrders(xs)
+	return xs, nil
+}
+
+// Delete removes the record
+func (s *MemoryOrderStore) Delete(ctx context.Context, exchangeName, orderID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.orders, OrderKey{ExchangeName: exchangeName, OrderID: orderID})
+	return nil
+}
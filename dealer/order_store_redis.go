@@ -0,0 +1,202 @@
+package dealer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	orderKeyPrefix      = "autodealer:orders:order:"
+	orderIndexKey       = "autodealer:orders"
+	orderExchangePrefix = "autodealer:orders:exchange:"
+	orderStatePrefix    = "autodealer:orders:state:"
+)
+
+// orderStates are the states records are indexed by
+var orderStates = []OrderState{OrderSubmitted, OrderPartiallyFilled, OrderFilled, OrderCancelled, OrderRejected}
+
+// RedisOrderStore persists order records in Redis. Every record is stored as a JSON document under its own key,
+// the keys of all records are kept in a set and in sets per exchange and per state. A record is in the set of
+// its current state only, so orders that reached a final state drop out of the open ones.
+type RedisOrderStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisOrderStore returns an order store using the given redis client
+func NewRedisOrderStore(client redis.UniversalClient) *RedisOrderStore {
+	return &RedisOrderStore{client: client}
+}
+
+func orderKey(exchangeName, orderID string) string {
+	return exchangeName + ":" + orderID
+}
+
+func orderExchangeKey(exchangeName string) string {
+	return orderExchangePrefix + strings.ToLower(exchangeName)
+}
+
+func orderStateKey(state OrderState) string {
+	return orderStatePrefix + string(state)
+}
+
+// Save writes the record to redis and moves it to the index of its state
+func (s *RedisOrderStore) Save(ctx context.Context, o OrderRecord) error {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+
+	key := orderKey(o.Exchange, o.OrderID)
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, orderKeyPrefix+key, data, 0)
+		index(ctx, pipe, key, o)
+		return nil
+	})
+	return err
+}
+
+// index adds the key to the set of all records, of the exchange and of the state of the record, and removes it from
+// the sets of the other states
+func index(ctx context.Context, pipe redis.Pipeliner, key string, o OrderRecord) {
+	pipe.SAdd(ctx, orderIndexKey, key)
+	pipe.SAdd(ctx, orderExchangeKey(o.Exchange), key)
+	for _, state := range orderStates {
+		if state != o.State {
+			pipe.SRem(ctx, orderStateKey(state), key)
+		}
+	}
+	pipe.SAdd(ctx, orderStateKey(o.State), key)
+}
+
+// Reindex rebuilds the exchange and state sets from the records, for records saved before they existed
+func (s *RedisOrderStore) Reindex(ctx context.Context) error {
+	keys, err := s.client.SMembers(ctx, orderIndexKey).Result()
+	if err != nil {
+		return err
+	}
+	xs, err := s.load(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, o := range xs {
+			index(ctx, pipe, orderKey(o.Exchange, o.OrderID), o)
+		}
+		return nil
+	})
+	return err
+}
+
+// Get loads the record of the order
+func (s *RedisOrderStore) Get(ctx context.Context, exchangeName, orderID string) (OrderRecord, error) {
+	return s.get(ctx, orderKey(exchangeName, orderID))
+}
+
+func (s *RedisOrderStore) get(ctx context.Context, key string) (OrderRecord, error) {
+	var o OrderRecord
+
+	data, err := s.client.Get(ctx, orderKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return o, ErrOrderNotFound
+	}
+	if err != nil {
+		return o, err
+	}
+
+	err = json.Unmarshal(data, &o)
+	return o, err
+}
+
+// Query returns the matching records, most recent first. Only the records in the sets of the exchange and the states
+// of the query are loaded.
+func (s *RedisOrderStore) Query(ctx context.Context, q OrderQuery) ([]OrderRecord, error) {
+	keys, err := s.keys(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	xs, err := s.load(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := xs[:0]
+	for _, o := range xs {
+		if q.Match(o) {
+			matches = append(matches, o)
+		}
+	}
+
+	SortOrders(matches)
+	return matches, nil
+}
+
+// keys returns the keys of the records that can match the query
+func (s *RedisOrderStore) keys(ctx context.Context, q OrderQuery) ([]string, error) {
+	set := orderIndexKey
+	if q.Exchange != "" {
+		set = orderExchangeKey(q.Exchange)
+	}
+	if len(q.States) == 0 {
+		return s.client.SMembers(ctx, set).Result()
+	}
+
+	var keys []string
+	for _, state := range q.States {
+		xs, err := s.client.SInter(ctx, set, orderStateKey(state)).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, xs...)
+	}
+	return keys, nil
+}
+
+// load fetches the records of the keys in one round trip, keys without a record are skipped
+func (s *RedisOrderStore) load(ctx context.Context, keys []string) ([]OrderRecord, error) {
+	if len(keys) == 0 {
+		return []OrderRecord{}, nil
+	}
+
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = orderKeyPrefix + key
+	}
+	values, err := s.client.MGet(ctx, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	xs := make([]OrderRecord, 0, len(values))
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var o OrderRecord
+		if err = json.Unmarshal([]byte(data), &o); err != nil {
+			return nil, err
+		}
+		xs = append(xs, o)
+	}
+	return xs, nil
+}
+
+// Delete removes the record
+func (s *RedisOrderStore) Delete(ctx context.Context, exchangeName, orderID string) error {
+	key := orderKey(exchangeName, orderID)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, orderKeyPrefix+key)
+		pipe.SRem(ctx, orderIndexKey, key)
+		pipe.SRem(ctx, orderExchangeKey(exchangeName), key)
+		for _, state := range orderStates {
+			pipe.SRem(ctx, orderStateKey(state), key)
+		}
+		return nil
+	})
+	return err
+}
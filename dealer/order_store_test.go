@@ -0,0 +1,114 @@
+package dealer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+func TestNewOrderState(t *testing.T) {
+	tests := []struct {
+		status   order.Status
+		executed float64
+		want     OrderState
+	}{
+		{order.New, 0, OrderSubmitted},
+		{order.Active, 0.5, OrderPartiallyFilled},
+		{order.PartiallyFilled, 0.5, OrderPartiallyFilled},
+		{order.Filled, 1, OrderFilled},
+		{order.Cancelled, 0.5, OrderCancelled},
+		{order.Closed, 1, OrderFilled},
+		{order.Expired, 0, OrderCancelled},
+		{order.InsufficientBalance, 0, OrderRejected},
+		{order.UnknownStatus, 1, OrderFilled},
+	}
+
+	for _, test := range tests {
+		if state := NewOrderState(test.status, test.executed, 1); state != test.want {
+			t.Errorf("expected: %v, actual: %v (%s)", test.want, state, test.status)
+		}
+	}
+}
+
+func TestOrderRecordJSON(t *testing.T) {
+	o := OrderRecord{
+		Exchange:  "binance",
+		OrderID:   "1",
+		Pair:      currency.NewPair(currency.BTC, currency.USDT),
+		Asset:     asset.Spot,
+		Side:      order.Sell,
+		Type:      order.Limit,
+		Price:     100,
+		Amount:    1,
+		State:     OrderSubmitted,
+		CreatedAt: time.Unix(1600000000, 0).UTC(),
+	}
+
+	data, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var x OrderRecord
+	if err = json.Unmarshal(data, &x); err != nil {
+		t.Fatal(err)
+	}
+	if !x.Pair.Equal(o.Pair) || x.Side != o.Side || x.Type != o.Type || x.Asset != o.Asset || !x.CreatedAt.Equal(o.CreatedAt) {
+		t.Errorf("expected: %v, actual: %v", o, x)
+	}
+}
+
+func TestOrderQuery(t *testing.T) {
+	s := NewMemoryOrderStore()
+	ctx := context.Background()
+	start := time.Unix(1600000000, 0)
+
+	for i, o := range []OrderRecord{
+		{Exchange: "binance", OrderID: "1", Pair: currency.NewPair(currency.BTC, currency.USDT), Asset: asset.Spot, State: OrderFilled},
+		{Exchange: "binance", OrderID: "2", Pair: currency.NewPair(currency.ETH, currency.USDT), Asset: asset.Spot, State: OrderSubmitted},
+		{Exchange: "kraken", OrderID: "3", Pair: currency.NewPair(currency.BTC, currency.USDT), Asset: asset.Spot, State: OrderSubmitted},
+	} {
+		o.CreatedAt = start.Add(time.Duration(i) * time.Hour)
+		if err := s.Save(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		q    OrderQuery
+		want []string
+	}{
+		{OrderQuery{}, []string{"3", "2", "1"}},
+		{OrderQuery{Exchange: "Binance"}, []string{"2", "1"}},
+		{OrderQuery{Pair: currency.NewPair(currency.BTC, currency.USDT)}, []string{"3", "1"}},
+		{OrderQuery{States: []OrderState{OrderSubmitted}}, []string{"3", "2"}},
+		{OrderQuery{Since: start.Add(time.Hour), Until: start.Add(time.Hour)}, []string{"2"}},
+	}
+
+	for _, test := range tests {
+		xs, err := s.Query(ctx, test.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := make([]string, 0, len(xs))
+		for _, x := range xs {
+			ids = append(ids, x.OrderID)
+		}
+		if len(ids) != len(test.want) {
+			t.Errorf("expected: %v, actual: %v", test.want, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != test.want[i] {
+				t.Errorf("expected: %v, actual: %v", test.want, ids)
+				break
+			}
+		}
+	}
+}
@@ -33,10 +33,70 @@ const (
 	GetActiveOrdersMetric
 	GetActiveOrdersLatencyMetric
 	GetActiveOrdersErrorMetric
+	// ArbitrageOpportunityMetric Return of arbitrage opportunities.
+	ArbitrageOpportunityMetric
+	// RiskRejectMetric Orders rejected by the pre-trade risk check, labelled with the exchange and the rule.
+	RiskRejectMetric
+	// WebsocketReconnectMetric Websocket connections that were restored after they were lost.
+	WebsocketReconnectMetric
+	// StreamMessageMetric Messages received from the websocket, labelled with the exchange and the event type.
+	StreamMessageMetric
+	// HandlerErrorMetric Errors returned by strategy handlers, labelled with the exchange and the handler.
+	HandlerErrorMetric
 	// MaxMetrics this should always be the last one.
 	MaxMetrics
 )
 
+// metricNames are the names metrics are exported under
+var metricNames = [MaxMetrics]string{
+	SubmitOrderMetric:            "submit_order",
+	SubmitOrderLatencyMetric:     "submit_order_latency",
+	SubmitOrderErrorMetric:       "submit_order_error",
+	SubmitBulkOrderMetric:        "submit_bulk_order",
+	SubmitBulkOrderLatencyMetric: "submit_bulk_order_latency",
+	ModifyOrderMetric:            "modify_order",
+	ModifyOrderLatencyMetric:     "modify_order_latency",
+	ModifyOrderErrorMetric:       "modify_order_error",
+	CancelOrderMetric:            "cancel_order",
+	CancelOrderLatencyMetric:     "cancel_order_latency",
+	CancelOrderErrorMetric:       "cancel_order_error",
+	CancelAllOrdersMetric:        "cancel_all_orders",
+	CancelAllOrdersLatencyMetric: "cancel_all_orders_latency",
+	CancelAllOrdersErrorMetric:   "cancel_all_orders_error",
+	GetActiveOrdersMetric:        "get_active_orders",
+	GetActiveOrdersLatencyMetric: "get_active_orders_latency",
+	GetActiveOrdersErrorMetric:   "get_active_orders_error",
+	ArbitrageOpportunityMetric:   "arbitrage_opportunity",
+	RiskRejectMetric:             "risk_reject",
+	WebsocketReconnectMetric:     "websocket_reconnect",
+	StreamMessageMetric:          "stream_message",
+	HandlerErrorMetric:           "handler_error",
+}
+
+// metricLabels are the names of the labels of metrics that have more than the exchange
+var metricLabels = map[Metric][]string{
+	ArbitrageOpportunityMetric: {"exchange", "counterparty"},
+	RiskRejectMetric:           {"exchange", "rule"},
+	StreamMessageMetric:        {"exchange", "event"},
+	HandlerErrorMetric:         {"exchange", "handler"},
+}
+
+// String returns the name of the metric
+func (m Metric) String() string {
+	if m < 0 || m >= MaxMetrics {
+		return "unknown"
+	}
+	return metricNames[m]
+}
+
+// Labels returns the names of the labels the metric is reported with, in order
+func (m Metric) Labels() []string {
+	if labels, ok := metricLabels[m]; ok {
+		return labels
+	}
+	return []string{"exchange"}
+}
+
 // Reporter interface is implemented by the clients that wish to report their metrics for this library.
 type Reporter interface {
 	// Event metrics in a single occurrence
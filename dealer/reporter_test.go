@@ -0,0 +1,71 @@
+package dealer
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+)
+
+// recordingReporter keeps the events it is reported
+type recordingReporter struct {
+	mu     sync.Mutex
+	events [][]string
+}
+
+func (r *recordingReporter) Event(m Metric, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, append([]string{m.String()}, labels...))
+}
+
+func (r *recordingReporter) Latency(m Metric, d time.Duration, labels ...string) {}
+
+func (r *recordingReporter) Value(m Metric, v float64, labels ...string) {}
+
+// failingStrategy fails on every price
+type failingStrategy struct {
+	Strategy
+}
+
+func (failingStrategy) OnPrice(d *Dealer, e exchange.IBotExchange, x ticker.Price) error {
+	return errors.New("failed")
+}
+
+func TestMetricNames(t *testing.T) {
+	seen := make(map[string]bool)
+	for m := Metric(0); m < MaxMetrics; m++ {
+		name := m.String()
+		if name == "" || seen[name] {
+			t.Errorf("expected a unique name for metric %d, actual: %q", m, name)
+		}
+		seen[name] = true
+	}
+}
+
+func TestDispatchReports(t *testing.T) {
+	r := &recordingReporter{}
+	d, err := NewBuilder().Balances(0).Reporter(r).BuildWithExchanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Dispatch(d, reconcileExchange{}, failingStrategy{}, &ticker.Price{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{{"stream_message", "test", "price"}, {"handler_error", "test", "OnPrice"}}
+	if len(r.events) != len(expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, r.events)
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if r.events[i][j] != expected[i][j] {
+				t.Errorf("expected: %v, actual: %v", expected, r.events)
+			}
+		}
+	}
+}
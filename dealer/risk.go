@@ -0,0 +1,15 @@
+package dealer
+
+import (
+	"context"
+
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// RiskCheck vets orders before the dealer sends them to the exchange. An error rejects the order, it is returned to the submitter as is.
+// SubmitOrders submits every order through SubmitOrderUD, so batches are checked order by order.
+type RiskCheck interface {
+	CheckSubmit(ctx context.Context, d *Dealer, e exchange.IBotExchange, s order.Submit) error
+	CheckModify(ctx context.Context, d *Dealer, e exchange.IBotExchange, m order.Modify) error
+}
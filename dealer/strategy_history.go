@@ -106,6 +106,7 @@ func (u *Historian) Update(now time.Time, x interface{}) {
 		if u.epoch == epoch {
 			return
 		}
+		u.epoch = epoch
 	}
 	u.state.(*CircularArray).Push(x)
 	u.f(u.state)
@@ -151,12 +152,12 @@ func (r *HistoryStrategy) AddHistorian(exchangeName, eventName string, interval
 	historian := NewHistorian(interval, stateLength, f)
 
 	r.mu.Lock()
-	defer r.mu.Lock()
+	defer r.mu.Unlock()
 
 	switch eventName {
 	case "OnPrice":
 		xs := r.onPriceUnits[key]
-		r.onOrderUnits[key] = append(xs, &historian)
+		r.onPriceUnits[key] = append(xs, &historian)
 	case "OnOrder":
 		xs := r.onOrderUnits[key]
 		r.onOrderUnits[key] = append(xs, &historian)
@@ -187,7 +188,7 @@ func (r *HistoryStrategy) OnFunding(d *Dealer, e exchange.IBotExchange, x stream
 func (r *HistoryStrategy) OnPrice(d *Dealer, e exchange.IBotExchange, x ticker.Price) error {
 	lastUpdated := x.LastUpdated
 	if lastUpdated.IsZero() {
-		lastUpdated = time.Now()
+		lastUpdated = d.Now()
 	}
 	return fire(r.onPriceUnits, e, lastUpdated, x)
 }
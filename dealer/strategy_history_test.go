@@ -0,0 +1,36 @@
+package dealer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistorianUpdateOncePerInterval(t *testing.T) {
+	var calls int
+	h := NewHistorian(time.Minute, 10, func(state Array) {
+		calls++
+	})
+
+	start := time.Unix(600, 0)
+	for _, offset := range []time.Duration{0, time.Second, 30 * time.Second, time.Minute, 90 * time.Second, 3 * time.Minute} {
+		h.Update(start.Add(offset), offset)
+	}
+
+	if calls != 3 {
+		t.Errorf("expected: %v, actual: %v", 3, calls)
+	}
+	if h.state.Len() != 3 {
+		t.Errorf("expected: %v, actual: %v", 3, h.state.Len())
+	}
+}
+
+func TestAddHistorianOnPrice(t *testing.T) {
+	r := NewHistoryStrategy()
+	if err := r.AddHistorian("test", "OnPrice", 0, 1, func(state Array) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.onPriceUnits) != 1 || len(r.onOrderUnits) != 0 {
+		t.Errorf("expected: %v, actual: %v %v", "1 price historian", len(r.onPriceUnits), len(r.onOrderUnits))
+	}
+}
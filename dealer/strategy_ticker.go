@@ -5,7 +5,6 @@ import (
 	"sync"
 	"time"
 
-	util2 "github.com/romanornr/autodealer/util"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
 
@@ -50,24 +49,16 @@ type TickerStrategy struct {
 // We can easily build a reoccurring ticker using the information in the ExchangeConnections struct by establishing a goroutine runtime.
 // The runtime clock enables us to manage time without the need for a goroutine.
 func (s *TickerStrategy) Init(ctx context.Context, d *Dealer, e exchange.IBotExchange) error {
-	t := *time.NewTicker(s.Interval)
+	stop := func() {}
 
 	if s.TickFunc != nil {
-		go func() {
-			util2.CheckerPush()
-
-			defer util2.CheckerPop()
-
-			// Call now initially.
+		// the dealer's clock calls now initially and then once every interval
+		stop = d.Clock().Every(s.Interval, func() {
 			s.TickFunc(d, e)
-
-			for range t.C {
-				s.TickFunc(d, e)
-			}
-		}()
+		})
 	}
 
-	_, loaded := s.tickers.LoadOrStore(e.GetName(), t)
+	_, loaded := s.tickers.LoadOrStore(e.GetName(), stop)
 	if loaded {
 		panic("one exchange can have just one ticker")
 	}
@@ -134,10 +125,10 @@ func (s *TickerStrategy) Deinit(d *Dealer, e exchange.IBotExchange) error {
 		panic("exchange has not registered a ticker")
 	}
 
-	tickers, ok := pointer.(time.Ticker)
+	stop, ok := pointer.(func())
 	if !ok {
-		panic("want time.Ticker")
+		panic("want stop func")
 	}
-	tickers.Stop()
+	stop()
 	return nil
 }
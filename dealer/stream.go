@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
@@ -16,6 +18,9 @@ import (
 	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
 )
 
+// connectionCheckInterval is how often the websocket connection is checked to count reconnects
+const connectionCheckInterval = 5 * time.Second
+
 var (
 	ErrWebsocketNotSupported = errors.New("websocket not supported")
 	ErrWebsocketNotEnabled   = errors.New("websocket is not enabled")
@@ -36,6 +41,10 @@ func Stream(ctx context.Context, d *Dealer, e exchange.IBotExchange, s Strategy)
 		return err
 	}
 
+	// count the reconnects of the websocket
+	stop := d.Clock().Every(connectionCheckInterval, watchConnection(d, e, ws))
+	defer stop()
+
 	// This goroutine is supposed to never finish
 	for data := range ws.ToRoutine {
 		err := handleData(d, e, s, data)
@@ -64,24 +73,34 @@ func Stream(ctx context.Context, d *Dealer, e exchange.IBotExchange, s Strategy)
 // handleData function is a matcher for incoming subscription messages from websockets. Messages of different classes below websocket type are matched.
 // These messages will be paired with a strategy to be sent on a go channel set up by dealer.
 func handleData(d *Dealer, e exchange.IBotExchange, s Strategy, data interface{}) error {
+	d.ReportEvent(StreamMessageMetric, e.GetName(), eventType(data))
+
+	// once the kill switch is engaged the strategies are stopped, the registry still follows the orders
+	if d.Halted() {
+		if x, ok := data.(*order.Detail); ok {
+			d.OnOrder(e, *x)
+		}
+		return nil
+	}
+
 	switch x := data.(type) {
 	case string:
 		unhandledType(data, true)
 	case error:
 		return x
 	case stream.FundingData:
-		handleError("OnFunding", s.OnFunding(d, e, x))
+		handleError(d, e, "OnFunding", s.OnFunding(d, e, x))
 	case *ticker.Price:
-		handleError("OnPrice", s.OnPrice(d, e, *x))
+		handleError(d, e, "OnPrice", s.OnPrice(d, e, *x))
 	case *stream.KlineData:
-		handleError("OnKline", s.OnKline(d, e, *x))
+		handleError(d, e, "OnKline", s.OnKline(d, e, *x))
 	case *orderbook.Base:
-		handleError("OnOrderBook", s.OnOrderBook(d, e, *x))
+		handleError(d, e, "OnOrderBook", s.OnOrderBook(d, e, *x))
 	case *order.Detail:
 		d.OnOrder(e, *x)
-		handleError("OnOrder", s.OnOrder(d, e, *x))
+		handleError(d, e, "OnOrder", s.OnOrder(d, e, *x))
 	case *order.Modify:
-		handleError("OnModify", s.OnModify(d, e, *x))
+		handleError(d, e, "OnModify", s.OnModify(d, e, *x))
 
 	case order.ClassificationError:
 		unhandledType(data, true)
@@ -94,13 +113,13 @@ func handleData(d *Dealer, e exchange.IBotExchange, s Strategy, data interface{}
 	case stream.UnhandledMessageWarning:
 		unhandledType(data, true)
 	case account.Change:
-		handleError("OnBalanceChange", s.OnBalanceChange(d, e, x))
+		handleError(d, e, "OnBalanceChange", s.OnBalanceChange(d, e, x))
 	case []trade.Data:
-		handleError("OnTrade", s.OnTrade(d, e, x))
+		handleError(d, e, "OnTrade", s.OnTrade(d, e, x))
 	case []fill.Data:
-		handleError("OnFill", s.OnFill(d, e, x))
+		handleError(d, e, "OnFill", s.OnFill(d, e, x))
 	default:
-		handleError("OnUnrecognized", s.OnUnrecognized(d, e, data))
+		handleError(d, e, "OnUnrecognized", s.OnUnrecognized(d, e, data))
 	}
 
 	return nil
@@ -109,8 +128,10 @@ func handleData(d *Dealer, e exchange.IBotExchange, s Strategy, data interface{}
 // handleError function checks to see if there are actually an error. This is triggered by the inclusion of the string "err" being != to the string "nil".
 // If it does not equal nil, this means there is an error, so it will print out the method responsible for the error along with the error itself.
 // If this is true, Go will output "error: <errormessage>". Otherwise, nothing is outputted.
-func handleError(method string, err error) {
+// Errors are counted per exchange and handler.
+func handleError(d *Dealer, e exchange.IBotExchange, method string, err error) {
 	if err != nil {
+		d.ReportEvent(HandlerErrorMetric, e.GetName(), method)
 		What(log.Warn().
 			Err(err).
 			Str("method", method),
@@ -118,6 +139,50 @@ func handleError(method string, err error) {
 	}
 }
 
+// eventType returns the name of the kind of message the websocket delivered, messages are counted by it
+func eventType(data interface{}) string {
+	switch data.(type) {
+	case string, stream.UnhandledMessageWarning:
+		return "unhandled"
+	case error, order.ClassificationError:
+		return "error"
+	case stream.FundingData:
+		return "funding"
+	case *ticker.Price:
+		return "price"
+	case *stream.KlineData:
+		return "kline"
+	case *orderbook.Base:
+		return "orderbook"
+	case *order.Detail:
+		return "order"
+	case *order.Modify:
+		return "modify"
+	case account.Change:
+		return "balance"
+	case []trade.Data:
+		return "trade"
+	case []fill.Data:
+		return "fill"
+	default:
+		return "unrecognized"
+	}
+}
+
+// watchConnection returns a check that reports a reconnect every time the websocket is connected again after it was lost
+func watchConnection(d *Dealer, e exchange.IBotExchange, ws *stream.Websocket) func() {
+	connected := int32(1)
+	return func() {
+		now := int32(0)
+		if ws.IsConnected() {
+			now = 1
+		}
+		if atomic.SwapInt32(&connected, now) == 0 && now == 1 {
+			d.ReportEvent(WebsocketReconnectMetric, e.GetName())
+		}
+	}
+}
+
 // OpenWebsocket function is responsible for opening a Websocket connection.
 // The `req.Exchange.GetWebsocket` method performs the actual functionality of creating a new Websocket in a struct.
 // Understandably, if a connection can't be opened, the error would be returned.
@@ -169,3 +234,9 @@ func unhandledType(data interface{}, warn bool) {
 
 	What(e, "unhandled type")
 }
+
+// Dispatch hands a single message to the strategy the same way a message from the websocket is handled.
+// It allows recorded data to be replayed through a strategy.
+func Dispatch(d *Dealer, e exchange.IBotExchange, s Strategy, data interface{}) error {
+	return handleData(d, e, s, data)
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 
+	"github.com/thrasher-corp/gocryptotrader/currency"
 	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
 )
@@ -120,3 +122,62 @@ func ModifyToSubmit(mod order.Modify) order.Submit {
 	}
 	return sub
 }
+
+// Executed returns the base amount an order executed and the quote it cost, according to the response of the exchange.
+// The trades of the response are used when it lists them, otherwise a filled order counts for its full amount and a partially
+// filled one for its cost at the price. Nothing is executed when the response tells neither.
+func Executed(resp *order.SubmitResponse, price float64) (float64, float64) {
+	if resp == nil {
+		return 0, 0
+	}
+	if resp.Price > 0 {
+		price = resp.Price
+	}
+
+	var base, quote float64
+	for _, t := range resp.Trades {
+		base += t.Amount
+		quote += t.Amount * t.Price
+	}
+	if base > 0 {
+		return base, quote
+	}
+
+	switch resp.Status {
+	case order.Filled:
+		base = resp.Amount
+		quote = resp.Cost
+		if quote == 0 {
+			quote = base * price
+		}
+	case order.PartiallyFilled, order.PartiallyCancelled:
+		if resp.Cost > 0 && price > 0 {
+			base, quote = resp.Cost/price, resp.Cost
+		}
+	}
+	return base, quote
+}
+
+// FreeBalance returns the balance of the currency that is not held by orders, it is fetched from the exchange
+func FreeBalance(ctx context.Context, e exchange.IBotExchange, a asset.Item, code currency.Code) (float64, error) {
+	holdings, err := e.UpdateAccountInfo(ctx, a)
+	if err != nil {
+		return 0, err
+	}
+
+	var free float64
+	for _, account := range holdings.Accounts {
+		for _, c := range account.Currencies {
+			if !c.Currency.Equal(code) {
+				continue
+			}
+			// not every exchange reports the free balance
+			if c.Free == 0 && c.Total > c.Hold {
+				free += c.Total - c.Hold
+				continue
+			}
+			free += c.Free
+		}
+	}
+	return free, nil
+}
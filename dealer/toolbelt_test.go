@@ -0,0 +1,29 @@
+package dealer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+func TestExecuted(t *testing.T) {
+	tests := []struct {
+		name        string
+		resp        *order.SubmitResponse
+		base, quote float64
+	}{
+		{"trades", &order.SubmitResponse{Status: order.PartiallyCancelled, Amount: 1, Trades: []order.TradeHistory{{Amount: 0.2, Price: 100}, {Amount: 0.1, Price: 101}}}, 0.3, 30.1},
+		{"filled", &order.SubmitResponse{Status: order.Filled, Amount: 1}, 1, 100},
+		{"partial", &order.SubmitResponse{Status: order.PartiallyCancelled, Amount: 1, Cost: 40}, 0.4, 40},
+		{"cancelled", &order.SubmitResponse{Status: order.Cancelled, Amount: 1}, 0, 0},
+		{"missing", nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		base, quote := Executed(tt.resp, 100)
+		if math.Abs(base-tt.base) > 1e-9 || math.Abs(quote-tt.quote) > 1e-9 {
+			t.Errorf("%s expected: %v %v, actual: %v %v", tt.name, tt.base, tt.quote, base, quote)
+		}
+	}
+}
@@ -1,178 +1,332 @@
 package maker
 
-//import (
-//	"encoding/json"
-//	"fmt"
-//	"io/ioutil"
-//	"math"
-//	"math/rand"
-//	"net/http"
-//	"net/url"
-//	"strings"
-//	"time"
-//
-//	"github.com/romanornr/autodealer/algo"
-//	"github.com/sirupsen/logrus"
-//	"github.com/thrasher-corp/gocryptotrader/currency"
-//	"github.com/thrasher-corp/gocryptotrader/engine"
-//	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
-//	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
-//	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
-//)
-//
-//type Token struct {
-//	Address           string `json:"address"`
-//	Decimals          string `json:"decimals"`
-//	Name              string `json:"name"`
-//	Symbol            string `json:"symbol"`
-//	TotalSupply       string `json:"totalSupply"`
-//	TransfersCount    int    `json:"transfersCount"`
-//	LastUpdated       int    `json:"lastUpdated"`
-//	IssuancesCount    int    `json:"issuancesCount"`
-//	HoldersCount      int    `json:"holdersCount"`
-//	EthTransfersCount int    `json:"ethTransfersCount"`
-//	Price             struct {
-//		Rate            float64 `json:"rate"`
-//		Diff            float64 `json:"diff"`
-//		Diff7D          float64 `json:"diff7d"`
-//		Ts              int     `json:"ts"`
-//		MarketCapUsd    float64 `json:"marketCapUsd"`
-//		AvailableSupply int     `json:"availableSupply"`
-//		Volume24H       float64 `json:"volume24h"`
-//		VolDiff1        float64 `json:"volDiff1"`
-//		VolDiff7        float64 `json:"volDiff7"`
-//		Currency        string  `json:"currency"`
-//	} `json:"price"`
-//	CountOps int `json:"countOps"`
-//}
-//
-//func GetEthereumToken() *Token {
-//	logrus.Info("Get external price")
-//	apiEndpoint, err := url.Parse("https://api.ethplorer.io/getTokenInfo/0xb753428af26e81097e7fd17f40c88aaa3e04902c?apiKey=freekey")
-//	if err != nil {
-//		logrus.Fatalf("could not parse url endpoint: %s\n", err)
-//	}
-//
-//	client := http.Client{
-//		Transport:     nil,
-//		CheckRedirect: nil,
-//		Jar:           nil,
-//		Timeout:       time.Second * 15,
-//	}
-//
-//	req, err := http.NewRequest(http.MethodGet, apiEndpoint.String(), nil)
-//	if err != nil {
-//		logrus.Fatalf("could not create a new request to %s\n", apiEndpoint.Scheme)
-//	}
-//
-//	resp, err := client.Do(req)
-//	if err != nil {
-//		logrus.Fatalf("client do failed for API endpoint: %s\n", apiEndpoint.String())
-//	}
-//
-//	defer resp.Body.Close()
-//	body, err := ioutil.ReadAll(resp.Body)
-//	if err != nil {
-//		logrus.Fatalf("Could not read body response from API endpoint %s %s\n", apiEndpoint.String(), err)
-//	}
-//
-//	tokens := new(Token)
-//	err = json.Unmarshal(body, &tokens)
-//
-//	return tokens
-//}
-//
-//func btseGrid(bidLimitPrice, stepsize float64) {
-//	currencyPair, err := currency.NewPairFromString("SFI-USDT")
-//	if err != nil {
-//		logrus.Warnf("failed to retrieve trading pair: %s\n", currencyPair)
-//	}
-//
-//	token := GetEthereumToken()
-//	externalPrice := token.Price.Rate
-//
-//	exchangeEngine, _ := engine.Bot.GetExchangeByName("Btse")
-//	ticker, err := exchangeEngine.FetchTicker(currencyPair, asset.Spot)
-//	if err != nil {
-//		logrus.Warn(err)
-//	}
-//	logrus.Infof("ticker bid: %f\n", ticker.Bid)
-//
-//	diff := 100 / externalPrice * ticker.Bid
-//	fmt.Printf("Price difference is %f%%\n", 100-diff)
-//
-//	//if bidLimitPrice == 0 {
-//	//	bidLimitPrice = externalPrice / 100 * (100 - 3) // 3% below external price
-//	//}
-//
-//	logrus.Info("start placing grids")
-//
-//	go func() {
-//		orders := gridOrders(1, 6, 100, 488.00, stepsize, order.Buy, currencyPair)
-//		executeMultipleOrdersAtOnce(exchangeEngine, orders)
-//		logrus.Printf("%s orders added to the orderbook\n", orders[0].Side.Lower())
-//	}()
-//
-//	ordersReverse := gridOrders(3, 15, 300, 510, stepsize+1, order.Sell, currencyPair)
-//	executeMultipleOrdersAtOnce(exchangeEngine, ordersReverse)
-//}
-//
-//func gridOrders(dollarSizeMin, dollarSizeMax, amountOfOrders, quoteLimitPrice float64, stepsize float64, side order.Side, pair currency.Pair) []order.Submit {
-//	var orders = []order.Submit{}
-//
-//	rand.Seed(time.Now().UnixNano())
-//	var x = algo.RandFloats(dollarSizeMin, dollarSizeMax, int(amountOfOrders))
-//
-//	var gridPrice = quoteLimitPrice
-//	for i := 0.0; i < amountOfOrders; i++ {
-//
-//		if side == order.Buy {
-//			gridPrice = gridPrice - stepsize
-//		}
-//
-//		if side == order.Sell {
-//			gridPrice += stepsize
-//		}
-//
-//		amount := x[int(i)] / gridPrice
-//		amount = roundTo(amount, 3)
-//
-//		o := order.Submit{
-//			PostOnly:  true,
-//			Price:     roundTo(gridPrice, 1),
-//			Amount:    amount,
-//			Exchange:  "Btse",
-//			AssetType: asset.Spot,
-//			Type:      order.Limit,
-//			Side:      side,
-//			Pair:      pair,
-//		}
-//
-//		if o.Side == order.Buy && o.Price >= 480 {
-//			fmt.Printf("cancel price too high %f\n", o.Price)
-//			continue
-//		}
-//
-//		orders = append(orders, o)
-//	}
-//	return orders
-//}
-//
-//func roundTo(n float64, decimals uint32) float64 {
-//	return math.Round(n*math.Pow(10, float64(decimals))) / math.Pow(10, float64(decimals))
-//}
-//
-//func executeMultipleOrdersAtOnce(e exchange.IBotExchange, orders []order.Submit) {
-//	for _, o := range orders {
-//		_, err := e.SubmitOrder(&o)
-//		if err != nil {
-//			o.Price -= 1.0 // retry?
-//			_, err = e.SubmitOrder(&o)
-//			if err != nil {
-//				logrus.Warnf("Failed second try submitting order %s %f at %f: %s\n", o.Pair, o.Amount, o.Price, err)
-//			}
-//		}
-//		fmt.Printf("%s Amount: %f Price: %f\n", strings.ToLower(o.Side.Lower()), o.Amount, o.Price)
-//		time.Sleep(time.Millisecond * 50)
-//	}
-//}
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/orderbuilder"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+// Spacing determines how the price levels of the grid are distributed between the bounds
+type Spacing string
+
+const (
+	// Arithmetic spaces the levels by the same price difference
+	Arithmetic Spacing = "arithmetic"
+	// Geometric spaces the levels by the same percentage
+	Geometric Spacing = "geometric"
+)
+
+var (
+	ErrInvalidBounds  = errors.New("grid lower bound must be positive and below the upper bound")
+	ErrInvalidLevels  = errors.New("grid needs at least two levels")
+	ErrInvalidSpacing = errors.New("grid spacing must be arithmetic or geometric")
+	ErrInvalidSize    = errors.New("grid size per level must be positive")
+)
+
+// GridConfig describes a grid
+type GridConfig struct {
+	Exchange string
+	Pair     currency.Pair
+	Asset    asset.Item
+	Lower    float64
+	Upper    float64
+	Levels   int
+	Spacing  Spacing
+	// QuotePerLevel is the size of the order at every level in quote currency
+	QuotePerLevel float64
+	// StatePath is the file the grid state is persisted to, so the grid picks up where it left off after a restart.
+	// The state is only kept in memory when it is empty.
+	StatePath string
+}
+
+// Validate checks the grid configuration
+func (c GridConfig) Validate() error {
+	switch {
+	case c.Lower <= 0 || c.Upper <= c.Lower:
+		return ErrInvalidBounds
+	case c.Levels < 2:
+		return ErrInvalidLevels
+	case c.Spacing != Arithmetic && c.Spacing != Geometric:
+		return ErrInvalidSpacing
+	case c.QuotePerLevel <= 0:
+		return ErrInvalidSize
+	}
+	return nil
+}
+
+// Prices returns the price of every level from the lower to the upper bound
+func (c GridConfig) Prices() []float64 {
+	prices := make([]float64, c.Levels)
+	steps := float64(c.Levels - 1)
+
+	for i := range prices {
+		if c.Spacing == Geometric {
+			prices[i] = c.Lower * math.Pow(c.Upper/c.Lower, float64(i)/steps)
+		} else {
+			prices[i] = c.Lower + float64(i)*(c.Upper-c.Lower)/steps
+		}
+	}
+	return prices
+}
+
+// GridStrategy is a grid market maker. Buy orders rest at the levels below the current price and sell orders at the levels above it,
+// the level closest to the price is left empty. Every time a level fills, a counter-order is placed one level away:
+// a filled buy is sold one level up and a filled sell is bought back one level down, earning the spacing on each round trip.
+// The state of the grid is saved after every change and reconciled with the exchange when the strategy is initialized again.
+type GridStrategy struct {
+	config GridConfig
+	mu     sync.Mutex
+	state  *GridState
+}
+
+// NewGridStrategy returns a grid strategy for the given configuration
+func NewGridStrategy(c GridConfig) (*GridStrategy, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	state, err := loadState(c.StatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load grid state: %w", err)
+	}
+
+	return &GridStrategy{
+		config: c,
+		state:  state,
+	}, nil
+}
+
+// State returns a copy of the grid state
+func (g *GridStrategy) State() GridState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	state := *g.state
+	state.Levels = append([]Level(nil), g.state.Levels...)
+	return state
+}
+
+// Init places the grid, or reconciles the saved grid with the exchange when the strategy is restarted
+func (g *GridStrategy) Init(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	if e.GetName() != g.config.Exchange {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.state.Levels) == 0 {
+		return g.setup(ctx, d, e)
+	}
+	return g.reconcile(ctx, d, e)
+}
+
+// setup places the initial orders around the current price
+func (g *GridStrategy) setup(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	t, err := e.UpdateTicker(ctx, g.config.Pair, g.config.Asset)
+	if err != nil {
+		return err
+	}
+
+	prices := g.config.Prices()
+	if limits, err := e.GetOrderExecutionLimits(g.config.Asset, g.config.Pair); err == nil && limits.PriceStepIncrementSize > 0 {
+		for i := range prices {
+			prices[i] = math.Round(prices[i]/limits.PriceStepIncrementSize) * limits.PriceStepIncrementSize
+		}
+	}
+
+	// the level closest to the current price stays empty
+	empty := 0
+	for i := range prices {
+		if math.Abs(prices[i]-t.Last) < math.Abs(prices[empty]-t.Last) {
+			empty = i
+		}
+	}
+
+	g.state.Levels = make([]Level, len(prices))
+	for i, price := range prices {
+		side := order.Buy
+		if i > empty {
+			side = order.Sell
+		}
+		g.state.Levels[i] = Level{Price: price, Side: side, Amount: g.config.QuotePerLevel / price}
+	}
+
+	logrus.Printf("grid %s %s: %d levels between %f and %f, price %f\n", e.GetName(), g.config.Pair, len(prices), prices[0], prices[len(prices)-1], t.Last)
+
+	for i := range g.state.Levels {
+		if i == empty {
+			continue
+		}
+		g.place(ctx, d, e, i)
+	}
+	return g.save()
+}
+
+// reconcile checks the orders of the saved grid. Orders that were filled while the strategy was not running get their counter-order,
+// orders that disappeared otherwise are placed again.
+func (g *GridStrategy) reconcile(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	for i := range g.state.Levels {
+		id := g.state.Levels[i].OrderID
+		if id == "" {
+			continue
+		}
+
+		x, err := e.GetOrderInfo(ctx, id, g.config.Pair, g.config.Asset)
+		if err != nil {
+			logrus.Errorf("grid order %s at %f: %s\n", id, g.state.Levels[i].Price, err)
+			continue
+		}
+
+		switch {
+		case x.Status == order.Filled:
+			g.fill(ctx, d, e, i)
+		case x.IsInactive():
+			g.place(ctx, d, e, i)
+		}
+	}
+
+	logrus.Printf("grid %s %s: resumed with %d fills\n", e.GetName(), g.config.Pair, g.state.Fills)
+	return g.save()
+}
+
+// place submits the order of level i. Failures are logged and leave the level empty, a grid with a missing level keeps working.
+func (g *GridStrategy) place(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, i int) {
+	level := &g.state.Levels[i]
+	level.OrderID = ""
+
+	amount := level.Amount
+	if limits, err := e.GetOrderExecutionLimits(g.config.Asset, g.config.Pair); err == nil {
+		amount = limits.ConformToAmount(amount)
+	}
+
+	ob := orderbuilder.NewOrderBuilder()
+	ob.
+		AtExchange(e.GetName()).
+		ForCurrencyPair(g.config.Pair).
+		WithAssetType(g.config.Asset).
+		ForPrice(level.Price).
+		WithAmount(amount).
+		UseOrderType(order.Limit).
+		WithPostOnly(true).
+		SetSide(level.Side)
+
+	o, err := ob.Build()
+	if err != nil {
+		logrus.Errorf("grid %s order at %f: %s\n", level.Side.Lower(), level.Price, err)
+		return
+	}
+
+	response, err := d.SubmitOrder(ctx, e, *o)
+	if err != nil {
+		logrus.Errorf("grid %s order at %f: %s\n", level.Side.Lower(), level.Price, err)
+		return
+	}
+
+	level.OrderID = response.OrderID
+	logrus.Printf("grid %s %f at %f placed %s\n", level.Side.Lower(), amount, level.Price, response.OrderID)
+}
+
+// fill books the fill of level i and places the counter-order
+func (g *GridStrategy) fill(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, i int) {
+	logrus.Printf("grid %s at %f filled\n", g.state.Levels[i].Side.Lower(), g.state.Levels[i].Price)
+
+	counter, ok := g.state.Fill(i)
+	switch {
+	case ok:
+		g.place(ctx, d, e, counter)
+	case counter >= 0:
+		logrus.Warnf("grid %s: level %f already has order %s\n", g.config.Pair, g.state.Levels[counter].Price, g.state.Levels[counter].OrderID)
+	default:
+		logrus.Warnf("grid %s: price left the grid at %f\n", g.config.Pair, g.state.Levels[i].Price)
+	}
+}
+
+// save persists the grid state
+func (g *GridStrategy) save() error {
+	if err := saveState(g.config.StatePath, g.state); err != nil {
+		return fmt.Errorf("failed to save grid state: %w", err)
+	}
+	return nil
+}
+
+func (g *GridStrategy) OnFunding(d *dealer.Dealer, e exchange.IBotExchange, x stream.FundingData) error {
+	return nil
+}
+
+func (g *GridStrategy) OnPrice(d *dealer.Dealer, e exchange.IBotExchange, x ticker.Price) error {
+	return nil
+}
+
+func (g *GridStrategy) OnKline(d *dealer.Dealer, e exchange.IBotExchange, x stream.KlineData) error {
+	return nil
+}
+
+func (g *GridStrategy) OnOrderBook(d *dealer.Dealer, e exchange.IBotExchange, x orderbook.Base) error {
+	return nil
+}
+
+// OnOrder places the counter-order when one of the grid orders is filled
+func (g *GridStrategy) OnOrder(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) error {
+	if e.GetName() != g.config.Exchange || x.Status != order.Filled {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	i, ok := g.state.Level(x.OrderID)
+	if !ok {
+		return nil
+	}
+
+	g.fill(context.Background(), d, e, i)
+	return g.save()
+}
+
+func (g *GridStrategy) OnModify(d *dealer.Dealer, e exchange.IBotExchange, x order.Modify) error {
+	return nil
+}
+
+func (g *GridStrategy) OnBalanceChange(d *dealer.Dealer, e exchange.IBotExchange, x account.Change) error {
+	return nil
+}
+
+func (g *GridStrategy) OnTrade(d *dealer.Dealer, e exchange.IBotExchange, x []trade.Data) error {
+	return nil
+}
+
+func (g *GridStrategy) OnFill(d *dealer.Dealer, e exchange.IBotExchange, x []fill.Data) error {
+	return nil
+}
+
+func (g *GridStrategy) OnUnrecognized(d *dealer.Dealer, e exchange.IBotExchange, x interface{}) error {
+	return nil
+}
+
+// Deinit keeps the grid orders on the exchange, they are picked up again from the saved state on the next start
+func (g *GridStrategy) Deinit(d *dealer.Dealer, e exchange.IBotExchange) error {
+	if e.GetName() != g.config.Exchange {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.save()
+}
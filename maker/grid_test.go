@@ -0,0 +1,108 @@
+package maker
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+func TestGridPrices(t *testing.T) {
+	c := GridConfig{Lower: 100, Upper: 200, Levels: 5, Spacing: Arithmetic, QuotePerLevel: 10}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for i, want := range []float64{100, 125, 150, 175, 200} {
+		if p := c.Prices()[i]; p != want {
+			t.Errorf("expected: %f, actual: %f", want, p)
+		}
+	}
+
+	c.Spacing = Geometric
+	c.Levels = 3
+	for i, want := range []float64{100, 100 * math.Sqrt2, 200} {
+		if p := c.Prices()[i]; math.Abs(p-want) > 1e-9 {
+			t.Errorf("expected: %f, actual: %f", want, p)
+		}
+	}
+
+	c.Upper = 50
+	if err := c.Validate(); err != ErrInvalidBounds {
+		t.Errorf("expected: %v, actual: %v", ErrInvalidBounds, err)
+	}
+}
+
+func TestGridStateFill(t *testing.T) {
+	s := GridState{Levels: []Level{
+		{Price: 100, Side: order.Buy, Amount: 1, OrderID: "1"},
+		{Price: 110},
+		{Price: 120, Side: order.Sell, Amount: 1, OrderID: "3"},
+	}}
+
+	i, ok := s.Level("1")
+	if !ok || i != 0 {
+		t.Fatalf("expected level 0, actual: %d", i)
+	}
+
+	counter, ok := s.Fill(i)
+	if !ok || counter != 1 {
+		t.Fatalf("expected counter-order at level 1, actual: %d", counter)
+	}
+	if s.Levels[1].Side != order.Sell || s.Levels[1].Amount != 1 {
+		t.Errorf("expected sell of 1, actual: %s of %f", s.Levels[1].Side, s.Levels[1].Amount)
+	}
+
+	// the counter sell fills and completes a round trip
+	s.Levels[1].OrderID = "2"
+	counter, ok = s.Fill(1)
+	if !ok || counter != 0 || s.Levels[0].Side != order.Buy {
+		t.Fatalf("expected counter buy at level 0, actual: %d", counter)
+	}
+	if s.Profit != 10 {
+		t.Errorf("expected: %f, actual: %f", 10.0, s.Profit)
+	}
+
+	// there is no level above the top of the grid
+	s.Levels[2].Side = order.Buy
+	if _, ok = s.Fill(2); ok {
+		t.Error("expected no counter-order above the grid")
+	}
+	if s.Fills != 3 {
+		t.Errorf("expected: %d, actual: %d", 3, s.Fills)
+	}
+
+	// the order resting at the counter level is left alone
+	s.Levels[0] = Level{Price: 100, Side: order.Buy, Amount: 2, OrderID: "4"}
+	s.Levels[1] = Level{Price: 110, Side: order.Sell, Amount: 1, OrderID: "5"}
+	if counter, ok = s.Fill(1); ok || counter != 0 {
+		t.Errorf("expected occupied level 0, actual: %d %v", counter, ok)
+	}
+	if s.Levels[0].Side != order.Buy || s.Levels[0].Amount != 2 || s.Levels[0].OrderID != "4" || s.Levels[0].Counter {
+		t.Errorf("expected: %v, actual: %+v", "buy of 2 with order 4", s.Levels[0])
+	}
+}
+
+func TestGridState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grid.json")
+
+	s, err := loadState(path)
+	if err != nil || len(s.Levels) != 0 {
+		t.Fatalf("expected empty state, got %v %v", s, err)
+	}
+
+	s.Levels = []Level{{Price: 100, Side: order.Sell, Amount: 1, OrderID: "1"}}
+	s.Fills = 2
+	if err = saveState(path, s); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	loaded, err := loadState(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if loaded.Fills != 2 || len(loaded.Levels) != 1 || loaded.Levels[0].Side != order.Sell || loaded.Levels[0].OrderID != "1" {
+		t.Errorf("expected: %+v, actual: %+v", s, loaded)
+	}
+}
@@ -0,0 +1,91 @@
+package maker
+
+import (
+	"github.com/romanornr/autodealer/util"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// Level is a single price level of the grid. A level without order ID has no order resting on the exchange.
+type Level struct {
+	Price   float64    `json:"price"`
+	Side    order.Side `json:"side"`
+	Amount  float64    `json:"amount"`
+	OrderID string     `json:"orderID"`
+	// Counter is set for orders that were placed after a fill on the neighbouring level
+	Counter bool `json:"counter"`
+}
+
+// MarshalJSON encodes the side as a string
+func (l Level) MarshalJSON() ([]byte, error) {
+	type alias Level
+	return util.MarshalSide(alias(l), "side", l.Side)
+}
+
+// GridState is the persisted state of a grid
+type GridState struct {
+	Levels []Level `json:"levels"`
+	// Fills is the amount of filled grid orders
+	Fills int `json:"fills"`
+	// Profit is the quote currency earned by round trips between neighbouring levels
+	Profit float64 `json:"profit"`
+}
+
+// Fill books the fill of the order at level i and returns the level the counter-order goes to.
+// A filled buy is sold one level up and a filled sell is bought back one level down, the counter-order keeps the amount of the filled order.
+// False is returned with level -1 when there is no level to place the counter-order at. When an order still rests at the
+// counter level, false is returned with that level, which is left unchanged so its order keeps its side and amount.
+func (s *GridState) Fill(i int) (int, bool) {
+	filled := s.Levels[i]
+	s.Levels[i].OrderID = ""
+	s.Fills++
+
+	counter, side := i+1, order.Sell
+	if filled.Side == order.Sell {
+		counter, side = i-1, order.Buy
+	}
+
+	if counter < 0 || counter >= len(s.Levels) {
+		return -1, false
+	}
+
+	// a counter sell completes a round trip that was bought one level down
+	if filled.Side == order.Sell && filled.Counter {
+		s.Profit += filled.Amount * (filled.Price - s.Levels[counter].Price)
+	}
+
+	if s.Levels[counter].OrderID != "" {
+		return counter, false
+	}
+
+	s.Levels[counter].Side = side
+	s.Levels[counter].Amount = filled.Amount
+	s.Levels[counter].Counter = true
+	return counter, true
+}
+
+// Level returns the index of the level with the given order ID
+func (s *GridState) Level(orderID string) (int, bool) {
+	if orderID == "" {
+		return 0, false
+	}
+	for i := range s.Levels {
+		if s.Levels[i].OrderID == orderID {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// loadState reads the grid state from the file at path. An empty state is returned when the file does not exist yet.
+func loadState(path string) (*GridState, error) {
+	state := &GridState{}
+	if err := util.ReadJSON(path, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// saveState writes the grid state to the file at path. The file is replaced atomically so a crash never leaves a partial state behind.
+func saveState(path string, state *GridState) error {
+	return util.WriteJSON(path, state)
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/sirupsen/logrus"
+)
+
+const namespace = "autodealer"
+
+// Prometheus is a dealer.Reporter that exports the metrics to Prometheus. Events are counted, latencies observed in a histogram
+// and values set on a gauge. Collectors are registered the first time a metric is reported, labelled as the metric describes.
+type Prometheus struct {
+	registerer prometheus.Registerer
+
+	mu         sync.Mutex
+	counters   map[dealer.Metric]*prometheus.CounterVec
+	histograms map[dealer.Metric]*prometheus.HistogramVec
+	gauges     map[dealer.Metric]*prometheus.GaugeVec
+}
+
+var _ dealer.Reporter = (*Prometheus)(nil)
+
+// NewPrometheus returns a reporter that registers its collectors with the registerer
+func NewPrometheus(registerer prometheus.Registerer) *Prometheus {
+	return &Prometheus{
+		registerer: registerer,
+		counters:   make(map[dealer.Metric]*prometheus.CounterVec),
+		histograms: make(map[dealer.Metric]*prometheus.HistogramVec),
+		gauges:     make(map[dealer.Metric]*prometheus.GaugeVec),
+	}
+}
+
+var (
+	reporter     *Prometheus
+	reporterOnce sync.Once
+)
+
+// Default returns the reporter shared by the application, it registers with the default Prometheus registry
+func Default() *Prometheus {
+	reporterOnce.Do(func() {
+		reporter = NewPrometheus(prometheus.DefaultRegisterer)
+	})
+	return reporter
+}
+
+// Event counts an occurrence of the metric
+func (p *Prometheus) Event(m dealer.Metric, labels ...string) {
+	p.mu.Lock()
+	c, ok := p.counters[m]
+	if !ok {
+		c = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      m.String() + "_total",
+			Help:      "Number of " + m.String() + " events.",
+		}, m.Labels())
+		c = p.register(c).(*prometheus.CounterVec)
+		p.counters[m] = c
+	}
+	p.mu.Unlock()
+
+	c.WithLabelValues(labelValues(m, labels)...).Inc()
+}
+
+// Latency observes the duration in seconds
+func (p *Prometheus) Latency(m dealer.Metric, d time.Duration, labels ...string) {
+	p.mu.Lock()
+	h, ok := p.histograms[m]
+	if !ok {
+		h = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      m.String() + "_seconds",
+			Help:      "Duration of " + m.String() + " in seconds.",
+			Buckets:   prometheus.DefBuckets,
+		}, m.Labels())
+		h = p.register(h).(*prometheus.HistogramVec)
+		p.histograms[m] = h
+	}
+	p.mu.Unlock()
+
+	h.WithLabelValues(labelValues(m, labels)...).Observe(d.Seconds())
+}
+
+// Value sets the gauge of the metric
+func (p *Prometheus) Value(m dealer.Metric, v float64, labels ...string) {
+	p.mu.Lock()
+	g, ok := p.gauges[m]
+	if !ok {
+		g = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      m.String(),
+			Help:      "Last value of " + m.String() + ".",
+		}, m.Labels())
+		g = p.register(g).(*prometheus.GaugeVec)
+		p.gauges[m] = g
+	}
+	p.mu.Unlock()
+
+	g.WithLabelValues(labelValues(m, labels)...).Set(v)
+}
+
+// register registers the collector, when an equal one is registered already that one is returned
+func (p *Prometheus) register(c prometheus.Collector) prometheus.Collector {
+	if err := p.registerer.Register(c); err != nil {
+		if existing, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return existing.ExistingCollector
+		}
+		logrus.Errorf("failed to register metric: %s\n", err)
+	}
+	return c
+}
+
+// labelValues fits the labels to the label names of the metric, missing labels are empty and extra labels are dropped
+func labelValues(m dealer.Metric, labels []string) []string {
+	values := make([]string, len(m.Labels()))
+	copy(values, labels)
+	return values
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/romanornr/autodealer/dealer"
+)
+
+func TestPrometheus(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	p := NewPrometheus(registry)
+
+	p.Event(dealer.SubmitOrderMetric, "binance")
+	p.Event(dealer.SubmitOrderMetric, "binance")
+	p.Event(dealer.StreamMessageMetric, "binance", "price")
+	p.Latency(dealer.SubmitOrderLatencyMetric, 250*time.Millisecond, "binance")
+	p.Value(dealer.ArbitrageOpportunityMetric, 0.5, "binance")
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make(map[string]bool)
+	for _, f := range families {
+		found[f.GetName()] = true
+
+		switch f.GetName() {
+		case "autodealer_submit_order_total":
+			if v := f.GetMetric()[0].GetCounter().GetValue(); v != 2 {
+				t.Errorf("expected: %v, actual: %v", 2, v)
+			}
+		case "autodealer_stream_message_total":
+			labels := f.GetMetric()[0].GetLabel()
+			if len(labels) != 2 || labels[1].GetName() != "exchange" || labels[0].GetValue() != "price" {
+				t.Errorf("expected: event price on exchange binance, actual: %v", labels)
+			}
+		case "autodealer_submit_order_latency_seconds":
+			if n := f.GetMetric()[0].GetHistogram().GetSampleCount(); n != 1 {
+				t.Errorf("expected: %v, actual: %v", 1, n)
+			}
+		case "autodealer_arbitrage_opportunity":
+			// the counterparty label is missing and left empty
+			if v := f.GetMetric()[0].GetGauge().GetValue(); v != 0.5 {
+				t.Errorf("expected: %v, actual: %v", 0.5, v)
+			}
+		}
+	}
+
+	for _, name := range []string{
+		"autodealer_submit_order_total", "autodealer_stream_message_total", "autodealer_submit_order_latency_seconds", "autodealer_arbitrage_opportunity",
+	} {
+		if !found[name] {
+			t.Errorf("expected metric %s to be exported", name)
+		}
+	}
+}
+
+func TestPrometheusRegisteredTwice(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	NewPrometheus(registry).Event(dealer.CancelOrderMetric, "kraken")
+	NewPrometheus(registry).Event(dealer.CancelOrderMetric, "kraken")
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 1 || families[0].GetMetric()[0].GetCounter().GetValue() != 2 {
+		t.Errorf("expected: one counter at 2, actual: %v", families)
+	}
+}
@@ -0,0 +1,312 @@
+package paper
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+)
+
+var (
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderNotActive      = errors.New("order is not active")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrPostOnlyCrossed     = errors.New("post only order would cross the book")
+)
+
+// balance is a virtual balance, Hold is reserved by resting orders
+type balance struct {
+	Total float64
+	Hold  float64
+}
+
+// balance returns the balance of the currency, creating it when it does not exist. The caller must hold the lock.
+func (p *Exchange) balance(a asset.Item, code currency.Code) *balance {
+	if _, ok := p.balances[a]; !ok {
+		p.balances[a] = make(map[currency.Code]*balance)
+	}
+
+	code = code.Upper()
+	b, ok := p.balances[a][code]
+	if !ok {
+		b = &balance{}
+		p.balances[a][code] = b
+	}
+	return b
+}
+
+// Walk takes liquidity from the levels, best price first, until the amount is filled or the limit price is reached.
+// Buys walk the asks and stop above the limit, sells walk the bids and stop below it. A limit of zero takes any price.
+func Walk(side order.Side, levels orderbook.Items, amount, limit float64) (filled, cost float64) {
+	for _, level := range levels {
+		if limit > 0 && ((side == order.Buy && level.Price > limit) || (side == order.Sell && level.Price < limit)) {
+			break
+		}
+
+		take := math.Min(level.Amount, amount-filled)
+		filled += take
+		cost += take * level.Price
+
+		if filled >= amount {
+			break
+		}
+	}
+	return filled, cost
+}
+
+// levels returns the side of the book the order takes liquidity from
+func levels(side order.Side, b *orderbook.Base) orderbook.Items {
+	if side == order.Buy {
+		return b.Asks
+	}
+	return b.Bids
+}
+
+// rests reports whether the remainder of the order stays in the book
+func rests(d *order.Detail) bool {
+	return d.Type != order.Market && !d.ImmediateOrCancel && !d.FillOrKill
+}
+
+// place creates the order from the submission and executes it against the book. The caller must hold the lock.
+func (p *Exchange) place(s *order.Submit, b *orderbook.Base) (*order.Detail, error) {
+	now := p.config.Now()
+	d := &order.Detail{
+		Exchange:          p.GetName(),
+		OrderID:           uuid.NewString(),
+		ClientOrderID:     s.ClientOrderID,
+		AccountID:         paperAccount,
+		Type:              s.Type,
+		Side:              s.Side,
+		Pair:              s.Pair,
+		AssetType:         s.AssetType,
+		Price:             s.Price,
+		Amount:            s.Amount,
+		RemainingAmount:   s.Amount,
+		PostOnly:          s.PostOnly,
+		ImmediateOrCancel: s.ImmediateOrCancel,
+		FillOrKill:        s.FillOrKill,
+		Status:            order.New,
+		CostAsset:         s.Pair.Quote,
+		FeeAsset:          s.Pair.Quote,
+		Date:              now,
+		LastUpdated:       now,
+	}
+
+	if d.Type == order.Market {
+		d.Price = 0
+		// market buys may be sized in quote, they are converted at the best ask
+		if d.Amount == 0 && s.QuoteAmount > 0 && len(b.Asks) > 0 {
+			d.Amount = s.QuoteAmount / b.Asks[0].Price
+			d.RemainingAmount = d.Amount
+		}
+	}
+
+	if err := p.execute(d, b); err != nil {
+		return nil, err
+	}
+
+	p.orders[d.OrderID] = d
+	p.emit(d)
+	return d, nil
+}
+
+// modify changes the price and amount of a resting order and executes it against the book again. The caller must hold the lock.
+func (p *Exchange) modify(m *order.Modify, b *orderbook.Base) (*order.Detail, error) {
+	d, ok := p.orders[m.OrderID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, m.OrderID)
+	}
+	if !d.IsActive() {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotActive, m.OrderID)
+	}
+
+	p.release(d, d.RemainingAmount)
+
+	previous := *d
+	if m.Price > 0 {
+		d.Price = m.Price
+	}
+	if m.Amount > 0 {
+		d.Amount = m.Amount
+	}
+	d.RemainingAmount = d.Amount - d.ExecutedAmount
+	d.LastUpdated = p.config.Now()
+
+	if err := p.execute(d, b); err != nil {
+		*d = previous
+		p.hold(d, d.RemainingAmount)
+		return nil, err
+	}
+
+	p.emit(d)
+	return d, nil
+}
+
+// cancel cancels a resting order and releases its hold. The caller must hold the lock.
+func (p *Exchange) cancel(id string) error {
+	d, ok := p.orders[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, id)
+	}
+	if !d.IsActive() {
+		return fmt.Errorf("%w: %s", ErrOrderNotActive, id)
+	}
+
+	p.release(d, d.RemainingAmount)
+	d.Status = order.Cancelled
+	if d.ExecutedAmount > 0 {
+		d.Status = order.PartiallyCancelled
+	}
+	d.LastUpdated = p.config.Now()
+	d.CloseTime = d.LastUpdated
+
+	p.emit(d)
+	return nil
+}
+
+// execute takes liquidity from the book for the remainder of the order at the taker fee, what can't be taken is held in the book
+// when the order rests, otherwise it is cancelled. Nothing is changed when the balance does not cover the order. The caller must hold the lock.
+func (p *Exchange) execute(d *order.Detail, b *orderbook.Base) error {
+	filled, cost := Walk(d.Side, levels(d.Side, b), d.RemainingAmount, d.Price)
+
+	if d.PostOnly && filled > 0 {
+		return ErrPostOnlyCrossed
+	}
+
+	if d.FillOrKill && filled < d.RemainingAmount {
+		filled, cost = 0, 0
+	}
+
+	if d.Type == order.Market && filled == 0 {
+		return fmt.Errorf("%w %s", ErrNoBook, d.Pair)
+	}
+
+	resting := 0.0
+	if rests(d) {
+		resting = d.RemainingAmount - filled
+	}
+
+	if err := p.cover(d, cost*(1+p.config.TakerFee), filled, resting); err != nil {
+		return err
+	}
+
+	if filled > 0 {
+		p.fill(d, filled, cost, p.config.TakerFee)
+	}
+
+	switch {
+	case d.RemainingAmount <= 0:
+		d.Status = order.Filled
+		d.CloseTime = d.LastUpdated
+	case rests(d):
+		p.hold(d, d.RemainingAmount)
+	case d.ExecutedAmount > 0:
+		d.Status = order.PartiallyCancelled
+		d.CloseTime = d.LastUpdated
+	default:
+		d.Status = order.Cancelled
+		d.CloseTime = d.LastUpdated
+	}
+	return nil
+}
+
+// cover checks whether the free balance covers the quote spent on taking liquidity and the amount that is going to rest.
+// The caller must hold the lock.
+func (p *Exchange) cover(d *order.Detail, spent, filled, resting float64) error {
+	if d.Side == order.Buy {
+		quote := p.balance(d.AssetType, d.Pair.Quote)
+		needed := spent + resting*d.Price*(1+p.config.MakerFee)
+		if needed > quote.Total-quote.Hold {
+			return fmt.Errorf("%w: %f %s needed, %f available", ErrInsufficientBalance, needed, d.Pair.Quote, quote.Total-quote.Hold)
+		}
+		return nil
+	}
+
+	base := p.balance(d.AssetType, d.Pair.Base)
+	if needed := filled + resting; needed > base.Total-base.Hold {
+		return fmt.Errorf("%w: %f %s needed, %f available", ErrInsufficientBalance, needed, d.Pair.Base, base.Total-base.Hold)
+	}
+	return nil
+}
+
+// hold reserves the balance a resting amount of the order needs. The caller must hold the lock.
+func (p *Exchange) hold(d *order.Detail, amount float64) {
+	if d.Side == order.Buy {
+		p.balance(d.AssetType, d.Pair.Quote).Hold += amount * d.Price * (1 + p.config.MakerFee)
+		return
+	}
+	p.balance(d.AssetType, d.Pair.Base).Hold += amount
+}
+
+// release frees the balance reserved for a resting amount of the order. The caller must hold the lock.
+func (p *Exchange) release(d *order.Detail, amount float64) {
+	b := p.balance(d.AssetType, d.Pair.Base)
+	reserved := amount
+	if d.Side == order.Buy {
+		b = p.balance(d.AssetType, d.Pair.Quote)
+		reserved = amount * d.Price * (1 + p.config.MakerFee)
+	}
+	b.Hold = math.Max(0, b.Hold-reserved)
+}
+
+// fill settles an execution of the order on the balances. The caller must hold the lock.
+func (p *Exchange) fill(d *order.Detail, amount, cost, feeRate float64) {
+	fee := cost * feeRate
+	base := p.balance(d.AssetType, d.Pair.Base)
+	quote := p.balance(d.AssetType, d.Pair.Quote)
+
+	if d.Side == order.Buy {
+		quote.Total -= cost + fee
+		base.Total += amount
+	} else {
+		base.Total -= amount
+		quote.Total += cost - fee
+	}
+
+	now := p.config.Now()
+	d.ExecutedAmount += amount
+	d.RemainingAmount = math.Max(0, d.RemainingAmount-amount)
+	d.Cost += cost
+	d.Fee += fee
+	d.AverageExecutedPrice = d.Cost / d.ExecutedAmount
+	d.LastUpdated = now
+	d.Status = order.PartiallyFilled
+	d.Trades = append(d.Trades, order.TradeHistory{
+		Price:     cost / amount,
+		Amount:    amount,
+		Fee:       fee,
+		Exchange:  d.Exchange,
+		TID:       uuid.NewString(),
+		Side:      d.Side,
+		Type:      d.Type,
+		Timestamp: now,
+	})
+}
+
+// match fills the resting orders of the pair that are crossed by the book at their own price and the maker fee.
+// The caller must hold the lock.
+func (p *Exchange) match(b *orderbook.Base) {
+	for _, d := range p.orders {
+		if !d.IsActive() || d.AssetType != b.Asset || !d.Pair.Equal(b.Pair) {
+			continue
+		}
+
+		filled, _ := Walk(d.Side, levels(d.Side, b), d.RemainingAmount, d.Price)
+		if filled <= 0 {
+			continue
+		}
+
+		p.release(d, filled)
+		p.fill(d, filled, filled*d.Price, p.config.MakerFee)
+		if d.RemainingAmount <= 0 {
+			d.Status = order.Filled
+			d.CloseTime = d.LastUpdated
+		}
+		p.emit(d)
+	}
+}
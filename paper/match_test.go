@@ -0,0 +1,147 @@
+package paper
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+)
+
+// testExchange only provides the name, the paper exchange handles everything the tests touch
+type testExchange struct {
+	exchange.IBotExchange
+}
+
+func (testExchange) GetName() string {
+	return "test"
+}
+
+var testPair = currency.NewPair(currency.BTC, currency.USDT)
+
+func testBook() *orderbook.Base {
+	return &orderbook.Base{
+		Pair:  testPair,
+		Asset: asset.Spot,
+		Bids:  orderbook.Items{{Price: 99, Amount: 1}, {Price: 98, Amount: 2}},
+		Asks:  orderbook.Items{{Price: 101, Amount: 1}, {Price: 102, Amount: 2}},
+	}
+}
+
+func testPaper() *Exchange {
+	return New(testExchange{}, Config{
+		Balances: map[currency.Code]float64{currency.USDT: 1000, currency.BTC: 1},
+		MakerFee: 0.001,
+		TakerFee: 0.002,
+	})
+}
+
+func TestWalk(t *testing.T) {
+	b := testBook()
+
+	filled, cost := Walk(order.Buy, b.Asks, 2, 0)
+	if filled != 2 || cost != 101+102 {
+		t.Errorf("expected: %v, actual: %v %v", "2 for 203", filled, cost)
+	}
+
+	filled, _ = Walk(order.Buy, b.Asks, 2, 101)
+	if filled != 1 {
+		t.Errorf("expected: %v, actual: %v", 1, filled)
+	}
+
+	filled, cost = Walk(order.Sell, b.Bids, 1.5, 0)
+	if filled != 1.5 || cost != 99+49 {
+		t.Errorf("expected: %v, actual: %v %v", "1.5 for 148", filled, cost)
+	}
+
+	if filled, _ = Walk(order.Sell, b.Bids, 1, 100); filled != 0 {
+		t.Errorf("expected: %v, actual: %v", 0, filled)
+	}
+}
+
+func TestMarketOrder(t *testing.T) {
+	p := testPaper()
+
+	d, err := p.place(&order.Submit{Exchange: "test", Type: order.Market, Side: order.Buy, Pair: testPair, AssetType: asset.Spot, Amount: 2}, testBook())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Status != order.Filled || d.ExecutedAmount != 2 || d.AverageExecutedPrice != 101.5 {
+		t.Errorf("expected: %v, actual: %v %v %v", "filled 2 at 101.5", d.Status, d.ExecutedAmount, d.AverageExecutedPrice)
+	}
+
+	quote := p.balance(asset.Spot, currency.USDT).Total
+	if expected := 1000 - 203*1.002; math.Abs(quote-expected) > 1e-9 {
+		t.Errorf("expected: %v, actual: %v", expected, quote)
+	}
+	if base := p.balance(asset.Spot, currency.BTC).Total; base != 3 {
+		t.Errorf("expected: %v, actual: %v", 3, base)
+	}
+}
+
+func TestRestingOrder(t *testing.T) {
+	p := testPaper()
+
+	d, err := p.place(&order.Submit{Exchange: "test", Type: order.Limit, Side: order.Buy, Pair: testPair, AssetType: asset.Spot, Amount: 2, Price: 100}, testBook())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Status != order.New {
+		t.Errorf("expected: %v, actual: %v", order.New, d.Status)
+	}
+	if hold := p.balance(asset.Spot, currency.USDT).Hold; math.Abs(hold-200.2) > 1e-9 {
+		t.Errorf("expected: %v, actual: %v", 200.2, hold)
+	}
+
+	// the ask drops to the limit price, half of the order is filled
+	b := testBook()
+	b.Asks = orderbook.Items{{Price: 100, Amount: 1}}
+	p.match(b)
+
+	if d.Status != order.PartiallyFilled || d.RemainingAmount != 1 {
+		t.Errorf("expected: %v, actual: %v %v", "partially filled, 1 remaining", d.Status, d.RemainingAmount)
+	}
+	if quote := p.balance(asset.Spot, currency.USDT).Total; math.Abs(quote-(1000-100.1)) > 1e-9 {
+		t.Errorf("expected: %v, actual: %v", 1000-100.1, quote)
+	}
+
+	if err = p.cancel(d.OrderID); err != nil {
+		t.Fatal(err)
+	}
+	if d.Status != order.PartiallyCancelled {
+		t.Errorf("expected: %v, actual: %v", order.PartiallyCancelled, d.Status)
+	}
+	if hold := p.balance(asset.Spot, currency.USDT).Hold; math.Abs(hold) > 1e-9 {
+		t.Errorf("expected: %v, actual: %v", 0, hold)
+	}
+}
+
+func TestRejectedOrders(t *testing.T) {
+	p := testPaper()
+
+	_, err := p.place(&order.Submit{Exchange: "test", Type: order.Limit, Side: order.Buy, Pair: testPair, AssetType: asset.Spot, Amount: 20, Price: 100}, testBook())
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("expected: %v, actual: %v", ErrInsufficientBalance, err)
+	}
+
+	_, err = p.place(&order.Submit{Exchange: "test", Type: order.Limit, Side: order.Sell, Pair: testPair, AssetType: asset.Spot, Amount: 0.5, Price: 98, PostOnly: true}, testBook())
+	if !errors.Is(err, ErrPostOnlyCrossed) {
+		t.Errorf("expected: %v, actual: %v", ErrPostOnlyCrossed, err)
+	}
+
+	d, err := p.place(&order.Submit{Exchange: "test", Type: order.Limit, Side: order.Sell, Pair: testPair, AssetType: asset.Spot, Amount: 1, Price: 98.5, FillOrKill: true}, testBook())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Status != order.Filled {
+		t.Errorf("expected: %v, actual: %v", order.Filled, d.Status)
+	}
+
+	if len(p.orders) != 1 {
+		t.Errorf("expected: %v, actual: %v", 1, len(p.orders))
+	}
+}
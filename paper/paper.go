@@ -0,0 +1,622 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/config"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/engine"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/deposit"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/protocol"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/portfolio/withdraw"
+)
+
+const (
+	defaultMatchInterval  = time.Second
+	defaultTrafficTimeout = 30 * time.Second
+
+	// paperAccount is the ID of the single virtual sub account
+	paperAccount = "paper"
+)
+
+var (
+	ErrNotSupported = errors.New("not supported while paper trading")
+	ErrNoBook       = errors.New("no order book available for pair")
+)
+
+// Config configures a paper trading exchange
+type Config struct {
+	// Balances are the virtual balances the account starts with
+	Balances map[currency.Code]float64
+	MakerFee float64
+	TakerFee float64
+	// Latency delays every order action, like the round trip to a real exchange would
+	Latency time.Duration
+	// MatchInterval is how often resting orders are matched against the live order book
+	MatchInterval time.Duration
+	// Replay disconnects the exchange from its live feed, market data then only arrives through Feed
+	Replay bool
+	// Now stamps orders and trades, the wall clock is used when it is not set
+	Now func() time.Time
+}
+
+// Factory creates paper trading exchanges for the configured names and real exchanges for all others.
+// It is meant to be passed to dealer.Builder.CustomExchange.
+type Factory struct {
+	Exchanges map[string]Config
+
+	mu      sync.Mutex
+	created map[string]*Exchange
+}
+
+// NewFactory returns a factory that paper trades the given exchanges
+func NewFactory(exchanges map[string]Config) *Factory {
+	c := make(map[string]Config, len(exchanges))
+	for name, config := range exchanges {
+		c[strings.ToLower(name)] = config
+	}
+	return &Factory{Exchanges: c, created: make(map[string]*Exchange)}
+}
+
+var _ dealer.ExchangeFactory = (*Factory)(nil)
+
+// NewExchangeByName returns a paper trading exchange on top of the real exchange when the name is configured
+func (f *Factory) NewExchangeByName(name string) (exchange.IBotExchange, error) {
+	e, err := engine.NewSupportedExchangeByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := f.Exchanges[strings.ToLower(name)]
+	if !ok {
+		return e, nil
+	}
+
+	p := New(e, c)
+
+	f.mu.Lock()
+	f.created[strings.ToLower(name)] = p
+	f.mu.Unlock()
+	return p, nil
+}
+
+// Exchange returns the paper trading exchange created for the name, so market data can be fed into it
+func (f *Factory) Exchange(name string) (*Exchange, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.created[strings.ToLower(name)]
+	return p, ok
+}
+
+// Exchange is a simulated exchange. Pairs, execution limits and market data come from the wrapped exchange, or from Feed
+// when replaying, while orders are matched against the order book and settled on virtual balances. Order updates are emitted on the
+// websocket data channel, so strategies receive them through OnOrder like they would from a real exchange.
+type Exchange struct {
+	exchange.IBotExchange
+	config Config
+
+	mu       sync.Mutex
+	balances map[asset.Item]map[currency.Code]*balance
+	orders   map[string]*order.Detail
+	books    map[string]*orderbook.Base
+	tickers  map[string]*ticker.Price
+
+	// websocket replaces the live websocket of the wrapped exchange when replaying
+	websocket *stream.Websocket
+	updates   chan interface{}
+	// pending are the updates emitted while the lock was held, sending keeps them in order while they are sent
+	pending []interface{}
+	sending sync.Mutex
+}
+
+// New wraps the exchange in a paper trading exchange
+func New(e exchange.IBotExchange, c Config) *Exchange {
+	if c.MatchInterval == 0 {
+		c.MatchInterval = defaultMatchInterval
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	p := &Exchange{
+		IBotExchange: e,
+		config:       c,
+		balances:     make(map[asset.Item]map[currency.Code]*balance),
+		orders:       make(map[string]*order.Detail),
+		books:        make(map[string]*orderbook.Base),
+		tickers:      make(map[string]*ticker.Price),
+		updates:      make(chan interface{}, 1024),
+	}
+
+	for code, amount := range c.Balances {
+		p.balance(asset.Spot, code).Total = amount
+	}
+	return p
+}
+
+func bookKey(a asset.Item, p currency.Pair) string {
+	return a.String() + "/" + p.Upper().String()
+}
+
+// Setup sets up the wrapped exchange, when replaying the live websocket is replaced by one that only carries fed data
+func (p *Exchange) Setup(exch *config.Exchange) error {
+	if err := p.IBotExchange.Setup(exch); err != nil {
+		return err
+	}
+
+	if !p.config.Replay {
+		return nil
+	}
+
+	if exch.Features == nil {
+		exch.Features = &config.FeaturesConfig{}
+	}
+	exch.Features.Enabled.Websocket = true
+	if exch.WebsocketTrafficTimeout < time.Second {
+		exch.WebsocketTrafficTimeout = defaultTrafficTimeout
+	}
+
+	p.websocket = stream.New()
+	return p.websocket.Setup(&stream.WebsocketSetup{
+		ExchangeConfig:        exch,
+		DefaultURL:            "paper://" + exch.Name,
+		RunningURL:            "paper://" + exch.Name,
+		Connector:             func() error { return nil },
+		Subscriber:            func([]stream.ChannelSubscription) error { return nil },
+		GenerateSubscriptions: func() ([]stream.ChannelSubscription, error) { return nil, nil },
+		Features:              &protocol.Features{},
+	})
+}
+
+// Start starts matching resting orders and forwarding order updates. The wrapped exchange is only started when trading live data.
+func (p *Exchange) Start(ctx context.Context, wg *sync.WaitGroup) error {
+	go p.forward()
+
+	if p.config.Replay {
+		return nil
+	}
+
+	go func() {
+		t := time.NewTicker(p.config.MatchInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				p.matchLive(ctx)
+			}
+		}
+	}()
+	return p.IBotExchange.Start(ctx, wg)
+}
+
+// GetWebsocket returns the websocket order updates are emitted on
+func (p *Exchange) GetWebsocket() (*stream.Websocket, error) {
+	if p.websocket != nil {
+		return p.websocket, nil
+	}
+	return p.IBotExchange.GetWebsocket()
+}
+
+// IsWebsocketEnabled reports whether updates can be streamed, a replaying exchange always streams
+func (p *Exchange) IsWebsocketEnabled() bool {
+	if p.websocket != nil {
+		return true
+	}
+	return p.IBotExchange.IsWebsocketEnabled()
+}
+
+// SupportsWebsocket reports whether the exchange supports streaming, a replaying exchange always does
+func (p *Exchange) SupportsWebsocket() bool {
+	if p.websocket != nil {
+		return true
+	}
+	return p.IBotExchange.SupportsWebsocket()
+}
+
+// forward emits the queued updates in order on the websocket data channel
+func (p *Exchange) forward() {
+	for x := range p.updates {
+		ws, err := p.GetWebsocket()
+		if err != nil || ws == nil || ws.DataHandler == nil {
+			logrus.Errorf("paper %s: no websocket to emit %T on\n", p.GetName(), x)
+			continue
+		}
+		ws.DataHandler <- x
+	}
+}
+
+// emit queues a copy of the order to be sent as an update once the lock is released. The caller must hold the lock.
+func (p *Exchange) emit(d *order.Detail) {
+	p.pending = append(p.pending, d.CopyToPointer())
+}
+
+// unlock releases the lock and sends the updates emitted so far. Sending under the lock would block every caller, readers
+// included, while the update queue is full. The updates are taken in the order they were emitted, whoever sends first
+// sends those of the others as well.
+func (p *Exchange) unlock() {
+	p.mu.Unlock()
+
+	p.sending.Lock()
+	defer p.sending.Unlock()
+
+	p.mu.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+
+	for _, x := range pending {
+		p.updates <- x
+	}
+}
+
+// Feed publishes replayed market data. Order books and tickers are used to match resting orders and every message is passed
+// on to the strategies as if it arrived from the exchange.
+func (p *Exchange) Feed(x interface{}) {
+	switch x := x.(type) {
+	case *orderbook.Base:
+		p.Match(x)
+	case *ticker.Price:
+		p.mu.Lock()
+		p.tickers[bookKey(x.AssetType, x.Pair)] = x
+		p.mu.Unlock()
+	}
+
+	p.updates <- x
+}
+
+// Match makes the order book the current book of its pair and fills the resting orders it crosses, without passing the book on
+// to the strategies. It allows a fill model to price orders from data that carries no order book.
+func (p *Exchange) Match(b *orderbook.Base) {
+	p.mu.Lock()
+	defer p.unlock()
+
+	p.books[bookKey(b.Asset, b.Pair)] = b
+	p.match(b)
+}
+
+// Drain returns the queued updates without emitting them. It is used when the updates are dispatched by a replay
+// instead of the websocket, in which case Start must not be called.
+func (p *Exchange) Drain() []interface{} {
+	var xs []interface{}
+	for {
+		select {
+		case x := <-p.updates:
+			xs = append(xs, x)
+		default:
+			return xs
+		}
+	}
+}
+
+// FetchTicker returns the last fed ticker when replaying
+func (p *Exchange) FetchTicker(ctx context.Context, pair currency.Pair, a asset.Item) (*ticker.Price, error) {
+	if !p.config.Replay {
+		return p.IBotExchange.FetchTicker(ctx, pair, a)
+	}
+	return p.UpdateTicker(ctx, pair, a)
+}
+
+// UpdateTicker returns the last fed ticker when replaying, the top of the book is used when no ticker was fed
+func (p *Exchange) UpdateTicker(ctx context.Context, pair currency.Pair, a asset.Item) (*ticker.Price, error) {
+	if !p.config.Replay {
+		return p.IBotExchange.UpdateTicker(ctx, pair, a)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if t, ok := p.tickers[bookKey(a, pair)]; ok {
+		return t, nil
+	}
+	if b, ok := p.books[bookKey(a, pair)]; ok && len(b.Bids) > 0 && len(b.Asks) > 0 {
+		return &ticker.Price{
+			ExchangeName: p.GetName(),
+			Pair:         pair,
+			AssetType:    a,
+			Bid:          b.Bids[0].Price,
+			Ask:          b.Asks[0].Price,
+			Last:         (b.Bids[0].Price + b.Asks[0].Price) / 2,
+			LastUpdated:  b.LastUpdated,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w %s", ErrNoBook, pair)
+}
+
+// FetchOrderbook returns the last fed order book when replaying
+func (p *Exchange) FetchOrderbook(ctx context.Context, pair currency.Pair, a asset.Item) (*orderbook.Base, error) {
+	return p.UpdateOrderbook(ctx, pair, a)
+}
+
+// UpdateOrderbook returns the last fed order book when replaying
+func (p *Exchange) UpdateOrderbook(ctx context.Context, pair currency.Pair, a asset.Item) (*orderbook.Base, error) {
+	if !p.config.Replay {
+		return p.IBotExchange.FetchOrderbook(ctx, pair, a)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if b, ok := p.books[bookKey(a, pair)]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w %s", ErrNoBook, pair)
+}
+
+// wait simulates the latency of the exchange
+func (p *Exchange) wait(ctx context.Context) error {
+	if p.config.Latency <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(p.config.Latency)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// ValidateAPICredentials always succeeds, no credentials are needed to paper trade
+func (p *Exchange) ValidateAPICredentials(ctx context.Context, a asset.Item) error {
+	return nil
+}
+
+// UpdateAccountInfo returns the virtual balances
+func (p *Exchange) UpdateAccountInfo(ctx context.Context, a asset.Item) (account.Holdings, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sub := account.SubAccount{ID: paperAccount, AssetType: a}
+	for code, b := range p.balances[a] {
+		sub.Currencies = append(sub.Currencies, account.Balance{
+			Currency:               code,
+			Total:                  b.Total,
+			Hold:                   b.Hold,
+			Free:                   b.Total - b.Hold,
+			AvailableWithoutBorrow: b.Total - b.Hold,
+		})
+	}
+
+	return account.Holdings{Exchange: p.GetName(), Accounts: []account.SubAccount{sub}}, nil
+}
+
+// FetchAccountInfo returns the virtual balances
+func (p *Exchange) FetchAccountInfo(ctx context.Context, a asset.Item) (account.Holdings, error) {
+	return p.UpdateAccountInfo(ctx, a)
+}
+
+// SubmitOrder matches the order against the order book, what can't be matched rests until the book crosses its price
+func (p *Exchange) SubmitOrder(ctx context.Context, s *order.Submit) (*order.SubmitResponse, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	b, err := p.UpdateOrderbook(ctx, s.Pair, s.AssetType)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.unlock()
+
+	d, err := p.place(s, b)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.DeriveSubmitResponse(d.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	response.Status = d.Status
+	response.Date = d.Date
+	response.LastUpdated = d.LastUpdated
+	response.Fee = d.Fee
+	response.FeeAsset = d.FeeAsset
+	response.Cost = d.Cost
+	if d.ExecutedAmount > 0 {
+		response.Price = d.AverageExecutedPrice
+	}
+	return response, nil
+}
+
+// ModifyOrder changes the price and amount of a resting order, it is matched again against the current book
+func (p *Exchange) ModifyOrder(ctx context.Context, action *order.Modify) (*order.ModifyResponse, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	b, err := p.UpdateOrderbook(ctx, action.Pair, action.AssetType)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.unlock()
+
+	d, err := p.modify(action, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order.ModifyResponse{
+		Exchange:        d.Exchange,
+		OrderID:         d.OrderID,
+		ClientOrderID:   d.ClientOrderID,
+		Pair:            d.Pair,
+		Type:            d.Type,
+		Side:            d.Side,
+		Status:          d.Status,
+		AssetType:       d.AssetType,
+		PostOnly:        d.PostOnly,
+		Price:           d.Price,
+		Amount:          d.Amount,
+		RemainingAmount: d.RemainingAmount,
+		Date:            d.Date,
+		LastUpdated:     d.LastUpdated,
+	}, nil
+}
+
+// CancelOrder cancels a resting order and releases its hold
+func (p *Exchange) CancelOrder(ctx context.Context, o *order.Cancel) error {
+	if err := p.wait(ctx); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.unlock()
+	return p.cancel(o.OrderID)
+}
+
+// CancelBatchOrders cancels the given orders
+func (p *Exchange) CancelBatchOrders(ctx context.Context, o []order.Cancel) (*order.CancelBatchResponse, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.unlock()
+
+	response := &order.CancelBatchResponse{Status: make(map[string]string)}
+	for _, c := range o {
+		if err := p.cancel(c.OrderID); err != nil {
+			response.Status[c.OrderID] = err.Error()
+			continue
+		}
+		response.Status[c.OrderID] = order.Cancelled.String()
+	}
+	return response, nil
+}
+
+// CancelAllOrders cancels all resting orders, of a single pair when it is set
+func (p *Exchange) CancelAllOrders(ctx context.Context, o *order.Cancel) (order.CancelAllResponse, error) {
+	if err := p.wait(ctx); err != nil {
+		return order.CancelAllResponse{}, err
+	}
+
+	p.mu.Lock()
+	defer p.unlock()
+
+	response := order.CancelAllResponse{Status: make(map[string]string)}
+	for id, d := range p.orders {
+		if !d.IsActive() || (!o.Pair.IsEmpty() && !d.Pair.Equal(o.Pair)) {
+			continue
+		}
+		if err := p.cancel(id); err != nil {
+			response.Status[id] = err.Error()
+			continue
+		}
+		response.Status[id] = order.Cancelled.String()
+		response.Count++
+	}
+	return response, nil
+}
+
+// GetOrderInfo returns the order
+func (p *Exchange) GetOrderInfo(ctx context.Context, orderID string, pair currency.Pair, assetType asset.Item) (*order.Detail, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	d, ok := p.orders[orderID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+	}
+	return d.CopyToPointer(), nil
+}
+
+// GetActiveOrders returns the resting orders
+func (p *Exchange) GetActiveOrders(ctx context.Context, r *order.MultiOrderRequest) (order.FilteredOrders, error) {
+	return p.filter(r, true), nil
+}
+
+// GetOrderHistory returns the orders that are no longer active
+func (p *Exchange) GetOrderHistory(ctx context.Context, r *order.MultiOrderRequest) (order.FilteredOrders, error) {
+	return p.filter(r, false), nil
+}
+
+// filter returns the active or inactive orders matching the request
+func (p *Exchange) filter(r *order.MultiOrderRequest, active bool) order.FilteredOrders {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var orders order.FilteredOrders
+	for _, d := range p.orders {
+		if d.IsActive() != active || d.AssetType != r.AssetType {
+			continue
+		}
+		if len(r.Pairs) > 0 && !r.Pairs.Contains(d.Pair, true) {
+			continue
+		}
+		if r.Side != order.UnknownSide && r.Side != order.AnySide && r.Side != d.Side {
+			continue
+		}
+		orders = append(orders, d.Copy())
+	}
+	return orders
+}
+
+// GetDepositAddress is not supported, funds can't be moved into a paper account
+func (p *Exchange) GetDepositAddress(ctx context.Context, code currency.Code, accountID, chain string) (*deposit.Address, error) {
+	return nil, ErrNotSupported
+}
+
+// WithdrawCryptocurrencyFunds is not supported, funds can't be moved out of a paper account
+func (p *Exchange) WithdrawCryptocurrencyFunds(ctx context.Context, r *withdraw.Request) (*withdraw.ExchangeResponse, error) {
+	return nil, ErrNotSupported
+}
+
+// WithdrawFiatFunds is not supported, funds can't be moved out of a paper account
+func (p *Exchange) WithdrawFiatFunds(ctx context.Context, r *withdraw.Request) (*withdraw.ExchangeResponse, error) {
+	return nil, ErrNotSupported
+}
+
+// WithdrawFiatFundsToInternationalBank is not supported, funds can't be moved out of a paper account
+func (p *Exchange) WithdrawFiatFundsToInternationalBank(ctx context.Context, r *withdraw.Request) (*withdraw.ExchangeResponse, error) {
+	return nil, ErrNotSupported
+}
+
+// matchLive matches the resting orders against the live order books of their pairs
+func (p *Exchange) matchLive(ctx context.Context) {
+	p.mu.Lock()
+	pairs := make(map[string]*order.Detail)
+	for _, d := range p.orders {
+		if d.IsActive() {
+			pairs[bookKey(d.AssetType, d.Pair)] = d
+		}
+	}
+	p.mu.Unlock()
+
+	for _, d := range pairs {
+		b, err := p.IBotExchange.FetchOrderbook(ctx, d.Pair, d.AssetType)
+		if err != nil {
+			logrus.Errorf("paper %s: %s order book: %s\n", p.GetName(), d.Pair, err)
+			continue
+		}
+
+		p.mu.Lock()
+		p.match(b)
+		p.unlock()
+	}
+}
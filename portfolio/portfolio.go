@@ -0,0 +1,239 @@
+// Package portfolio merges the balances of every exchange, sub-account and asset type into positions per currency,
+// valued in a single reference currency.
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/pricing"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+)
+
+// ErrNoHoldings is returned when none of the exchanges has reported its balances yet
+var ErrNoHoldings = errors.New("no holdings on any exchange")
+
+// holdings and value are replaced in tests
+var (
+	holdings = dealer.Holdings
+	value    = Price
+)
+
+// Price returns the price of the currency in the quote currency on the market of the exchange, currencies that are
+// worth one unit of the quote are priced at 1 without asking the market
+func Price(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, code, quote currency.Code, a asset.Item) (float64, error) {
+	if pricing.Equivalent(code, quote) {
+		return 1, nil
+	}
+	c, err := pricing.Value(ctx, d, e, code, quote, a)
+	return c.Rate, err
+}
+
+// Holding is the balance of a currency on one sub-account of an exchange
+type Holding struct {
+	Exchange string        `json:"exchange"`
+	Account  string        `json:"account"`
+	Asset    asset.Item    `json:"asset"`
+	Currency currency.Code `json:"currency"`
+	Amount   float64       `json:"amount"`
+	Hold     float64       `json:"hold"`
+	Price    float64       `json:"price"`
+	Value    float64       `json:"value"`
+	Priced   bool          `json:"priced"`
+}
+
+// Position is a currency held over all venues
+type Position struct {
+	Currency   currency.Code `json:"currency"`
+	Amount     float64       `json:"amount"`
+	Value      float64       `json:"value"`
+	Allocation float64       `json:"allocation"`
+	Venues     []Holding     `json:"venues"`
+}
+
+// Venue is the value held on an exchange
+type Venue struct {
+	Exchange   string  `json:"exchange"`
+	Value      float64 `json:"value"`
+	Allocation float64 `json:"allocation"`
+}
+
+// Portfolio is the value of all positions in the quote currency. Allocations are percentages of the total,
+// holdings that can't be valued are listed as unpriced and left out of the totals.
+type Portfolio struct {
+	Quote     currency.Code `json:"quote"`
+	Total     float64       `json:"total"`
+	Positions []Position    `json:"positions"`
+	Venues    []Venue       `json:"venues"`
+	Unpriced  []Holding     `json:"unpriced"`
+	Time      time.Time     `json:"time"`
+}
+
+// Aggregate merges the holdings of all exchanges of the dealer and values them in the quote currency
+func Aggregate(ctx context.Context, d *dealer.Dealer, quote currency.Code) (*Portfolio, error) {
+	quote = quote.Upper()
+	p := &Portfolio{
+		Quote:     quote,
+		Positions: []Position{},
+		Venues:    []Venue{},
+		Unpriced:  []Holding{},
+		Time:      time.Now(),
+	}
+
+	exchanges := d.GetExchanges()
+	v := valuer{ctx: ctx, d: d, quote: quote, exchanges: exchanges, prices: make(map[string]float64)}
+	positions := make(map[currency.Code]*Position)
+	venues := make(map[string]*Venue)
+	found := false
+
+	for _, e := range exchanges {
+		h, err := holdings(d, e.GetName())
+		if err != nil {
+			logrus.Debugf("portfolio: no holdings for %s: %s\n", e.GetName(), err)
+			continue
+		}
+		found = true
+
+		for account, sub := range h.Accounts {
+			for a, balances := range sub.Balances {
+				for code, balance := range balances {
+					if balance.TotalValue == 0 {
+						continue
+					}
+
+					holding := Holding{
+						Exchange: e.GetName(),
+						Account:  account,
+						Asset:    a,
+						Currency: code.Upper(),
+						Amount:   balance.TotalValue,
+						Hold:     balance.Hold,
+					}
+
+					price, ok := v.price(e, code, a)
+					if !ok {
+						p.Unpriced = append(p.Unpriced, holding)
+						continue
+					}
+					holding.Price = price
+					holding.Value = balance.TotalValue * price
+					holding.Priced = true
+
+					position, ok := positions[holding.Currency]
+					if !ok {
+						position = &Position{Currency: holding.Currency}
+						positions[holding.Currency] = position
+					}
+					position.Amount += holding.Amount
+					position.Value += holding.Value
+					position.Venues = append(position.Venues, holding)
+
+					venue, ok := venues[holding.Exchange]
+					if !ok {
+						venue = &Venue{Exchange: holding.Exchange}
+						venues[holding.Exchange] = venue
+					}
+					venue.Value += holding.Value
+
+					p.Total += holding.Value
+				}
+			}
+		}
+	}
+
+	if !found && len(exchanges) > 0 {
+		return nil, ErrNoHoldings
+	}
+
+	for _, position := range positions {
+		position.Allocation = allocation(position.Value, p.Total)
+		sort.Slice(position.Venues, func(i, j int) bool {
+			return position.Venues[i].Value > position.Venues[j].Value
+		})
+		p.Positions = append(p.Positions, *position)
+	}
+	sort.Slice(p.Positions, func(i, j int) bool {
+		if p.Positions[i].Value == p.Positions[j].Value {
+			return p.Positions[i].Currency.String() < p.Positions[j].Currency.String()
+		}
+		return p.Positions[i].Value > p.Positions[j].Value
+	})
+
+	for _, venue := range venues {
+		venue.Allocation = allocation(venue.Value, p.Total)
+		p.Venues = append(p.Venues, *venue)
+	}
+	sort.Slice(p.Venues, func(i, j int) bool {
+		if p.Venues[i].Value == p.Venues[j].Value {
+			return p.Venues[i].Exchange < p.Venues[j].Exchange
+		}
+		return p.Venues[i].Value > p.Venues[j].Value
+	})
+
+	return p, nil
+}
+
+// allocation returns the share of the total as a percentage
+func allocation(value, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return value / total * 100
+}
+
+// valuer prices currencies in the quote currency and remembers the prices during one aggregation
+type valuer struct {
+	ctx       context.Context
+	d         *dealer.Dealer
+	quote     currency.Code
+	exchanges []exchange.IBotExchange
+	prices    map[string]float64
+}
+
+// price returns the price of the currency on the exchange and asset it is held on. When that market can't value it,
+// the spot markets of the exchange and then those of the other exchanges are tried.
+func (v *valuer) price(e exchange.IBotExchange, code currency.Code, a asset.Item) (float64, bool) {
+	if pricing.Equivalent(code, v.quote) {
+		return 1, true
+	}
+
+	if price, ok := v.lookup(e, code, a); ok {
+		return price, true
+	}
+	if a != asset.Spot {
+		if price, ok := v.lookup(e, code, asset.Spot); ok {
+			return price, true
+		}
+	}
+	for _, other := range v.exchanges {
+		if other.GetName() == e.GetName() {
+			continue
+		}
+		if price, ok := v.lookup(other, code, asset.Spot); ok {
+			return price, true
+		}
+	}
+	return 0, false
+}
+
+// lookup values the currency on one market, failures are remembered as well so the market is asked only once
+func (v *valuer) lookup(e exchange.IBotExchange, code currency.Code, a asset.Item) (float64, bool) {
+	key := e.GetName() + "|" + a.String() + "|" + code.Upper().String()
+	if price, ok := v.prices[key]; ok {
+		return price, price > 0
+	}
+
+	price, err := value(v.ctx, v.d, e, code, v.quote, a)
+	if err != nil {
+		logrus.Debugf("portfolio: unable to value %s on %s %s: %s\n", code, e.GetName(), a, err)
+		price = 0
+	}
+	v.prices[key] = price
+	return price, price > 0
+}
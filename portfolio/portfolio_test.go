@@ -0,0 +1,127 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+)
+
+type namedExchange struct {
+	exchange.IBotExchange
+
+	name string
+}
+
+func (e *namedExchange) GetName() string {
+	return e.name
+}
+
+func (e *namedExchange) GetBase() *exchange.Base {
+	return &exchange.Base{Name: e.name}
+}
+
+func balances(a asset.Item, amounts map[currency.Code]float64) map[asset.Item]map[currency.Code]dealer.CurrencyBalance {
+	b := make(map[currency.Code]dealer.CurrencyBalance)
+	for code, amount := range amounts {
+		b[code] = dealer.CurrencyBalance{Currency: code, TotalValue: amount}
+	}
+	return map[asset.Item]map[currency.Code]dealer.CurrencyBalance{a: b}
+}
+
+func TestAggregate(t *testing.T) {
+	binance := &namedExchange{name: "Binance"}
+	kraken := &namedExchange{name: "Kraken"}
+	d, err := dealer.NewBuilder().Balances(0).BuildWithExchanges(binance, kraken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(h func(*dealer.Dealer, string) (*dealer.ExchangeHoldings, error)) { holdings = h }(holdings)
+	holdings = func(d *dealer.Dealer, name string) (*dealer.ExchangeHoldings, error) {
+		switch name {
+		case "Binance":
+			return &dealer.ExchangeHoldings{Accounts: map[string]dealer.SubAccount{
+				"": {Balances: balances(asset.Spot, map[currency.Code]float64{currency.BTC: 0.5, currency.USDT: 1000})},
+				"futures": {ID: "futures", Balances: balances(asset.USDTMarginedFutures,
+					map[currency.Code]float64{currency.USDT: 2000, currency.DOGE: 100})},
+			}}, nil
+		default:
+			return &dealer.ExchangeHoldings{Accounts: map[string]dealer.SubAccount{
+				"": {Balances: balances(asset.Spot, map[currency.Code]float64{currency.BTC: 0.5, currency.XRP: 10})},
+			}}, nil
+		}
+	}
+
+	// BTC can only be valued on the spot market of Binance, DOGE and XRP have no market at all
+	defer func(v func(context.Context, *dealer.Dealer, exchange.IBotExchange, currency.Code, currency.Code, asset.Item) (float64, error)) {
+		value = v
+	}(value)
+	asked := 0
+	value = func(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, code, quote currency.Code, a asset.Item) (float64, error) {
+		asked++
+		if e.GetName() == "Binance" && a == asset.Spot && code.Equal(currency.BTC) {
+			return 20000, nil
+		}
+		return 0, errors.New("no market")
+	}
+
+	p, err := Aggregate(context.Background(), d, currency.USD)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Total != 23000 {
+		t.Errorf("expected: %v, actual: %v", 23000.0, p.Total)
+	}
+	if len(p.Positions) != 2 {
+		t.Fatalf("expected: %v, actual: %+v", 2, p.Positions)
+	}
+
+	btc := p.Positions[0]
+	if !btc.Currency.Equal(currency.BTC) || btc.Amount != 1 || btc.Value != 20000 || len(btc.Venues) != 2 {
+		t.Errorf("expected: %v, actual: %+v", "1 BTC worth 20000 on two venues", btc)
+	}
+	if math.Abs(btc.Allocation-20000.0/23000*100) > 1e-9 {
+		t.Errorf("expected: %v, actual: %v", 20000.0/23000*100, btc.Allocation)
+	}
+
+	usdt := p.Positions[1]
+	if !usdt.Currency.Equal(currency.USDT) || usdt.Amount != 3000 || usdt.Value != 3000 {
+		t.Errorf("expected: %v, actual: %+v", "3000 USDT", usdt)
+	}
+
+	if len(p.Venues) != 2 || p.Venues[0].Exchange != "Binance" || p.Venues[0].Value != 13000 || p.Venues[1].Value != 10000 {
+		t.Errorf("expected: %v, actual: %+v", "Binance 13000, Kraken 10000", p.Venues)
+	}
+
+	if len(p.Unpriced) != 2 {
+		t.Errorf("expected: %v, actual: %+v", 2, p.Unpriced)
+	}
+
+	// BTC on Kraken falls back to Binance, every market is asked once
+	if asked != 7 {
+		t.Errorf("expected: %v, actual: %v", 7, asked)
+	}
+}
+
+func TestAggregateNoHoldings(t *testing.T) {
+	d, err := dealer.NewBuilder().Balances(0).BuildWithExchanges(&namedExchange{name: "Binance"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(h func(*dealer.Dealer, string) (*dealer.ExchangeHoldings, error)) { holdings = h }(holdings)
+	holdings = func(d *dealer.Dealer, name string) (*dealer.ExchangeHoldings, error) {
+		return nil, dealer.ErrHoldingsNotFound
+	}
+
+	if _, err = Aggregate(context.Background(), d, currency.EUR); !errors.Is(err, ErrNoHoldings) {
+		t.Errorf("expected: %v, actual: %v", ErrNoHoldings, err)
+	}
+}
@@ -0,0 +1,113 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/RyanCarrier/dijkstra"
+	"github.com/romanornr/autodealer/util"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("currency is not traded on the exchange")
+	ErrNoPathFound     = errors.New("no conversion path found")
+)
+
+// Step is a single conversion of a path. Converting the base of the pair into its quote sells at the bid,
+// converting the quote into the base buys at the ask.
+type Step struct {
+	Pair currency.Pair `json:"pair"`
+	Side order.Side    `json:"side"`
+	From currency.Code `json:"from"`
+	To   currency.Code `json:"to"`
+	// Rate is the amount of To received for one From
+	Rate float64 `json:"rate"`
+}
+
+// MarshalJSON encodes the side as a string
+func (s Step) MarshalJSON() ([]byte, error) {
+	type alias Step
+	return util.MarshalSide(alias(s), "side", s.Side)
+}
+
+// Graph is the currency graph of an exchange. Every currency code is a single vertex and every pair connects its base and quote in both
+// directions, so a path can be found whichever way round the exchange lists a pair.
+type Graph struct {
+	graph *dijkstra.Graph
+	pairs map[[2]int]currency.Pair
+}
+
+// NewGraph builds the currency graph of the given pairs
+func NewGraph(pairs currency.Pairs) *Graph {
+	g := &Graph{
+		graph: dijkstra.NewGraph(),
+		pairs: make(map[[2]int]currency.Pair),
+	}
+
+	for _, p := range pairs {
+		if p.Base.IsEmpty() || p.Quote.IsEmpty() || p.Base.Equal(p.Quote) {
+			continue
+		}
+
+		base := g.graph.AddMappedVertex(p.Base.Upper().String())
+		quote := g.graph.AddMappedVertex(p.Quote.Upper().String())
+
+		// the first listing of a pair wins, exchanges rarely list both orientations
+		if _, ok := g.pairs[[2]int{base, quote}]; ok {
+			continue
+		}
+		g.pairs[[2]int{base, quote}] = p
+		g.pairs[[2]int{quote, base}] = p
+
+		// every conversion costs the same, so the path with the least conversions is preferred
+		_ = g.graph.AddArc(base, quote, 1)
+		_ = g.graph.AddArc(quote, base, 1)
+	}
+	return g
+}
+
+// Has reports whether the currency is part of the graph
+func (g *Graph) Has(code currency.Code) bool {
+	_, err := g.graph.GetMapping(code.Upper().String())
+	return err == nil
+}
+
+// Path returns the conversions from one currency into another, without rates
+func (g *Graph) Path(from, to currency.Code) ([]Step, error) {
+	src, err := g.graph.GetMapping(from.Upper().String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCurrency, from)
+	}
+	dest, err := g.graph.GetMapping(to.Upper().String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCurrency, to)
+	}
+	if src == dest {
+		return []Step{}, nil
+	}
+
+	best, err := g.graph.Shortest(src, dest)
+	if err != nil {
+		return nil, fmt.Errorf("%w from %s to %s", ErrNoPathFound, from, to)
+	}
+
+	steps := make([]Step, 0, len(best.Path)-1)
+	for i := 1; i < len(best.Path); i++ {
+		pair := g.pairs[[2]int{best.Path[i-1], best.Path[i]}]
+
+		step := Step{Pair: pair, Side: order.Sell, From: pair.Base, To: pair.Quote}
+		if pair.Quote.Equal(mustCode(g.graph, best.Path[i-1])) {
+			step = Step{Pair: pair, Side: order.Buy, From: pair.Quote, To: pair.Base}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// mustCode returns the currency code of a vertex of the graph
+func mustCode(g *dijkstra.Graph, id int) currency.Code {
+	code, _ := g.GetMapped(id)
+	return currency.NewCode(code)
+}
@@ -0,0 +1,280 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+const (
+	strategyName = "pricing"
+
+	// defaultMaxAge is the age after which a streamed ticker is fetched again
+	defaultMaxAge = 30 * time.Second
+)
+
+var ErrNoPrice = errors.New("no price available for pair")
+
+// equivalents are currencies that are valued one to one with the target when the exchange does not list the target itself,
+// most exchanges only quote in stablecoins
+var equivalents = map[currency.Code][]currency.Code{
+	currency.USD: {currency.USDT, currency.USDC, currency.BUSD},
+	currency.EUR: {currency.EURT},
+}
+
+// Conversion is the value of one unit of a currency in the target currency
+type Conversion struct {
+	Exchange string        `json:"exchange"`
+	Asset    asset.Item    `json:"asset"`
+	From     currency.Code `json:"from"`
+	To       currency.Code `json:"to"`
+	Rate     float64       `json:"rate"`
+	// Path are the conversions the rate is derived from, it is empty when the currencies are equivalent
+	Path []Step `json:"path"`
+	// Time is the time of the oldest ticker used in the path
+	Time time.Time `json:"time"`
+}
+
+// Service converts currencies on an exchange into each other. A currency graph is built from all enabled pairs of the exchange
+// and the path with the least conversions is priced from the tickers. Tickers that arrive over the stream are cached,
+// tickers that are missing or older than MaxAge are fetched from the exchange.
+type Service struct {
+	MaxAge time.Duration
+
+	mu      sync.Mutex
+	tickers map[string]ticker.Price
+}
+
+// NewService returns a pricing service with an empty ticker cache
+func NewService() *Service {
+	return &Service{
+		MaxAge:  defaultMaxAge,
+		tickers: make(map[string]ticker.Price),
+	}
+}
+
+var (
+	service     *Service
+	serviceOnce sync.Once
+)
+
+// Default returns the pricing service shared by the application
+func Default() *Service {
+	serviceOnce.Do(func() {
+		service = NewService()
+	})
+	return service
+}
+
+// Watch adds the shared pricing service to the dealer so its tickers are cached from the stream, adding it more than once has no effect
+func Watch(d *dealer.Dealer) {
+	d.Root.Add(strategyName, Default())
+}
+
+// Value returns the value of the currency in the target currency on the exchange using the shared pricing service
+func Value(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, code, target currency.Code, a asset.Item) (Conversion, error) {
+	Watch(d)
+	return Default().Value(ctx, e, code, target, a)
+}
+
+func tickerKey(exchangeName string, a asset.Item, p currency.Pair) string {
+	return exchangeName + "/" + a.String() + "/" + p.Upper().String()
+}
+
+// Store caches the ticker
+func (s *Service) Store(exchangeName string, t ticker.Price) {
+	if t.LastUpdated.IsZero() {
+		t.LastUpdated = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tickers[tickerKey(exchangeName, t.AssetType, t.Pair)] = t
+}
+
+// Ticker returns the cached ticker of the pair when it is recent enough, otherwise it is fetched from the exchange
+func (s *Service) Ticker(ctx context.Context, e exchange.IBotExchange, p currency.Pair, a asset.Item) (ticker.Price, error) {
+	s.mu.Lock()
+	t, ok := s.tickers[tickerKey(e.GetName(), a, p)]
+	s.mu.Unlock()
+
+	if ok && time.Since(t.LastUpdated) <= s.MaxAge {
+		return t, nil
+	}
+
+	fetched, err := e.FetchTicker(ctx, p, a)
+	if err != nil {
+		return ticker.Price{}, err
+	}
+	if time.Since(fetched.LastUpdated) > s.MaxAge {
+		if fetched, err = e.UpdateTicker(ctx, p, a); err != nil {
+			return ticker.Price{}, err
+		}
+	}
+
+	s.Store(e.GetName(), *fetched)
+	return *fetched, nil
+}
+
+// Value returns the value of one unit of the currency in the target currency on the exchange
+func (s *Service) Value(ctx context.Context, e exchange.IBotExchange, code, target currency.Code, a asset.Item) (Conversion, error) {
+	c := Conversion{
+		Exchange: e.GetName(),
+		Asset:    a,
+		From:     code.Upper(),
+		To:       target.Upper(),
+		Rate:     1,
+		Path:     []Step{},
+		Time:     time.Now(),
+	}
+
+	if Equivalent(code, target) {
+		return c, nil
+	}
+
+	pairs, err := e.GetEnabledPairs(a)
+	if err != nil {
+		return c, err
+	}
+
+	path, err := Resolve(NewGraph(pairs), code, target)
+	if err != nil {
+		return c, err
+	}
+
+	for i, step := range path {
+		t, err := s.Ticker(ctx, e, step.Pair, a)
+		if err != nil {
+			return c, err
+		}
+
+		rate, err := Rate(step, t)
+		if err != nil {
+			return c, err
+		}
+
+		path[i].Rate = rate
+		c.Rate *= rate
+		if t.LastUpdated.Before(c.Time) {
+			c.Time = t.LastUpdated
+		}
+	}
+
+	c.Path = path
+	return c, nil
+}
+
+// Resolve returns the path from the currency to the target. When the target is not traded on the exchange,
+// or can't be reached, the path ends in the first equivalent of the target that can be reached.
+func Resolve(g *Graph, code, target currency.Code) ([]Step, error) {
+	path, err := g.Path(code, target)
+	if err == nil {
+		return path, nil
+	}
+
+	for _, equivalent := range equivalents[target.Upper()] {
+		if p, e := g.Path(code, equivalent); e == nil {
+			return p, nil
+		}
+	}
+	return nil, err
+}
+
+// Equivalent reports whether the currency is worth exactly one unit of the target
+func Equivalent(code, target currency.Code) bool {
+	if code.Equal(target) {
+		return true
+	}
+	for _, equivalent := range equivalents[target.Upper()] {
+		if code.Equal(equivalent) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rate returns the rate of the step from the ticker. Selling the base receives the bid, buying the base pays the ask.
+// The last traded price is used when the ticker has no top of book.
+func Rate(step Step, t ticker.Price) (float64, error) {
+	price := t.Last
+	switch {
+	case step.Side == order.Sell && t.Bid > 0:
+		price = t.Bid
+	case step.Side == order.Buy && t.Ask > 0:
+		price = t.Ask
+	}
+
+	if price <= 0 {
+		return 0, fmt.Errorf("%w %s", ErrNoPrice, step.Pair)
+	}
+
+	if step.Side == order.Buy {
+		return 1 / price, nil
+	}
+	return price, nil
+}
+
+// Init does nothing, the tickers are cached as they arrive
+func (s *Service) Init(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	return nil
+}
+
+func (s *Service) OnFunding(d *dealer.Dealer, e exchange.IBotExchange, x stream.FundingData) error {
+	return nil
+}
+
+// OnPrice caches the streamed ticker
+func (s *Service) OnPrice(d *dealer.Dealer, e exchange.IBotExchange, x ticker.Price) error {
+	s.Store(e.GetName(), x)
+	return nil
+}
+
+func (s *Service) OnKline(d *dealer.Dealer, e exchange.IBotExchange, x stream.KlineData) error {
+	return nil
+}
+
+func (s *Service) OnOrderBook(d *dealer.Dealer, e exchange.IBotExchange, x orderbook.Base) error {
+	return nil
+}
+
+func (s *Service) OnOrder(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) error {
+	return nil
+}
+
+func (s *Service) OnModify(d *dealer.Dealer, e exchange.IBotExchange, x order.Modify) error {
+	return nil
+}
+
+func (s *Service) OnBalanceChange(d *dealer.Dealer, e exchange.IBotExchange, x account.Change) error {
+	return nil
+}
+
+func (s *Service) OnTrade(d *dealer.Dealer, e exchange.IBotExchange, x []trade.Data) error {
+	return nil
+}
+
+func (s *Service) OnFill(d *dealer.Dealer, e exchange.IBotExchange, x []fill.Data) error {
+	return nil
+}
+
+func (s *Service) OnUnrecognized(d *dealer.Dealer, e exchange.IBotExchange, x interface{}) error {
+	return nil
+}
+
+func (s *Service) Deinit(d *dealer.Dealer, e exchange.IBotExchange) error {
+	return nil
+}
@@ -0,0 +1,101 @@
+package pricing
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+)
+
+func TestGraphPath(t *testing.T) {
+	pairs := currency.Pairs{
+		currency.NewPair(currency.ANC, currency.BTC),
+		currency.NewPair(currency.BTC, currency.USDT),
+		currency.NewPair(currency.ETH, currency.BTC),
+		currency.NewPair(currency.USDT, currency.TRY),
+	}
+	g := NewGraph(pairs)
+
+	path, err := g.Path(currency.ANC, currency.USDT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(path) != 2 {
+		t.Fatalf("expected: %v, actual: %v", 2, len(path))
+	}
+	if !path[0].Pair.Equal(pairs[0]) || path[0].Side != order.Sell || !path[0].To.Equal(currency.BTC) {
+		t.Errorf("expected: %v, actual: %v", "sell ANC-BTC", path[0])
+	}
+	if !path[1].Pair.Equal(pairs[1]) || path[1].Side != order.Sell {
+		t.Errorf("expected: %v, actual: %v", "sell BTC-USDT", path[1])
+	}
+
+	// the pairs are listed the other way round, so the base is bought with the quote
+	path, err = g.Path(currency.TRY, currency.ETH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sides := []order.Side{order.Buy, order.Buy, order.Buy}
+	if len(path) != len(sides) {
+		t.Fatalf("expected: %v, actual: %v", len(sides), len(path))
+	}
+	for i, step := range path {
+		if step.Side != sides[i] {
+			t.Errorf("expected: %v, actual: %v", sides[i], step.Side)
+		}
+	}
+	if !path[2].To.Equal(currency.ETH) {
+		t.Errorf("expected: %v, actual: %v", currency.ETH, path[2].To)
+	}
+
+	if _, err = g.Path(currency.XRP, currency.USDT); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("expected: %v, actual: %v", ErrUnknownCurrency, err)
+	}
+}
+
+func TestRate(t *testing.T) {
+	p := currency.NewPair(currency.BTC, currency.USDT)
+	tick := ticker.Price{Pair: p, Last: 20000, Bid: 19990, Ask: 20010}
+
+	rate, err := Rate(Step{Pair: p, Side: order.Sell}, tick)
+	if err != nil || rate != 19990 {
+		t.Errorf("expected: %v, actual: %v %v", 19990, rate, err)
+	}
+
+	rate, err = Rate(Step{Pair: p, Side: order.Buy}, tick)
+	if err != nil || math.Abs(rate-1.0/20010) > 1e-15 {
+		t.Errorf("expected: %v, actual: %v %v", 1.0/20010, rate, err)
+	}
+
+	// without top of book the last price is used
+	rate, _ = Rate(Step{Pair: p, Side: order.Sell}, ticker.Price{Pair: p, Last: 20000})
+	if rate != 20000 {
+		t.Errorf("expected: %v, actual: %v", 20000, rate)
+	}
+
+	if _, err = Rate(Step{Pair: p, Side: order.Sell}, ticker.Price{Pair: p}); !errors.Is(err, ErrNoPrice) {
+		t.Errorf("expected: %v, actual: %v", ErrNoPrice, err)
+	}
+}
+
+func TestResolveEquivalent(t *testing.T) {
+	g := NewGraph(currency.Pairs{currency.NewPair(currency.ETH, currency.USDT)})
+
+	path, err := Resolve(g, currency.ETH, currency.USD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(path) != 1 || !path[0].To.Equal(currency.USDT) {
+		t.Errorf("expected: %v, actual: %v", "ETH to USDT", path)
+	}
+
+	if !Equivalent(currency.USDC, currency.USD) {
+		t.Errorf("expected: %v, actual: %v", true, false)
+	}
+	if Equivalent(currency.BTC, currency.USD) {
+		t.Errorf("expected: %v, actual: %v", false, true)
+	}
+}
@@ -0,0 +1,232 @@
+package rebalance
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/romanornr/autodealer/util"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// Position is the amount of a currency held on an exchange, valued in the quote currency
+type Position struct {
+	Exchange string        `json:"exchange"`
+	Currency currency.Code `json:"currency"`
+	Amount   float64       `json:"amount"`
+	Price    float64       `json:"price"`
+	Value    float64       `json:"value"`
+}
+
+// Allocation compares the current weight of a currency in the portfolio with its target weight
+type Allocation struct {
+	Currency currency.Code `json:"currency"`
+	Value    float64       `json:"value"`
+	Weight   float64       `json:"weight"`
+	Target   float64       `json:"target"`
+	Drift    float64       `json:"drift"`
+}
+
+// Trade is a single order of the plan, the size is expressed in the quote currency
+type Trade struct {
+	Exchange    string        `json:"exchange"`
+	Pair        currency.Pair `json:"pair"`
+	Side        order.Side    `json:"side"`
+	QuoteAmount float64       `json:"quoteAmount"`
+	Price       float64       `json:"price"`
+	OrderID     string        `json:"orderID,omitempty"`
+	JobID       string        `json:"jobID,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the side as a string
+func (t Trade) MarshalJSON() ([]byte, error) {
+	type alias Trade
+	return util.MarshalSide(alias(t), "side", t.Side)
+}
+
+// Snapshot is the state of the portfolio the plan is made from
+type Snapshot struct {
+	Positions []Position
+	// Prices maps an exchange to the price of every target currency in the quote currency
+	Prices map[string]map[currency.Code]float64
+	// Markets maps an exchange to the pairs that can be traded on it
+	Markets map[string]currency.Pairs
+}
+
+// Plan describes the trades required to bring the portfolio back to the target weights
+type Plan struct {
+	Quote       currency.Code `json:"quote"`
+	TotalValue  float64       `json:"totalValue"`
+	Positions   []Position    `json:"positions"`
+	Allocations []Allocation  `json:"allocations"`
+	Trades      []Trade       `json:"trades"`
+	// Unallocated is the value that could not be bought because there is not enough quote currency on the exchanges that list the pair
+	Unallocated float64   `json:"unallocated"`
+	DryRun      bool      `json:"dryRun"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// NewPlan computes the trades that bring the currencies that drifted outside the tolerance band back to their target weight.
+// Currencies without a target weight are sold, whatever is not allocated to a target stays in the quote currency.
+// Sells are routed to the exchanges that hold the most of the currency, buys to the exchanges with the most quote currency available,
+// including the proceeds of the sells on that exchange. Trades below the minimal trade size are dropped.
+func NewPlan(c Config, s Snapshot) *Plan {
+	p := &Plan{
+		Quote:     c.Quote,
+		Positions: s.Positions,
+		DryRun:    c.DryRun,
+		CreatedAt: time.Now(),
+	}
+
+	// codes are compared in upper case, the same currency may be reported in different cases
+	targets := make(map[currency.Code]float64)
+	for code, weight := range c.Targets {
+		targets[code.Upper()] = weight
+	}
+
+	values := make(map[currency.Code]float64)
+	for _, position := range s.Positions {
+		values[position.Currency.Upper()] += position.Value
+		p.TotalValue += position.Value
+	}
+	if p.TotalValue <= 0 {
+		return p
+	}
+
+	// every currency that is either held or targeted gets an allocation
+	codes := make(map[currency.Code]struct{})
+	for code := range values {
+		codes[code] = struct{}{}
+	}
+	for code := range targets {
+		codes[code] = struct{}{}
+	}
+
+	for code := range codes {
+		if code.Equal(c.Quote) {
+			continue
+		}
+
+		a := Allocation{
+			Currency: code,
+			Value:    values[code],
+			Weight:   values[code] / p.TotalValue,
+			Target:   targets[code],
+		}
+		a.Drift = a.Weight - a.Target
+		p.Allocations = append(p.Allocations, a)
+	}
+
+	sort.Slice(p.Allocations, func(i, j int) bool {
+		return p.Allocations[i].Currency.String() < p.Allocations[j].Currency.String()
+	})
+
+	// quote currency available per exchange, increased by the proceeds of the sells
+	cash := make(map[string]float64)
+	for _, position := range s.Positions {
+		if position.Currency.Equal(c.Quote) {
+			cash[position.Exchange] += position.Value
+		}
+	}
+
+	var buys []Allocation
+	for _, a := range p.Allocations {
+		if math.Abs(a.Drift) <= c.Tolerance {
+			continue
+		}
+		if a.Drift < 0 {
+			buys = append(buys, a)
+			continue
+		}
+
+		p.sell(c, s, a, cash)
+	}
+
+	for _, a := range buys {
+		p.buy(c, s, a, cash)
+	}
+	return p
+}
+
+// venues returns the exchanges the currency can be traded against the quote currency on
+func venues(c Config, s Snapshot, code currency.Code) []string {
+	pair := currency.NewPair(code, c.Quote)
+
+	var names []string
+	for name, pairs := range s.Markets {
+		if s.Prices[name][code] > 0 && pairs.Contains(pair, false) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sell routes the sale of the excess value of a currency to the exchanges that hold it
+func (p *Plan) sell(c Config, s Snapshot, a Allocation, cash map[string]float64) {
+	held := make(map[string]float64)
+	for _, position := range s.Positions {
+		if position.Currency.Equal(a.Currency) {
+			held[position.Exchange] += position.Value
+		}
+	}
+
+	names := venues(c, s, a.Currency)
+	sort.SliceStable(names, func(i, j int) bool { return held[names[i]] > held[names[j]] })
+
+	remaining := a.Value - a.Target*p.TotalValue
+	for _, name := range names {
+		if remaining < c.MinTradeQuote {
+			break
+		}
+
+		amount := math.Min(remaining, held[name])
+		if amount < c.MinTradeQuote {
+			continue
+		}
+
+		p.Trades = append(p.Trades, Trade{
+			Exchange:    name,
+			Pair:        currency.NewPair(a.Currency, c.Quote),
+			Side:        order.Sell,
+			QuoteAmount: amount,
+			Price:       s.Prices[name][a.Currency],
+		})
+		cash[name] += amount
+		remaining -= amount
+	}
+}
+
+// buy routes the purchase of the missing value of a currency to the exchanges with the most quote currency available
+func (p *Plan) buy(c Config, s Snapshot, a Allocation, cash map[string]float64) {
+	names := venues(c, s, a.Currency)
+	sort.SliceStable(names, func(i, j int) bool { return cash[names[i]] > cash[names[j]] })
+
+	remaining := a.Target*p.TotalValue - a.Value
+	for _, name := range names {
+		if remaining < c.MinTradeQuote {
+			break
+		}
+
+		amount := math.Min(remaining, cash[name])
+		if amount < c.MinTradeQuote {
+			continue
+		}
+
+		p.Trades = append(p.Trades, Trade{
+			Exchange:    name,
+			Pair:        currency.NewPair(a.Currency, c.Quote),
+			Side:        order.Buy,
+			QuoteAmount: amount,
+			Price:       s.Prices[name][a.Currency],
+		})
+		cash[name] -= amount
+		remaining -= amount
+	}
+
+	if remaining >= c.MinTradeQuote {
+		p.Unallocated += remaining
+	}
+}
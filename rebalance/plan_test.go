@@ -0,0 +1,108 @@
+package rebalance
+
+import (
+	"math"
+	"testing"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+func TestNewPlan(t *testing.T) {
+	c := Config{
+		Targets:       map[currency.Code]float64{currency.BTC: 0.5, currency.ETH: 0.3},
+		Quote:         currency.USDT,
+		Tolerance:     0.02,
+		MinTradeQuote: 10,
+		Mode:          Immediate,
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	markets := currency.Pairs{
+		currency.NewPair(currency.BTC, currency.USDT),
+		currency.NewPair(currency.ETH, currency.USDT),
+		currency.NewPair(currency.LTC, currency.USDT),
+	}
+
+	s := Snapshot{
+		Positions: []Position{
+			{Exchange: "Binance", Currency: currency.BTC, Amount: 0.2, Price: 5000, Value: 1000},
+			{Exchange: "Kraken", Currency: currency.BTC, Amount: 0.1, Price: 5000, Value: 500},
+			{Exchange: "Kraken", Currency: currency.LTC, Amount: 10, Price: 10, Value: 100},
+			{Exchange: "Binance", Currency: currency.USDT, Amount: 200, Price: 1, Value: 200},
+			{Exchange: "Kraken", Currency: currency.USDT, Amount: 200, Price: 1, Value: 200},
+		},
+		Prices: map[string]map[currency.Code]float64{
+			"Binance": {currency.BTC: 5000, currency.ETH: 300, currency.USDT: 1},
+			"Kraken":  {currency.BTC: 5000, currency.ETH: 300, currency.LTC: 10, currency.USDT: 1},
+		},
+		Markets: map[string]currency.Pairs{"Binance": markets, "Kraken": markets},
+	}
+
+	p := NewPlan(c, s)
+	if p.TotalValue != 2000 {
+		t.Fatalf("expected: %f, actual: %f", 2000.0, p.TotalValue)
+	}
+
+	// BTC is 75% and sold down to 50%, LTC has no target and is sold, ETH is bought up to 30%
+	want := map[string]float64{
+		"Binance sell BTCUSDT": 500,
+		"Kraken sell LTCUSDT":  100,
+		"Binance buy ETHUSDT":  600,
+	}
+	if len(p.Trades) != len(want) {
+		t.Fatalf("expected %d trades, actual: %+v", len(want), p.Trades)
+	}
+	for _, trade := range p.Trades {
+		key := trade.Exchange + " " + trade.Side.Lower() + " " + trade.Pair.String()
+		if amount, ok := want[key]; !ok || math.Abs(amount-trade.QuoteAmount) > 1e-9 {
+			t.Errorf("unexpected trade %s of %f", key, trade.QuoteAmount)
+		}
+	}
+	if p.Unallocated != 0 {
+		t.Errorf("expected: %f, actual: %f", 0.0, p.Unallocated)
+	}
+}
+
+func TestNewPlanTolerance(t *testing.T) {
+	c := Config{
+		Targets:       map[currency.Code]float64{currency.BTC: 0.5},
+		Quote:         currency.USDT,
+		Tolerance:     0.05,
+		MinTradeQuote: 10,
+		Mode:          Immediate,
+	}
+
+	s := Snapshot{
+		Positions: []Position{
+			{Exchange: "Binance", Currency: currency.BTC, Value: 520},
+			{Exchange: "Binance", Currency: currency.USDT, Value: 480},
+		},
+		Prices:  map[string]map[currency.Code]float64{"Binance": {currency.BTC: 5000}},
+		Markets: map[string]currency.Pairs{"Binance": {currency.NewPair(currency.BTC, currency.USDT)}},
+	}
+
+	if p := NewPlan(c, s); len(p.Trades) != 0 {
+		t.Errorf("expected no trades within the tolerance band, actual: %+v", p.Trades)
+	}
+
+	c.Tolerance = 0.01
+	p := NewPlan(c, s)
+	if len(p.Trades) != 1 || p.Trades[0].Side != order.Sell || p.Trades[0].QuoteAmount != 20 {
+		t.Errorf("expected a sell of 20, actual: %+v", p.Trades)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := Config{Targets: map[currency.Code]float64{currency.BTC: 0.7, currency.ETH: 0.4}, Mode: Immediate}
+	if err := c.Validate(); err != ErrInvalidWeights {
+		t.Errorf("expected: %v, actual: %v", ErrInvalidWeights, err)
+	}
+
+	c = Config{Mode: TWAP}
+	if err := c.Validate(); err != ErrInvalidDuration {
+		t.Errorf("expected: %v, actual: %v", ErrInvalidDuration, err)
+	}
+}
@@ -0,0 +1,288 @@
+package rebalance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/romanornr/autodealer/algo/twap"
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/portfolio"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// Mode determines how the trades of a plan are executed
+type Mode string
+
+const (
+	// Immediate executes every trade as a market order
+	Immediate Mode = "immediate"
+	// TWAP executes every trade as a TWAP job
+	TWAP Mode = "twap"
+
+	defaultMinTradeQuote = 10
+)
+
+var (
+	ErrInvalidWeights   = errors.New("target weights must be positive and add up to at most 1")
+	ErrInvalidTolerance = errors.New("tolerance must be between 0 and 1")
+	ErrInvalidMode      = errors.New("mode must be immediate or twap")
+	ErrInvalidDuration  = errors.New("twap rebalancing needs a positive duration")
+)
+
+// Config describes the target portfolio
+type Config struct {
+	// Targets maps a currency to its target weight in the portfolio. The quote currency holds whatever is left.
+	Targets map[currency.Code]float64
+	// Quote is the currency the portfolio is valued and traded in
+	Quote currency.Code
+	Asset asset.Item
+	// Exchanges limits the portfolio to the given exchanges, all exchanges are used when empty
+	Exchanges []string
+	// Tolerance is the drift in weight that is accepted before a currency is rebalanced, e.g. 0.02 for 2 percentage points
+	Tolerance float64
+	// MinTradeQuote is the smallest trade in quote currency that is worth placing
+	MinTradeQuote float64
+	Mode          Mode
+	// Duration is the time every TWAP job gets to complete
+	Duration time.Duration
+	// DryRun only returns the plan without placing any order
+	DryRun bool
+}
+
+// Validate checks the configuration
+func (c Config) Validate() error {
+	var sum float64
+	for _, weight := range c.Targets {
+		if weight < 0 {
+			return ErrInvalidWeights
+		}
+		sum += weight
+	}
+
+	switch {
+	case sum > 1+1e-9:
+		return ErrInvalidWeights
+	case c.Tolerance < 0 || c.Tolerance >= 1:
+		return ErrInvalidTolerance
+	case c.Mode != Immediate && c.Mode != TWAP:
+		return ErrInvalidMode
+	case c.Mode == TWAP && c.Duration <= 0:
+		return ErrInvalidDuration
+	}
+	return nil
+}
+
+// Rebalance plans the trades that bring the portfolio back to the target weights and executes them, unless the configuration is a dry run.
+// Sells are executed before buys, so their proceeds can be used to buy.
+func Rebalance(ctx context.Context, d *dealer.Dealer, c Config) (*Plan, error) {
+	if c.Asset == asset.Empty {
+		c.Asset = asset.Spot
+	}
+	if c.MinTradeQuote <= 0 {
+		c.MinTradeQuote = defaultMinTradeQuote
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := Collect(ctx, d, c)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := NewPlan(c, snapshot)
+	logrus.Printf("rebalance: portfolio worth %f %s, %d trades\n", plan.TotalValue, c.Quote, len(plan.Trades))
+
+	if c.DryRun {
+		return plan, nil
+	}
+	return plan, Execute(ctx, d, c, plan)
+}
+
+// Collect reads the holdings of every exchange from the balances strategy and values them in the quote currency
+func Collect(ctx context.Context, d *dealer.Dealer, c Config) (Snapshot, error) {
+	s := Snapshot{
+		Prices:  make(map[string]map[currency.Code]float64),
+		Markets: make(map[string]currency.Pairs),
+	}
+
+	for _, e := range exchanges(d, c) {
+		holdings, err := dealer.Holdings(d, e.GetName())
+		if err != nil {
+			return s, fmt.Errorf("%s: %w", e.GetName(), err)
+		}
+
+		pairs, err := e.GetAvailablePairs(c.Asset)
+		if err != nil {
+			logrus.Errorf("rebalance: %s pairs: %s\n", e.GetName(), err)
+			continue
+		}
+		s.Markets[e.GetName()] = pairs
+
+		amounts := make(map[currency.Code]float64)
+		for _, account := range holdings.Accounts {
+			for code, balance := range account.Balances[c.Asset] {
+				amounts[code.Upper()] += balance.TotalValue
+			}
+		}
+
+		// target currencies that are not held yet need a price as well
+		targeted := make(map[currency.Code]bool)
+		for code := range c.Targets {
+			targeted[code.Upper()] = true
+			if _, ok := amounts[code.Upper()]; !ok {
+				amounts[code.Upper()] = 0
+			}
+		}
+
+		prices := make(map[currency.Code]float64)
+		for code, amount := range amounts {
+			if amount <= 0 && !targeted[code] {
+				continue
+			}
+
+			price, err := price(ctx, d, e, code, c)
+			if err != nil {
+				logrus.Errorf("rebalance: no %s price for %s on %s: %s\n", c.Quote, code, e.GetName(), err)
+				continue
+			}
+			prices[code] = price
+
+			if amount > 0 {
+				s.Positions = append(s.Positions, Position{
+					Exchange: e.GetName(),
+					Currency: code,
+					Amount:   amount,
+					Price:    price,
+					Value:    amount * price,
+				})
+			}
+		}
+		s.Prices[e.GetName()] = prices
+	}
+	return s, nil
+}
+
+// price returns the price of the currency in the quote currency
+func price(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, code currency.Code, c Config) (float64, error) {
+	return portfolio.Price(ctx, d, e, code, c.Quote, c.Asset)
+}
+
+// exchanges returns the exchanges the portfolio is spread over
+func exchanges(d *dealer.Dealer, c Config) []exchange.IBotExchange {
+	all := d.GetExchanges()
+	if len(c.Exchanges) == 0 {
+		return all
+	}
+
+	var selected []exchange.IBotExchange
+	for _, e := range all {
+		for _, name := range c.Exchanges {
+			if strings.EqualFold(e.GetName(), name) {
+				selected = append(selected, e)
+			}
+		}
+	}
+	return selected
+}
+
+// Execute places the trades of the plan. The outcome of every trade is recorded on the plan, a failed trade does not stop the others.
+// In TWAP mode the buy jobs are scheduled to start when the sell jobs end.
+func Execute(ctx context.Context, d *dealer.Dealer, c Config, p *Plan) error {
+	var failed int
+	start := time.Now()
+
+	for _, side := range []order.Side{order.Sell, order.Buy} {
+		var scheduled bool
+		for i := range p.Trades {
+			t := &p.Trades[i]
+			if t.Side != side {
+				continue
+			}
+
+			var err error
+			switch c.Mode {
+			case TWAP:
+				err = executeTWAP(ctx, c, t, start)
+			default:
+				err = executeImmediate(ctx, d, c, t)
+			}
+
+			if err != nil {
+				logrus.Errorf("rebalance: %s %s %f on %s: %s\n", t.Side.Lower(), t.Pair, t.QuoteAmount, t.Exchange, err)
+				t.Error = err.Error()
+				failed++
+				continue
+			}
+			scheduled = true
+		}
+
+		// the TWAP sells take the whole duration, the buys start once they are done so their proceeds are there to pay for them
+		if c.Mode == TWAP && scheduled {
+			start = start.Add(c.Duration)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rebalance trades failed", failed, len(p.Trades))
+	}
+	return nil
+}
+
+// executeImmediate places the trade as a market order
+func executeImmediate(ctx context.Context, d *dealer.Dealer, c Config, t *Trade) error {
+	e, err := d.GetExchangeByName(t.Exchange)
+	if err != nil {
+		return err
+	}
+
+	payload := twap.OrderPayload{
+		Exchange:    t.Exchange,
+		Pair:        t.Pair,
+		Asset:       c.Asset,
+		Side:        t.Side,
+		OrderType:   order.Market,
+		QuoteAmount: t.QuoteAmount,
+	}
+
+	o, err := twap.BuildSliceOrder(e, payload, t.Price)
+	if err != nil {
+		return err
+	}
+
+	response, err := d.SubmitOrder(ctx, e, *o)
+	if err != nil {
+		return err
+	}
+	t.OrderID = response.OrderID
+	return nil
+}
+
+// executeTWAP starts a TWAP job for the trade at the given time. Its slices are capped to the free balance,
+// a buy never spends quote currency the sells have not brought in yet.
+func executeTWAP(ctx context.Context, c Config, t *Trade, start time.Time) error {
+	job, err := twap.Execute(ctx, twap.Payload{
+		ID:                twap.NewJobID(),
+		Exchange:          t.Exchange,
+		Pair:              t.Pair,
+		Asset:             c.Asset,
+		Start:             start,
+		End:               start.Add(c.Duration),
+		TargetAmountQuote: t.QuoteAmount,
+		Side:              t.Side,
+		OrderType:         order.Market,
+		CapToFree:         true,
+	})
+	if err != nil {
+		return err
+	}
+	t.JobID = job.ID
+	return nil
+}
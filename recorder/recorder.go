@@ -0,0 +1,283 @@
+package recorder
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/romanornr/autodealer/backtest"
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+const (
+	defaultMaxSize       = 64 << 20
+	defaultMaxAge        = time.Hour
+	defaultFlushInterval = 10 * time.Second
+
+	// fileTimeFormat names the files after the time they were opened, so they sort chronologically
+	fileTimeFormat = "20060102T150405.000000000"
+	fileExtension  = ".jsonl.gz"
+)
+
+var ErrClosed = errors.New("recorder is closed")
+
+// Config configures where and how market data is recorded
+type Config struct {
+	// Dir is the root directory, files are written to Dir/<exchange>/<asset>/<pair>/
+	Dir string
+	// MaxSize is the uncompressed size in bytes after which a new file is started
+	MaxSize int64
+	// MaxAge is how long a file is written to before a new file is started
+	MaxAge time.Duration
+	// FlushInterval is how often compressed data is flushed to disk, it bounds what is lost when the process dies
+	FlushInterval time.Duration
+}
+
+// Recorder is a strategy that writes all market data the dealer streams to gzip compressed JSONL files, one file per exchange, asset and pair at a time.
+// Every line is a backtest.Record stamped with the time it was received in nanoseconds, so the files can be replayed with backtest.Load.
+type Recorder struct {
+	config Config
+
+	mu     sync.Mutex
+	files  map[string]*file
+	closed bool
+}
+
+// file is the open file of a single exchange, asset and pair
+type file struct {
+	exchange string
+	f        *os.File
+	gz       *gzip.Writer
+	size     int64
+	opened   time.Time
+	flushed  time.Time
+}
+
+// New returns a recorder writing below the configured directory
+func New(c Config) *Recorder {
+	if c.MaxSize <= 0 {
+		c.MaxSize = defaultMaxSize
+	}
+	if c.MaxAge <= 0 {
+		c.MaxAge = defaultMaxAge
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushInterval
+	}
+
+	return &Recorder{
+		config: c,
+		files:  make(map[string]*file),
+	}
+}
+
+// Init makes sure the directory exists
+func (r *Recorder) Init(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	return os.MkdirAll(r.config.Dir, 0o755)
+}
+
+// OnFunding records the funding rate update
+func (r *Recorder) OnFunding(d *dealer.Dealer, e exchange.IBotExchange, x stream.FundingData) error {
+	return r.record(d, e, x.CurrencyPair, x.AssetType, x)
+}
+
+// OnPrice records the ticker
+func (r *Recorder) OnPrice(d *dealer.Dealer, e exchange.IBotExchange, x ticker.Price) error {
+	return r.record(d, e, x.Pair, x.AssetType, &x)
+}
+
+// OnKline records the candle
+func (r *Recorder) OnKline(d *dealer.Dealer, e exchange.IBotExchange, x stream.KlineData) error {
+	return r.record(d, e, x.Pair, x.AssetType, &x)
+}
+
+// OnOrderBook records the order book
+func (r *Recorder) OnOrderBook(d *dealer.Dealer, e exchange.IBotExchange, x orderbook.Base) error {
+	return r.record(d, e, x.Pair, x.Asset, &x)
+}
+
+func (r *Recorder) OnOrder(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) error {
+	return nil
+}
+
+func (r *Recorder) OnModify(d *dealer.Dealer, e exchange.IBotExchange, x order.Modify) error {
+	return nil
+}
+
+func (r *Recorder) OnBalanceChange(d *dealer.Dealer, e exchange.IBotExchange, x account.Change) error {
+	return nil
+}
+
+// OnTrade records every trade, a batch can hold trades of several pairs
+func (r *Recorder) OnTrade(d *dealer.Dealer, e exchange.IBotExchange, xs []trade.Data) error {
+	for i := range xs {
+		if err := r.record(d, e, xs[i].CurrencyPair, xs[i].AssetType, xs[i:i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) OnFill(d *dealer.Dealer, e exchange.IBotExchange, x []fill.Data) error {
+	return nil
+}
+
+func (r *Recorder) OnUnrecognized(d *dealer.Dealer, e exchange.IBotExchange, x interface{}) error {
+	return nil
+}
+
+// Deinit closes the files of the exchange
+func (r *Recorder) Deinit(d *dealer.Dealer, e exchange.IBotExchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var err error
+	for key, f := range r.files {
+		if f.exchange != e.GetName() {
+			continue
+		}
+		if e := f.close(); e != nil && err == nil {
+			err = e
+		}
+		delete(r.files, key)
+	}
+	return err
+}
+
+// Close closes all files, nothing is recorded afterwards
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var err error
+	for key, f := range r.files {
+		if e := f.close(); e != nil && err == nil {
+			err = e
+		}
+		delete(r.files, key)
+	}
+	r.closed = true
+	return err
+}
+
+// record appends the data to the file of its exchange, asset and pair
+func (r *Recorder) record(d *dealer.Dealer, e exchange.IBotExchange, pair currency.Pair, a asset.Item, data interface{}) error {
+	now := d.Now()
+
+	records, err := backtest.NewRecords(now, e.GetName(), data)
+	if err != nil || len(records) == 0 {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+
+	f, err := r.file(e.GetName(), pair, a, now)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		n, err := f.gz.Write(append(line, '\n'))
+		f.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	if now.Sub(f.flushed) >= r.config.FlushInterval {
+		f.flushed = now
+		return f.gz.Flush()
+	}
+	return nil
+}
+
+// file returns the file to write to, a new file is started when the current one is too large or too old
+func (r *Recorder) file(exchangeName string, pair currency.Pair, a asset.Item, now time.Time) (*file, error) {
+	dir := filepath.Join(
+		r.config.Dir,
+		strings.ToLower(exchangeName),
+		a.String(),
+		pair.Format(currency.PairFormat{Uppercase: true, Delimiter: currency.DashDelimiter}).String(),
+	)
+
+	f, ok := r.files[dir]
+	if ok && f.size < r.config.MaxSize && now.Sub(f.opened) < r.config.MaxAge {
+		return f, nil
+	}
+
+	if ok {
+		delete(r.files, dir)
+		if err := f.close(); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := open(dir, exchangeName, now)
+	if err != nil {
+		return nil, err
+	}
+	r.files[dir] = f
+	return f, nil
+}
+
+// open creates a new file in the directory named after the time
+func open(dir, exchangeName string, now time.Time) (*file, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	// files are never appended to, a file that was rotated at the same time gets a sequence number
+	base := filepath.Join(dir, now.UTC().Format(fileTimeFormat))
+	name := base + fileExtension
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	for i := 1; errors.Is(err, os.ErrExist); i++ {
+		name = base + "-" + strconv.Itoa(i) + fileExtension
+		f, err = os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &file{
+		exchange: exchangeName,
+		f:        f,
+		gz:       gzip.NewWriter(f),
+		opened:   now,
+		flushed:  now,
+	}, nil
+}
+
+// close completes the gzip stream and closes the file
+func (f *file) close() error {
+	err := f.gz.Close()
+	if e := f.f.Close(); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
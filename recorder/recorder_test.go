@@ -0,0 +1,117 @@
+package recorder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/romanornr/autodealer/backtest"
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+// testExchange only provides the name, the recorder doesn't touch anything else
+type testExchange struct {
+	exchange.IBotExchange
+}
+
+func (testExchange) GetName() string {
+	return "test"
+}
+
+var testPair = currency.NewPair(currency.BTC, currency.USDT)
+
+func TestRecorder(t *testing.T) {
+	start := time.Unix(0, 1600000000123456789)
+	clock := backtest.NewVirtualClock(start)
+	d, err := dealer.NewBuilder().Clock(clock).BuildWithExchanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	r := New(Config{Dir: dir, MaxAge: time.Minute})
+	e := testExchange{}
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(start.Add(time.Duration(i) * 40 * time.Second))
+		if err = r.OnPrice(d, e, ticker.Price{Pair: testPair, AssetType: asset.Spot, Last: float64(100 + i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trades := []trade.Data{
+		{CurrencyPair: testPair, AssetType: asset.Spot, Side: order.Buy, Price: 101, Amount: 1},
+		{CurrencyPair: currency.NewPair(currency.ETH, currency.USDT), AssetType: asset.Spot, Side: order.Sell, Price: 10, Amount: 2},
+	}
+	if err = r.OnTrade(d, e, trades); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.OnFunding(d, e, stream.FundingData{CurrencyPair: testPair, AssetType: asset.PerpetualSwap, Rate: 0.0001}); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the third ticker is more than a minute after the first and goes to a new file
+	files, err := filepath.Glob(filepath.Join(dir, "test", "spot", "BTC-USDT", "*"+fileExtension))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected: %v, actual: %v", 2, files)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "test", "spot", "ETH-USDT")); err != nil {
+		t.Errorf("expected: %v, actual: %v", "ETH-USDT directory", err)
+	}
+
+	events, err := backtest.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 6 {
+		t.Fatalf("expected: %v, actual: %v", 6, len(events))
+	}
+	if !events[0].Time.Equal(start) || events[0].Time.Nanosecond() != start.Nanosecond() {
+		t.Errorf("expected: %v, actual: %v", start, events[0].Time)
+	}
+	if x, ok := events[0].Data.(*ticker.Price); !ok || x.Last != 100 {
+		t.Errorf("expected: %v, actual: %v", 100, events[0].Data)
+	}
+
+	if err = r.OnPrice(d, e, ticker.Price{Pair: testPair, AssetType: asset.Spot}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected: %v, actual: %v", ErrClosed, err)
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	clock := backtest.NewVirtualClock(time.Unix(1600000000, 0))
+	d, err := dealer.NewBuilder().Clock(clock).BuildWithExchanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	r := New(Config{Dir: dir, MaxSize: 1})
+	for i := 0; i < 3; i++ {
+		if err = r.OnPrice(d, testExchange{}, ticker.Price{Pair: testPair, AssetType: asset.Spot, Last: 100}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test", "spot", "BTC-USDT", "*"+fileExtension))
+	if len(files) != 3 {
+		t.Errorf("expected: %v, actual: %v", 3, files)
+	}
+}
@@ -0,0 +1,530 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/portfolio"
+	"github.com/romanornr/autodealer/pricing"
+	"github.com/romanornr/autodealer/transfer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/fill"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/orderbook"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/stream"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/trade"
+)
+
+const (
+	strategyName = "risk"
+
+	defaultEquityInterval = time.Minute
+)
+
+// Rule names a pre-trade check, it labels the rejections reported to the dealer's reporters
+type Rule string
+
+const (
+	RuleMaxNotional   Rule = "max_notional"
+	RuleMaxPosition   Rule = "max_position"
+	RuleMaxOpenOrders Rule = "max_open_orders"
+	RulePriceBand     Rule = "price_band"
+	RuleDailyLoss     Rule = "daily_loss"
+	// RuleNoPrice rejects orders a price-dependent limit can't be checked for, as the market has no price
+	RuleNoPrice Rule = "no_price"
+)
+
+var (
+	ErrMaxNotional   = errors.New("order notional above the maximum")
+	ErrMaxPosition   = errors.New("position would exceed the maximum")
+	ErrMaxOpenOrders = errors.New("too many open orders for the pair")
+	ErrPriceBand     = errors.New("order price outside the band around the last price")
+	ErrDailyLoss     = errors.New("daily loss limit reached")
+	ErrNoPrice       = errors.New("no price to check the order against")
+)
+
+var ruleErrors = map[Rule]error{
+	RuleMaxNotional:   ErrMaxNotional,
+	RuleMaxPosition:   ErrMaxPosition,
+	RuleMaxOpenOrders: ErrMaxOpenOrders,
+	RulePriceBand:     ErrPriceBand,
+	RuleDailyLoss:     ErrDailyLoss,
+	RuleNoPrice:       ErrNoPrice,
+}
+
+// Violation is the error an order is rejected with. It matches the error of its rule with errors.Is.
+type Violation struct {
+	Rule     Rule
+	Exchange string
+	Pair     currency.Pair
+	// Value is what the order would have caused and Limit what is allowed
+	Value float64
+	Limit float64
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s: %s %s %f, limit %f", ruleErrors[v.Rule], v.Exchange, v.Pair, v.Value, v.Limit)
+}
+
+func (v *Violation) Unwrap() error {
+	return ruleErrors[v.Rule]
+}
+
+// Config holds the limits, zero disables a limit. Values are in the quote currency unless noted otherwise.
+type Config struct {
+	// Quote is the currency notional and losses are valued in, USDT by default
+	Quote currency.Code
+	// MaxNotional is the largest value of a single order
+	MaxNotional float64
+	// MaxPosition is the largest amount of a currency held on an exchange, in units of the currency.
+	// Open orders count as if they were filled.
+	MaxPosition map[currency.Code]float64
+	// MaxOpenOrders is the largest amount of open orders per exchange and pair
+	MaxOpenOrders int
+	// PriceBand is the largest relative distance of the order price from the last price, 0.05 allows prices within 5%
+	PriceBand float64
+	// MaxDailyLoss is the largest drop of the equity of all exchanges since the start of the UTC day, new orders are rejected beyond it.
+	// Withdrawals made since then are added back to the equity, moving funds is no loss.
+	MaxDailyLoss float64
+	// EquityInterval is how often the equity is valued for the daily loss limit
+	EquityInterval time.Duration
+}
+
+// Engine is a pre-trade risk check for the dealer, see dealer.Builder.Risk. It is also a strategy, added to the dealer
+// with Watch, so it values the equity of all exchanges for the daily loss limit.
+type Engine struct {
+	config Config
+
+	mu sync.Mutex
+	// day is the start of the UTC day the start equity belongs to
+	day         time.Time
+	startEquity float64
+	equity      float64
+	valued      bool
+
+	// aggregate values the holdings and outflows the funds withdrawn since a time, they are replaced in tests
+	aggregate func(ctx context.Context, d *dealer.Dealer, quote currency.Code) (*portfolio.Portfolio, error)
+	outflows  func(ctx context.Context, d *dealer.Dealer, quote currency.Code, since time.Time) float64
+
+	// running counts the exchanges the engine is initialised for, equity is valued while it is above zero
+	runMu   sync.Mutex
+	running int
+	stop    func()
+}
+
+// maxPositionKey prefixes the keys of the position limits, RISK_MAX_POSITION_BTC limits the BTC held per exchange
+const maxPositionKey = "RISK_MAX_POSITION_"
+
+// LoadConfig reads the limits from the configuration, limits that are not set stay disabled
+func LoadConfig() Config {
+	c := Config{
+		Quote:          currency.NewCode(strings.ToUpper(viper.GetString("RISK_QUOTE"))),
+		MaxNotional:    viper.GetFloat64("RISK_MAX_NOTIONAL"),
+		MaxPosition:    make(map[currency.Code]float64),
+		MaxOpenOrders:  viper.GetInt("RISK_MAX_OPEN_ORDERS"),
+		PriceBand:      viper.GetFloat64("RISK_PRICE_BAND"),
+		MaxDailyLoss:   viper.GetFloat64("RISK_MAX_DAILY_LOSS"),
+		EquityInterval: viper.GetDuration("RISK_EQUITY_INTERVAL"),
+	}
+
+	// viper lower cases the keys it read
+	for _, key := range viper.AllKeys() {
+		code := strings.TrimPrefix(strings.ToUpper(key), maxPositionKey)
+		if code == strings.ToUpper(key) || code == "" {
+			continue
+		}
+		if limit := viper.GetFloat64(key); limit > 0 {
+			c.MaxPosition[currency.NewCode(code)] = limit
+		}
+	}
+	return c
+}
+
+// New returns a risk engine enforcing the limits
+func New(c Config) *Engine {
+	if c.Quote.IsEmpty() {
+		c.Quote = currency.USDT
+	}
+	if c.EquityInterval <= 0 {
+		c.EquityInterval = defaultEquityInterval
+	}
+	return &Engine{config: c, aggregate: portfolio.Aggregate, outflows: Outflows, stop: func() {}}
+}
+
+// Watch adds the engine to the dealer, which keeps the equity valued for the daily loss limit
+func (r *Engine) Watch(d *dealer.Dealer) {
+	d.Root.Add(strategyName, r)
+}
+
+// CheckSubmit runs all checks against the new order
+func (r *Engine) CheckSubmit(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, s order.Submit) error {
+	return r.reject(d, e, r.check(ctx, d, e, s, true))
+}
+
+// CheckModify runs the checks against the order as it would be after the modification, it does not count as another open order
+func (r *Engine) CheckModify(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, m order.Modify) error {
+	s := dealer.ModifyToSubmit(m)
+
+	// modifications often only carry the changed fields
+	if o, err := d.GetOrder(ctx, e.GetName(), m.OrderID); err == nil {
+		if s.Pair.IsEmpty() {
+			s.Pair = o.Pair
+		}
+		if s.AssetType == asset.Empty {
+			s.AssetType = o.Asset
+		}
+		if s.Side == order.UnknownSide {
+			s.Side = o.Side
+		}
+		if s.Amount == 0 {
+			s.Amount = o.Remaining()
+		}
+		if s.Price == 0 {
+			s.Price = o.Price
+		}
+	}
+
+	return r.reject(d, e, r.check(ctx, d, e, s, false))
+}
+
+// reject reports the violation
+func (r *Engine) reject(d *dealer.Dealer, e exchange.IBotExchange, err error) error {
+	var v *Violation
+	if errors.As(err, &v) {
+		logrus.Warnf("risk rejected %s order: %s\n", e.GetName(), v)
+		d.ReportEvent(dealer.RiskRejectMetric, e.GetName(), string(v.Rule))
+	}
+	return err
+}
+
+func (r *Engine) check(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, s order.Submit, opens bool) error {
+	violation := func(rule Rule, value, limit float64) error {
+		return &Violation{Rule: rule, Exchange: e.GetName(), Pair: s.Pair, Value: value, Limit: limit}
+	}
+
+	if r.config.MaxDailyLoss > 0 {
+		if loss := r.Loss(); loss >= r.config.MaxDailyLoss {
+			return violation(RuleDailyLoss, loss, r.config.MaxDailyLoss)
+		}
+	}
+
+	if opens && r.config.MaxOpenOrders > 0 {
+		open, err := d.QueryOrders(ctx, dealer.OrderQuery{
+			Exchange: e.GetName(),
+			Pair:     s.Pair,
+			Asset:    s.AssetType,
+			States:   []dealer.OrderState{dealer.OrderSubmitted, dealer.OrderPartiallyFilled},
+		})
+		if err != nil {
+			return err
+		}
+		if len(open) >= r.config.MaxOpenOrders {
+			return violation(RuleMaxOpenOrders, float64(len(open)+1), float64(r.config.MaxOpenOrders))
+		}
+	}
+
+	// the price-dependent limits fail closed, an order they can't check is rejected
+	var last ticker.Price
+	if r.config.PriceBand > 0 || r.config.MaxNotional > 0 || len(r.config.MaxPosition) > 0 {
+		t, err := pricing.Default().Ticker(ctx, e, s.Pair, s.AssetType)
+		if err != nil {
+			logrus.Warnf("risk: no ticker for %s %s: %s\n", e.GetName(), s.Pair, err)
+			return violation(RuleNoPrice, 0, 0)
+		}
+		last = t
+	}
+
+	if r.config.PriceBand > 0 && s.Price > 0 {
+		if last.Last <= 0 {
+			return violation(RuleNoPrice, 0, 0)
+		}
+		if distance := math.Abs(s.Price-last.Last) / last.Last; distance > r.config.PriceBand {
+			return violation(RulePriceBand, distance, r.config.PriceBand)
+		}
+	}
+
+	price := Price(s, last)
+	if (r.config.MaxNotional > 0 || len(r.config.MaxPosition) > 0) && price <= 0 {
+		return violation(RuleNoPrice, 0, 0)
+	}
+
+	if r.config.MaxNotional > 0 {
+		notional, err := r.notional(ctx, d, e, s, price)
+		if err != nil {
+			return err
+		}
+		if notional > r.config.MaxNotional {
+			return violation(RuleMaxNotional, notional, r.config.MaxNotional)
+		}
+	}
+
+	if len(r.config.MaxPosition) > 0 {
+		code, amount := Receives(s.Pair, s.Side, s.Amount, price)
+		if limit, ok := r.config.MaxPosition[code]; ok {
+			position, err := r.position(ctx, d, e, code, s.AssetType)
+			if err != nil {
+				return err
+			}
+			if position+amount > limit {
+				return violation(RuleMaxPosition, position+amount, limit)
+			}
+		}
+	}
+	return nil
+}
+
+// Price returns the price the order is expected to execute at. Orders without a price are market orders, buys execute at the ask
+// and sells at the bid, with the last price as fallback.
+func Price(s order.Submit, t ticker.Price) float64 {
+	switch {
+	case s.Price > 0:
+		return s.Price
+	case s.Side.IsLong() && t.Ask > 0:
+		return t.Ask
+	case s.Side.IsShort() && t.Bid > 0:
+		return t.Bid
+	default:
+		return t.Last
+	}
+}
+
+// Receives returns the currency and amount the order adds to the account when it is filled, the base for buys and the quote for sells
+func Receives(p currency.Pair, side order.Side, amount, price float64) (currency.Code, float64) {
+	if side.IsShort() {
+		return p.Quote, amount * price
+	}
+	return p.Base, amount
+}
+
+// notional returns the value of the order in the quote currency of the engine
+func (r *Engine) notional(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, s order.Submit, price float64) (float64, error) {
+	notional := s.Amount * price
+	if s.QuoteAmount > 0 {
+		notional = s.QuoteAmount
+	}
+
+	if notional == 0 || pricing.Equivalent(s.Pair.Quote, r.config.Quote) {
+		return notional, nil
+	}
+
+	c, err := pricing.Value(ctx, d, e, s.Pair.Quote, r.config.Quote, s.AssetType)
+	if err != nil {
+		return 0, err
+	}
+	return notional * c.Rate, nil
+}
+
+// position returns the amount of the currency held on the exchange plus what open orders would add to it
+func (r *Engine) position(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, code currency.Code, a asset.Item) (float64, error) {
+	var position float64
+
+	if h, err := dealer.Holdings(d, e.GetName()); err == nil {
+		for _, sub := range h.Accounts {
+			position += sub.Balances[a][code].TotalValue
+		}
+	}
+
+	open, err := d.QueryOrders(ctx, dealer.OrderQuery{
+		Exchange: e.GetName(),
+		Asset:    a,
+		States:   []dealer.OrderState{dealer.OrderSubmitted, dealer.OrderPartiallyFilled},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, o := range open {
+		c, amount := Receives(o.Pair, o.Side, o.Remaining(), o.Price)
+		if c.Equal(code) {
+			position += amount
+		}
+	}
+	return position, nil
+}
+
+// Loss returns how much the equity dropped since the start of the UTC day, zero when it didn't
+func (r *Engine) Loss() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.valued {
+		return 0
+	}
+	return math.Max(0, r.startEquity-r.equity)
+}
+
+// record stores the equity, the first equity of a day is what losses of that day are measured against
+func (r *Engine) record(now time.Time, equity float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	day := now.UTC().Truncate(24 * time.Hour)
+	if !r.valued || day.After(r.day) {
+		r.day = day
+		r.startEquity = equity
+		r.valued = true
+	}
+	r.equity = equity
+}
+
+// value records the equity, funds withdrawn since the start of the day are added back as they were moved and not lost
+func (r *Engine) value(ctx context.Context, d *dealer.Dealer) {
+	now := d.Now()
+	equity, err := r.Equity(ctx, d)
+	if err != nil {
+		// a missing valuation is neither the start of the day nor a loss
+		logrus.Warnf("risk: unable to value the equity: %s\n", err)
+		return
+	}
+	r.record(now, equity+r.outflows(ctx, d, r.config.Quote, now.UTC().Truncate(24*time.Hour)))
+}
+
+// Outflows returns the value of the withdrawals made since the time in the quote currency, failed and cancelled ones
+// are left out as the funds returned. Transfers between the exchanges only count until they arrived, after that the
+// destination holds the funds again.
+func Outflows(ctx context.Context, d *dealer.Dealer, quote currency.Code, since time.Time) float64 {
+	amounts := make(map[string]map[currency.Code]float64)
+	add := func(exchangeName string, code currency.Code, amount float64) {
+		if amounts[exchangeName] == nil {
+			amounts[exchangeName] = make(map[currency.Code]float64)
+		}
+		amounts[exchangeName][code.Upper()] += amount
+	}
+
+	for _, w := range transfer.DefaultTracker().Since(since) {
+		if w.Status == transfer.WithdrawalFailed || w.Status == transfer.WithdrawalCancelled {
+			continue
+		}
+		add(w.Exchange, w.Currency, w.Amount)
+	}
+	jobs, err := transfer.Jobs().List(ctx)
+	if err != nil {
+		logrus.Errorf("risk: failed to list transfers: %s\n", err)
+	}
+	for _, j := range jobs {
+		if j.Stage == transfer.StageDeposited && j.Withdrawal != nil && !j.Withdrawal.Time.Before(since) {
+			add(j.Source, j.Currency, -j.Withdrawal.Amount)
+		}
+	}
+
+	var total float64
+	for exchangeName, codes := range amounts {
+		e, err := d.GetExchangeByName(exchangeName)
+		if err != nil {
+			continue
+		}
+		for code, amount := range codes {
+			if amount <= 0 {
+				continue
+			}
+			price, err := portfolio.Price(ctx, d, e, code, quote, asset.Spot)
+			if err != nil {
+				logrus.Debugf("risk: unable to value withdrawn %s on %s: %s\n", code, exchangeName, err)
+				continue
+			}
+			total += amount * price
+		}
+	}
+	return total
+}
+
+// Equity returns the value of the balances of all exchanges in the quote currency, balances that can't be valued are skipped
+func (r *Engine) Equity(ctx context.Context, d *dealer.Dealer) (float64, error) {
+	p, err := r.aggregate(ctx, d, r.config.Quote)
+	if err != nil {
+		return 0, err
+	}
+	return p.Total, nil
+}
+
+// Init starts valuing the equity for the daily loss limit when the first exchange is initialised
+func (r *Engine) Init(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange) error {
+	pricing.Watch(d)
+
+	if r.config.MaxDailyLoss <= 0 {
+		return nil
+	}
+
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	r.running++
+	if r.running == 1 {
+		r.stop = d.Clock().Every(r.config.EquityInterval, func() {
+			r.value(ctx, d)
+		})
+	}
+	return nil
+}
+
+func (r *Engine) OnFunding(d *dealer.Dealer, e exchange.IBotExchange, x stream.FundingData) error {
+	return nil
+}
+
+func (r *Engine) OnPrice(d *dealer.Dealer, e exchange.IBotExchange, x ticker.Price) error {
+	return nil
+}
+
+func (r *Engine) OnKline(d *dealer.Dealer, e exchange.IBotExchange, x stream.KlineData) error {
+	return nil
+}
+
+func (r *Engine) OnOrderBook(d *dealer.Dealer, e exchange.IBotExchange, x orderbook.Base) error {
+	return nil
+}
+
+func (r *Engine) OnOrder(d *dealer.Dealer, e exchange.IBotExchange, x order.Detail) error {
+	return nil
+}
+
+func (r *Engine) OnModify(d *dealer.Dealer, e exchange.IBotExchange, x order.Modify) error {
+	return nil
+}
+
+func (r *Engine) OnBalanceChange(d *dealer.Dealer, e exchange.IBotExchange, x account.Change) error {
+	return nil
+}
+
+func (r *Engine) OnTrade(d *dealer.Dealer, e exchange.IBotExchange, x []trade.Data) error {
+	return nil
+}
+
+func (r *Engine) OnFill(d *dealer.Dealer, e exchange.IBotExchange, x []fill.Data) error {
+	return nil
+}
+
+func (r *Engine) OnUnrecognized(d *dealer.Dealer, e exchange.IBotExchange, x interface{}) error {
+	return nil
+}
+
+// Deinit stops valuing the equity once the last exchange is deinitialised
+func (r *Engine) Deinit(d *dealer.Dealer, e exchange.IBotExchange) error {
+	if r.config.MaxDailyLoss <= 0 {
+		return nil
+	}
+
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	if r.running == 0 {
+		return nil
+	}
+	r.running--
+	if r.running == 0 {
+		r.stop()
+		r.stop = func() {}
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/portfolio"
+	"github.com/spf13/viper"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
+)
+
+var testPair = currency.NewPair(currency.BTC, currency.USDT)
+
+// testExchange prices BTC at 100 and accepts every order
+type testExchange struct {
+	exchange.IBotExchange
+	orders int
+}
+
+func (*testExchange) GetName() string {
+	return "risktest"
+}
+
+func (*testExchange) FetchTicker(ctx context.Context, p currency.Pair, a asset.Item) (*ticker.Price, error) {
+	return &ticker.Price{Pair: p, AssetType: a, Bid: 99, Ask: 101, Last: 100, LastUpdated: time.Now()}, nil
+}
+
+func (e *testExchange) SubmitOrder(ctx context.Context, s *order.Submit) (*order.SubmitResponse, error) {
+	e.orders++
+	return &order.SubmitResponse{OrderID: strconv.Itoa(e.orders), Pair: s.Pair, AssetType: s.AssetType, Side: s.Side, Price: s.Price, Amount: s.Amount, Status: order.New}, nil
+}
+
+func (*testExchange) ModifyOrder(ctx context.Context, m *order.Modify) (*order.ModifyResponse, error) {
+	return &order.ModifyResponse{OrderID: m.OrderID, Price: m.Price}, nil
+}
+
+// testReporter counts the rejections per rule
+type testReporter struct {
+	rejections map[string]int
+}
+
+func (r *testReporter) Event(m dealer.Metric, labels ...string) {
+	if m == dealer.RiskRejectMetric && len(labels) == 2 {
+		r.rejections[labels[1]]++
+	}
+}
+
+func (r *testReporter) Latency(m dealer.Metric, d time.Duration, labels ...string) {}
+
+func (r *testReporter) Value(m dealer.Metric, v float64, labels ...string) {}
+
+func testDealer(t *testing.T, c Config) (*dealer.Dealer, *testExchange, *testReporter) {
+	e := &testExchange{}
+	reporter := &testReporter{rejections: make(map[string]int)}
+
+	d, err := dealer.NewBuilder().Balances(0).Reporter(reporter).Risk(New(c)).BuildWithExchanges(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, e, reporter
+}
+
+func limit(side order.Side, price, amount float64) order.Submit {
+	return order.Submit{Pair: testPair, AssetType: asset.Spot, Side: side, Type: order.Limit, Price: price, Amount: amount}
+}
+
+func TestPriceBand(t *testing.T) {
+	d, e, reporter := testDealer(t, Config{PriceBand: 0.05})
+	ctx := context.Background()
+
+	if _, err := d.SubmitOrder(ctx, e, limit(order.Buy, 104, 1)); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+
+	_, err := d.SubmitOrder(ctx, e, limit(order.Buy, 120, 1))
+	if !errors.Is(err, ErrPriceBand) {
+		t.Errorf("expected: %v, actual: %v", ErrPriceBand, err)
+	}
+
+	var v *Violation
+	if !errors.As(err, &v) || v.Rule != RulePriceBand || v.Limit != 0.05 {
+		t.Errorf("expected: %v, actual: %v", RulePriceBand, err)
+	}
+	if reporter.rejections[string(RulePriceBand)] != 1 || e.orders != 1 {
+		t.Errorf("expected: %v, actual: %v %v", 1, reporter.rejections, e.orders)
+	}
+}
+
+func TestMaxNotional(t *testing.T) {
+	d, e, _ := testDealer(t, Config{MaxNotional: 1000})
+	ctx := context.Background()
+
+	if _, err := d.SubmitOrder(ctx, e, limit(order.Sell, 100, 11)); !errors.Is(err, ErrMaxNotional) {
+		t.Errorf("expected: %v, actual: %v", ErrMaxNotional, err)
+	}
+
+	// market buys are valued at the ask
+	market := order.Submit{Pair: testPair, AssetType: asset.Spot, Side: order.Buy, Type: order.Market, Amount: 9.95}
+	if _, err := d.SubmitOrder(ctx, e, market); !errors.Is(err, ErrMaxNotional) {
+		t.Errorf("expected: %v, actual: %v", ErrMaxNotional, err)
+	}
+
+	market.Amount = 9.9
+	if _, err := d.SubmitOrder(ctx, e, market); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+}
+
+// quietExchange has no ticker
+type quietExchange struct {
+	testExchange
+}
+
+func (*quietExchange) GetName() string {
+	return "quiet"
+}
+
+func (*quietExchange) FetchTicker(ctx context.Context, p currency.Pair, a asset.Item) (*ticker.Price, error) {
+	return nil, errors.New("no ticker")
+}
+
+func TestNoPrice(t *testing.T) {
+	e := &quietExchange{}
+	d, err := dealer.NewBuilder().Balances(0).Risk(New(Config{MaxNotional: 1000})).BuildWithExchanges(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a market order can't be valued without a price
+	market := order.Submit{Pair: testPair, AssetType: asset.Spot, Side: order.Buy, Type: order.Market, Amount: 100}
+	if _, err = d.SubmitOrder(context.Background(), e, market); !errors.Is(err, ErrNoPrice) {
+		t.Errorf("expected: %v, actual: %v", ErrNoPrice, err)
+	}
+	if e.orders != 0 {
+		t.Errorf("expected: %v, actual: %v", 0, e.orders)
+	}
+}
+
+func TestMaxOpenOrders(t *testing.T) {
+	d, e, _ := testDealer(t, Config{MaxOpenOrders: 1})
+	ctx := context.Background()
+
+	if _, err := d.SubmitOrder(ctx, e, limit(order.Buy, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.SubmitOrder(ctx, e, limit(order.Buy, 100, 1)); !errors.Is(err, ErrMaxOpenOrders) {
+		t.Errorf("expected: %v, actual: %v", ErrMaxOpenOrders, err)
+	}
+
+	// a modification is not another open order
+	if _, err := d.ModifyOrder(ctx, e, order.Modify{OrderID: "1", Price: 99}); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+
+	// filled orders are not open anymore
+	d.OnOrder(e, order.Detail{OrderID: "1", Status: order.Filled})
+	if _, err := d.SubmitOrder(ctx, e, limit(order.Buy, 100, 1)); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+}
+
+func TestMaxPosition(t *testing.T) {
+	d, e, _ := testDealer(t, Config{MaxPosition: map[currency.Code]float64{currency.BTC: 1}, PriceBand: 0.05})
+	ctx := context.Background()
+
+	if _, err := d.SubmitOrder(ctx, e, limit(order.Buy, 100, 0.6)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the open buy counts as if it was filled
+	if _, err := d.SubmitOrder(ctx, e, limit(order.Buy, 100, 0.6)); !errors.Is(err, ErrMaxPosition) {
+		t.Errorf("expected: %v, actual: %v", ErrMaxPosition, err)
+	}
+
+	// the modified order is checked with the fields it keeps
+	if _, err := d.ModifyOrder(ctx, e, order.Modify{OrderID: "1", Price: 130}); !errors.Is(err, ErrPriceBand) {
+		t.Errorf("expected: %v, actual: %v", ErrPriceBand, err)
+	}
+
+	if _, err := d.SubmitOrder(ctx, e, limit(order.Sell, 100, 0.6)); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+}
+
+func TestDailyLoss(t *testing.T) {
+	d, e, _ := testDealer(t, Config{MaxDailyLoss: 50})
+	r := New(Config{MaxDailyLoss: 50})
+	ctx := context.Background()
+
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.record(day.Add(time.Hour), 1000)
+	r.record(day.Add(2*time.Hour), 960)
+	if err := r.CheckSubmit(ctx, d, e, limit(order.Buy, 100, 0.1)); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+
+	r.record(day.Add(3*time.Hour), 940)
+	if err := r.CheckSubmit(ctx, d, e, limit(order.Buy, 100, 0.1)); !errors.Is(err, ErrDailyLoss) {
+		t.Errorf("expected: %v, actual: %v", ErrDailyLoss, err)
+	}
+
+	// the limit starts over the next day
+	r.record(day.Add(25*time.Hour), 930)
+	if loss := r.Loss(); loss != 0 {
+		t.Errorf("expected: %v, actual: %v", 0, loss)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	viper.Set("RISK_MAX_NOTIONAL", 1000)
+	viper.Set("RISK_MAX_POSITION_BTC", 2)
+	defer func() {
+		viper.Set("RISK_MAX_NOTIONAL", nil)
+		viper.Set("RISK_MAX_POSITION_BTC", nil)
+	}()
+
+	c := LoadConfig()
+	if c.MaxNotional != 1000 || c.MaxPosition[currency.BTC] != 2 || c.MaxDailyLoss != 0 {
+		t.Errorf("expected: %v, actual: %+v", "notional 1000 and 2 BTC", c)
+	}
+}
+
+// testClock counts the loops it runs and how many were stopped
+type testClock struct {
+	now            time.Time
+	loops, stopped int
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Every(interval time.Duration, f func()) func() {
+	c.loops++
+	f()
+	return func() { c.stopped++ }
+}
+
+func TestEquityValuation(t *testing.T) {
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &testClock{now: day.Add(2 * time.Hour)}
+	d, err := dealer.NewBuilder().Balances(0).Clock(clock).BuildWithExchanges(&testExchange{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, _ := d.GetExchangeByName("risktest")
+
+	r := New(Config{MaxDailyLoss: 50})
+	r.record(day.Add(time.Hour), 1000)
+	// everything was withdrawn, which is no loss
+	r.outflows = func(ctx context.Context, d *dealer.Dealer, quote currency.Code, since time.Time) float64 {
+		if !since.Equal(day) {
+			t.Errorf("expected: %v, actual: %v", day, since)
+		}
+		return 1000
+	}
+
+	ctx := context.Background()
+	// a failed valuation is not recorded, it would look like the loss of everything
+	r.aggregate = func(ctx context.Context, d *dealer.Dealer, quote currency.Code) (*portfolio.Portfolio, error) {
+		return nil, portfolio.ErrNoHoldings
+	}
+	r.value(ctx, d)
+	if r.equity != 1000 || r.Loss() != 0 {
+		t.Errorf("expected: %v, actual: %v %v", 1000, r.equity, r.Loss())
+	}
+	r.aggregate = func(ctx context.Context, d *dealer.Dealer, quote currency.Code) (*portfolio.Portfolio, error) {
+		return &portfolio.Portfolio{Quote: quote}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = r.Init(ctx, d, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if clock.loops != 1 || r.Loss() != 0 {
+		t.Errorf("expected: %v, actual: %v %v", "one loop without loss", clock.loops, r.Loss())
+	}
+
+	// valuation goes on until the last exchange stops
+	_ = r.Deinit(d, e)
+	if clock.stopped != 0 {
+		t.Errorf("expected: %v, actual: %v", 0, clock.stopped)
+	}
+	_ = r.Deinit(d, e)
+	if clock.stopped != 1 {
+		t.Errorf("expected: %v, actual: %v", 1, clock.stopped)
+	}
+
+	// and starts again with the exchange
+	_ = r.Init(ctx, d, e)
+	if clock.loops != 2 {
+		t.Errorf("expected: %v, actual: %v", 2, clock.loops)
+	}
+}
@@ -3,11 +3,19 @@ package singleton
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/romanornr/autodealer/audit"
 	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/metrics"
+	"github.com/romanornr/autodealer/risk"
+	"github.com/romanornr/autodealer/transfer"
 	"github.com/rs/zerolog/log"
 	"sync"
 )
 
+const redisAddr = "127.0.0.1:6379"
+
 var Ds = &DealerSingleton{}
 
 type DealerSingleton struct {
@@ -34,19 +42,48 @@ func (ds *DealerSingleton) InitAndGetDealer(ctx context.Context) (*dealer.Dealer
 
 	// Only initialize if not already initialized
 	if !ds.initialized {
-		ds.instance, ds.err = dealer.NewBuilder().Build(ctx)
+		// orders are kept in redis, so resting orders are known again after a restart
+		orders := dealer.NewRedisOrderStore(redis.NewClient(&redis.Options{Addr: redisAddr}))
+		if err := orders.Reindex(ctx); err != nil {
+			log.Warn().Err(err).Msg("failed to reindex orders")
+		}
+		builder, limits, err := newBuilder(orders)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to create instance")
+			return nil, err
+		}
+		ds.instance, ds.err = builder.Build(ctx)
 		if ds.err != nil {
 			log.Error().Err(ds.err).Msg("failed to create instance")
 			return nil, ds.err
 		}
+		// the risk engine values the equity of all exchanges for the daily loss limit
+		limits.Watch(ds.instance)
 		// As run does not return an error, we just run it in a goroutine
 		go ds.instance.Run(ctx)
+		// withdrawals that were in flight before a restart are followed until the exchange confirms them
+		transfer.DefaultTracker().Resume(ds.instance)
+		// as are transfers between exchanges
+		transfer.Transfers().Resume(ds.instance)
 		ds.initialized = true
 		log.Info().Msg("Created dealer instance")
 	}
 	return ds.instance, nil
 }
 
+// newBuilder returns the builder of the dealer, every order has to pass the risk limits of the configuration.
+// Trading and transfers are recorded in the audit log, no dealer is built when it can't be opened.
+func newBuilder(orders dealer.OrderStore) (*dealer.Builder, *risk.Engine, error) {
+	l, err := audit.Default()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	limits := risk.New(risk.LoadConfig())
+	builder := dealer.NewBuilder().Orders(orders, 0).Reporter(metrics.Default()).Risk(limits).Auditor(l)
+	return builder, limits, nil
+}
+
 func (ds *DealerSingleton) isDealerInitialized() bool {
 	return ds.initialized
 }
@@ -2,8 +2,21 @@ package singleton
 
 import (
 	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
 	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/risk"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/mock"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/ticker"
 )
 
 // MockDealerBuilder is an autogenerated mock type for the Dealer
@@ -20,3 +33,56 @@ func (m *MockDealerBuilder) Build(ctx context.Context) (*dealer.Dealer, error) {
 	args := m.Called()
 	return args.Get(0).(*dealer.Dealer), args.Error(1)
 }
+
+// orderExchange prices BTC at 100 and accepts every order
+type orderExchange struct {
+	exchange.IBotExchange
+	orders int
+}
+
+func (*orderExchange) GetName() string {
+	return "singletontest"
+}
+
+func (*orderExchange) GetBase() *exchange.Base {
+	return &exchange.Base{Name: "singletontest"}
+}
+
+func (*orderExchange) FetchTicker(ctx context.Context, p currency.Pair, a asset.Item) (*ticker.Price, error) {
+	return &ticker.Price{Pair: p, AssetType: a, Bid: 99, Ask: 101, Last: 100, LastUpdated: time.Now()}, nil
+}
+
+func (e *orderExchange) SubmitOrder(ctx context.Context, s *order.Submit) (*order.SubmitResponse, error) {
+	e.orders++
+	return &order.SubmitResponse{OrderID: strconv.Itoa(e.orders), Pair: s.Pair, AssetType: s.AssetType, Side: s.Side, Price: s.Price, Amount: s.Amount, Status: order.New}, nil
+}
+
+func TestBuilderRiskLimits(t *testing.T) {
+	t.Setenv("AUDIT_LOG", filepath.Join(t.TempDir(), "audit.jsonl"))
+	viper.Set("RISK_MAX_NOTIONAL", 1000)
+	defer viper.Set("RISK_MAX_NOTIONAL", nil)
+
+	builder, _, err := newBuilder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &orderExchange{}
+	d, err := builder.Balances(0).BuildWithExchanges(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := order.Submit{Pair: currency.NewPair(currency.BTC, currency.USDT), AssetType: asset.Spot, Side: order.Buy, Type: order.Limit, Price: 100}
+	s.Amount = 11
+	if _, err = d.SubmitOrder(context.Background(), e, s); !errors.Is(err, risk.ErrMaxNotional) {
+		t.Errorf("expected: %v, actual: %v", risk.ErrMaxNotional, err)
+	}
+
+	s.Amount = 9
+	if _, err = d.SubmitOrder(context.Background(), e, s); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+	if e.orders != 1 {
+		t.Errorf("expected: %v, actual: %v", 1, e.orders)
+	}
+}
@@ -0,0 +1,93 @@
+package transfer
+
+import (
+	"context"
+
+	"github.com/romanornr/autodealer/addressbook"
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/portfolio/withdraw"
+)
+
+// BookWithdrawResponse is a withdrawal to an entry of the address book, the response of the exchange is empty while the
+// withdrawal waits for approval
+type BookWithdrawResponse struct {
+	Withdrawal addressbook.Withdrawal    `json:"withdrawal"`
+	Response   *ExchangeWithdrawResponse `json:"response,omitempty"`
+}
+
+// WithdrawToBook withdraws the currency to a destination of the address book, by entry name or by address and chain.
+// Destinations that are not whitelisted or still cooling off are refused, large withdrawals are held until ApproveWithdrawal.
+func WithdrawToBook(ctx context.Context, d *dealer.Dealer, book *addressbook.Book, e exchange.IBotExchange, code currency.Code, chain, destination string, amount float64) (BookWithdrawResponse, error) {
+	entry, err := book.Resolve(code, chain, destination)
+	if err != nil {
+		return BookWithdrawResponse{}, err
+	}
+
+	w, err := book.Request(e.GetName(), entry, amount, dealer.Initiator(ctx))
+	if err != nil {
+		return BookWithdrawResponse{Withdrawal: w}, err
+	}
+	if w.Status == addressbook.StatusPending {
+		d.Audit(ctx, dealer.AuditEntry{Action: dealer.AuditApproval, Exchange: w.Exchange, Request: w})
+		return BookWithdrawResponse{Withdrawal: w}, nil
+	}
+	return executeBookWithdrawal(ctx, d, book, e, w)
+}
+
+// ApproveWithdrawal approves a pending withdrawal and executes it
+func ApproveWithdrawal(ctx context.Context, d *dealer.Dealer, book *addressbook.Book, id string) (BookWithdrawResponse, error) {
+	w, err := book.Approve(id, dealer.Initiator(ctx))
+	d.Audit(ctx, dealer.AuditEntry{Action: dealer.AuditApproval, Exchange: w.Exchange, Request: id, Response: w, Error: dealer.AuditError(err)})
+	if err != nil {
+		return BookWithdrawResponse{Withdrawal: w}, err
+	}
+
+	e, err := d.GetExchangeByName(w.Exchange)
+	if err != nil {
+		return BookWithdrawResponse{Withdrawal: w}, err
+	}
+	return executeBookWithdrawal(ctx, d, book, e, w)
+}
+
+// RejectWithdrawal rejects a pending withdrawal, it is never executed
+func RejectWithdrawal(ctx context.Context, d *dealer.Dealer, book *addressbook.Book, id string) (BookWithdrawResponse, error) {
+	w, err := book.Reject(id, dealer.Initiator(ctx))
+	d.Audit(ctx, dealer.AuditEntry{Action: dealer.AuditApproval, Exchange: w.Exchange, Request: id, Response: w, Error: dealer.AuditError(err)})
+	return BookWithdrawResponse{Withdrawal: w}, err
+}
+
+func executeBookWithdrawal(ctx context.Context, d *dealer.Dealer, book *addressbook.Book, e exchange.IBotExchange, w addressbook.Withdrawal) (BookWithdrawResponse, error) {
+	// the network of the entry by the name the exchange uses for it
+	chain := w.Entry.Chain
+	if chains, err := e.GetAvailableTransferChains(ctx, w.Entry.Currency); err == nil && chain != "" {
+		if c := FindChain(w.Entry.Currency, chains, chain); c != "" {
+			chain = c
+		}
+	}
+
+	withdrawRequest := &withdraw.Request{
+		Exchange: e.GetName(),
+		Currency: w.Entry.Currency,
+		Amount:   w.Amount,
+		Type:     withdraw.Crypto,
+		Crypto: withdraw.CryptoRequest{
+			Address:    w.Entry.Address,
+			AddressTag: w.Entry.Tag,
+			Chain:      chain,
+		},
+	}
+
+	response, err := CreateExchangeWithdrawResponse(ctx, d, withdrawRequest, e)
+	if completeErr := book.Complete(w.ID, err); completeErr != nil {
+		logrus.Errorf("failed to record withdrawal %s in the address book: %s\n", w.ID, completeErr)
+	}
+
+	w.Status = addressbook.StatusExecuted
+	if err != nil {
+		w.Status, w.Error = addressbook.StatusFailed, err.Error()
+	}
+	return BookWithdrawResponse{Withdrawal: w, Response: &response}, err
+}
@@ -0,0 +1,308 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/binance"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/okx"
+)
+
+var (
+	ErrNoCommonChain     = errors.New("exchanges have no network in common")
+	ErrChainNotSupported = errors.New("network is not supported by both exchanges")
+)
+
+// Network is a blockchain network, the names exchanges use for it are its aliases
+type Network struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	// Confirmation is the usual time until a deposit on the network is credited
+	Confirmation time.Duration `json:"confirmation"`
+}
+
+// networks are the networks the resolver knows, names it does not know are compared as they are
+var networks = []Network{
+	{Name: "BTC", Aliases: []string{"bitcoin"}, Confirmation: time.Hour},
+	{Name: "ETH", Aliases: []string{"erc20", "ethereum"}, Confirmation: 5 * time.Minute},
+	{Name: "TRX", Aliases: []string{"trc20", "tron"}, Confirmation: 3 * time.Minute},
+	{Name: "BSC", Aliases: []string{"bep20", "bnbsmartchain", "binancesmartchain"}, Confirmation: 3 * time.Minute},
+	{Name: "BNB", Aliases: []string{"bep2", "bnbbeaconchain"}, Confirmation: 2 * time.Minute},
+	{Name: "SOL", Aliases: []string{"solana", "spl"}, Confirmation: time.Minute},
+	{Name: "MATIC", Aliases: []string{"polygon"}, Confirmation: 5 * time.Minute},
+	{Name: "ARBITRUM", Aliases: []string{"arb", "arbitrumone"}, Confirmation: 2 * time.Minute},
+	{Name: "OPTIMISM", Aliases: []string{"op"}, Confirmation: 2 * time.Minute},
+	{Name: "AVAXC", Aliases: []string{"avaxcchain", "avalanchec", "avalanchecchain", "cchain"}, Confirmation: time.Minute},
+	{Name: "LTC", Aliases: []string{"litecoin"}, Confirmation: 30 * time.Minute},
+	{Name: "DOGE", Aliases: []string{"dogecoin"}, Confirmation: 20 * time.Minute},
+	{Name: "XRP", Aliases: []string{"ripple"}, Confirmation: time.Minute},
+	{Name: "XLM", Aliases: []string{"stellar"}, Confirmation: time.Minute},
+	{Name: "ALGO", Aliases: []string{"algorand"}, Confirmation: time.Minute},
+	{Name: "ATOM", Aliases: []string{"cosmos"}, Confirmation: time.Minute},
+	{Name: "DOT", Aliases: []string{"polkadot"}, Confirmation: 2 * time.Minute},
+	{Name: "NEAR", Aliases: []string{"nearprotocol"}, Confirmation: time.Minute},
+	{Name: "TON", Aliases: []string{"toncoin"}, Confirmation: time.Minute},
+}
+
+var (
+	networksMu sync.RWMutex
+	// networkIndex maps the normalised names and aliases to the networks
+	networkIndex = make(map[string]Network)
+)
+
+func init() {
+	for _, n := range networks {
+		RegisterNetwork(n)
+	}
+	RegisterChainFees("binance", binanceChainFees)
+	RegisterChainFees("okx", okxChainFees)
+}
+
+// RegisterNetwork adds the network, or replaces the one with the same name
+func RegisterNetwork(n Network) {
+	networksMu.Lock()
+	defer networksMu.Unlock()
+
+	n.Name = strings.ToUpper(n.Name)
+	networkIndex[chainKey(n.Name)] = n
+	for _, alias := range n.Aliases {
+		networkIndex[chainKey(alias)] = n
+	}
+}
+
+// chainKey is the name in lower case without spaces, dashes or other punctuation
+func chainKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// lookupNetwork returns the network of a chain name. Some exchanges prefix the chain with the currency, such as USDT-TRC20 on OKX.
+func lookupNetwork(code currency.Code, chain string) (Network, bool) {
+	if prefix := code.String() + "-"; !code.IsEmpty() && len(chain) > len(prefix) && strings.EqualFold(chain[:len(prefix)], prefix) {
+		chain = chain[len(prefix):]
+	}
+
+	networksMu.RLock()
+	defer networksMu.RUnlock()
+
+	n, ok := networkIndex[chainKey(chain)]
+	if !ok {
+		return Network{Name: strings.ToUpper(chainKey(chain))}, false
+	}
+	return n, true
+}
+
+// NormaliseChain returns the name of the network an exchange calls chain, the same on every exchange: ERC20, erc20,
+// Ethereum and USDT-ERC20 are all ETH.
+func NormaliseChain(code currency.Code, chain string) string {
+	n, _ := lookupNetwork(code, chain)
+	return n.Name
+}
+
+// FindChain returns the chain of the exchange that is the requested network, empty when none of them is
+func FindChain(code currency.Code, chains []string, requested string) string {
+	network := NormaliseChain(code, requested)
+	if network == "" {
+		return ""
+	}
+	for _, c := range chains {
+		if NormaliseChain(code, c) == network {
+			return c
+		}
+	}
+	return ""
+}
+
+// ChainFee is what a withdrawal of a currency on a chain of an exchange costs
+type ChainFee struct {
+	Chain     string  `json:"chain"`
+	Fee       float64 `json:"fee"`
+	MinAmount float64 `json:"minAmount"`
+	Withdraw  bool    `json:"withdraw"`
+	Deposit   bool    `json:"deposit"`
+}
+
+// ChainFeeSource returns the fees per chain of the currency on the exchange
+type ChainFeeSource func(ctx context.Context, e exchange.IBotExchange, code currency.Code) ([]ChainFee, error)
+
+var chainFeeSources sync.Map
+
+// RegisterChainFees sets the fee source of the exchange, names are case insensitive
+func RegisterChainFees(exchangeName string, f ChainFeeSource) {
+	chainFeeSources.Store(strings.ToLower(exchangeName), f)
+}
+
+// chainFees returns the fees of the exchange by network, nil when the exchange has no fee source
+func chainFees(ctx context.Context, e exchange.IBotExchange, code currency.Code) map[string]ChainFee {
+	f, ok := chainFeeSources.Load(strings.ToLower(e.GetName()))
+	if !ok {
+		return nil
+	}
+
+	fees, err := f.(ChainFeeSource)(ctx, e, code)
+	if err != nil {
+		logrus.Warnf("%s %s chain fees: %s\n", e.GetName(), code, err)
+		return nil
+	}
+	byNetwork := make(map[string]ChainFee, len(fees))
+	for _, fee := range fees {
+		byNetwork[NormaliseChain(code, fee.Chain)] = fee
+	}
+	return byNetwork
+}
+
+// ChainOption is a network the currency can be moved on from one exchange to another
+type ChainOption struct {
+	Network string `json:"network"`
+	// Source and Destination are the names of the network on each exchange, empty is the default network of the exchange
+	Source       string        `json:"source"`
+	Destination  string        `json:"destination"`
+	Fee          float64       `json:"fee"`
+	FeeKnown     bool          `json:"feeKnown"`
+	MinAmount    float64       `json:"minAmount"`
+	Confirmation time.Duration `json:"confirmation"`
+}
+
+// ResolveChains returns the networks the source can withdraw the currency on and the destination can receive it on, cheapest
+// first. Options without a known fee come last, ties go to the network that confirms first. When an exchange does not list
+// its networks the default networks of both exchanges are the only option.
+func ResolveChains(ctx context.Context, src, dst exchange.IBotExchange, code currency.Code) ([]ChainOption, error) {
+	srcChains, err := src.GetAvailableTransferChains(ctx, code)
+	if err != nil {
+		logrus.Warnf("%s %s transfer chains: %s\n", src.GetName(), code, err)
+	}
+	dstChains, err := dst.GetAvailableTransferChains(ctx, code)
+	if err != nil {
+		logrus.Warnf("%s %s transfer chains: %s\n", dst.GetName(), code, err)
+	}
+	if len(srcChains) == 0 || len(dstChains) == 0 {
+		return []ChainOption{{}}, nil
+	}
+
+	srcFees := chainFees(ctx, src, code)
+	dstFees := chainFees(ctx, dst, code)
+
+	var options []ChainOption
+	for _, s := range srcChains {
+		network, _ := lookupNetwork(code, s)
+		d := FindChain(code, dstChains, s)
+		if d == "" {
+			continue
+		}
+
+		option := ChainOption{Network: network.Name, Source: s, Destination: d, Confirmation: network.Confirmation}
+		if fee, ok := srcFees[network.Name]; ok {
+			if !fee.Withdraw {
+				continue
+			}
+			option.Fee, option.MinAmount, option.FeeKnown = fee.Fee, fee.MinAmount, true
+		}
+		if fee, ok := dstFees[network.Name]; ok && !fee.Deposit {
+			continue
+		}
+		options = append(options, option)
+	}
+	if len(options) == 0 {
+		return nil, fmt.Errorf("%w: %s %v and %s %v", ErrNoCommonChain, src.GetName(), srcChains, dst.GetName(), dstChains)
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		a, b := options[i], options[j]
+		switch {
+		case a.FeeKnown != b.FeeKnown:
+			return a.FeeKnown
+		case a.Fee != b.Fee:
+			return a.Fee < b.Fee
+		case a.Confirmation != b.Confirmation:
+			// networks without a known confirmation time go last
+			return b.Confirmation == 0 || (a.Confirmation != 0 && a.Confirmation < b.Confirmation)
+		}
+		return a.Network < b.Network
+	})
+	return options, nil
+}
+
+// SelectChain picks the option for the amount. The requested network is used when both exchanges support it, otherwise the
+// cheapest option whose minimum the amount meets.
+func SelectChain(options []ChainOption, code currency.Code, requested string, amount float64) (ChainOption, error) {
+	if requested != "" {
+		network := NormaliseChain(code, requested)
+		for _, o := range options {
+			// exchanges that don't list their networks are given the request as it is
+			if o.Network == "" {
+				return ChainOption{Network: network, Source: requested, Destination: requested}, nil
+			}
+			if o.Network == network {
+				return o, nil
+			}
+		}
+		return ChainOption{}, fmt.Errorf("%w: %s", ErrChainNotSupported, requested)
+	}
+
+	for _, o := range options {
+		if amount >= o.MinAmount && amount > o.Fee {
+			return o, nil
+		}
+	}
+	return ChainOption{}, fmt.Errorf("%w: %f %s is below the minimum withdrawal of every network", ErrNoCommonChain, amount, code)
+}
+
+// binanceChainFees reads the fees from the coin information of Binance
+func binanceChainFees(ctx context.Context, e exchange.IBotExchange, code currency.Code) ([]ChainFee, error) {
+	b, ok := e.(*binance.Binance)
+	if !ok {
+		return nil, nil
+	}
+	coins, err := b.GetAllCoinsInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fees []ChainFee
+	for _, coin := range coins {
+		if !strings.EqualFold(coin.Coin, code.String()) {
+			continue
+		}
+		for _, n := range coin.NetworkList {
+			fees = append(fees, ChainFee{Chain: n.Network, Fee: n.WithdrawFee, MinAmount: n.WithdrawMinimum, Withdraw: n.WithdrawEnable, Deposit: n.DepositEnable})
+		}
+	}
+	return fees, nil
+}
+
+// okxChainFees reads the fees from the funding currencies of OKX
+func okxChainFees(ctx context.Context, e exchange.IBotExchange, code currency.Code) ([]ChainFee, error) {
+	o, ok := e.(*okx.Okx)
+	if !ok {
+		return nil, nil
+	}
+	currencies, err := o.GetFundingCurrencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fees []ChainFee
+	for _, c := range currencies {
+		if !strings.EqualFold(c.Currency, code.String()) {
+			continue
+		}
+		// the minimum is left out when OKX does not report it
+		minAmount, _ := strconv.ParseFloat(c.MinWithdrawal, 64)
+		fees = append(fees, ChainFee{Chain: c.Chain, Fee: float64(c.MinFee), MinAmount: minAmount, Withdraw: c.CanWithdraw, Deposit: c.CanDeposit})
+	}
+	return fees, nil
+}
@@ -0,0 +1,98 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+)
+
+func TestNormaliseChain(t *testing.T) {
+	cases := []struct {
+		chain    string
+		expected string
+	}{
+		{"ETH", "ETH"},
+		{"erc20", "ETH"},
+		{"Ethereum", "ETH"},
+		{"USDT-ERC20", "ETH"},
+		{"USDT-Arbitrum one", "ARBITRUM"},
+		{"BEP20", "BSC"},
+		{"AVAX C-Chain", "AVAXC"},
+		{"trx", "TRX"},
+		{"zksync", "ZKSYNC"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if actual := NormaliseChain(currency.USDT, c.chain); actual != c.expected {
+			t.Errorf("%s expected: %v, actual: %v", c.chain, c.expected, actual)
+		}
+	}
+
+	if actual := FindChain(currency.USDT, []string{"USDT-TRC20", "USDT-ERC20"}, "erc20"); actual != "USDT-ERC20" {
+		t.Errorf("expected: %v, actual: %v", "USDT-ERC20", actual)
+	}
+}
+
+func TestResolveChains(t *testing.T) {
+	src := &chainExchange{name: "feesource", chains: []string{"ETH", "TRX", "BSC", "SOL", "MATIC"}}
+	dst := &chainExchange{name: "feedestination", chains: []string{"USDT-ERC20", "USDT-TRC20", "USDT-BEP20", "USDT-Polygon", "USDT-Solana"}}
+
+	RegisterChainFees(src.name, func(ctx context.Context, e exchange.IBotExchange, code currency.Code) ([]ChainFee, error) {
+		return []ChainFee{
+			{Chain: "ETH", Fee: 5, MinAmount: 10, Withdraw: true, Deposit: true},
+			{Chain: "TRX", Fee: 1, MinAmount: 10, Withdraw: true, Deposit: true},
+			{Chain: "BSC", Fee: 0.3, MinAmount: 50, Withdraw: true, Deposit: true},
+			{Chain: "SOL", Fee: 0.1, MinAmount: 1, Withdraw: false, Deposit: true},
+		}, nil
+	})
+
+	options, err := ResolveChains(context.Background(), src, dst, currency.USDT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SOL is not withdrawable, MATIC has no known fee
+	var networks []string
+	for _, o := range options {
+		networks = append(networks, o.Network)
+	}
+	expected := []string{"BSC", "TRX", "ETH", "MATIC"}
+	if len(networks) != len(expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, networks)
+	}
+	for i := range expected {
+		if networks[i] != expected[i] {
+			t.Fatalf("expected: %v, actual: %v", expected, networks)
+		}
+	}
+	if options[0].Source != "BSC" || options[0].Destination != "USDT-BEP20" || options[0].Confirmation != 3*time.Minute {
+		t.Errorf("expected: BSC to USDT-BEP20, actual: %+v", options[0])
+	}
+
+	// below the minimum of BSC the next cheapest network is used
+	o, err := SelectChain(options, currency.USDT, "", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Network != "TRX" {
+		t.Errorf("expected: %v, actual: %v", "TRX", o.Network)
+	}
+
+	if o, err = SelectChain(options, currency.USDT, "erc20", 20); err != nil || o.Destination != "USDT-ERC20" {
+		t.Errorf("expected: %v, actual: %v %v", "USDT-ERC20", o.Destination, err)
+	}
+	if _, err = SelectChain(options, currency.USDT, "sol", 20); !errors.Is(err, ErrChainNotSupported) {
+		t.Errorf("expected: %v, actual: %v", ErrChainNotSupported, err)
+	}
+	// without a known minimum the network is tried
+	if o, err = SelectChain(options, currency.USDT, "", 0.5); err != nil || o.Network != "MATIC" {
+		t.Errorf("expected: %v, actual: %v %v", "MATIC", o.Network, err)
+	}
+	if _, err = SelectChain(options[:3], currency.USDT, "", 0.5); !errors.Is(err, ErrNoCommonChain) {
+		t.Errorf("expected: %v, actual: %v", ErrNoCommonChain, err)
+	}
+}
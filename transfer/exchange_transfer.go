@@ -0,0 +1,356 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/portfolio/withdraw"
+)
+
+const (
+	// DefaultDepositInterval is how often the holdings of the destination are checked for the deposit
+	DefaultDepositInterval = 30 * time.Second
+	// DefaultDepositTimeout is how long a job waits for the deposit
+	DefaultDepositTimeout = 24 * time.Hour
+	// depositTolerance is the part of the amount that may be missing from the deposit, for fees that are not known up front
+	depositTolerance = 0.05
+)
+
+var (
+	ErrInvalidTransfer   = errors.New("transfer needs two different exchanges and an amount")
+	ErrWithdrawalUnknown = errors.New("withdrawal was being sent when the job stopped, check the withdrawal history of the source")
+	ErrDepositTimeout    = errors.New("deposit did not arrive")
+)
+
+// Transferer runs transfer jobs between the exchanges of the dealer. The withdrawal goes to the deposit address the destination
+// exchange hands out, it does not go through the address book.
+type Transferer struct {
+	Store JobStore
+	// Interval is how often the destination is checked for the deposit
+	Interval time.Duration
+	// Timeout is how long the deposit is waited for after the withdrawal
+	Timeout time.Duration
+
+	now     func() time.Time
+	balance func(d *dealer.Dealer, e exchange.IBotExchange, code currency.Code) (float64, error)
+	running sync.Map
+}
+
+// NewTransferer returns a transferer keeping its jobs in the store
+func NewTransferer(store JobStore) *Transferer {
+	return &Transferer{
+		Store:    store,
+		Interval: DefaultDepositInterval,
+		Timeout:  DefaultDepositTimeout,
+		now:      time.Now,
+		balance:  holdingsBalance,
+	}
+}
+
+var (
+	transferer     *Transferer
+	transfererOnce sync.Once
+)
+
+// Transfers returns the transferer shared by the application, its jobs are kept in Jobs
+func Transfers() *Transferer {
+	transfererOnce.Do(func() {
+		transferer = NewTransferer(Jobs())
+	})
+	return transferer
+}
+
+// Start creates a job moving the amount of the currency from the source to the destination and runs it in the background.
+// An empty chain lets the job pick a network both exchanges support.
+func (t *Transferer) Start(d *dealer.Dealer, source, destination string, code currency.Code, amount float64, chain string) (*Job, error) {
+	if source == "" || destination == "" || strings.EqualFold(source, destination) || code.IsEmpty() || amount <= 0 {
+		return nil, ErrInvalidTransfer
+	}
+
+	src, err := d.GetExchangeByName(source)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := d.GetExchangeByName(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	j := NewJob(src.GetName(), dst.GetName(), code, amount, chain)
+	if err = t.Store.Save(context.Background(), j); err != nil {
+		return nil, err
+	}
+
+	go t.run(d, j.ID)
+	return j, nil
+}
+
+// Resume runs the jobs that had not finished when the application stopped
+func (t *Transferer) Resume(d *dealer.Dealer) {
+	jobs, err := t.Store.List(context.Background())
+	if err != nil {
+		logrus.Errorf("failed to resume transfers: %s\n", err)
+		return
+	}
+	for _, j := range jobs {
+		if !j.Stage.Final() {
+			go t.run(d, j.ID)
+		}
+	}
+}
+
+// run takes the job through its stages, the job is saved after each of them
+func (t *Transferer) run(d *dealer.Dealer, id string) {
+	if _, running := t.running.LoadOrStore(id, true); running {
+		return
+	}
+	defer t.running.Delete(id)
+
+	ctx := context.Background()
+	j, err := t.Store.Get(ctx, id)
+	if err != nil {
+		logrus.Errorf("transfer %s: %s\n", id, err)
+		return
+	}
+
+	for !j.Stage.Final() {
+		stage := j.Stage
+		if err = t.step(ctx, d, j); err != nil {
+			j.fail(t.now().UTC(), err)
+		}
+		if err = t.Store.Save(ctx, j); err != nil {
+			logrus.Errorf("failed to save transfer %s: %s\n", j.ID, err)
+		}
+
+		// the job is waiting for the deposit
+		if j.Stage == stage {
+			time.Sleep(t.Interval)
+		}
+	}
+}
+
+// step moves the job to its next stage, or leaves it where it is while it waits for the deposit
+func (t *Transferer) step(ctx context.Context, d *dealer.Dealer, j *Job) error {
+	src, err := d.GetExchangeByName(j.Source)
+	if err != nil {
+		return err
+	}
+	dst, err := d.GetExchangeByName(j.Destination)
+	if err != nil {
+		return err
+	}
+
+	switch j.Stage {
+	case StageCreated:
+		return t.depositAddress(ctx, src, dst, j)
+	case StageDepositAddress:
+		j.StartBalance, err = t.balance(d, dst, j.Currency)
+		if errors.Is(err, dealer.ErrHoldingsNotFound) {
+			// the holdings are fetched shortly after a start, unless the destination doesn't report any
+			if t.now().Sub(j.UpdatedAt) >= t.Timeout {
+				return fmt.Errorf("%w after %s", err, t.Timeout)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// saved before sending, a restart from here must not send the withdrawal again
+		j.advance(StageWithdrawing, t.now().UTC(), "")
+		if err = t.Store.Save(ctx, j); err != nil {
+			return err
+		}
+		return t.withdraw(ctx, d, src, j)
+	case StageWithdrawing:
+		return ErrWithdrawalUnknown
+	case StageWithdrawn:
+		return t.awaitDeposit(ctx, d, dst, j)
+	}
+	return nil
+}
+
+// depositAddress fetches the address of the destination on the cheapest network both exchanges support
+func (t *Transferer) depositAddress(ctx context.Context, src, dst exchange.IBotExchange, j *Job) error {
+	options, err := ResolveChains(ctx, src, dst, j.Currency)
+	if err != nil {
+		return err
+	}
+	option, err := SelectChain(options, j.Currency, j.Chain, j.Amount)
+	if err != nil {
+		return err
+	}
+	j.Network, j.SourceChain, j.DestinationChain, j.Fee = option.Network, option.Source, option.Destination, option.Fee
+
+	address, err := dst.GetDepositAddress(ctx, j.Currency, "", j.DestinationChain)
+	if err != nil {
+		return fmt.Errorf("%s deposit address: %w", dst.GetName(), err)
+	}
+	j.Address, j.Tag = address.Address, address.Tag
+
+	j.advance(StageDepositAddress, t.now().UTC(), fmt.Sprintf("%s on %s", j.Address, j.DestinationChain))
+	return nil
+}
+
+// withdraw sends the amount from the source to the deposit address
+func (t *Transferer) withdraw(ctx context.Context, d *dealer.Dealer, src exchange.IBotExchange, j *Job) error {
+	request := &withdraw.Request{
+		Exchange:    src.GetName(),
+		Currency:    j.Currency,
+		Description: "transfer " + j.ID,
+		Amount:      j.Amount,
+		Type:        withdraw.Crypto,
+		Crypto: withdraw.CryptoRequest{
+			Address:    j.Address,
+			AddressTag: j.Tag,
+			Chain:      j.SourceChain,
+		},
+	}
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validation error withdraw request: %w", err)
+	}
+
+	response, err := CreateExchangeWithdrawResponse(ctx, d, request, src)
+	if err != nil {
+		return err
+	}
+
+	j.Withdrawal = response.Tracking
+	var id string
+	if response.ExchangeResponse != nil {
+		id = response.ExchangeResponse.ID
+	}
+	j.advance(StageWithdrawn, t.now().UTC(), id)
+	return nil
+}
+
+// awaitDeposit checks whether the deposit arrived on the destination. The deposit history of the destination is used when the
+// exchange provides it, otherwise the spot balance is compared with the one before the withdrawal, which trades in the currency
+// can throw off. The fee is the one of the network, or the one the source reports for the tracked withdrawal, without either
+// some of the amount may be missing.
+func (t *Transferer) awaitDeposit(ctx context.Context, d *dealer.Dealer, dst exchange.IBotExchange, j *Job) error {
+	var record WithdrawalRecord
+	if j.Withdrawal != nil {
+		// the tracker key is stable, the ID may only be filled in once the withdrawal shows up in the history of the source
+		var ok bool
+		if record, ok = DefaultTracker().Get(j.Withdrawal.Key); !ok {
+			if j.Withdrawal.ID != "" {
+				record, ok = DefaultTracker().Record(j.Source, j.Withdrawal.ID)
+			}
+			if !ok {
+				record = *j.Withdrawal
+			}
+		}
+		j.Withdrawal = &record
+	}
+	if record.Status == WithdrawalFailed || record.Status == WithdrawalCancelled {
+		return fmt.Errorf("withdrawal %s is %s on %s", record.ID, record.Status, j.Source)
+	}
+
+	fee := j.Fee
+	if record.Fee != 0 {
+		fee = record.Fee
+	}
+	net := j.Amount - fee
+	expected := net * (1 - depositTolerance)
+
+	if history, err := dst.GetAccountFundingHistory(ctx); err == nil {
+		if h, ok := t.findDeposit(ctx, history, j, record, net); ok {
+			j.DepositID, j.Received = h.TransferID, h.Amount
+			j.advance(StageDeposited, t.now().UTC(), fmt.Sprintf("received %f in %s", j.Received, h.TransferID))
+			return nil
+		}
+	} else {
+		balance, err := t.balance(d, dst, j.Currency)
+		if err != nil {
+			logrus.Errorf("transfer %s: %s\n", j.ID, err)
+		}
+		j.Received = balance - j.StartBalance
+
+		if j.Received > 0 && (j.Received >= expected || record.Status == WithdrawalCompleted) {
+			j.advance(StageDeposited, t.now().UTC(), fmt.Sprintf("received %f", j.Received))
+			return nil
+		}
+	}
+
+	if t.now().Sub(j.UpdatedAt) >= t.Timeout {
+		return fmt.Errorf("%w after %s", ErrDepositTimeout, t.Timeout)
+	}
+	return nil
+}
+
+// findDeposit returns the deposit of the job in the funding history of the destination. It is matched by transaction ID when
+// the withdrawal has one, otherwise by currency, deposit address and the net amount within the tolerance after the withdrawal
+// was sent. Only deposits with a transfer ID can be claimed, deposits claimed by other jobs and deposits that failed are left out.
+func (t *Transferer) findDeposit(ctx context.Context, history []exchange.FundingHistory, j *Job, w WithdrawalRecord, net float64) (exchange.FundingHistory, bool) {
+	jobs, err := t.Store.List(ctx)
+	if err != nil {
+		logrus.Errorf("transfer %s: %s\n", j.ID, err)
+		return exchange.FundingHistory{}, false
+	}
+
+	claimed := make(map[string]bool)
+	for _, other := range jobs {
+		if other.ID != j.ID && other.Destination == j.Destination && other.DepositID != "" {
+			claimed[other.DepositID] = true
+		}
+	}
+
+	since := j.UpdatedAt
+	if !w.Time.IsZero() {
+		since = w.Time
+	}
+
+	for _, h := range history {
+		if !strings.Contains(strings.ToLower(h.TransferType), "deposit") || !currency.NewCode(h.Currency).Equal(j.Currency) {
+			continue
+		}
+		if h.TransferID == "" || claimed[h.TransferID] {
+			continue
+		}
+		if status := NormaliseStatus("", h.Status); status == WithdrawalFailed || status == WithdrawalCancelled {
+			continue
+		}
+
+		if w.TxID != "" && h.CryptoTxID != "" {
+			if strings.EqualFold(w.TxID, h.CryptoTxID) {
+				return h, true
+			}
+			continue
+		}
+		if h.Timestamp.Before(since.Add(-time.Minute)) || math.Abs(h.Amount-net) > net*depositTolerance {
+			continue
+		}
+		if h.CryptoToAddress == "" || j.Address == "" || h.CryptoToAddress == j.Address {
+			return h, true
+		}
+	}
+	return exchange.FundingHistory{}, false
+}
+
+// holdingsBalance returns the spot balance of the currency in the holdings of the exchange, over all its accounts
+func holdingsBalance(d *dealer.Dealer, e exchange.IBotExchange, code currency.Code) (float64, error) {
+	h, err := dealer.Holdings(d, e.GetName())
+	if err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for _, a := range h.Accounts {
+		for c, b := range a.Balances[asset.Spot] {
+			if c.Equal(code) {
+				total += b.TotalValue
+			}
+		}
+	}
+	return total, nil
+}
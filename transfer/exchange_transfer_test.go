@@ -0,0 +1,194 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/thrasher-corp/gocryptotrader/common"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/deposit"
+	"github.com/thrasher-corp/gocryptotrader/portfolio/withdraw"
+)
+
+// chainExchange supports some networks, hands out deposit addresses and keeps the withdrawals
+type chainExchange struct {
+	exchange.IBotExchange
+
+	name      string
+	chains    []string
+	withdrawn []withdraw.Request
+	// deposits is the funding history, without it the exchange doesn't provide one
+	deposits []exchange.FundingHistory
+}
+
+func (e *chainExchange) GetName() string {
+	return e.name
+}
+
+func (e *chainExchange) GetBase() *exchange.Base {
+	return &exchange.Base{Name: e.name}
+}
+
+func (e *chainExchange) GetAvailableTransferChains(ctx context.Context, code currency.Code) ([]string, error) {
+	return e.chains, nil
+}
+
+func (e *chainExchange) GetDepositAddress(ctx context.Context, code currency.Code, accountID, chain string) (*deposit.Address, error) {
+	return &deposit.Address{Address: e.name + "-" + chain, Tag: "memo", Chain: chain}, nil
+}
+
+func (e *chainExchange) WithdrawCryptocurrencyFunds(ctx context.Context, r *withdraw.Request) (*withdraw.ExchangeResponse, error) {
+	e.withdrawn = append(e.withdrawn, *r)
+	return &withdraw.ExchangeResponse{ID: "w1"}, nil
+}
+
+func (e *chainExchange) GetAccountFundingHistory(ctx context.Context) ([]exchange.FundingHistory, error) {
+	if e.deposits == nil {
+		return nil, common.ErrFunctionNotSupported
+	}
+	return e.deposits, nil
+}
+
+func (e *chainExchange) GetWithdrawalsHistory(ctx context.Context, code currency.Code, a asset.Item) ([]exchange.WithdrawalHistory, error) {
+	return nil, common.ErrFunctionNotSupported
+}
+
+func TestTransferJob(t *testing.T) {
+	src := &chainExchange{name: "source", chains: []string{"ETH", "TRX"}}
+	dst := &chainExchange{name: "destination", chains: []string{"trx"}}
+	d, err := dealer.NewBuilder().Balances(0).BuildWithExchanges(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryJobStore()
+	transfers := NewTransferer(store)
+	transfers.Interval = time.Millisecond
+	// the deposit arrives minus a fee once the withdrawal is sent
+	transfers.balance = func(d *dealer.Dealer, e exchange.IBotExchange, code currency.Code) (float64, error) {
+		if len(src.withdrawn) == 0 {
+			return 10, nil
+		}
+		return 10 + 99.9, nil
+	}
+
+	j := NewJob(src.name, dst.name, currency.USDT, 100, "")
+	if err = store.Save(context.Background(), j); err != nil {
+		t.Fatal(err)
+	}
+	transfers.run(d, j.ID)
+
+	if j, err = store.Get(context.Background(), j.ID); err != nil {
+		t.Fatal(err)
+	}
+	if j.Stage != StageDeposited {
+		t.Fatalf("expected: %v, actual: %v %v", StageDeposited, j.Stage, j.Error)
+	}
+	if j.SourceChain != "TRX" || j.DestinationChain != "trx" || j.Address != "destination-trx" {
+		t.Errorf("expected: TRX to destination-trx, actual: %v %v %v", j.SourceChain, j.DestinationChain, j.Address)
+	}
+	if len(src.withdrawn) != 1 || src.withdrawn[0].Crypto.AddressTag != "memo" || src.withdrawn[0].Crypto.Chain != "TRX" {
+		t.Errorf("expected: one withdrawal on TRX with memo, actual: %+v", src.withdrawn)
+	}
+	var stages []Stage
+	for _, s := range j.Stages {
+		stages = append(stages, s.Stage)
+	}
+	if len(stages) != 5 || stages[2] != StageWithdrawing {
+		t.Errorf("expected: every stage reported, actual: %v", stages)
+	}
+
+	// a job that stopped while withdrawing is not sent again
+	stopped := NewJob(src.name, dst.name, currency.USDT, 100, "")
+	stopped.advance(StageWithdrawing, time.Now(), "")
+	if err = store.Save(context.Background(), stopped); err != nil {
+		t.Fatal(err)
+	}
+	transfers.run(d, stopped.ID)
+
+	if stopped, err = store.Get(context.Background(), stopped.ID); err != nil {
+		t.Fatal(err)
+	}
+	if stopped.Stage != StageFailed || len(src.withdrawn) != 1 {
+		t.Errorf("expected: failed without withdrawal, actual: %v after %d withdrawals", stopped.Stage, len(src.withdrawn))
+	}
+}
+
+func TestTransferDepositHistory(t *testing.T) {
+	src := &chainExchange{name: "source", chains: []string{"TRX"}}
+	dst := &chainExchange{name: "destination", chains: []string{"TRX"}, deposits: []exchange.FundingHistory{}}
+	d, err := dealer.NewBuilder().Balances(0).BuildWithExchanges(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryJobStore()
+	transfers := NewTransferer(store)
+	transfers.Interval = time.Millisecond
+	// the balance doesn't move, only the deposit history shows the transfer. The older deposit was there before.
+	transfers.balance = func(d *dealer.Dealer, e exchange.IBotExchange, code currency.Code) (float64, error) {
+		return 10, nil
+	}
+	dst.deposits = []exchange.FundingHistory{
+		{TransferID: "old", TransferType: "deposit", Currency: "USDT", Amount: 50,
+			CryptoToAddress: "destination-TRX", Timestamp: time.Now().Add(-time.Hour)},
+		// neither a deposit of another amount nor one without transfer ID is the transfer
+		{TransferID: "other", TransferType: "deposit", Currency: "USDT", Amount: 30,
+			CryptoToAddress: "destination-TRX", Timestamp: time.Now()},
+		{TransferType: "deposit", Currency: "USDT", Amount: 99,
+			CryptoToAddress: "destination-TRX", Timestamp: time.Now()},
+		{TransferID: "d1", TransferType: "Deposit", Currency: "usdt", Amount: 99,
+			CryptoToAddress: "destination-TRX", Timestamp: time.Now()},
+	}
+
+	j := NewJob(src.name, dst.name, currency.USDT, 100, "")
+	if err = store.Save(context.Background(), j); err != nil {
+		t.Fatal(err)
+	}
+	transfers.run(d, j.ID)
+
+	if j, err = store.Get(context.Background(), j.ID); err != nil {
+		t.Fatal(err)
+	}
+	if j.Stage != StageDeposited || j.DepositID != "d1" || j.Received != 99 {
+		t.Errorf("expected: deposit d1 of 99, actual: %v %v %v %v", j.Stage, j.DepositID, j.Received, j.Error)
+	}
+	if j.Withdrawal == nil || j.Withdrawal.Key == "" {
+		t.Errorf("expected: tracked withdrawal, actual: %+v", j.Withdrawal)
+	}
+}
+
+func TestTransferNoHoldings(t *testing.T) {
+	src := &chainExchange{name: "source", chains: []string{"TRX"}}
+	dst := &chainExchange{name: "destination", chains: []string{"TRX"}}
+	d, err := dealer.NewBuilder().Balances(0).BuildWithExchanges(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryJobStore()
+	transfers := NewTransferer(store)
+	transfers.Interval = time.Millisecond
+	transfers.Timeout = 10 * time.Millisecond
+	// the destination never reports its holdings
+	transfers.balance = func(d *dealer.Dealer, e exchange.IBotExchange, code currency.Code) (float64, error) {
+		return 0, dealer.ErrHoldingsNotFound
+	}
+
+	j := NewJob(src.name, dst.name, currency.USDT, 100, "")
+	if err = store.Save(context.Background(), j); err != nil {
+		t.Fatal(err)
+	}
+	transfers.run(d, j.ID)
+
+	if j, err = store.Get(context.Background(), j.ID); err != nil {
+		t.Fatal(err)
+	}
+	if j.Stage != StageFailed || len(src.withdrawn) != 0 {
+		t.Errorf("expected: failed without withdrawal, actual: %v after %d withdrawals", j.Stage, len(src.withdrawn))
+	}
+}
@@ -0,0 +1,51 @@
+package transfer
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/portfolio/withdraw"
+)
+
+// FiatAdapter withdraws fiat from an exchange to the bank account of the request. Exchanges differ in how a bank account
+// is referenced, an adapter translates the request into what the exchange expects.
+type FiatAdapter interface {
+	WithdrawFiat(ctx context.Context, e exchange.IBotExchange, r *withdraw.Request) (*withdraw.ExchangeResponse, error)
+}
+
+// FiatAdapterFunc is a function that is a FiatAdapter
+type FiatAdapterFunc func(ctx context.Context, e exchange.IBotExchange, r *withdraw.Request) (*withdraw.ExchangeResponse, error)
+
+// WithdrawFiat calls the function
+func (f FiatAdapterFunc) WithdrawFiat(ctx context.Context, e exchange.IBotExchange, r *withdraw.Request) (*withdraw.ExchangeResponse, error) {
+	return f(ctx, e, r)
+}
+
+var fiatAdapters sync.Map
+
+func init() {
+	RegisterFiatAdapter("kraken", FiatAdapterFunc(krakenWithdrawFiat))
+}
+
+// RegisterFiatAdapter sets the adapter of the exchange, names are case insensitive
+func RegisterFiatAdapter(exchangeName string, a FiatAdapter) {
+	fiatAdapters.Store(strings.ToLower(exchangeName), a)
+}
+
+// GetFiatAdapter returns the adapter of the exchange. Exchanges without one withdraw through the fiat withdrawal of their wrapper,
+// to an international bank when the request asks for an express wire.
+func GetFiatAdapter(exchangeName string) FiatAdapter {
+	if a, ok := fiatAdapters.Load(strings.ToLower(exchangeName)); ok {
+		return a.(FiatAdapter)
+	}
+	return FiatAdapterFunc(defaultWithdrawFiat)
+}
+
+func defaultWithdrawFiat(ctx context.Context, e exchange.IBotExchange, r *withdraw.Request) (*withdraw.ExchangeResponse, error) {
+	if r.Fiat.IsExpressWire {
+		return e.WithdrawFiatFundsToInternationalBank(ctx, r)
+	}
+	return e.WithdrawFiatFunds(ctx, r)
+}
@@ -0,0 +1,103 @@
+package transfer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+)
+
+var (
+	ErrJobNotFound = errors.New("transfer job not found")
+	ErrJobFinished = errors.New("transfer job has already finished")
+)
+
+// Stage is the progress of a transfer job
+type Stage string
+
+const (
+	StageCreated        Stage = "created"
+	StageDepositAddress Stage = "deposit_address"
+	// StageWithdrawing is saved right before the withdrawal is sent, a job found in it after a restart is not withdrawn again
+	StageWithdrawing Stage = "withdrawing"
+	StageWithdrawn   Stage = "withdrawn"
+	StageDeposited   Stage = "deposited"
+	StageFailed      Stage = "failed"
+)
+
+// Final reports whether the job has finished
+func (s Stage) Final() bool {
+	return s == StageDeposited || s == StageFailed
+}
+
+// StageReport is a stage the job went through
+type StageReport struct {
+	Stage   Stage     `json:"stage"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message,omitempty"`
+}
+
+// Job moves a currency from one exchange to another: the deposit address of the destination is fetched, the currency is
+// withdrawn from the source to it and the job waits until the deposit shows up in the holdings of the destination.
+// It is saved after every stage, so a transfer survives restarts.
+type Job struct {
+	ID          string        `json:"id"`
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	Currency    currency.Code `json:"currency"`
+	Amount      float64       `json:"amount"`
+	// Chain is the network that was asked for, empty lets the job pick one both exchanges support
+	Chain string `json:"chain"`
+	// Network is the network that was picked, SourceChain and DestinationChain are its names on each exchange
+	Network          string `json:"network"`
+	SourceChain      string `json:"sourceChain"`
+	DestinationChain string `json:"destinationChain"`
+	// Fee is the withdrawal fee of the network when the source reports it
+	Fee     float64 `json:"fee"`
+	Address string  `json:"address,omitempty"`
+	Tag     string  `json:"tag,omitempty"`
+	// StartBalance is the balance of the destination before the withdrawal, the deposit is what comes on top of it
+	StartBalance float64 `json:"startBalance"`
+	// Withdrawal is the withdrawal from the source as it was tracked when it was sent
+	Withdrawal *WithdrawalRecord `json:"withdrawal,omitempty"`
+	Received   float64           `json:"received"`
+	// DepositID is the deposit in the funding history of the destination, when the exchange provides the history
+	DepositID string        `json:"depositId,omitempty"`
+	Stage     Stage         `json:"stage"`
+	Stages    []StageReport `json:"stages"`
+	Error     string        `json:"error,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+// NewJob returns a job moving the amount of the currency from the source to the destination exchange
+func NewJob(source, destination string, code currency.Code, amount float64, chain string) *Job {
+	now := time.Now().UTC()
+	j := &Job{
+		ID:          uuid.NewString(),
+		Source:      source,
+		Destination: destination,
+		Currency:    code.Upper(),
+		Amount:      amount,
+		Chain:       chain,
+		CreatedAt:   now,
+	}
+	j.advance(StageCreated, now, "")
+	return j
+}
+
+// advance moves the job to the stage and reports it
+func (j *Job) advance(stage Stage, now time.Time, message string) {
+	j.Stage = stage
+	j.UpdatedAt = now
+	j.Stages = append(j.Stages, StageReport{Stage: stage, Time: now, Message: message})
+	logrus.Infof("transfer %s of %f %s from %s to %s: %s %s\n", j.ID, j.Amount, j.Currency, j.Source, j.Destination, stage, message)
+}
+
+// fail finishes the job with the error
+func (j *Job) fail(now time.Time, err error) {
+	j.Error = err.Error()
+	j.advance(StageFailed, now, j.Error)
+}
@@ -0,0 +1,178 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisAddr    = "127.0.0.1:6379"
+	jobKeyPrefix = "autodealer:transfer:job:"
+	jobIndexKey  = "autodealer:transfer:jobs"
+)
+
+// JobStore persists transfer jobs
+type JobStore interface {
+	Save(ctx context.Context, j *Job) error
+	// Get returns ErrJobNotFound for unknown jobs
+	Get(ctx context.Context, id string) (*Job, error)
+	// List returns all jobs, most recent first
+	List(ctx context.Context) ([]*Job, error)
+}
+
+var (
+	jobStore     JobStore
+	jobStoreOnce sync.Once
+)
+
+// Jobs returns the transfer job store shared by the application, jobs are kept in Redis next to the TWAP jobs
+func Jobs() JobStore {
+	jobStoreOnce.Do(func() {
+		jobStore = NewRedisJobStore(redis.NewClient(&redis.Options{Addr: redisAddr}))
+	})
+	return jobStore
+}
+
+// RedisJobStore persists transfer jobs in Redis. Every job is stored as a JSON document under its own key
+// and the IDs of all jobs are kept in a set.
+type RedisJobStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisJobStore returns a job store using the given redis client
+func NewRedisJobStore(client redis.UniversalClient) *RedisJobStore {
+	return &RedisJobStore{client: client}
+}
+
+// Save writes the job to redis
+func (s *RedisJobStore) Save(ctx context.Context, j *Job) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, jobKeyPrefix+j.ID, data, 0)
+		pipe.SAdd(ctx, jobIndexKey, j.ID)
+		return nil
+	})
+	return err
+}
+
+// Get loads a job by its ID
+func (s *RedisJobStore) Get(ctx context.Context, id string) (*Job, error) {
+	data, err := s.client.Get(ctx, jobKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var j Job
+	if err = json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// List loads all jobs in one round trip
+func (s *RedisJobStore) List(ctx context.Context) ([]*Job, error) {
+	ids, err := s.client.SMembers(ctx, jobIndexKey).Result()
+	if err != nil || len(ids) == 0 {
+		return []*Job{}, err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = jobKeyPrefix + id
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(values))
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var j Job
+		if err = json.Unmarshal([]byte(data), &j); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &j)
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// MemoryJobStore keeps the jobs in memory
+type MemoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewMemoryJobStore returns an empty job store
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: make(map[string]*Job)}
+}
+
+// Save stores a copy of the job
+func (s *MemoryJobStore) Save(ctx context.Context, j *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[j.ID] = j.copy()
+	return nil
+}
+
+// Get returns a copy of the job
+func (s *MemoryJobStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return j.copy(), nil
+}
+
+// List returns copies of all jobs
+func (s *MemoryJobStore) List(ctx context.Context) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.copy())
+	}
+	sortJobs(jobs)
+	return jobs, nil
+}
+
+// copy returns a copy of the job that shares nothing with it
+func (j *Job) copy() *Job {
+	x := *j
+	x.Stages = append([]StageReport{}, j.Stages...)
+	if j.Withdrawal != nil {
+		w := *j.Withdrawal
+		x.Withdrawal = &w
+	}
+	return &x
+}
+
+// sortJobs orders the jobs by creation time, most recent first
+func sortJobs(jobs []*Job) {
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].CreatedAt.After(jobs[b].CreatedAt)
+	})
+}
@@ -0,0 +1,233 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/portfolio/withdraw"
+)
+
+var (
+	ErrNoOffRamp       = errors.New("no off-ramp configured")
+	ErrNoConversion    = errors.New("no market to convert into the fiat currency")
+	ErrBelowMinimum    = errors.New("balance is below the minimum withdrawal")
+	ErrInvalidBankAcct = errors.New("bank account can't be withdrawn to")
+	ErrNotSettled      = errors.New("conversion did not show up in the balance")
+)
+
+// settleInterval and settleTimeout are how often and how long the balance is checked for the proceeds of a conversion
+var (
+	settleInterval = time.Second
+	settleTimeout  = 2 * time.Minute
+)
+
+// OffRamp moves funds from an exchange to a bank account: the source asset is optionally converted into the fiat currency first,
+// once the proceeds show up in the balance the whole fiat balance is withdrawn to the bank account. The bank account is
+// referenced by its ID in the bank accounts of the exchange configuration.
+type OffRamp struct {
+	Exchange string        `json:"exchange"`
+	Fiat     currency.Code `json:"fiat"`
+	// Source is converted into Fiat before the withdrawal, when it is empty only fiat that is already held is withdrawn
+	Source      currency.Code `json:"source"`
+	BankAccount string        `json:"bankAccount"`
+	// MinAmount is the smallest withdrawal the exchange accepts
+	MinAmount float64 `json:"minAmount"`
+	Express   bool    `json:"express"`
+}
+
+// OffRampResult is the outcome of each step of an off-ramp
+type OffRampResult struct {
+	OffRamp    OffRamp                   `json:"offRamp"`
+	Conversion *order.SubmitResponse     `json:"conversion,omitempty"`
+	Amount     float64                   `json:"amount"`
+	Withdrawal *ExchangeWithdrawResponse `json:"withdrawal,omitempty"`
+}
+
+// LoadOffRamp reads the off-ramp of the fiat currency from the application config. The keys OFFRAMP_EXCHANGE, OFFRAMP_SOURCE,
+// OFFRAMP_BANK_ACCOUNT, OFFRAMP_MIN_AMOUNT and OFFRAMP_EXPRESS apply to every fiat currency, a key with the currency in it,
+// such as OFFRAMP_EUR_BANK_ACCOUNT, applies to that currency only and takes precedence.
+func LoadOffRamp(fiat currency.Code) (OffRamp, error) {
+	key := func(name string) string {
+		if specific := "OFFRAMP_" + fiat.Upper().String() + "_" + name; viper.IsSet(specific) {
+			return specific
+		}
+		return "OFFRAMP_" + name
+	}
+
+	o := OffRamp{
+		Exchange:    viper.GetString(key("EXCHANGE")),
+		Fiat:        fiat.Upper(),
+		BankAccount: viper.GetString(key("BANK_ACCOUNT")),
+		MinAmount:   viper.GetFloat64(key("MIN_AMOUNT")),
+		Express:     viper.GetBool(key("EXPRESS")),
+	}
+	if source := viper.GetString(key("SOURCE")); source != "" {
+		o.Source = currency.NewCode(source).Upper()
+	}
+	return o, o.Validate()
+}
+
+// Validate checks that the off-ramp names an exchange, a fiat currency and a bank account
+func (o OffRamp) Validate() error {
+	var missing []string
+	if o.Exchange == "" {
+		missing = append(missing, "exchange")
+	}
+	if o.Fiat.IsEmpty() || !o.Fiat.IsFiatCurrency() {
+		missing = append(missing, "fiat currency")
+	}
+	if o.BankAccount == "" {
+		missing = append(missing, "bank account")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w for %s: %s", ErrNoOffRamp, o.Fiat, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Run executes the off-ramp. The result holds the steps that were taken, also when a later one failed.
+func (o OffRamp) Run(ctx context.Context, d *dealer.Dealer) (OffRampResult, error) {
+	result := OffRampResult{OffRamp: o}
+	if err := o.Validate(); err != nil {
+		return result, err
+	}
+
+	e, err := d.GetExchangeByName(o.Exchange)
+	if err != nil {
+		return result, err
+	}
+
+	converted := false
+	if !o.Source.IsEmpty() && !o.Source.Equal(o.Fiat) {
+		before, err := dealer.FreeBalance(ctx, e, asset.Spot, o.Fiat)
+		if err != nil {
+			return result, err
+		}
+		resp, err := Convert(ctx, d, e, o.Source, o.Fiat)
+		if err != nil {
+			return result, fmt.Errorf("failed to convert %s to %s: %w", o.Source, o.Fiat, err)
+		}
+		result.Conversion = resp
+
+		if resp != nil {
+			if result.Amount, err = settled(ctx, e, o.Fiat, before, Proceeds(resp, o.Fiat)); err != nil {
+				return result, err
+			}
+			converted = true
+		}
+	}
+
+	bank, err := d.Config.GetExchangeBankAccounts(e.GetName(), o.BankAccount, o.Fiat.String())
+	if err != nil {
+		return result, err
+	}
+	if errs := bank.ValidateForWithdrawal(e.GetName(), o.Fiat); len(errs) > 0 {
+		return result, fmt.Errorf("%w: %s", ErrInvalidBankAcct, strings.Join(errs, ", "))
+	}
+
+	if !converted {
+		if result.Amount, err = dealer.FreeBalance(ctx, e, asset.Spot, o.Fiat); err != nil {
+			return result, err
+		}
+	}
+	logrus.Infof("%s %s balance before withdraw: %f\n", e.GetName(), o.Fiat, result.Amount)
+	if result.Amount <= 0 || result.Amount < o.MinAmount {
+		return result, fmt.Errorf("%w: %f %s, minimum is %f", ErrBelowMinimum, result.Amount, o.Fiat, o.MinAmount)
+	}
+
+	withdrawRequest := &withdraw.Request{
+		Exchange: e.GetName(),
+		Currency: o.Fiat,
+		Amount:   result.Amount,
+		Type:     withdraw.Fiat,
+		Fiat: withdraw.FiatRequest{
+			Bank:          *bank,
+			IsExpressWire: o.Express,
+		},
+	}
+	if err = withdrawRequest.Validate(); err != nil {
+		return result, fmt.Errorf("validation error withdraw request: %w", err)
+	}
+
+	response, err := CreateExchangeWithdrawResponse(ctx, d, withdrawRequest, e)
+	result.Withdrawal = &response
+	return result, err
+}
+
+// Convert sells the free balance of the currency into the target currency with a market order on the spot market.
+// Nothing is converted when the balance is too small to trade.
+func Convert(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, code, target currency.Code) (*order.SubmitResponse, error) {
+	amount, err := dealer.FreeBalance(ctx, e, asset.Spot, code)
+	if err != nil {
+		return nil, err
+	}
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	submit, err := dealer.ConversionOrder(ctx, e, code, target, amount)
+	if errors.Is(err, dealer.ErrBelowMinimum) {
+		return nil, nil
+	}
+	if errors.Is(err, dealer.ErrNoMarket) {
+		return nil, fmt.Errorf("%w: %s to %s on %s", ErrNoConversion, code, target, e.GetName())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.SubmitOrder(ctx, e, submit)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("order response: %v\n", resp)
+	return resp, nil
+}
+
+// Proceeds returns the amount of the target currency a conversion order brought in, according to the response of the
+// exchange. It is zero when the response doesn't tell.
+func Proceeds(resp *order.SubmitResponse, target currency.Code) float64 {
+	if resp == nil {
+		return 0
+	}
+	base, quote := dealer.Executed(resp, resp.Price)
+	if resp.Pair.Base.Equal(target) {
+		return base
+	}
+	return quote
+}
+
+// settled waits until the free balance of the currency holds the proceeds of a conversion on top of the balance before it,
+// market orders fill right away but the balance of the exchange can lag behind. When the proceeds are not known, any
+// increase of the balance counts. The balance that was found is returned.
+func settled(ctx context.Context, e exchange.IBotExchange, code currency.Code, before, proceeds float64) (float64, error) {
+	deadline := time.Now().Add(settleTimeout)
+	for {
+		balance, err := dealer.FreeBalance(ctx, e, asset.Spot, code)
+		if err != nil {
+			return 0, err
+		}
+		if (proceeds > 0 && balance >= before+proceeds*(1-depositTolerance)) || (proceeds <= 0 && balance > before) {
+			return balance, nil
+		}
+		if time.Now().After(deadline) {
+			return balance, fmt.Errorf("%w: %f %s after %s, was %f", ErrNotSettled, balance, code, settleTimeout, before)
+		}
+
+		select {
+		case <-ctx.Done():
+			return balance, ctx.Err()
+		case <-time.After(settleInterval):
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/spf13/viper"
+	"github.com/thrasher-corp/gocryptotrader/common"
+	"github.com/thrasher-corp/gocryptotrader/config"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/account"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+	"github.com/thrasher-corp/gocryptotrader/portfolio/banking"
+	"github.com/thrasher-corp/gocryptotrader/portfolio/withdraw"
+)
+
+var testFiatPair = currency.NewPairWithDelimiter("USDT", "EUR", "-")
+
+// fiatExchange converts USDT into EUR at par and keeps the fiat withdrawals
+type fiatExchange struct {
+	exchange.IBotExchange
+
+	name      string
+	balances  map[currency.Code]float64
+	submitted []order.Submit
+	withdrawn []withdraw.Request
+	// lag is how many balance updates the proceeds of an order take to show up
+	lag     int
+	pending map[currency.Code]float64
+}
+
+func (e *fiatExchange) GetName() string {
+	return e.name
+}
+
+func (e *fiatExchange) GetBase() *exchange.Base {
+	return &exchange.Base{Name: e.name}
+}
+
+func (e *fiatExchange) UpdateAccountInfo(ctx context.Context, a asset.Item) (account.Holdings, error) {
+	if e.lag--; e.lag < 0 {
+		for code, amount := range e.pending {
+			e.balances[code] += amount
+		}
+		e.pending = nil
+	}
+	var balances []account.Balance
+	for code, amount := range e.balances {
+		balances = append(balances, account.Balance{Currency: code, Total: amount, Free: amount})
+	}
+	return account.Holdings{Exchange: e.name, Accounts: []account.SubAccount{{AssetType: a, Currencies: balances}}}, nil
+}
+
+func (e *fiatExchange) GetEnabledPairs(a asset.Item) (currency.Pairs, error) {
+	return currency.Pairs{testFiatPair}, nil
+}
+
+func (e *fiatExchange) SubmitOrder(ctx context.Context, s *order.Submit) (*order.SubmitResponse, error) {
+	e.submitted = append(e.submitted, *s)
+	e.pending = map[currency.Code]float64{currency.EUR: s.Amount}
+	e.balances[currency.USDT] -= s.Amount
+	return &order.SubmitResponse{OrderID: fmt.Sprintf("convert-%d", len(e.submitted)), Pair: s.Pair, AssetType: s.AssetType, Side: s.Side, Amount: s.Amount, Cost: s.Amount, Status: order.Filled}, nil
+}
+
+func (e *fiatExchange) GetOrderExecutionLimits(a asset.Item, p currency.Pair) (order.MinMaxLevel, error) {
+	return order.MinMaxLevel{}, common.ErrFunctionNotSupported
+}
+
+func (e *fiatExchange) WithdrawFiatFunds(ctx context.Context, r *withdraw.Request) (*withdraw.ExchangeResponse, error) {
+	e.withdrawn = append(e.withdrawn, *r)
+	return &withdraw.ExchangeResponse{Status: "refid"}, nil
+}
+
+func testOffRampDealer(t *testing.T, e *fiatExchange) *dealer.Dealer {
+	d, err := dealer.NewBuilder().Balances(0).BuildWithExchanges(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Config.Exchanges = []config.Exchange{{Name: e.name, BankAccounts: []banking.Account{{
+		ID:                  "main",
+		Enabled:             true,
+		SupportedExchanges:  e.name,
+		AccountNumber:       "1",
+		AccountName:         "autodealer",
+		BankName:            "bank",
+		SupportedCurrencies: "EUR",
+		IBAN:                "NL02ABNA0123456789",
+		SWIFTCode:           "ABNANL2A",
+	}}}}
+	return d
+}
+
+func TestLoadOffRamp(t *testing.T) {
+	viper.Set("OFFRAMP_EXCHANGE", "kraken")
+	viper.Set("OFFRAMP_BANK_ACCOUNT", "main")
+	viper.Set("OFFRAMP_SOURCE", "usdt")
+	viper.Set("OFFRAMP_USD_BANK_ACCOUNT", "wire")
+	defer viper.Reset()
+
+	eur, err := LoadOffRamp(currency.EUR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eur.Exchange != "kraken" || eur.BankAccount != "main" || !eur.Source.Equal(currency.USDT) {
+		t.Errorf("expected: kraken main USDT, actual: %v", eur)
+	}
+
+	usd, err := LoadOffRamp(currency.USD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usd.BankAccount != "wire" {
+		t.Errorf("expected: %v, actual: %v", "wire", usd.BankAccount)
+	}
+
+	if _, err = LoadOffRamp(currency.BTC); !errors.Is(err, ErrNoOffRamp) {
+		t.Errorf("expected: %v, actual: %v", ErrNoOffRamp, err)
+	}
+}
+
+func TestOffRampRun(t *testing.T) {
+	// the euros of the conversion only show up in the third balance update after it
+	e := &fiatExchange{name: "fiat", balances: map[currency.Code]float64{currency.USDT: 100, currency.EUR: 5}, lag: 3}
+	d := testOffRampDealer(t, e)
+	defer func(interval time.Duration) { settleInterval = interval }(settleInterval)
+	settleInterval = time.Millisecond
+	RegisterFiatAdapter(e.name, FiatAdapterFunc(krakenWithdrawFiat))
+
+	o := OffRamp{Exchange: e.name, Fiat: currency.EUR, Source: currency.USDT, BankAccount: "main", MinAmount: 10}
+	result, err := o.Run(context.Background(), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(e.submitted) != 1 || e.submitted[0].Side != order.Sell || e.submitted[0].Amount != 100 {
+		t.Errorf("expected: sell of 100 USDT, actual: %v", e.submitted)
+	}
+	if result.Amount != 105 {
+		t.Errorf("expected: %v, actual: %v", 105, result.Amount)
+	}
+	if len(e.withdrawn) != 1 || e.withdrawn[0].TradePassword != "main" {
+		t.Fatalf("expected: withdrawal with key main, actual: %v", e.withdrawn)
+	}
+	if result.Withdrawal == nil || result.Withdrawal.ExchangeResponse.ID != "refid" {
+		t.Errorf("expected: withdrawal refid, actual: %v", result.Withdrawal)
+	}
+
+	// nothing is withdrawn while the balance is below the minimum
+	e.balances[currency.EUR] = 5
+	if _, err = (OffRamp{Exchange: e.name, Fiat: currency.EUR, BankAccount: "main", MinAmount: 10}).Run(context.Background(), d); !errors.Is(err, ErrBelowMinimum) {
+		t.Errorf("expected: %v, actual: %v", ErrBelowMinimum, err)
+	}
+
+	// the proceeds of a conversion that never show up are not waited for forever
+	defer func(timeout time.Duration) { settleTimeout = timeout }(settleTimeout)
+	settleTimeout = 10 * time.Millisecond
+	e.balances[currency.USDT], e.lag = 50, 1000
+	if _, err = o.Run(context.Background(), d); !errors.Is(err, ErrNotSettled) {
+		t.Errorf("expected: %v, actual: %v", ErrNotSettled, err)
+	}
+	if len(e.withdrawn) != 1 {
+		t.Errorf("expected: %v, actual: %v", 1, len(e.withdrawn))
+	}
+}
@@ -0,0 +1,478 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/util"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/portfolio/withdraw"
+)
+
+const (
+	// defaultTrackerPath is where tracked withdrawals are kept unless WITHDRAWAL_TRACKER points elsewhere
+	defaultTrackerPath = "~/.autodealer/withdrawals.json"
+	// DefaultPollInterval is how often the withdrawal history of an exchange is fetched while a withdrawal is in flight
+	DefaultPollInterval = 30 * time.Second
+	// DefaultTrackTimeout is how long a withdrawal is polled for before it is given up on
+	DefaultTrackTimeout = 24 * time.Hour
+)
+
+// WithdrawalStatus is the status of a withdrawal, the same on every exchange
+type WithdrawalStatus string
+
+const (
+	WithdrawalUnknown    WithdrawalStatus = "unknown"
+	WithdrawalPending    WithdrawalStatus = "pending"
+	WithdrawalProcessing WithdrawalStatus = "processing"
+	WithdrawalCompleted  WithdrawalStatus = "completed"
+	WithdrawalFailed     WithdrawalStatus = "failed"
+	WithdrawalCancelled  WithdrawalStatus = "cancelled"
+)
+
+// Final reports whether the status will not change anymore
+func (s WithdrawalStatus) Final() bool {
+	return s == WithdrawalCompleted || s == WithdrawalFailed || s == WithdrawalCancelled
+}
+
+// exchangeStatuses are the statuses exchanges report as a code
+var exchangeStatuses = map[string]map[string]WithdrawalStatus{
+	"binance": {
+		"0": WithdrawalPending, // email sent
+		"1": WithdrawalCancelled,
+		"2": WithdrawalPending, // awaiting approval
+		"3": WithdrawalFailed,  // rejected
+		"4": WithdrawalProcessing,
+		"5": WithdrawalFailed,
+		"6": WithdrawalCompleted,
+	},
+	"okx": {
+		"-3": WithdrawalCancelled, // cancelling
+		"-2": WithdrawalCancelled,
+		"-1": WithdrawalFailed,
+		"0":  WithdrawalPending,
+		"1":  WithdrawalProcessing, // broadcasting
+		"2":  WithdrawalCompleted,
+	},
+}
+
+// statusWords are the words exchanges use in their statuses, checked in order so that "confirm-error" is a failure
+var statusWords = []struct {
+	status WithdrawalStatus
+	words  []string
+}{
+	{WithdrawalFailed, []string{"fail", "failed", "failure", "reject", "rejected", "error", "invalid"}},
+	{WithdrawalCancelled, []string{"cancel", "canceled", "cancelled", "canceling", "cancelling", "repealed", "revoked"}},
+	{WithdrawalCompleted, []string{"complete", "completed", "success", "successful", "confirmed", "done", "finished", "paid"}},
+	{WithdrawalProcessing, []string{"processing", "sending", "sent", "broadcast", "broadcasting", "pass", "settled", "authorized", "approved", "transfer", "transferring"}},
+	{WithdrawalPending, []string{"pending", "initial", "wait", "waiting", "awaiting", "submitted", "requested", "reexamine", "new", "created"}},
+}
+
+// NormaliseStatus translates the withdrawal status reported by the exchange
+func NormaliseStatus(exchangeName, status string) WithdrawalStatus {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if s, ok := exchangeStatuses[strings.ToLower(exchangeName)][status]; ok {
+		return s
+	}
+
+	words := strings.FieldsFunc(status, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, x := range statusWords {
+		for _, w := range words {
+			for _, known := range x.words {
+				if w == known {
+					return x.status
+				}
+			}
+		}
+	}
+	return WithdrawalUnknown
+}
+
+// WithdrawalRecord is a withdrawal in the history of an exchange. Withdrawals made by the dealer are tracked,
+// they carry the request next to what the exchange reports about them.
+type WithdrawalRecord struct {
+	// Key identifies a tracked withdrawal in the tracker, it is known before the exchange reports an ID
+	Key            string           `json:"key,omitempty"`
+	ID             string           `json:"id"`
+	Exchange       string           `json:"exchange"`
+	Currency       currency.Code    `json:"currency"`
+	Amount         float64          `json:"amount"`
+	Fee            float64          `json:"fee"`
+	Address        string           `json:"address,omitempty"`
+	Chain          string           `json:"chain,omitempty"`
+	Bank           string           `json:"bank,omitempty"`
+	TxID           string           `json:"txId,omitempty"`
+	Status         WithdrawalStatus `json:"status"`
+	ExchangeStatus string           `json:"exchangeStatus,omitempty"`
+	Time           time.Time        `json:"time"`
+	UpdatedAt      time.Time        `json:"updatedAt"`
+	Tracked        bool             `json:"tracked"`
+	Error          string           `json:"error,omitempty"`
+}
+
+// update copies what the exchange reports about the withdrawal
+func (r *WithdrawalRecord) update(h exchange.WithdrawalHistory, now time.Time) {
+	if r.ID == "" {
+		r.ID = h.TransferID
+	}
+	if h.CryptoTxID != "" {
+		r.TxID = h.CryptoTxID
+	}
+	if h.Fee != 0 {
+		r.Fee = h.Fee
+	}
+	r.ExchangeStatus = h.Status
+	r.Status = NormaliseStatus(r.Exchange, h.Status)
+	r.UpdatedAt = now
+}
+
+// newWithdrawalRecord returns the record of a withdrawal in the history of the exchange
+func newWithdrawalRecord(exchangeName string, h exchange.WithdrawalHistory) WithdrawalRecord {
+	r := WithdrawalRecord{
+		Exchange: exchangeName,
+		Currency: currency.NewCode(h.Currency).Upper(),
+		Amount:   h.Amount,
+		Address:  h.CryptoToAddress,
+		Chain:    h.CryptoChain,
+		Bank:     h.BankTo,
+		Time:     h.Timestamp,
+	}
+	r.update(h, h.Timestamp)
+	return r
+}
+
+// matches reports whether the entry of the history is the tracked withdrawal. Exchanges that don't return an ID when
+// withdrawing are matched by currency and address, with the first withdrawal after the request.
+func (r *WithdrawalRecord) matches(h exchange.WithdrawalHistory) bool {
+	if r.ID != "" {
+		return h.TransferID == r.ID || h.CryptoTxID == r.ID
+	}
+	if !currency.NewCode(h.Currency).Equal(r.Currency) || h.Timestamp.Before(r.Time.Add(-time.Minute)) {
+		return false
+	}
+	return r.Address == "" || h.CryptoToAddress == r.Address
+}
+
+// Tracker follows withdrawals until the exchange confirms or fails them. The withdrawals are kept in a JSON file,
+// the ones in flight are followed again after a restart with Resume.
+type Tracker struct {
+	// Interval is how often the history of an exchange is fetched
+	Interval time.Duration
+	// Timeout is how long a withdrawal is followed
+	Timeout time.Duration
+
+	path string
+	now  func() time.Time
+
+	mu          sync.Mutex
+	withdrawals []*WithdrawalRecord
+	watching    map[string]bool
+}
+
+// OpenTracker opens the tracker kept at the path, with an empty path nothing is kept
+func OpenTracker(path string) (*Tracker, error) {
+	t := &Tracker{
+		Interval: DefaultPollInterval,
+		Timeout:  DefaultTrackTimeout,
+		path:     path,
+		now:      time.Now,
+		watching: make(map[string]bool),
+	}
+	if err := util.ReadJSON(path, &t.withdrawals); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+var (
+	tracker     *Tracker
+	trackerOnce sync.Once
+)
+
+// DefaultTracker returns the tracker shared by the application. It is kept at the path of the WITHDRAWAL_TRACKER environment
+// variable, or ~/.autodealer/withdrawals.json. When the file can't be read withdrawals are only tracked in memory.
+func DefaultTracker() *Tracker {
+	trackerOnce.Do(func() {
+		var err error
+		if tracker, err = OpenTracker(util.StatePath("WITHDRAWAL_TRACKER", defaultTrackerPath)); err != nil {
+			logrus.Errorf("failed to open withdrawal tracker: %s\n", err)
+			tracker, _ = OpenTracker("")
+		}
+	})
+	return tracker
+}
+
+// Track follows the withdrawal the exchange accepted, the exchange is polled until it reports a final status
+func (t *Tracker) Track(e exchange.IBotExchange, request *withdraw.Request, response *withdraw.ExchangeResponse) WithdrawalRecord {
+	r := &WithdrawalRecord{
+		Key:      uuid.NewString(),
+		Exchange: e.GetName(),
+		Currency: request.Currency.Upper(),
+		Amount:   request.Amount,
+		Status:   WithdrawalPending,
+		Time:     t.now().UTC(),
+		Tracked:  true,
+	}
+	r.UpdatedAt = r.Time
+	if response != nil {
+		r.ID = response.ID
+		if response.Status != response.ID {
+			r.ExchangeStatus = response.Status
+		}
+	}
+	switch request.Type {
+	case withdraw.Crypto:
+		r.Address, r.Chain = request.Crypto.Address, request.Crypto.Chain
+	case withdraw.Fiat:
+		r.Bank = request.Fiat.Bank.ID
+	}
+
+	t.mu.Lock()
+	t.withdrawals = append(t.withdrawals, r)
+	if err := t.save(); err != nil {
+		logrus.Errorf("failed to save withdrawal tracker: %s\n", err)
+	}
+	t.mu.Unlock()
+
+	t.watch(e)
+	return *r
+}
+
+// Record returns the tracked withdrawal with the ID on the exchange
+func (t *Tracker) Record(exchangeName, id string) (WithdrawalRecord, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, r := range t.withdrawals {
+		if id != "" && r.Exchange == exchangeName && r.ID == id {
+			return *r, true
+		}
+	}
+	return WithdrawalRecord{}, false
+}
+
+// Get returns the tracked withdrawal with the key
+func (t *Tracker) Get(key string) (WithdrawalRecord, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, r := range t.withdrawals {
+		if key != "" && r.Key == key {
+			return *r, true
+		}
+	}
+	return WithdrawalRecord{}, false
+}
+
+// Since returns the tracked withdrawals that were requested at or after the time
+func (t *Tracker) Since(since time.Time) []WithdrawalRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var records []WithdrawalRecord
+	for _, r := range t.withdrawals {
+		if r.Tracked && !r.Time.Before(since) {
+			records = append(records, *r)
+		}
+	}
+	return records
+}
+
+// Resume follows the withdrawals that were in flight when the application stopped
+func (t *Tracker) Resume(d *dealer.Dealer) {
+	t.mu.Lock()
+	names := make(map[string]bool)
+	for _, r := range t.withdrawals {
+		if t.inFlight(r) {
+			names[r.Exchange] = true
+		}
+	}
+	t.mu.Unlock()
+
+	for name := range names {
+		e, err := d.GetExchangeByName(name)
+		if err != nil {
+			logrus.Errorf("failed to resume tracking withdrawals on %s: %s\n", name, err)
+			continue
+		}
+		t.watch(e)
+	}
+}
+
+// Poll fetches the withdrawal history of the exchange once and updates the withdrawals in flight on it
+func (t *Tracker) Poll(ctx context.Context, e exchange.IBotExchange) error {
+	t.mu.Lock()
+	var codes []currency.Code
+	for _, r := range t.withdrawals {
+		if r.Exchange == e.GetName() && t.inFlight(r) && !containsCode(codes, r.Currency) {
+			codes = append(codes, r.Currency)
+		}
+	}
+	t.mu.Unlock()
+
+	var firstErr error
+	for _, code := range codes {
+		history, err := e.GetWithdrawalsHistory(ctx, code, asset.Spot)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s %s withdrawal history: %w", e.GetName(), code, err)
+			}
+			continue
+		}
+		t.apply(e.GetName(), code, history)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, r := range t.withdrawals {
+		if r.Exchange == e.GetName() && !r.Status.Final() && r.Error == "" && now.Sub(r.Time) >= t.Timeout {
+			r.Error = fmt.Sprintf("no final status after %s", t.Timeout)
+		}
+	}
+	if err := t.save(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// apply updates the tracked withdrawals of the currency with the history of the exchange
+func (t *Tracker) apply(exchangeName string, code currency.Code, history []exchange.WithdrawalHistory) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	claimed := make(map[string]bool)
+	for _, r := range t.withdrawals {
+		if r.Exchange == exchangeName && r.ID != "" {
+			claimed[r.ID] = true
+		}
+	}
+
+	// the oldest withdrawals are matched first, they are the first ones in the history without an ID
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+
+	now := t.now().UTC()
+	for _, r := range t.withdrawals {
+		if r.Exchange != exchangeName || !r.Currency.Equal(code) || !t.inFlight(r) {
+			continue
+		}
+		for _, h := range history {
+			if r.ID == "" && claimed[h.TransferID] {
+				continue
+			}
+			if r.matches(h) {
+				r.update(h, now)
+				claimed[h.TransferID] = true
+				break
+			}
+		}
+	}
+}
+
+// History returns the withdrawals of the currency on the exchange, most recent first. The history of the exchange is merged
+// with the tracked withdrawals, which are returned together with the error when the exchange does not provide its history.
+func (t *Tracker) History(ctx context.Context, e exchange.IBotExchange, code currency.Code) ([]WithdrawalRecord, error) {
+	history, err := e.GetWithdrawalsHistory(ctx, code, asset.Spot)
+	if err == nil {
+		t.apply(e.GetName(), code, history)
+	}
+
+	t.mu.Lock()
+	records := []WithdrawalRecord{}
+	known := make(map[string]bool)
+	for _, r := range t.withdrawals {
+		if r.Exchange == e.GetName() && r.Currency.Equal(code) {
+			records = append(records, *r)
+			known[r.ID] = true
+		}
+	}
+	t.mu.Unlock()
+
+	for _, h := range history {
+		if h.TransferID == "" || !known[h.TransferID] {
+			records = append(records, newWithdrawalRecord(e.GetName(), h))
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.After(records[j].Time)
+	})
+	return records, err
+}
+
+// watch polls the exchange while withdrawals are in flight on it, there is one poller per exchange
+func (t *Tracker) watch(e exchange.IBotExchange) {
+	name := e.GetName()
+
+	t.mu.Lock()
+	if t.watching[name] {
+		t.mu.Unlock()
+		return
+	}
+	t.watching[name] = true
+	t.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(t.Interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), t.Interval)
+			if err := t.Poll(ctx, e); err != nil {
+				logrus.Errorf("failed to track withdrawals: %s\n", err)
+			}
+			cancel()
+
+			t.mu.Lock()
+			done := true
+			for _, r := range t.withdrawals {
+				if r.Exchange == name && t.inFlight(r) {
+					done = false
+					break
+				}
+			}
+			if done {
+				delete(t.watching, name)
+			}
+			t.mu.Unlock()
+
+			if done {
+				return
+			}
+		}
+	}()
+}
+
+// inFlight reports whether the withdrawal is still followed
+func (t *Tracker) inFlight(r *WithdrawalRecord) bool {
+	return r.Tracked && !r.Status.Final() && r.Error == ""
+}
+
+// save writes the tracked withdrawals to a temporary file and moves it in place
+func (t *Tracker) save() error {
+	return util.WriteJSON(t.path, t.withdrawals)
+}
+
+func containsCode(codes []currency.Code, code currency.Code) bool {
+	for _, c := range codes {
+		if c.Equal(code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/portfolio/withdraw"
+)
+
+func TestMain(m *testing.M) {
+	// withdrawals made by the tests are tracked outside of the home directory
+	dir, err := os.MkdirTemp("", "transfer")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("WITHDRAWAL_TRACKER", filepath.Join(dir, "withdrawals.json"))
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// historyExchange returns a fixed withdrawal history
+type historyExchange struct {
+	exchange.IBotExchange
+
+	name    string
+	history []exchange.WithdrawalHistory
+}
+
+func (e *historyExchange) GetName() string {
+	return e.name
+}
+
+func (e *historyExchange) GetWithdrawalsHistory(ctx context.Context, code currency.Code, a asset.Item) ([]exchange.WithdrawalHistory, error) {
+	var xs []exchange.WithdrawalHistory
+	for _, h := range e.history {
+		if currency.NewCode(h.Currency).Equal(code) {
+			xs = append(xs, h)
+		}
+	}
+	return xs, nil
+}
+
+func TestNormaliseStatus(t *testing.T) {
+	cases := []struct {
+		exchange, status string
+		expected         WithdrawalStatus
+	}{
+		{"Binance", "6", WithdrawalCompleted},
+		{"Binance", "4", WithdrawalProcessing},
+		{"okx", "-1", WithdrawalFailed},
+		{"Kraken", "Success", WithdrawalCompleted},
+		{"Kraken", "Settled", WithdrawalProcessing},
+		{"Huobi", "confirm-error", WithdrawalFailed},
+		{"Huobi", "wallet-transfer", WithdrawalProcessing},
+		{"Bittrex", "CANCELLED", WithdrawalCancelled},
+		{"Bitfinex", "PENDING REVIEW", WithdrawalPending},
+		{"FTX", "unconfirmed", WithdrawalUnknown},
+	}
+	for _, c := range cases {
+		if actual := NormaliseStatus(c.exchange, c.status); actual != c.expected {
+			t.Errorf("%s %s expected: %v, actual: %v", c.exchange, c.status, c.expected, actual)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "withdrawals.json")
+	tracker, err := OpenTracker(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.Interval = time.Hour
+	start := time.Now()
+
+	e := &historyExchange{name: "Binance"}
+	withID := tracker.Track(e, &withdraw.Request{Currency: currency.BTC, Amount: 1, Type: withdraw.Crypto, Crypto: withdraw.CryptoRequest{Address: "bc1a"}}, &withdraw.ExchangeResponse{ID: "w1"})
+	tracker.Track(e, &withdraw.Request{Currency: currency.ETH, Amount: 2, Type: withdraw.Crypto, Crypto: withdraw.CryptoRequest{Address: "0xb"}}, &withdraw.ExchangeResponse{})
+	if withID.Status != WithdrawalPending {
+		t.Errorf("expected: %v, actual: %v", WithdrawalPending, withID.Status)
+	}
+
+	e.history = []exchange.WithdrawalHistory{
+		{TransferID: "w0", Currency: "BTC", Amount: 3, Status: "6", Timestamp: start.Add(-time.Hour)},
+		{TransferID: "w1", Currency: "BTC", Amount: 1, Fee: 0.0005, Status: "6", CryptoTxID: "tx1", Timestamp: start},
+		{TransferID: "w2", Currency: "ETH", Amount: 2, Fee: 0.01, Status: "4", CryptoToAddress: "0xb", Timestamp: start.Add(time.Second)},
+	}
+	if err = tracker.Poll(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+
+	// the tracked withdrawals are kept after a restart
+	tracker, err = OpenTracker(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btc, err := tracker.History(context.Background(), e, currency.BTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(btc) != 2 {
+		t.Fatalf("expected: %v, actual: %v", 2, len(btc))
+	}
+	if r := btc[0]; !r.Tracked || r.Status != WithdrawalCompleted || r.TxID != "tx1" || r.Fee != 0.0005 {
+		t.Errorf("expected: completed w1 with tx1, actual: %+v", r)
+	}
+	if r := btc[1]; r.Tracked || r.ID != "w0" {
+		t.Errorf("expected: w0 from the exchange history, actual: %+v", r)
+	}
+
+	eth, err := tracker.History(context.Background(), e, currency.ETH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the withdrawal without ID is matched by its address
+	if len(eth) != 1 || eth[0].ID != "w2" || eth[0].Status != WithdrawalProcessing {
+		t.Errorf("expected: processing w2, actual: %+v", eth)
+	}
+}
@@ -5,10 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/romanornr/autodealer/dealer"
 	"github.com/sirupsen/logrus"
-	"github.com/thrasher-corp/gocryptotrader/currency"
 	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
-	"github.com/thrasher-corp/gocryptotrader/exchanges/kraken"
 	"github.com/thrasher-corp/gocryptotrader/portfolio/withdraw"
 )
 
@@ -25,6 +24,7 @@ type ExchangeWithdrawResponse struct {
 	Time               time.Time            `json:"time"`
 	Error              error                `json:"error"`
 	Success            bool                 `json:"success"`
+	Tracking           *WithdrawalRecord    `json:"tracking,omitempty"`
 }
 
 // CreateExchangeWithdrawResponse function creates a withraw request using exchangeManager and returns the exchangeWithdrawResponse including response
@@ -34,25 +34,26 @@ type ExchangeWithdrawResponse struct {
 // so here's the thing  this function returns an Exchange response which holds the deposit id  on that exchange.
 // Finally, we update the results which we return in JSON format.
 // After we make sure that the withdrawal functionality is working we can inject the functionality in the withdrawal method of the engine struct.
-func CreateExchangeWithdrawResponse(withdrawRequest *withdraw.Request, exchangeManager exchange.IBotExchange) (ExchangeWithdrawResponse, error) { // withdrawManager *engine.WithdrawManager) exchangeWithdrawResponse {
+// Every withdrawal is recorded with the auditor of the dealer, fiat withdrawals as bank transfer. Accepted withdrawals are tracked
+// until the exchange confirms or fails them.
+func CreateExchangeWithdrawResponse(ctx context.Context, d *dealer.Dealer, withdrawRequest *withdraw.Request, exchangeManager exchange.IBotExchange) (ExchangeWithdrawResponse, error) { // withdrawManager *engine.WithdrawManager) exchangeWithdrawResponse {
 	var exchangeResponse *withdraw.ExchangeResponse
 	var err error
 	logrus.Info("creating withdraw response for exchange")
 
 	if withdrawRequest.Type == withdraw.Crypto {
-		exchangeResponse, err = exchangeManager.WithdrawCryptocurrencyFunds(context.Background(), withdrawRequest)
+		exchangeResponse, err = exchangeManager.WithdrawCryptocurrencyFunds(ctx, withdrawRequest)
 		if err != nil {
 			err = fmt.Errorf("failed to withdraw crypto asset %s\n", err)
 		}
 		logrus.Infof("exchange response: %v\n", exchangeResponse)
 	}
 
-	if withdrawRequest.Type == withdraw.Fiat && exchangeManager.GetName() == "Kraken" {
-		logrus.Info("withdraw kraken")
-		k := kraken.Kraken{Base: *exchangeManager.GetBase()}
-		exchangeResponse.ID, err = k.Withdraw(context.Background(), currency.EUR.String(), withdrawRequest.Fiat.Bank.ID, withdrawRequest.Amount)
+	if withdrawRequest.Type == withdraw.Fiat {
+		logrus.Infof("withdraw %s to bank account %s\n", withdrawRequest.Currency, withdrawRequest.Fiat.Bank.ID)
+		exchangeResponse, err = GetFiatAdapter(exchangeManager.GetName()).WithdrawFiat(ctx, exchangeManager, withdrawRequest)
 		if err != nil {
-			err = fmt.Errorf("failed international bank withdraw request: %s\n", err)
+			err = fmt.Errorf("failed bank withdraw request: %w", err)
 		}
 	}
 
@@ -72,7 +73,22 @@ func CreateExchangeWithdrawResponse(withdrawRequest *withdraw.Request, exchangeM
 
 	if err == nil {
 		response.Success = true
+		// the exchange accepted the withdrawal, its history tells when it is sent
+		record := DefaultTracker().Track(exchangeManager, withdrawRequest, exchangeResponse)
+		response.Tracking = &record
 	}
 
+	action := dealer.AuditWithdraw
+	if withdrawRequest.Type == withdraw.Fiat {
+		action = dealer.AuditBankTransfer
+	}
+	d.Audit(ctx, dealer.AuditEntry{
+		Action:   action,
+		Exchange: response.Exchange,
+		Request:  withdrawRequest,
+		Response: exchangeResponse,
+		Error:    dealer.AuditError(err),
+	})
+
 	return response, err
 }
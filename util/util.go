@@ -1,13 +1,17 @@
 package util
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
 	"go.uber.org/multierr"
 )
 
@@ -197,3 +201,71 @@ func (m *ErrorWaitGroup) Wait() error {
 	m.group.Wait()
 	return m.err
 }
+
+// MarshalSide encodes v with the order side under the key as a string. order.Side is encoded as a number but only
+// knows how to unmarshal from its string form, types holding a side use it in their MarshalJSON. Pass v as an alias
+// of the type, without the MarshalJSON method, or it calls itself.
+func MarshalSide(v interface{}, key string, side order.Side) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if fields[key], err = json.Marshal(side.String()); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// StatePath returns the path in the environment variable, or the default path when it is not set, with ~ expanded
+func StatePath(env, defaultPath string) string {
+	if path := os.Getenv(env); path != "" {
+		return ExpandUser(path)
+	}
+	return ExpandUser(defaultPath)
+}
+
+// ReadJSON decodes the JSON file at the path into v. v is left as it is when the path is empty or the file does not exist yet.
+func ReadJSON(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteJSON writes v as JSON to a temporary file and moves it in place, a crash never leaves half a file behind.
+// Nothing is written when the path is empty.
+func WriteJSON(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -1,8 +1,12 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"testing"
+
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
 )
 
 func TestLocation(t *testing.T) {
@@ -24,3 +28,54 @@ func TestLocation2(t *testing.T) {
 func TestExpandUser(t *testing.T) {
 	fmt.Println(ExpandUser("~/.gocryptotrader/config.json"))
 }
+
+func TestMarshalSide(t *testing.T) {
+	type level struct {
+		Price float64    `json:"price"`
+		Side  order.Side `json:"side"`
+	}
+
+	data, err := MarshalSide(level{Price: 10, Side: order.Sell}, "side", order.Sell)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded level
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Price != 10 || decoded.Side != order.Sell {
+		t.Errorf("expected: %v, actual: %v", level{Price: 10, Side: order.Sell}, decoded)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "state.json")
+
+	var missing map[string]int
+	if err := ReadJSON(path, &missing); err != nil || missing != nil {
+		t.Errorf("expected: %v, actual: %v %v", nil, missing, err)
+	}
+
+	if err := WriteJSON(path, map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	var state map[string]int
+	if err := ReadJSON(path, &state); err != nil || state["a"] != 1 {
+		t.Errorf("expected: %v, actual: %v %v", 1, state, err)
+	}
+
+	if err := WriteJSON("", state); err != nil {
+		t.Errorf("expected: %v, actual: %v", nil, err)
+	}
+}
+
+func TestStatePath(t *testing.T) {
+	t.Setenv("STATE_PATH_TEST", "/tmp/state.json")
+	if path := StatePath("STATE_PATH_TEST", "~/state.json"); path != "/tmp/state.json" {
+		t.Errorf("expected: %v, actual: %v", "/tmp/state.json", path)
+	}
+	if path := StatePath("STATE_PATH_UNSET", "/var/state.json"); path != "/var/state.json" {
+		t.Errorf("expected: %v, actual: %v", "/var/state.json", path)
+	}
+}
@@ -0,0 +1,171 @@
+package webserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romanornr/autodealer/addressbook"
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/singleton"
+	transfer2 "github.com/romanornr/autodealer/transfer"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+)
+
+var (
+	ErrUnknownApprovalAction = errors.New("unknown approval action")
+	ErrOperatorRequired      = errors.New("approval needs the operator in the " + operatorHeader + " header and their token as bearer token")
+)
+
+// getAddressBookResponse returns the entries of the address book
+func getAddressBookResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").([]addressbook.Entry)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	render.JSON(w, r, response)
+}
+
+// getPendingWithdrawalsResponse returns the withdrawals that needed approval
+func getPendingWithdrawalsResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").([]addressbook.Withdrawal)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	render.JSON(w, r, response)
+}
+
+// AddressBookCtx lists the address book, adds an entry to it or removes one. Changes are recorded in the audit log.
+// The tag or memo of an address is passed as ?tag=
+// GET addressbook
+// POST addressbook/{name}/{asset}/{chain}/{address}
+// DELETE addressbook/{name}
+func AddressBookCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		book, err := addressbook.Default()
+		if err != nil {
+			logrus.Errorf("failed to open address book: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		d, err := singleton.GetDealer(context.Background())
+		if err != nil {
+			logrus.Errorf("failed to get dealer: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		name := chi.URLParam(request, "name")
+		switch request.Method {
+		case http.MethodPost:
+			entry := addressbook.Entry{
+				Name:     name,
+				Currency: currency.NewCode(chi.URLParam(request, "asset")),
+				Chain:    chi.URLParam(request, "chain"),
+				Address:  chi.URLParam(request, "address"),
+				Tag:      request.URL.Query().Get("tag"),
+			}
+			if entry.Chain == "default" {
+				entry.Chain = ""
+			}
+			entry, err = book.Add(entry)
+			d.Audit(request.Context(), dealer.AuditEntry{Action: dealer.AuditAddressBook, Request: "add", Response: entry, Error: dealer.AuditError(err)})
+		case http.MethodDelete:
+			err = book.Remove(name)
+			d.Audit(request.Context(), dealer.AuditEntry{Action: dealer.AuditAddressBook, Request: "remove", Response: name, Error: dealer.AuditError(err)})
+		}
+
+		if errors.Is(err, addressbook.ErrEntryNotFound) {
+			render.Render(w, request, ErrNotFound)
+			return
+		}
+		if err != nil {
+			logrus.Errorf("address book: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), "response", book.Entries())
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
+
+// PendingWithdrawalsCtx loads the withdrawals that needed approval
+// withdraw/pending
+func PendingWithdrawalsCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		book, err := addressbook.Default()
+		if err != nil {
+			logrus.Errorf("failed to open address book: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), "response", book.Withdrawals())
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
+
+// WithdrawApprovalCtx approves a pending withdrawal, which executes it, or rejects it. The operator deciding is named
+// in the X-Operator header and authenticated by the token configured for them, they can't be the one who requested
+// the withdrawal.
+// POST withdraw/pending/{id}/{action}
+func WithdrawApprovalCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		book, err := addressbook.Default()
+		if err != nil {
+			logrus.Errorf("failed to open address book: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		d, err := singleton.GetDealer(context.Background())
+		if err != nil {
+			logrus.Errorf("failed to get dealer: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		if _, ok := operator(request); !ok {
+			render.Render(w, request, ErrInvalidRequest(ErrOperatorRequired))
+			return
+		}
+
+		var (
+			response transfer2.BookWithdrawResponse
+			id       = chi.URLParam(request, "id")
+		)
+
+		switch action := chi.URLParam(request, "action"); action {
+		case "approve":
+			response, err = transfer2.ApproveWithdrawal(request.Context(), d, book, id)
+		case "reject":
+			response, err = transfer2.RejectWithdrawal(request.Context(), d, book, id)
+		default:
+			err = fmt.Errorf("%w: %s", ErrUnknownApprovalAction, action)
+		}
+
+		if errors.Is(err, addressbook.ErrNotFound) {
+			render.Render(w, request, ErrNotFound)
+			return
+		}
+		// a withdrawal that was approved but failed on the exchange is returned with its error
+		if err != nil && response.Response == nil {
+			logrus.Errorf("withdrawal %s: %s\n", id, err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), "response", &response)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
@@ -0,0 +1,63 @@
+package webserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romanornr/autodealer/audit"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditResponse is the response for the '/audit' request. Verified is false when the hash chain of the log is broken,
+// the records are then the ones up to the break.
+type AuditResponse struct {
+	Verified bool           `json:"verified"`
+	Error    string         `json:"error,omitempty"`
+	Records  []audit.Record `json:"records"`
+}
+
+// getAuditResponse returns the records of the audit log
+func getAuditResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").(*AuditResponse)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	render.JSON(w, r, response)
+}
+
+// AuditCtx reads the audit log, optionally only the records of an action on an exchange
+// audit
+// audit/{action}
+// audit/{action}/{exchange}
+func AuditCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		l, err := audit.Default()
+		if err != nil {
+			logrus.Errorf("failed to open audit log: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		q := audit.Query{Action: chi.URLParam(request, "action"), Exchange: chi.URLParam(request, "exchange")}
+		records, err := l.Query(q)
+		if err != nil && !errors.Is(err, audit.ErrChainBroken) {
+			logrus.Errorf("failed to read audit log: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		response := &AuditResponse{Verified: err == nil, Records: records}
+		if err != nil {
+			logrus.Errorf("audit log: %s\n", err)
+			response.Error = err.Error()
+		}
+
+		ctx := context.WithValue(request.Context(), "response", response)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
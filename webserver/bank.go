@@ -5,6 +5,7 @@ import (
 	"github.com/romanornr/autodealer/singleton"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
 	transfer2 "github.com/romanornr/autodealer/transfer"
 	"github.com/thrasher-corp/gocryptotrader/currency"
 
@@ -14,40 +15,38 @@ import (
 
 func getBankTransfer(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	exchangeResponse, ok := ctx.Value("response").(*transfer2.ExchangeWithdrawResponse)
+	result, ok := ctx.Value("response").(*transfer2.OffRampResult)
 	if !ok {
 		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
 		render.JSON(w, r, http.StatusUnprocessableEntity)
 		return
 	}
 
-	render.JSON(w, r, exchangeResponse)
+	render.JSON(w, r, result)
 }
 
+// BankTransferCtx runs the off-ramp of the currency in the URL, see transfer.LoadOffRamp for its configuration
+// POST bank/transfer/{currency}
 func BankTransferCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
-		// currencyCode := currency.NewCode(chi.URLParam(request, "exchange"))     // TODO fix currency.EUR
-		currencyCode := currency.EUR
+		currencyCode := currency.NewCode(chi.URLParam(request, "currency")).Upper()
 		d, _ := singleton.GetDealer(context.Background())
 
-		submitResponse, err := transfer2.KrakenConvertUSDT(currencyCode, d)
+		offRamp, err := transfer2.LoadOffRamp(currencyCode)
 		if err != nil {
-			logrus.Errorf("Failed to sell USDT to Euro: %s\n", err)
-			render.Status(request, http.StatusUnprocessableEntity)
-			render.JSON(w, request, http.StatusUnprocessableEntity)
+			logrus.Errorf("failed to load off-ramp: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
 			return
 		}
-		logrus.Infof("submit response %v\n", submitResponse)
 
-		response, err := transfer2.KrakenInternationalBankAccountWithdrawal(currencyCode, d)
+		result, err := offRamp.Run(request.Context(), d)
 		if err != nil {
-			logrus.Errorf("Failed to withdraw EUR from bank account: %s\n", err)
-			render.Status(request, http.StatusUnprocessableEntity)
-			render.JSON(w, request, http.StatusUnprocessableEntity)
+			logrus.Errorf("failed to transfer %s to bank account %s: %s\n", currencyCode, offRamp.BankAccount, err)
+			render.Render(w, request, ErrInvalidRequest(err))
 			return
 		}
 
-		ctx := context.WithValue(request.Context(), "response", &response)
+		ctx := context.WithValue(request.Context(), "response", &result)
 		next.ServeHTTP(w, request.WithContext(ctx))
 	})
 }
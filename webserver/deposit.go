@@ -6,7 +6,7 @@ package webserver
 
 import (
 	"context"
-	"github.com/romanornr/autodealer/algo/shortestPath"
+	"github.com/romanornr/autodealer/pricing"
 	"net/http"
 	"strings"
 	"time"
@@ -78,7 +78,7 @@ func DepositAddressCtx(next http.Handler) http.Handler {
 		depositRequest.Asset = depositRequest.Code.Item
 		depositRequest.AccountID = subAccount.ID
 
-		selectedChain := chainSelection(e.GetName(), chainReq, depositRequest.Chains)
+		selectedChain := chainSelection(e.GetName(), depositRequest.Code, chainReq, depositRequest.Chains)
 
 		depositRequest.Address, err = e.GetDepositAddress(context.Background(), depositRequest.Code, depositRequest.AccountID, selectedChain)
 		if err != nil {
@@ -99,7 +99,7 @@ func DepositAddressCtx(next http.Handler) http.Handler {
 
 		depositRequest.Balance = balance.TotalValue
 
-		depositRequest.Price, err = getDollarValue(e, depositRequest.Code, asset.Spot)
+		depositRequest.Price, err = getDollarValue(request.Context(), d, e, depositRequest.Code, asset.Spot)
 		if err != nil {
 			logrus.Errorf("failed to get dollar value: %s\n", err)
 		}
@@ -135,18 +135,18 @@ func GetSubAccountByID(e exchange.IBotExchange, accountId string) (account.SubAc
 }
 
 // getDollarValue returns the dollar value of the currency and route if there's no USDT pair available
-func getDollarValue(e exchange.IBotExchange, code currency.Code, assetType asset.Item) (float64, error) {
+func getDollarValue(ctx context.Context, d *dealer.Dealer, e exchange.IBotExchange, code currency.Code, assetType asset.Item) (float64, error) {
 
 	if code.Item.Symbol == "USDT" || code.Item.Symbol == "USD" || code.Item.Symbol == "BUSD" || code.Item.Symbol == "UST" {
 		return 1, nil
 	}
 
-	price, err := shortestPath.GetPrice(e, code, currency.USDT, assetType)
+	conversion, err := pricing.Value(ctx, d, e, code, currency.USD, assetType)
 	if err != nil {
 		logrus.Errorf("failed to get price: %s\n", err)
 	}
 
-	logrus.Printf("price: %f\n", price)
+	logrus.Printf("price: %f\n", conversion.Rate)
 
-	return price, nil
+	return conversion.Rate, nil
 }
@@ -1,47 +1,69 @@
 package webserver
 
 import (
-	"github.com/go-chi/render"
-	exchange "github.com/thrasher-corp/gocryptotrader/exchanges"
+	"context"
 	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romanornr/autodealer/singleton"
+	transfer2 "github.com/romanornr/autodealer/transfer"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
 )
 
 func (history WithdrawHistoryResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// WithdrawHistoryResponse is the response for the '/withdraw/history' request. Error is set when the exchange does not provide
+// its withdrawal history, the history then holds the withdrawals made by the dealer only.
 type WithdrawHistoryResponse struct {
-	History []exchange.WithdrawalHistory `json:"history"`
+	History []transfer2.WithdrawalRecord `json:"history"`
+	Error   string                       `json:"error,omitempty"`
 }
 
 // get withdrawal history from exchange from an asset
 func getWithdrawHistory(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	withdrawHistory, ok := ctx.Value("history").([]exchange.WithdrawalHistory)
+	history, ok := ctx.Value("response").(*WithdrawHistoryResponse)
 	if !ok {
-		http.Error(w, http.StatusText(400), 400)
-		render.Render(w, r, ErrNotFound)
+		logrus.Errorf("Got unexpected response %T\n", history)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
 		return
 	}
-	history := WithdrawHistoryResponse{
-		History: withdrawHistory,
-	}
 	render.Render(w, r, history)
 }
 
-//// TODO fix FTX ERRO[0006] failed fetch history: not yet implemented
-//// only works for binance
-//func withdrawHistoryCtx(next http.Handler) http.Handler {
-//	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
-//		var ctx context.Context
-//		exchangeNameReq := chi.URLParam(request, "exchange")
-//		exchangeEngine, _ := engine.Bot.GetExchangeByName(exchangeNameReq)
-//		code := currency.NewCode(strings.ToUpper(chi.URLParam(request, "asset")))
-//		history, err := exchangeEngine.GetWithdrawalsHistory(request.Context(), code)
-//		if err != nil {
-//			logrus.Errorf("failed fetch history: %s", err)
-//		}
-//		ctx = context.WithValue(request.Context(), "history", history)
-//		next.ServeHTTP(w, request.WithContext(ctx))
-//	})
-//}
+// withdrawHistoryCtx loads the withdrawal history of an asset on the exchange, merged with the withdrawals tracked by the dealer
+// withdraw/history/{exchange}/{asset}
+func withdrawHistoryCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		d, err := singleton.GetDealer(context.Background())
+		if err != nil {
+			logrus.Errorf("failed to get dealer: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		exchangeEngine, err := d.GetExchangeByName(chi.URLParam(request, "exchange"))
+		if err != nil {
+			logrus.Errorf("failed to return exchange %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		code := currency.NewCode(strings.ToUpper(chi.URLParam(request, "asset")))
+		history, err := transfer2.DefaultTracker().History(request.Context(), exchangeEngine, code)
+
+		response := &WithdrawHistoryResponse{History: history}
+		if err != nil {
+			logrus.Errorf("failed fetch history: %s\n", err)
+			response.Error = err.Error()
+		}
+
+		ctx := context.WithValue(request.Context(), "response", response)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
@@ -0,0 +1,67 @@
+package webserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romanornr/autodealer/dealer"
+	"github.com/romanornr/autodealer/singleton"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+)
+
+// killSwitchTimeout is how long cancelling and flattening may take, independent of the request
+const killSwitchTimeout = 2 * time.Minute
+
+// getKillSwitchResponse returns the report of the kill switch
+func getKillSwitchResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").(*dealer.KillReport)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	render.JSON(w, r, response)
+}
+
+// KillSwitchCtx halts the dealer and cancels every open order, with a quote currency the balances are flattened into it as well.
+// The report is returned even when some orders could not be cancelled, it lists them.
+// POST killswitch
+// POST killswitch/flatten/{quote}
+func KillSwitchCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		opts := dealer.KillOptions{Reason: "api request"}
+		if quote := chi.URLParam(request, "quote"); quote != "" {
+			opts.Flatten = true
+			opts.Quote = currency.NewCode(quote)
+		}
+
+		d, err := singleton.GetDealer(context.Background())
+		if err != nil {
+			logrus.Errorf("failed to get dealer: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		// the kill switch must not stop halfway when the client disconnects or the response times out,
+		// only the initiator is carried over from the request so the audit trail names who pulled it
+		ctx, cancel := context.WithTimeout(dealer.WithInitiator(context.Background(), dealer.Initiator(request.Context())), killSwitchTimeout)
+		defer cancel()
+
+		report, err := d.KillSwitch(ctx, opts)
+		if err != nil && !errors.Is(err, dealer.ErrKillIncomplete) {
+			logrus.Errorf("kill switch failed: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+		if err != nil {
+			logrus.Errorf("kill switch: %s\n", err)
+		}
+
+		next.ServeHTTP(w, request.WithContext(context.WithValue(request.Context(), "response", report)))
+	})
+}
@@ -1,12 +1,17 @@
 package webserver
 
 import (
+	"crypto/subtle"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httplog"
+	"github.com/romanornr/autodealer/dealer"
 	"github.com/rs/cors"
 	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -34,6 +39,47 @@ func corsMiddleware(config *CorsConfig) func(next http.Handler) http.Handler {
 	}
 }
 
+const (
+	// operatorHeader names the operator behind an API request
+	operatorHeader = "X-Operator"
+	// operatorTokenKey prefixes the configuration key holding the token of an operator, e.g. API_OPERATOR_ALICE
+	operatorTokenKey = "API_OPERATOR_"
+)
+
+// operator returns the operator named in the request, only when the request carries the token of the operator as bearer token
+func operator(r *http.Request) (string, bool) {
+	name := r.Header.Get(operatorHeader)
+	if name == "" {
+		return "", false
+	}
+
+	token := viper.GetString(operatorTokenKey + strings.ToUpper(name))
+	auth := r.Header.Get("Authorization")
+	if token == "" || !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(strings.TrimPrefix(auth, "Bearer "))) != 1 {
+		return "", false
+	}
+	return name, true
+}
+
+// initiatorMiddleware attributes the actions of a request to the API client in the audit log, by the operator it
+// authenticates as or else by its address. The initiator is the same for every request of a client, approvals compare
+// it with the requester.
+func initiatorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		initiator := "api " + r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			initiator = "api " + host
+		}
+		if name, ok := operator(r); ok {
+			initiator = "api operator " + name
+		}
+		next.ServeHTTP(w, r.WithContext(dealer.WithInitiator(r.Context(), initiator)))
+	})
+}
+
 // setupMiddleware sets up the common middleware used by the router
 func (s *Server) setupMiddleware() {
 	//logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Logger()
@@ -45,6 +91,7 @@ func (s *Server) setupMiddleware() {
 	//})
 	s.router.Use(middleware.RequestID)
 	s.router.Use(middleware.RealIP)
+	s.router.Use(initiatorMiddleware)
 	s.router.Use(httplog.RequestLogger(s.logger.Output(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Logger()))
 	s.router.Use(middleware.Recoverer)
 	s.router.Use(middleware.Timeout(60 * time.Second))
@@ -59,7 +106,7 @@ func (s *Server) setupMiddleware() {
 			http.MethodDelete,
 			http.MethodOptions,
 		},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", operatorHeader},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           900, // Maximum value not ignored by any of major browsers
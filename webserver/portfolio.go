@@ -0,0 +1,54 @@
+package webserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romanornr/autodealer/portfolio"
+	"github.com/romanornr/autodealer/singleton"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+)
+
+// getPortfolioResponse returns the portfolio over all exchanges
+func getPortfolioResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").(*portfolio.Portfolio)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	render.JSON(w, r, response)
+}
+
+// PortfolioCtx merges the holdings of all exchanges into positions valued in the quote currency, USD when none is given.
+// portfolio
+// portfolio/{quote}
+func PortfolioCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		quote := currency.USD
+		if q := chi.URLParam(request, "quote"); q != "" {
+			quote = currency.NewCode(strings.ToUpper(q))
+		}
+
+		d, err := singleton.GetDealer(context.Background())
+		if err != nil {
+			logrus.Errorf("failed to get dealer: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		p, err := portfolio.Aggregate(request.Context(), d, quote)
+		if err != nil {
+			logrus.Errorf("failed to aggregate portfolio: %s\n", err)
+			render.Render(w, request, ErrNotFound)
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), "response", p)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
@@ -4,13 +4,14 @@ import (
 	"context"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
-	"github.com/romanornr/autodealer/algo/shortestPath"
+	"github.com/romanornr/autodealer/pricing"
 	"github.com/romanornr/autodealer/singleton"
 	"github.com/sirupsen/logrus"
 	"github.com/thrasher-corp/gocryptotrader/currency"
 	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // PriceResponse represents the response from the price endpoint
@@ -20,6 +21,9 @@ type PriceResponse struct {
 	Quote     currency.Code `json:"quote"`
 	Price     float64       `json:"price"`
 	AssetType asset.Item    `json:"type"`
+	// Path are the conversions the price is derived from
+	Path []pricing.Step `json:"path"`
+	Time time.Time      `json:"time"`
 	// Error handling
 	Error string `json:"error,omitempty"` // TODO find out how to improve error handling for API response
 }
@@ -74,15 +78,21 @@ func PriceCtx(next http.Handler) http.Handler {
 		e, err := d.ExchangeManager.GetExchangeByName(response.Exchange)
 		if err != nil {
 			logrus.Errorf("Failed to get exchange: %s\n", err)
+			response.Error = err.Error()
+			ctx := context.WithValue(r.Context(), "response", &response)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
 		}
 
-		price, err := shortestPath.GetPrice(e, response.Base, response.Quote, response.AssetType)
+		conversion, err := pricing.Value(r.Context(), d, e, response.Base, response.Quote, response.AssetType)
 		if err != nil {
 			logrus.Errorf("Failed to get price: %s\n", err)
 			response.Error = err.Error()
 		}
 
-		response.Price = price
+		response.Price = conversion.Rate
+		response.Path = conversion.Path
+		response.Time = conversion.Time
 
 		ctx := context.WithValue(r.Context(), "response", &response)
 		next.ServeHTTP(w, r.WithContext(ctx))
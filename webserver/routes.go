@@ -2,26 +2,47 @@ package webserver
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
 )
 
 // Routes are API path constants.
 const (
 	routeAvailableTransferChains = "/transfer/chains/{exchange}/{asset}"
+	routeTransferNetworks        = "/transfer/networks/{source}/{destination}/{asset}"
+	routeTransfer                = "/transfer/{source}/{destination}/{asset}/{size}/{chain}"
+	routeTransferJobs            = "/transfer/jobs"
+	routeTransferJob             = "/transfer/jobs/{id}"
 	routeGetDepositAddr          = "/deposit/{exchange}/{asset}/{chain}"
 	routeWithdraw                = "/withdraw/{exchange}/{asset}/{size}/{destinationAddress}/{chain}"
 	routeGetWithdrawHistory      = "/withdraw/history/{exchange}/{asset}"
+	routeWithdrawPending         = "/withdraw/pending"
+	routeWithdrawApproval        = "/withdraw/pending/{id}/{action}"
+	routeAddressBook             = "/addressbook"
+	routeAddressBookEntry        = "/addressbook/{name}"
+	routeAddressBookAdd          = "/addressbook/{name}/{asset}/{chain}/{address}"
 	routePairs                   = "/pairs/{exchange}"
 	routeTrade                   = "/trade/{exchange}/{pair}/{qty}/{assetType}/{orderType}/{side}"
 	routeTWAP                    = "/twap/{exchange}/{pair}/{qty}/{assetType}/{orderType}/{side}/{hours}/{minutes}"
+	routeTWAPJobs                = "/twap/jobs"
+	routeTWAPJob                 = "/twap/jobs/{id}"
+	routeTWAPJobAction           = "/twap/jobs/{id}/{action}"
+	routeVWAP                    = "/vwap/{exchange}/{pair}/{qty}/{assetType}/{orderType}/{side}/{hours}/{minutes}"
 	routeGetTicker               = "/ticker/{exchange}/{base}/{quote}"
 	routePrice                   = "/price/{exchange}/{base}/{quote}/{assetType}"
 	routeMoveTermStructure       = "/move"
 	routeMoveStats               = "/move/stats"
 	routeBankTransfer            = "/bank/transfer/{currency}"
 	routeHoldingsExchange        = "/holdings/{exchange}/{asset}"
+	routePortfolio               = "/portfolio"
+	routePortfolioQuote          = "/portfolio/{quote}"
 	routeAssets                  = "/assets/{exchange}"
 	routeReferral                = "/referral"
+	routeKillSwitch              = "/killswitch"
+	routeKillSwitchFlatten       = "/killswitch/flatten/{quote}"
+	routeAudit                   = "/audit"
+	routeAuditAction             = "/audit/{action}"
+	routeAuditActionExchange     = "/audit/{action}/{exchange}"
 )
 
 // SetupRoutes configures the HTTP routes for the server. It takes a Handler object
@@ -39,6 +60,7 @@ func (s *Server) SetupRoutes(handler *Handler) *chi.Mux {
 	s.router.Get("/bank/transfer", handler.handleTemplate("bank.html"))
 	s.router.Get("/s", handler.handleTemplate("search.html"))
 	//r.Get("/move", MoveHandler) // http://127.0.0.1:3333/move
+	s.router.Handle("/metrics", promhttp.Handler()) // prometheus scrape endpoint
 
 	// func subrouter generates a new router for each sub route.
 	s.router.Mount("/api", apiSubrouter())
@@ -71,11 +93,41 @@ func apiSubrouter() http.Handler {
 		r.Get("/", getHoldingsExchangeResponse)
 	})
 
+	r.Route(routePortfolio, func(r chi.Router) {
+		r.Use(PortfolioCtx)
+		r.Get("/", getPortfolioResponse)
+	})
+
+	r.Route(routePortfolioQuote, func(r chi.Router) {
+		r.Use(PortfolioCtx)
+		r.Get("/", getPortfolioResponse)
+	})
+
 	r.Route(routeAvailableTransferChains, func(r chi.Router) {
 		r.Use(AvailableTransferChainsCtx)
 		r.Get("/", getAvailableTransferChainsResponse)
 	})
 
+	r.Route(routeTransferNetworks, func(r chi.Router) {
+		r.Use(TransferNetworksCtx)
+		r.Get("/", getTransferNetworksResponse)
+	})
+
+	r.Route(routeTransfer, func(r chi.Router) {
+		r.Use(TransferCtx)
+		r.Post("/", getTransferJobResponse)
+	})
+
+	r.Route(routeTransferJobs, func(r chi.Router) {
+		r.Use(TransferJobsCtx)
+		r.Get("/", getTransferJobsResponse)
+	})
+
+	r.Route(routeTransferJob, func(r chi.Router) {
+		r.Use(TransferJobCtx)
+		r.Get("/", getTransferJobResponse)
+	})
+
 	r.Route(routeGetDepositAddr, func(r chi.Router) {
 		r.Use(DepositAddressCtx)
 		r.Get("/", getDepositAddress)
@@ -86,9 +138,39 @@ func apiSubrouter() http.Handler {
 		r.Get("/", getExchangeWithdrawResponse)
 	})
 
+	r.Route(routeGetWithdrawHistory, func(r chi.Router) {
+		r.Use(withdrawHistoryCtx)
+		r.Get("/", getWithdrawHistory)
+	})
+
+	r.Route(routeWithdrawPending, func(r chi.Router) {
+		r.Use(PendingWithdrawalsCtx)
+		r.Get("/", getPendingWithdrawalsResponse)
+	})
+
+	r.Route(routeWithdrawApproval, func(r chi.Router) {
+		r.Use(WithdrawApprovalCtx)
+		r.Post("/", getExchangeWithdrawResponse)
+	})
+
+	r.Route(routeAddressBook, func(r chi.Router) {
+		r.Use(AddressBookCtx)
+		r.Get("/", getAddressBookResponse)
+	})
+
+	r.Route(routeAddressBookAdd, func(r chi.Router) {
+		r.Use(AddressBookCtx)
+		r.Post("/", getAddressBookResponse)
+	})
+
+	r.Route(routeAddressBookEntry, func(r chi.Router) {
+		r.Use(AddressBookCtx)
+		r.Delete("/", getAddressBookResponse)
+	})
+
 	r.Route(routeBankTransfer, func(r chi.Router) {
 		r.Use(BankTransferCtx)
-		r.Get("/", getBankTransfer)
+		r.Post("/", getBankTransfer)
 	})
 
 	r.Route(routeAssets, func(r chi.Router) {
@@ -100,5 +182,50 @@ func apiSubrouter() http.Handler {
 		r.Use(TWAPCtx)
 		r.Get("/", getTwapResponse)
 	})
+
+	r.Route(routeVWAP, func(r chi.Router) {
+		r.Use(VWAPCtx)
+		r.Get("/", getVwapResponse)
+	})
+
+	r.Route(routeTWAPJobs, func(r chi.Router) {
+		r.Use(TWAPJobsCtx)
+		r.Get("/", getTwapJobsResponse)
+	})
+
+	r.Route(routeTWAPJob, func(r chi.Router) {
+		r.Use(TWAPJobCtx)
+		r.Get("/", getTwapJobResponse)
+	})
+
+	r.Route(routeTWAPJobAction, func(r chi.Router) {
+		r.Use(TWAPJobActionCtx)
+		r.Post("/", getTwapJobResponse)
+	})
+
+	r.Route(routeKillSwitch, func(r chi.Router) {
+		r.Use(KillSwitchCtx)
+		r.Post("/", getKillSwitchResponse)
+	})
+
+	r.Route(routeKillSwitchFlatten, func(r chi.Router) {
+		r.Use(KillSwitchCtx)
+		r.Post("/", getKillSwitchResponse)
+	})
+
+	r.Route(routeAudit, func(r chi.Router) {
+		r.Use(AuditCtx)
+		r.Get("/", getAuditResponse)
+	})
+
+	r.Route(routeAuditAction, func(r chi.Router) {
+		r.Use(AuditCtx)
+		r.Get("/", getAuditResponse)
+	})
+
+	r.Route(routeAuditActionExchange, func(r chi.Router) {
+		r.Use(AuditCtx)
+		r.Get("/", getAuditResponse)
+	})
 	return r
 }
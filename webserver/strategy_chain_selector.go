@@ -1,91 +1,74 @@
 package webserver
 
 import (
-	"github.com/sirupsen/logrus"
+	"context"
+	"net/http"
 	"strings"
-)
-
-type ChainSelector interface {
-	SelectChain(chainReq string, availableTransferChains []string) string
-}
 
-type BinanceChainSelector struct{}
-type FTXChainSelector struct{}
-type HuobiChainSelector struct{}
-type KrakenCHainSelector struct{}
-type BTSEChainSelector struct{}
-type BittrexChainSelector struct{}
-
-func (c *BinanceChainSelector) SelectChain(chainReq string, availableTransferChains []string) string {
-	switch chainReq {
-	case "erc20":
-		return "ETH"
-	case "trx":
-		return "TRX"
-	case "sol":
-		return "SOL"
-	default:
-		return ""
-	}
-}
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romanornr/autodealer/singleton"
+	transfer2 "github.com/romanornr/autodealer/transfer"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+)
 
-func (c *FTXChainSelector) SelectChain(chainReq string, availableTransferChains []string) string {
-	switch chainReq {
-	case "erc20":
-		return "erc20"
-	case "trx":
-		return "trx"
-	case "sol":
-		return "sol"
-	default:
+// chainSelection returns the chain of the exchange that is the requested network, whatever the exchange calls it: erc20, ETH
+// and Ethereum are the same network. The exchange uses its default network for "default" or a network it does not support.
+func chainSelection(exchangeName string, code currency.Code, chainReq string, availableTransferChains []string) string {
+	if chainReq == "" || chainReq == "default" {
 		return ""
 	}
-}
 
-func (c *BittrexChainSelector) SelectChain(chainReq string, availableTransferChains []string) string {
-	switch chainReq {
-	case "erc20":
-		return "erc20"
-	case "trx":
-		return "trx"
-	case "sol":
-		return "sol"
-	default:
-		return ""
+	chain := transfer2.FindChain(code, availableTransferChains, chainReq)
+	if chain == "" {
+		logrus.Warnf("Chain Selection error: %s does not support %s for %s, available: %v\n", exchangeName, chainReq, code, availableTransferChains)
 	}
+	return chain
 }
 
-func GetChainSelector(exchangeName string) ChainSelector {
-	switch exchangeName {
-	case "Binance":
-		return &BinanceChainSelector{}
-	case "FTX":
-		return &FTXChainSelector{}
-	//case "Huobi":
-	//    return &HuobiChainSelector{}
-	//case "Kraken":
-	//    return &KrakenCHainSelector{}
-	//case "BTSE":
-	//    return &BTSEChainSelector{}
-	case "Bittrex":
-		return &BittrexChainSelector{}
-	default:
-		return nil
+// getTransferNetworksResponse returns the networks an asset can be transferred on
+func getTransferNetworksResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").([]transfer2.ChainOption)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
 	}
+	render.JSON(w, r, response)
 }
 
-func chainSelection(exchangeName string, chainReq string, availableTransferChains []string) string {
-	selector := GetChainSelector(strings.ToLower(exchangeName))
-	if selector == nil {
-		logrus.Warnf("Chain Selection error: Unsupported exchange: %s", exchangeName)
-	}
+// TransferNetworksCtx resolves the networks both exchanges support for the asset, cheapest first
+// transfer/networks/{source}/{destination}/{asset}
+func TransferNetworksCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		d, err := singleton.GetDealer(context.Background())
+		if err != nil {
+			logrus.Errorf("failed to get dealer: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
 
-	if len(availableTransferChains) == 0 {
-		logrus.Warnf("Chain Selection error: No multiple available transfer chains for exchange: %s")
-		//return selector.SelectChain(chainReq, availableTransferChains)
-	}
+		src, err := d.GetExchangeByName(chi.URLParam(request, "source"))
+		if err != nil {
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+		dst, err := d.GetExchangeByName(chi.URLParam(request, "destination"))
+		if err != nil {
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
 
-	chain := selector.SelectChain(chainReq, availableTransferChains)
+		code := currency.NewCode(strings.ToUpper(chi.URLParam(request, "asset")))
+		options, err := transfer2.ResolveChains(request.Context(), src, dst, code)
+		if err != nil {
+			logrus.Errorf("failed to resolve transfer networks: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
 
-	return chain
+		ctx := context.WithValue(request.Context(), "response", options)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
 }
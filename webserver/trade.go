@@ -126,7 +126,7 @@ func TradeCtx(next http.Handler) http.Handler {
 
 		newOrder.QuoteAmount = 15
 
-		submitResponse, err := d.SubmitOrderUD(context.Background(), e.GetName(), *newOrder, nil)
+		submitResponse, err := d.SubmitOrderUD(request.Context(), e.GetName(), *newOrder, nil)
 		if err != nil {
 			logrus.Errorf("submit order failed: %s\n", err)
 		}
@@ -228,6 +228,7 @@ func TWAPCtx(next http.Handler) http.Handler {
 		logrus.Printf("%s targetAmountQuote %f\n", p.String(), targetAmountQuote)
 
 		var orderPayload = twap.Payload{
+			ID:                twap.NewJobID(),
 			Exchange:          e.GetName(),
 			AccountID:         subAccount.ID,
 			Pair:              p,
@@ -239,6 +240,12 @@ func TWAPCtx(next http.Handler) http.Handler {
 			Side:              side,
 		}
 
+		if err = twapOptions(request, &orderPayload); err != nil {
+			logrus.Errorf("invalid twap options: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
 		task, err := twap.NewTwapTask(orderPayload)
 		if err != nil {
 			logrus.Errorf("failed to create twap order task %s\n", err)
@@ -268,3 +275,36 @@ func TWAPCtx(next http.Handler) http.Handler {
 		next.ServeHTTP(w, request.WithContext(ctx))
 	})
 }
+
+// twapOptions reads the optional twap parameters from the query string
+// ?minSlice=10&maxSlice=50&jitter=30s&limit=25000&participation=0.05
+func twapOptions(request *http.Request, p *twap.Payload) error {
+	query := request.URL.Query()
+
+	floats := map[string]*float64{
+		"minSlice":      &p.MinSliceQuote,
+		"maxSlice":      &p.MaxSliceQuote,
+		"limit":         &p.LimitPrice,
+		"participation": &p.MaxParticipation,
+	}
+	for key, value := range floats {
+		if query.Get(key) == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(query.Get(key), 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+		*value = f
+	}
+
+	if jitter := query.Get("jitter"); jitter != "" {
+		d, err := time.ParseDuration(jitter)
+		if err != nil {
+			return fmt.Errorf("failed to parse jitter: %w", err)
+		}
+		p.Jitter = d
+	}
+
+	return p.Validate()
+}
@@ -0,0 +1,111 @@
+package webserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romanornr/autodealer/singleton"
+	transfer2 "github.com/romanornr/autodealer/transfer"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+)
+
+// getTransferJobsResponse returns all transfer jobs
+func getTransferJobsResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").([]*transfer2.Job)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	render.JSON(w, r, response)
+}
+
+// getTransferJobResponse returns a single transfer job
+func getTransferJobResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").(*transfer2.Job)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	render.JSON(w, r, response)
+}
+
+// TransferCtx starts a job moving an asset from one exchange to another, the job is returned right away and runs in the background.
+// The chain "default" lets the job pick a network both exchanges support.
+// POST transfer/{source}/{destination}/{asset}/{size}/{chain}
+func TransferCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		d, err := singleton.GetDealer(context.Background())
+		if err != nil {
+			logrus.Errorf("failed to get dealer: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		size, err := strconv.ParseFloat(chi.URLParam(request, "size"), 64)
+		if err != nil {
+			logrus.Errorf("failed to convert size %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		chain := chi.URLParam(request, "chain")
+		if chain == "default" {
+			chain = ""
+		}
+		code := currency.NewCode(strings.ToUpper(chi.URLParam(request, "asset")))
+
+		job, err := transfer2.Transfers().Start(d, chi.URLParam(request, "source"), chi.URLParam(request, "destination"), code, size, chain)
+		if err != nil {
+			logrus.Errorf("failed to start transfer: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), "response", job)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
+
+// TransferJobsCtx loads all transfer jobs
+// transfer/jobs
+func TransferJobsCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		jobs, err := transfer2.Jobs().List(request.Context())
+		if err != nil {
+			logrus.Errorf("failed to list transfer jobs: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), "response", jobs)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
+
+// TransferJobCtx loads a transfer job by its ID
+// transfer/jobs/{id}
+func TransferJobCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		job, err := transfer2.Jobs().Get(request.Context(), chi.URLParam(request, "id"))
+		if errors.Is(err, transfer2.ErrJobNotFound) {
+			render.Render(w, request, ErrNotFound)
+			return
+		}
+		if err != nil {
+			logrus.Errorf("failed to get transfer job: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), "response", job)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
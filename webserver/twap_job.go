@@ -0,0 +1,109 @@
+package webserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/romanornr/autodealer/algo/twap"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrUnknownJobAction = errors.New("unknown job action")
+
+// getTwapJobsResponse returns all twap jobs
+func getTwapJobsResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").([]*twap.Job)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	render.JSON(w, r, response)
+}
+
+// getTwapJobResponse returns a single twap job
+func getTwapJobResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").(*twap.Job)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	render.JSON(w, r, response)
+}
+
+// TWAPJobsCtx loads all twap jobs from the job store
+// twap/jobs
+func TWAPJobsCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		jobs, err := twap.Jobs().List(request.Context())
+		if err != nil {
+			logrus.Errorf("failed to list twap jobs: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), "response", jobs)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
+
+// TWAPJobCtx loads a twap job by its ID
+// twap/jobs/{id}
+func TWAPJobCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		job, err := twap.Jobs().Get(request.Context(), chi.URLParam(request, "id"))
+		if errors.Is(err, twap.ErrJobNotFound) {
+			render.Render(w, request, ErrNotFound)
+			return
+		}
+		if err != nil {
+			logrus.Errorf("failed to get twap job: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), "response", job)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
+
+// TWAPJobActionCtx pauses, resumes or cancels a twap job
+// POST twap/jobs/{id}/{action}
+func TWAPJobActionCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		var (
+			job *twap.Job
+			err error
+			id  = chi.URLParam(request, "id")
+		)
+
+		switch action := chi.URLParam(request, "action"); action {
+		case "pause":
+			job, err = twap.Pause(request.Context(), id)
+		case "resume":
+			job, err = twap.Resume(request.Context(), id)
+		case "cancel":
+			job, err = twap.Cancel(request.Context(), id)
+		default:
+			err = fmt.Errorf("%w: %s", ErrUnknownJobAction, action)
+		}
+
+		if errors.Is(err, twap.ErrJobNotFound) {
+			render.Render(w, request, ErrNotFound)
+			return
+		}
+		if err != nil {
+			logrus.Errorf("twap job %s: %s\n", id, err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), "response", job)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
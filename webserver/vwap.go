@@ -0,0 +1,157 @@
+package webserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/hibiken/asynq"
+	"github.com/romanornr/autodealer/algo/twap"
+	"github.com/romanornr/autodealer/algo/vwap"
+	"github.com/romanornr/autodealer/singleton"
+	"github.com/sirupsen/logrus"
+	"github.com/thrasher-corp/gocryptotrader/currency"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/asset"
+	"github.com/thrasher-corp/gocryptotrader/exchanges/order"
+)
+
+// getVwapResponse returns the vwap response
+func getVwapResponse(w http.ResponseWriter, r *http.Request) {
+	response, ok := r.Context().Value("response").(*vwap.Payload)
+	if !ok {
+		logrus.Errorf("Got unexpected response %T\n", response)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	render.JSON(w, r, response)
+}
+
+// VWAPCtx is the context for the '/vwap' request, it takes the same parameters and query options as the '/twap' request.
+// The volume profile is configured with the optional query parameters ?lookback=168h&interval=15m
+// vwap/{exchange}/{pair}/{qty}/{assetType}/{orderType}/{side}/{hours}/{minutes}
+func VWAPCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		payload, err := vwapPayload(request)
+		if err != nil {
+			logrus.Errorf("invalid vwap request: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		task, err := vwap.NewVwapTask(*payload)
+		if err != nil {
+			logrus.Errorf("failed to create vwap task %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		client := asynq.NewClient(asynq.RedisClientOpt{Addr: redisAddr})
+		defer client.Close()
+
+		info, err := client.Enqueue(task)
+		if err != nil {
+			logrus.Errorf("could not enqueue vwap task: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
+		}
+
+		logrus.Printf("enqueued task: id=%s\n", info.ID)
+
+		ctx := context.WithValue(request.Context(), "response", payload)
+		next.ServeHTTP(w, request.WithContext(ctx))
+	})
+}
+
+// vwapPayload parses the vwap request
+func vwapPayload(request *http.Request) (*vwap.Payload, error) {
+	p, err := currency.NewPairFromString(chi.URLParam(request, "pair"))
+	if err != nil {
+		return nil, err
+	}
+
+	assetItem, err := asset.New(chi.URLParam(request, "assetType"))
+	if err != nil {
+		return nil, err
+	}
+
+	side, err := order.StringToOrderSide(chi.URLParam(request, "side"))
+	if err != nil {
+		return nil, err
+	}
+
+	orderType, err := order.StringToOrderType(chi.URLParam(request, "orderType"))
+	if err != nil {
+		return nil, err
+	}
+
+	qty, err := strconv.ParseFloat(chi.URLParam(request, "qty"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse qty: %w", err)
+	}
+
+	hours, err := strconv.ParseInt(chi.URLParam(request, "hours"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse hours: %w", err)
+	}
+
+	minutes, err := strconv.ParseInt(chi.URLParam(request, "minutes"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse minutes: %w", err)
+	}
+
+	d, err := singleton.GetDealer(request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := d.ExchangeManager.GetExchangeByName(chi.URLParam(request, "exchange"))
+	if err != nil {
+		return nil, err
+	}
+
+	subAccount, err := GetSubAccountByID(e, "")
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	payload := vwap.Payload{
+		Order: twap.Payload{
+			ID:                twap.NewJobID(),
+			Exchange:          e.GetName(),
+			AccountID:         subAccount.ID,
+			Pair:              p,
+			Asset:             assetItem,
+			Start:             start,
+			End:               start.Add(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute),
+			OrderType:         orderType,
+			TargetAmountQuote: qty,
+			Side:              side,
+		},
+	}
+
+	if err = twapOptions(request, &payload.Order); err != nil {
+		return nil, err
+	}
+
+	query := request.URL.Query()
+	if lookback := query.Get("lookback"); lookback != "" {
+		if payload.Lookback, err = time.ParseDuration(lookback); err != nil {
+			return nil, fmt.Errorf("failed to parse lookback: %w", err)
+		}
+	}
+	if interval := query.Get("interval"); interval != "" {
+		if payload.Interval, err = time.ParseDuration(interval); err != nil {
+			return nil, fmt.Errorf("failed to parse interval: %w", err)
+		}
+		if err = vwap.ValidateInterval(payload.Interval); err != nil {
+			return nil, err
+		}
+	}
+
+	return &payload, nil
+}
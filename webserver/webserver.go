@@ -8,6 +8,7 @@ import (
 	"github.com/go-chi/httplog"
 	"github.com/hibiken/asynq"
 	"github.com/romanornr/autodealer/algo/twap"
+	"github.com/romanornr/autodealer/algo/vwap"
 	"github.com/romanornr/autodealer/config"
 	"github.com/romanornr/autodealer/singleton"
 	"github.com/rs/zerolog"
@@ -157,6 +158,7 @@ func asyncWebWorker() {
 	mux := asynq.NewServeMux()
 	mux.HandleFunc(twap.TypeTwap, twap.HandleTwapTask) ///  TODO find url 127.0.0.1:3333/twap ??
 	mux.HandleFunc(twap.TypeOrder, twap.HandleOrderTask)
+	mux.HandleFunc(vwap.TypeVwap, vwap.HandleVwapTask)
 	// ...register other handlers...
 
 	if err := srv.Run(mux); err != nil {
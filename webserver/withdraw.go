@@ -2,6 +2,7 @@ package webserver
 
 import (
 	"context"
+	"errors"
 	"github.com/romanornr/autodealer/singleton"
 	"net/http"
 	"strconv"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/romanornr/autodealer/addressbook"
 	transfer2 "github.com/romanornr/autodealer/transfer"
 	"github.com/sirupsen/logrus"
 	"github.com/thrasher-corp/gocryptotrader/currency"
@@ -41,9 +43,9 @@ type ExchangeWithdrawResponse struct {
 // expected for what the function defines.
 func getExchangeWithdrawResponse(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	exchangeResponse, ok := ctx.Value("response").(*transfer2.ExchangeWithdrawResponse) // TODO fix
+	exchangeResponse, ok := ctx.Value("response").(*transfer2.BookWithdrawResponse)
 	if !ok {
-		logrus.Errorf("Got unexpected response %T instead of *ExchangeWithdrawResponse", exchangeResponse)
+		logrus.Errorf("Got unexpected response %T instead of *BookWithdrawResponse", exchangeResponse)
 		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
 		render.JSON(w, r, http.StatusUnprocessableEntity)
 		return
@@ -53,8 +55,9 @@ func getExchangeWithdrawResponse(w http.ResponseWriter, r *http.Request) {
 }
 
 // WithdrawCtx is an HTTP handler function which stores the request input with the help of chi.URLParams get method
-// in the response and call the createExchangeWithdrawResponse to create an exchange withdrawal transaction
-// for the specified exchange
+// in the response and withdraws to the destination when it is whitelisted in the address book.
+// The destination is the name of an address book entry or its address. Withdrawals above the approval threshold are only
+// registered, they are executed once approved with withdraw/pending/{id}/approve.
 func WithdrawCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
 		var err error
@@ -63,15 +66,19 @@ func WithdrawCtx(next http.Handler) http.Handler {
 		d, err := singleton.GetDealer(context.Background()) //d, err := dealer.NewBuilder().Build()
 		if err != nil {
 			logrus.Errorf("failed to create a dealer %s\n", err)
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
 		}
 
+		book, err := addressbook.Default()
 		if err != nil {
-			logrus.Errorf("failed to create a dealer %s\n", err)
+			logrus.Errorf("failed to open address book %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
 		}
 
 		exchangeNameReq := chi.URLParam(request, "exchange")
-		destinationAddress := chi.URLParam(request, "destinationAddress")
+		destination := chi.URLParam(request, "destinationAddress")
 		sizeReq := chi.URLParam(request, "size")
 		assetInfo.AssocChain = chi.URLParam(request, "chain")
 		if assetInfo.AssocChain == "default" {
@@ -81,8 +88,8 @@ func WithdrawCtx(next http.Handler) http.Handler {
 		size, err := strconv.ParseFloat(sizeReq, 64)
 		if err != nil {
 			logrus.Errorf("failed to convert size %s\n", err)
-			render.Status(request, http.StatusUnprocessableEntity)
-			render.JSON(w, request, http.StatusUnprocessableEntity)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
 		}
 
 		assetInfo.Code = currency.NewCode(strings.ToUpper(chi.URLParam(request, "asset")))
@@ -91,24 +98,18 @@ func WithdrawCtx(next http.Handler) http.Handler {
 		exchangeEngine, err := d.ExchangeManager.GetExchangeByName(exchangeNameReq)
 		if err != nil {
 			logrus.Errorf("failed to return exchange %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
 		}
 
-		wi := &withdraw.Request{
-			Exchange: exchangeEngine.GetName(),
-			Currency: assetInfo.Code,
-			Amount:   size,
-			Type:     withdraw.Crypto,
-			Crypto: withdraw.CryptoRequest{
-				Address:    destinationAddress,
-				AddressTag: "",
-				FeeAmount:  0,
-				Chain:      assetInfo.AssocChain,
-			},
+		response, err := transfer2.WithdrawToBook(request.Context(), d, book, exchangeEngine, assetInfo.Code, assetInfo.AssocChain, destination, size)
+		if errors.Is(err, addressbook.ErrNotWhitelisted) || errors.Is(err, addressbook.ErrCoolingOff) {
+			logrus.Errorf("refused withdrawal: %s\n", err)
+			render.Render(w, request, ErrInvalidRequest(err))
+			return
 		}
-
-		response, err := transfer2.CreateExchangeWithdrawResponse(wi, exchangeEngine)
 		if err != nil {
-			render.JSON(w, request, ErrRender(err))
+			logrus.Errorf("failed withdrawal: %s\n", err)
 		}
 
 		logrus.Infof("exchange withdraw response %v\n", response)